@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"net/netip"
+)
+
+// mathResult is an address moved by an offset.
+type mathResult struct {
+	Address string `json:"address"`
+	Offset  string `json:"offset"` // signed decimal
+	Result  string `json:"result"`
+}
+
+// distanceResult is how far apart two addresses are.
+type distanceResult struct {
+	A        string  `json:"a"`
+	B        string  `json:"b"`
+	Distance string  `json:"distance"` // decimal, as it can exceed 64 bits
+	Log2     float64 `json:"log2"`     // 0 when the addresses are equal
+}
+
+// addressDistance returns the absolute difference between two addresses.
+func addressDistance(a, b netip.Addr) *big.Int {
+	d := new(big.Int).Sub(ipToBigInt(a.AsSlice()), ipToBigInt(b.AsSlice()))
+	return d.Abs(d)
+}
+
+// formatDistance writes a distance with its power of two, exact when it is one
+// and to two decimal places otherwise: 4096 = 2^12, 5000 ~ 2^12.29.
+func formatDistance(d *big.Int) string {
+	switch {
+	case d.Sign() == 0:
+		return "0"
+	case new(big.Int).And(d, new(big.Int).Sub(d, big.NewInt(1))).Sign() == 0:
+		return fmt.Sprintf("%s = 2^%d", d, d.BitLen()-1)
+	}
+	return fmt.Sprintf("%s ~ 2^%.2f", d, bigLog2(d))
+}
+
+// runDistance prints how many addresses apart a and b are.
+func runDistance(a, b string) {
+	x, err := parseIPv6Addr(a)
+	if err != nil {
+		log.Fatal(err)
+	}
+	y, err := parseIPv6Addr(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d := addressDistance(x, y)
+	result := distanceResult{A: x.String(), B: y.String(), Distance: d.String()}
+	if d.Sign() > 0 {
+		result.Log2 = bigLog2(d)
+	}
+	writeResult(result, func() {
+		fmt.Println(formatDistance(d))
+	})
+}
+
+// parseOffset parses an offset in decimal or, with a 0x prefix, hex, with an
+// optional sign: 42, +0x1000, -0x10.
+func parseOffset(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid offset %q: want a decimal or 0x hex number", s)
+	}
+	return n, nil
+}
+
+// offsetAddress adds offset, which may be negative, to an address. Unlike
+// addBigIntToIP it refuses to wrap past either end of the address space.
+func offsetAddress(addr netip.Addr, offset *big.Int) (netip.Addr, error) {
+	sum := new(big.Int).Add(ipToBigInt(addr.AsSlice()), offset)
+	if sum.Sign() < 0 || sum.BitLen() > 128 {
+		return netip.Addr{}, fmt.Errorf("%s %+d is outside the IPv6 address space", addr, offset)
+	}
+	return netip.AddrFrom16([16]byte(addBigIntToIP(addr.AsSlice(), offset))), nil
+}
+
+// runMath prints address plus offset, or with subtract, address minus offset.
+func runMath(address, offset string, subtract bool) {
+	addr, err := parseIPv6Addr(address)
+	if err != nil {
+		log.Fatal(err)
+	}
+	n, err := parseOffset(offset)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if subtract {
+		n.Neg(n)
+	}
+	sum, err := offsetAddress(addr, n)
+	if err != nil {
+		log.Fatal(err)
+	}
+	result := mathResult{Address: addrText(addr), Offset: n.String(), Result: addrText(sum)}
+	writeResult(result, func() {
+		fmt.Println(result.Result)
+	})
+}
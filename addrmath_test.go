@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math/big"
+	"net/netip"
+	"testing"
+)
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+	}{
+		{"42", 42},
+		{"+0x1000", 4096},
+		{"0X10", 16},
+		{"-0x10", -16},
+		{"-7", -7},
+	}
+	for _, tt := range tests {
+		got, err := parseOffset(tt.s)
+		if err != nil || got.Int64() != tt.want {
+			t.Errorf("%s: got %v, %v, want %d", tt.s, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "0x", "12g", "1.5"} {
+		if _, err := parseOffset(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestOffsetAddress(t *testing.T) {
+	tests := []struct {
+		addr   string
+		offset string
+		want   string
+	}{
+		{"2001:db8::1", "0x1000", "2001:db8::1001"},
+		{"2001:db8::ffff", "1", "2001:db8::1:0"},
+		{"2001:db8:1::", "-1", "2001:db8:0:ffff:ffff:ffff:ffff:ffff"},
+		{"2001:db8::", "0x10000000000000000", "2001:db8:0:1::"},
+		{"::1", "-1", "::"},
+	}
+	for _, tt := range tests {
+		n, _ := new(big.Int).SetString(tt.offset, 0)
+		got, err := offsetAddress(netip.MustParseAddr(tt.addr), n)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s %s: got %v, %v, want %s", tt.addr, tt.offset, got, err, tt.want)
+		}
+	}
+	if _, err := offsetAddress(netip.MustParseAddr("::"), big.NewInt(-1)); err == nil {
+		t.Error("wrapped below ::")
+	}
+	if _, err := offsetAddress(netip.MustParseAddr("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), big.NewInt(1)); err == nil {
+		t.Error("wrapped past the last address")
+	}
+}
+
+func TestAddressDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"2001:db8::1", "2001:db8::1", "0"},
+		{"2001:db8::", "2001:db8::1000", "4096 = 2^12"},
+		{"2001:db8::1000", "2001:db8::", "4096 = 2^12"},
+		{"2001:db8::", "2001:db8::1388", "5000 ~ 2^12.29"},
+		{"2001:db8::", "2001:db8:0:1::", "18446744073709551616 = 2^64"},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "340282366920938463463374607431768211455 ~ 2^128.00"},
+	}
+	for _, tt := range tests {
+		got := formatDistance(addressDistance(netip.MustParseAddr(tt.a), netip.MustParseAddr(tt.b)))
+		if got != tt.want {
+			t.Errorf("%s %s: got %s, want %s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"time"
+)
+
+// allocResult is the JSON form of an allocation.
+type allocResult struct {
+	Parent   string   `json:"parent"`
+	Length   int      `json:"length"`
+	Prefixes []string `json:"prefixes"`
+}
+
+// nextFreeSubnets returns the first count subnets of newLen within parent that
+// overlap none of the allocations, in address order or the given order. It
+// returns fewer when the parent runs out.
+func nextFreeSubnets(parent netip.Prefix, newLen int, allocated []prefixEntry, count int, order subnetOrder) []netip.Prefix {
+	var free []netip.Prefix
+	for p := range limitSubnets(orderedSubnetSeq(parent, newLen, nil, allocated, order), count) {
+		free = append(free, p)
+	}
+	return free
+}
+
+// readAllocations reads an allocation file, leaving out entries past their
+// expires= date: those are free again even before -gc removes them.
+func readAllocations(path string) ([]prefixEntry, error) {
+	if path == "" {
+		return nil, nil
+	}
+	entries, err := readPrefixFile(path)
+	if err != nil {
+		return nil, err
+	}
+	active, _, err := splitExpired(entries, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return active, nil
+}
+
+// allocNextOptions are the settings of alloc next beyond the prefix and length.
+type allocNextOptions struct {
+	AllocatedFile string
+	Count         int
+	DB            string // allocation database to read, and to record in with Claim
+	Claim         bool   // record the subnets found in the database
+	Name          string // name of the claimed subnets
+	Strategy      string // RFC 3531 allocation order; empty for address order
+	Seed          int64  // for Strategy random; 0 for a new seed each run
+}
+
+// runAllocNext prints the first free subnets of newLen within prefix, given
+// the allocations in the allocation file and, with -db or -claim, the
+// database.
+func runAllocNext(prefix string, newLen int, opts allocNextOptions) {
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if newLen < parent.Bits() || newLen > 128 {
+		log.Fatalf("new prefix length must be between /%d and /128", parent.Bits())
+	}
+	if opts.Count < 1 {
+		log.Fatal("count must be at least 1")
+	}
+	if opts.Strategy == strategyRandom {
+		if opts.Seed, err = sampleSeed(opts.Seed); err != nil {
+			log.Fatal(err)
+		}
+	}
+	order, err := parseSubnetStrategy(opts.Strategy, newLen-parent.Bits(), opts.Seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	allocated, err := readAllocations(opts.AllocatedFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	next := func(allocated []prefixEntry) ([]netip.Prefix, error) {
+		free := nextFreeSubnets(parent, newLen, allocated, opts.Count, order)
+		if len(free) < opts.Count {
+			return nil, fmt.Errorf("only %d free /%d subnet(s) left in %s, %d requested", len(free), newLen, parent, opts.Count)
+		}
+		return free, nil
+	}
+	var free []netip.Prefix
+	if opts.DB == "" && !opts.Claim {
+		if free, err = next(allocated); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		// The database stays locked until the claim is saved, so two
+		// concurrent runs cannot hand out the same subnet.
+		withAllocDB(opts.DB, func(db *allocDB) (bool, error) {
+			active, _, err := splitExpired(db.entries(), time.Now())
+			if err != nil {
+				return false, err
+			}
+			if free, err = next(append(allocated, active...)); err != nil || !opts.Claim {
+				return false, err
+			}
+			records := make([]allocRecord, len(free))
+			for i, p := range free {
+				records[i] = allocRecord{Prefix: p, Name: opts.Name}
+			}
+			return true, db.add(records, time.Now().UTC())
+		})
+	}
+	result := allocResult{Parent: parent.String(), Length: newLen}
+	for _, p := range free {
+		result.Prefixes = append(result.Prefixes, p.String())
+	}
+	writeResult(result, func() {
+		for _, p := range result.Prefixes {
+			fmt.Println(p)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestNextFreeSubnets(t *testing.T) {
+	allocated, err := readPrefixEntries(strings.NewReader("3fff::/48 a\n3fff:0:1:10::/64 b\n3fff:0:2::/47 c\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := netip.MustParsePrefix("3fff::/44")
+	tests := []struct {
+		newLen, count int
+		want          string
+	}{
+		// 3fff:0:1::/48 holds an allocated /64, so it is not free as a whole.
+		{48, 1, "3fff:0:4::/48"},
+		{48, 3, "3fff:0:4::/48 3fff:0:5::/48 3fff:0:6::/48"},
+		{64, 2, "3fff:0:1::/64 3fff:0:1:1::/64"},
+		{60, 2, "3fff:0:1::/60 3fff:0:1:20::/60"},
+		// Only one /45 is free: fewer than requested.
+		{45, 2, "3fff:0:8::/45"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range nextFreeSubnets(parent, tt.newLen, allocated, tt.count, nil) {
+			got = append(got, p.String())
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("/%d x%d: got %v, want %s", tt.newLen, tt.count, got, tt.want)
+		}
+	}
+}
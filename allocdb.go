@@ -0,0 +1,314 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultAllocDB is the allocation database the alloc commands use when
+// neither -db nor IPV6UTILS_ALLOC_DB names one.
+const defaultAllocDB = "allocations.json"
+
+// allocLockWait is how long the alloc commands wait for another one to finish
+// with the database.
+const allocLockWait = 5 * time.Second
+
+// allocRecord is one tracked assignment.
+type allocRecord struct {
+	Prefix  netip.Prefix `json:"prefix"`
+	Name    string       `json:"name,omitempty"` // free text, as in a prefix list, including expires=
+	Created time.Time    `json:"created"`
+}
+
+// entry converts the record to a prefix-list entry.
+func (r allocRecord) entry() prefixEntry {
+	return prefixEntry{Net: prefixToIPNet(r.Prefix), Label: r.Name}
+}
+
+// allocDB is the persistent allocation database: a JSON file of assignments in
+// address order. Changes are written to a temporary file renamed over the old
+// one, so an interruption leaves either the old database or the new one, and a
+// lock file keeps two commands from changing it at once.
+type allocDB struct {
+	Allocations []allocRecord `json:"allocations"`
+
+	path string
+	lock string
+}
+
+// openAllocDB locks and reads the database at path; a missing file is an empty
+// database. Close releases the lock.
+func openAllocDB(path string) (*allocDB, error) {
+	db := &allocDB{path: path, lock: path + ".lock"}
+	deadline := time.Now().Add(allocLockWait)
+	for {
+		f, err := os.OpenFile(db.lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			fmt.Fprintln(f, os.Getpid())
+			f.Close()
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another command; remove %s if none is running", path, db.lock)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return db, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(data, db)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return db, nil
+}
+
+// Close releases the database lock.
+func (db *allocDB) Close() error {
+	return os.Remove(db.lock)
+}
+
+// save writes the database back.
+func (db *allocDB) save() error {
+	slices.SortFunc(db.Allocations, func(a, b allocRecord) int {
+		if c := a.Prefix.Addr().Compare(b.Prefix.Addr()); c != 0 {
+			return c
+		}
+		return a.Prefix.Bits() - b.Prefix.Bits()
+	})
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(db.path), ".alloc-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), db.path)
+}
+
+// entries returns the allocations as prefix-list entries.
+func (db *allocDB) entries() []prefixEntry {
+	entries := make([]prefixEntry, len(db.Allocations))
+	for i, r := range db.Allocations {
+		entries[i] = r.entry()
+	}
+	return entries
+}
+
+// add records allocations, refusing any that overlaps an existing one or
+// another of those being added. Either all are added or none.
+func (db *allocDB) add(records []allocRecord, now time.Time) error {
+	var conflicts []string
+	for i, r := range records {
+		if r.Prefix.Addr().Is4In6() {
+			return fmt.Errorf("%s is not an IPv6 prefix", r.Prefix)
+		}
+		if _, _, err := entryExpiry(r.entry()); err != nil {
+			return fmt.Errorf("%s: %v", r.Prefix, err)
+		}
+		for _, other := range append(slices.Clone(db.Allocations), records[:i]...) {
+			if other.Prefix.Overlaps(r.Prefix) {
+				conflicts = append(conflicts, fmt.Sprintf("%s overlaps %s", r.Prefix, describeEntry(other.entry())))
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return errors.New(strings.Join(conflicts, "; "))
+	}
+	for _, r := range records {
+		r.Prefix = r.Prefix.Masked()
+		if r.Created.IsZero() {
+			r.Created = now
+		}
+		db.Allocations = append(db.Allocations, r)
+	}
+	return nil
+}
+
+// free removes the allocation of exactly prefix.
+func (db *allocDB) free(prefix netip.Prefix) (allocRecord, error) {
+	for i, r := range db.Allocations {
+		if r.Prefix == prefix.Masked() {
+			db.Allocations = slices.Delete(db.Allocations, i, i+1)
+			return r, nil
+		}
+	}
+	return allocRecord{}, fmt.Errorf("%s is not allocated", prefix)
+}
+
+// within returns the allocations inside parent, or all of them when parent is
+// not valid.
+func (db *allocDB) within(parent netip.Prefix) []allocRecord {
+	var records []allocRecord
+	for _, r := range db.Allocations {
+		if !parent.IsValid() || parent.Bits() <= r.Prefix.Bits() && parent.Contains(r.Prefix.Addr()) {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// allocDBPath is the database -db names, or else IPV6UTILS_ALLOC_DB or
+// defaultAllocDB.
+func allocDBPath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv("IPV6UTILS_ALLOC_DB"); env != "" {
+		return env
+	}
+	return defaultAllocDB
+}
+
+// withAllocDB opens the database, runs fn, and saves the database when fn
+// reports a change. Errors are fatal.
+func withAllocDB(path string, fn func(db *allocDB) (changed bool, err error)) {
+	db, err := openAllocDB(allocDBPath(path))
+	if err != nil {
+		log.Fatal(err)
+	}
+	changed, err := fn(db)
+	if err == nil && changed {
+		err = db.save()
+	}
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseAllocPrefix parses a prefix given to alloc add or alloc free.
+func parseAllocPrefix(s string) (netip.Prefix, error) {
+	ipnet, err := parseIPv6Prefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid prefix: %v", err)
+	}
+	return entryPrefix(prefixEntry{Net: ipnet}), nil
+}
+
+// runAllocAdd records an allocation in the database.
+func runAllocAdd(dbPath, prefix, name string) {
+	p, err := parseAllocPrefix(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	withAllocDB(dbPath, func(db *allocDB) (bool, error) {
+		if err := db.add([]allocRecord{{Prefix: p, Name: name}}, time.Now().UTC()); err != nil {
+			return false, err
+		}
+		statusf("Allocated %s\n", p)
+		return true, nil
+	})
+}
+
+// runAllocFree releases an allocation from the database.
+func runAllocFree(dbPath, prefix string) {
+	p, err := parseAllocPrefix(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	withAllocDB(dbPath, func(db *allocDB) (bool, error) {
+		r, err := db.free(p)
+		if err != nil {
+			return false, err
+		}
+		statusf("Freed %s\n", describeEntry(r.entry()))
+		return true, nil
+	})
+}
+
+// runAllocList prints the allocations in the database, or those within a
+// prefix, with when each was made.
+func runAllocList(dbPath, within string) {
+	var parent netip.Prefix
+	if within != "" {
+		var err error
+		if parent, err = parseSubnetParent(within); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var records []allocRecord
+	withAllocDB(dbPath, func(db *allocDB) (bool, error) {
+		records = db.within(parent)
+		return false, nil
+	})
+	if records == nil {
+		records = []allocRecord{}
+	}
+	writeResult(records, func() {
+		for _, r := range records {
+			fmt.Printf("%-44s %-12s %s\n", r.Prefix, r.Created.Format(time.DateOnly), r.Name)
+		}
+	})
+}
+
+// runAllocImport adds the entries of a prefix list to the database.
+func runAllocImport(dbPath, file string) {
+	entries, err := readPrefixFile(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	records := make([]allocRecord, len(entries))
+	for i, e := range entries {
+		records[i] = allocRecord{Prefix: entryPrefix(e), Name: e.Label}
+	}
+	withAllocDB(dbPath, func(db *allocDB) (bool, error) {
+		if err := db.add(records, time.Now().UTC()); err != nil {
+			return false, fmt.Errorf("%s: %v", file, err)
+		}
+		statusf("Imported %d allocations from %s\n", len(records), file)
+		return true, nil
+	})
+}
+
+// runAllocExport writes the database as a prefix list, which every tool that
+// reads plans and allocation files takes.
+func runAllocExport(dbPath, outputFile string) {
+	withAllocDB(dbPath, func(db *allocDB) (bool, error) {
+		out, err := createOutput(outputFile, false)
+		if err != nil {
+			return false, err
+		}
+		err = writePrefixList(out, db.entries())
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return false, err
+		}
+		if outputFile != "" {
+			statusf("%d allocations saved to %s\n", len(db.Allocations), outputFile)
+		}
+		return false, nil
+	})
+}
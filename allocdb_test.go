@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllocDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allocations.json")
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	db, err := openAllocDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.add([]allocRecord{
+		{Prefix: netip.MustParsePrefix("3fff:0:2::/48"), Name: "denver"},
+		{Prefix: netip.MustParsePrefix("3fff:0:1::/48"), Name: "chicago"},
+	}, now); err != nil {
+		t.Fatal(err)
+	}
+	// One conflict refuses the whole batch.
+	err = db.add([]allocRecord{
+		{Prefix: netip.MustParsePrefix("3fff:0:3::/48")},
+		{Prefix: netip.MustParsePrefix("3fff:0:1:5::/64")},
+	}, now)
+	if err == nil || !strings.Contains(err.Error(), "3fff:0:1:5::/64 overlaps 3fff:0:1::/48 (chicago)") {
+		t.Errorf("overlapping add: got %v", err)
+	}
+	if err := db.add([]allocRecord{{Prefix: netip.MustParsePrefix("3fff:0:9::/48"), Name: "x expires=someday"}}, now); err == nil {
+		t.Error("add with a bad expiry date succeeded")
+	}
+	if len(db.Allocations) != 2 {
+		t.Fatalf("got %d allocations, want 2", len(db.Allocations))
+	}
+	if err := db.save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = openAllocDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := db.Allocations; len(got) != 2 || got[0].Name != "chicago" || !got[1].Created.Equal(now) {
+		t.Errorf("reopened database: got %+v", got)
+	}
+	if got := db.within(netip.MustParsePrefix("3fff:0:2::/47")); len(got) != 1 || got[0].Name != "denver" {
+		t.Errorf("within: got %+v", got)
+	}
+	if r, err := db.free(netip.MustParsePrefix("3fff:0:1::/48")); err != nil || r.Name != "chicago" {
+		t.Errorf("free: got %+v, %v", r, err)
+	}
+	if _, err := db.free(netip.MustParsePrefix("3fff:0:1::/48")); err == nil {
+		t.Error("freeing an unallocated prefix succeeded")
+	}
+}
+
+func TestAllocDBLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allocations.json")
+	db, err := openAllocDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		other, err := openAllocDB(path)
+		if err == nil {
+			err = other.Close()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("second open did not wait for the lock: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	db.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAllocDBPath(t *testing.T) {
+	t.Setenv("IPV6UTILS_ALLOC_DB", "")
+	if got := allocDBPath(""); got != defaultAllocDB {
+		t.Errorf("default: got %s", got)
+	}
+	t.Setenv("IPV6UTILS_ALLOC_DB", "/var/lib/ipam.json")
+	if got := allocDBPath(""); got != "/var/lib/ipam.json" {
+		t.Errorf("environment: got %s", got)
+	}
+	if got := allocDBPath("mine.json"); got != "mine.json" {
+		t.Errorf("flag: got %s", got)
+	}
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+)
+
+// anonymizer pseudonymizes addresses with a key, preserving prefixes as
+// Crypto-PAn does: each bit after the first Preserve is flipped or not by a
+// keyed hash of the bits before it, so two addresses sharing their first n
+// bits still do once anonymized, and the same address always gives the same
+// result. Without the key the mapping can be neither undone nor repeated.
+type anonymizer struct {
+	Key      []byte
+	Preserve int // leading bits kept as they are
+}
+
+func newAnonymizer(key string, preserve int) (*anonymizer, error) {
+	if key == "" {
+		return nil, errors.New("a key must be given with -key")
+	}
+	if preserve < 0 || preserve > 128 {
+		return nil, fmt.Errorf("preserved prefix length must be between 0 and 128, got %d", preserve)
+	}
+	return &anonymizer{Key: []byte(key), Preserve: preserve}, nil
+}
+
+// anonymize returns the pseudonym of a. Unspecified, loopback, and multicast
+// addresses, which say nothing of a network, are kept.
+func (z *anonymizer) anonymize(a netip.Addr) netip.Addr {
+	switch classifyAddress(a).Class {
+	case "unspecified", "loopback", "multicast":
+		return a
+	}
+	in := a.As16()
+	out := in
+	mac := hmac.New(sha256.New, z.Key)
+	for bit := z.Preserve; bit < 128; bit++ {
+		// The hash is of the bits before this one, and their number.
+		prefix := netip.PrefixFrom(a, bit).Masked().Addr().As16()
+		mac.Reset()
+		mac.Write(prefix[:])
+		mac.Write([]byte{byte(bit)})
+		if mac.Sum(nil)[0]&0x80 != 0 {
+			out[bit/8] ^= 0x80 >> (bit % 8)
+		}
+	}
+	return netip.AddrFrom16(out)
+}
+
+// runAnonymize prints the pseudonym of an address, or with an address of "-",
+// copies stdin to stdout with every address in it pseudonymized, so logs can be
+// shared for analysis without the addresses in them, while addresses on the
+// same subnet stay together. In JSON and the other formats it lists the
+// addresses found and their pseudonyms.
+func runAnonymize(address, key string, preserve int) {
+	z, err := newAnonymizer(key, preserve)
+	if err != nil {
+		log.Fatal(err)
+	}
+	runRewrite(address, func(a netip.Addr) string {
+		return addrText(z.anonymize(a))
+	})
+}
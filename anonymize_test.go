@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestAnonymizePreservesPrefixes(t *testing.T) {
+	z, err := newAnonymizer("s3cret", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs := []string{"2606:4700:10:5::1", "2606:4700:10:5::2", "2606:4700:10:6::1", "2606:4700:11::1", "2a00:1450::1", "fd12:3456::1"}
+	for _, x := range addrs {
+		for _, y := range addrs {
+			a, b := netip.MustParseAddr(x), netip.MustParseAddr(y)
+			if got, want := commonBits(z.anonymize(a), z.anonymize(b)), commonBits(a, b); got != want {
+				t.Errorf("%s and %s share %d bits, their pseudonyms %d", x, y, want, got)
+			}
+		}
+	}
+	a := netip.MustParseAddr("2606:4700:10:5::1")
+	if z.anonymize(a) == a {
+		t.Error("address not changed")
+	}
+	if z.anonymize(a) != z.anonymize(a) {
+		t.Error("same address, different pseudonyms")
+	}
+	other, _ := newAnonymizer("other", 0)
+	if other.anonymize(a) == z.anonymize(a) {
+		t.Error("different keys, same pseudonym")
+	}
+}
+
+func TestAnonymizePreserve(t *testing.T) {
+	z, err := newAnonymizer("s3cret", 48)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := netip.MustParseAddr("2606:4700:10:5::1")
+	if got := z.anonymize(a); commonBits(a, got) < 48 {
+		t.Errorf("%s: got %s, want the first 48 bits kept", a, got)
+	}
+	for _, keep := range []string{"::", "::1", "ff02::1:ff00:1"} {
+		if got := z.anonymize(netip.MustParseAddr(keep)); got.String() != keep {
+			t.Errorf("%s: got %s, want it kept", keep, got)
+		}
+	}
+}
+
+func TestAnonymizePrefixText(t *testing.T) {
+	z, _ := newAnonymizer("s3cret", 32)
+	got, found := rewriteAddresses("route 2606:4700:10::/48", func(a netip.Addr) string { return z.anonymize(a).String() })
+	if len(found) != 1 {
+		t.Fatalf("got %q, %v", got, found)
+	}
+	p, err := netip.ParsePrefix(found[0].Output)
+	if err != nil || p.Masked() != p || !netip.MustParsePrefix("2606:4700::/32").Contains(p.Addr()) {
+		t.Errorf("2606:4700:10::/48: got %s, %v, want a /48 of 2606:4700::/32 without host bits", found[0].Output, err)
+	}
+}
+
+func TestNewAnonymizer(t *testing.T) {
+	if _, err := newAnonymizer("", 0); err == nil {
+		t.Error("empty key accepted")
+	}
+	if _, err := newAnonymizer("k", 129); err == nil {
+		t.Error("preserved length of 129 accepted")
+	}
+}
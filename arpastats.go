@@ -0,0 +1,347 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// zoneRecord is a single resource record read from a DNS master file.
+type zoneRecord struct {
+	Name string
+	Type string
+	Data string
+}
+
+// delegationStats holds the PTR count beneath a delegated (NS) reverse zone.
+type delegationStats struct {
+	Prefix     string
+	NameServer string
+	PTRs       int
+}
+
+// arpaStats summarises how much of a prefix's ip6.arpa tree is populated.
+type arpaStats struct {
+	PTRs        int
+	Total64s    *big.Int
+	PerSlash64  map[string]int
+	Delegations []delegationStats
+}
+
+// reverseZoneName returns the ip6.arpa zone name covering the given prefix.
+// The prefix length is truncated to the enclosing nibble boundary.
+func reverseZoneName(ip net.IP, prefixLen int) string {
+	nibbles := strings.Split(dottedIPv6(ip), ".")[:prefixLen/4]
+	var sb strings.Builder
+	for i := len(nibbles) - 1; i >= 0; i-- {
+		sb.WriteString(nibbles[i])
+		sb.WriteString(".")
+	}
+	sb.WriteString("ip6.arpa.")
+	return sb.String()
+}
+
+// arpaNameToPrefix converts an ip6.arpa owner name back to the address and prefix
+// length it represents. A full 32-nibble name yields a /128.
+func arpaNameToPrefix(name string) (net.IP, int, error) {
+	lower := strings.TrimSuffix(strings.ToLower(name), ".")
+	if !strings.HasSuffix(lower, "ip6.arpa") {
+		return nil, 0, fmt.Errorf("not an ip6.arpa name: %s", name)
+	}
+	lower = strings.TrimSuffix(strings.TrimSuffix(lower, "ip6.arpa"), ".")
+	if lower == "" {
+		return make(net.IP, net.IPv6len), 0, nil
+	}
+	labels := strings.Split(lower, ".")
+	if len(labels) > 32 {
+		return nil, 0, fmt.Errorf("too many nibbles in ip6.arpa name: %s", name)
+	}
+	ip := make(net.IP, net.IPv6len)
+	for i := 0; i < len(labels); i++ {
+		label := labels[len(labels)-1-i]
+		if len(label) != 1 || !strings.Contains("0123456789abcdef", label) {
+			return nil, 0, fmt.Errorf("invalid nibble %q in ip6.arpa name: %s", label, name)
+		}
+		var v byte
+		fmt.Sscanf(label, "%x", &v)
+		if i%2 == 0 {
+			ip[i/2] |= v << 4
+		} else {
+			ip[i/2] |= v
+		}
+	}
+	return ip, len(labels) * 4, nil
+}
+
+// zoneTokens splits a master-file line into fields, dropping comments and
+// parentheses. Quoted strings are kept together as a single field.
+func zoneTokens(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	inQuote := false
+	flush := func() {
+		if cur.Len() > 0 {
+			fields = append(fields, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			cur.WriteRune(r)
+		case inQuote:
+			cur.WriteRune(r)
+		case r == ';':
+			flush()
+			return fields
+		case r == '(' || r == ')' || r == ' ' || r == '\t':
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return fields
+}
+
+// isZoneTTL reports whether a token looks like a TTL (e.g. 3600 or 1h30m).
+func isZoneTTL(tok string) bool {
+	if tok == "" || tok[0] < '0' || tok[0] > '9' {
+		return false
+	}
+	for _, r := range strings.ToLower(tok) {
+		if !strings.ContainsRune("0123456789smhdw", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// absoluteName qualifies a possibly relative owner or target name with the origin.
+func absoluteName(name, origin string) string {
+	if name == "@" {
+		return origin
+	}
+	if strings.HasSuffix(name, ".") {
+		return strings.ToLower(name)
+	}
+	if origin == "." {
+		return strings.ToLower(name) + "."
+	}
+	return strings.ToLower(name) + "." + origin
+}
+
+// parseZoneFile reads resource records from an RFC 1035 master file. Multi-line
+// records in parentheses, $ORIGIN, and omitted owners are handled; $INCLUDE is not.
+func parseZoneFile(r io.Reader, origin string) ([]zoneRecord, error) {
+	origin = absoluteName(origin, ".")
+	var records []zoneRecord
+	scanner := bufio.NewScanner(r)
+	lastOwner := origin
+	lineNo := 0
+	pending := ""
+	depth := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if idx := strings.Index(line, ";"); idx != -1 && !strings.Contains(line[:idx], "\"") {
+			line = line[:idx]
+		}
+		depth += strings.Count(line, "(") - strings.Count(line, ")")
+		pending += line + " "
+		if depth > 0 {
+			continue
+		}
+		line, pending, depth = pending, "", 0
+
+		fields := zoneTokens(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "$ORIGIN":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: $ORIGIN without a name", lineNo)
+			}
+			origin = absoluteName(fields[1], origin)
+			continue
+		case "$TTL":
+			continue
+		case "$INCLUDE":
+			return nil, fmt.Errorf("line %d: $INCLUDE is not supported", lineNo)
+		}
+
+		owner := lastOwner
+		if line[0] != ' ' && line[0] != '\t' {
+			owner = absoluteName(fields[0], origin)
+			fields = fields[1:]
+		}
+		lastOwner = owner
+
+		// Skip optional TTL and class in either order.
+		for len(fields) > 0 {
+			up := strings.ToUpper(fields[0])
+			if isZoneTTL(fields[0]) || up == "IN" || up == "CH" || up == "HS" || up == "CS" {
+				fields = fields[1:]
+				continue
+			}
+			break
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: missing record type", lineNo)
+		}
+		rec := zoneRecord{Name: owner, Type: strings.ToUpper(fields[0])}
+		if len(fields) > 1 {
+			rec.Data = strings.Join(fields[1:], " ")
+			if rec.Type == "PTR" || rec.Type == "NS" || rec.Type == "CNAME" {
+				rec.Data = absoluteName(fields[1], origin)
+			}
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// reverseTreeStats counts the PTR records under a prefix, grouping them by /64 and
+// by any NS delegations found beneath the zone apex.
+func reverseTreeStats(ipnet *net.IPNet, records []zoneRecord) arpaStats {
+	prefixLen, _ := ipnet.Mask.Size()
+	stats := arpaStats{PerSlash64: map[string]int{}, Total64s: big.NewInt(1)}
+	if prefixLen < 64 {
+		stats.Total64s.Lsh(stats.Total64s, uint(64-prefixLen))
+	}
+
+	apex := reverseZoneName(ipnet.IP, prefixLen)
+	type delegation struct {
+		ipnet *net.IPNet
+		stats *delegationStats
+	}
+	var delegations []delegation
+	byName := map[string]*delegationStats{}
+	for _, rec := range records {
+		if rec.Type != "NS" || rec.Name == apex {
+			continue
+		}
+		ip, plen, err := arpaNameToPrefix(rec.Name)
+		if err != nil || plen <= prefixLen || !ipnet.Contains(ip) {
+			continue
+		}
+		// A delegation usually carries several NS records; list them together.
+		if d, ok := byName[rec.Name]; ok {
+			d.NameServer += "," + rec.Data
+			continue
+		}
+		d := &delegationStats{Prefix: fmt.Sprintf("%s/%d", ip, plen), NameServer: rec.Data}
+		byName[rec.Name] = d
+		delegations = append(delegations, delegation{
+			ipnet: &net.IPNet{IP: ip, Mask: net.CIDRMask(plen, 128)},
+			stats: d,
+		})
+	}
+
+	seen := map[string]bool{}
+	for _, rec := range records {
+		if rec.Type != "PTR" {
+			continue
+		}
+		ip, plen, err := arpaNameToPrefix(rec.Name)
+		if err != nil || plen != 128 || !ipnet.Contains(ip) || seen[rec.Name] {
+			continue
+		}
+		seen[rec.Name] = true
+		stats.PTRs++
+		stats.PerSlash64[fmt.Sprintf("%s/64", networkAddress(ip, 64))]++
+		for _, d := range delegations {
+			if d.ipnet.Contains(ip) {
+				d.stats.PTRs++
+			}
+		}
+	}
+	// Order delegations by address so the report does not depend on record order.
+	sort.Slice(delegations, func(i, j int) bool {
+		return bytes.Compare(delegations[i].ipnet.IP, delegations[j].ipnet.IP) < 0
+	})
+	for _, d := range delegations {
+		stats.Delegations = append(stats.Delegations, *d.stats)
+	}
+	return stats
+}
+
+// reportArpaStats loads the given zone files and prints reverse tree statistics
+// for the prefix.
+func reportArpaStats(prefix string, zoneFiles string) {
+	ipnet, err := parseIPv6Prefix(prefix)
+	if err != nil {
+		log.Fatalf("invalid prefix: %v", err)
+	}
+	if zoneFiles == "" {
+		log.Fatal("at least one zone file must be given with -zone")
+	}
+	prefixLen, _ := ipnet.Mask.Size()
+	if !isNibbleAligned(prefixLen) {
+		log.Println("Warning: prefix length is not on a nibble boundary")
+	}
+
+	var records []zoneRecord
+	for _, path := range strings.Split(zoneFiles, ",") {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		recs, err := parseZoneFile(f, reverseZoneName(ipnet.IP, prefixLen))
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		records = append(records, recs...)
+	}
+
+	stats := reverseTreeStats(ipnet, records)
+	populated := big.NewInt(int64(len(stats.PerSlash64)))
+	pct := new(big.Float).Quo(new(big.Float).SetInt(populated), new(big.Float).SetInt(stats.Total64s))
+	pct.Mul(pct, big.NewFloat(100))
+
+	fmt.Printf("%-16s%s\n", "Zone:", reverseZoneName(ipnet.IP, prefixLen))
+	fmt.Printf("%-16s%d\n", "PTR records:", stats.PTRs)
+	fmt.Printf("%-16s%s of %s (%s%%)\n", "Populated /64s:", populated, stats.Total64s, pct.Text('g', 4))
+	fmt.Printf("%-16s%d\n", "Delegations:", len(stats.Delegations))
+
+	if len(stats.PerSlash64) > 0 {
+		keys := make([]string, 0, len(stats.PerSlash64))
+		for k := range stats.PerSlash64 {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			ip1 := net.ParseIP(strings.Split(keys[i], "/")[0])
+			ip2 := net.ParseIP(strings.Split(keys[j], "/")[0])
+			return bytes.Compare(ip1, ip2) < 0
+		})
+		fmt.Println()
+		fmt.Println("Per /64:")
+		for _, k := range keys {
+			fmt.Printf("  %-40s%d\n", k, stats.PerSlash64[k])
+		}
+	}
+
+	if len(stats.Delegations) > 0 {
+		fmt.Println()
+		fmt.Println("Per delegation:")
+		for _, d := range stats.Delegations {
+			fmt.Printf("  %-40s%-30s%d\n", d.Prefix, d.NameServer, d.PTRs)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReverseZoneName(t *testing.T) {
+	cases := []struct {
+		name      string
+		ip        string
+		prefixLen int
+		expect    string
+	}{
+		{name: "documentation /32", ip: "2001:db8::", prefixLen: 32, expect: "8.b.d.0.1.0.0.2.ip6.arpa."},
+		{name: "/48", ip: "2001:db8:abcd::", prefixLen: 48, expect: "d.c.b.a.8.b.d.0.1.0.0.2.ip6.arpa."},
+		{name: "non-nibble /50 truncates to /48", ip: "2001:db8:abcd::", prefixLen: 50, expect: "d.c.b.a.8.b.d.0.1.0.0.2.ip6.arpa."},
+		{name: "root", ip: "::", prefixLen: 0, expect: "ip6.arpa."},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := reverseZoneName(net.ParseIP(tc.ip), tc.prefixLen)
+			if got != tc.expect {
+				t.Errorf("expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestArpaNameToPrefix(t *testing.T) {
+	cases := []struct {
+		name        string
+		input       string
+		expectIP    string
+		expectLen   int
+		expectError bool
+	}{
+		{name: "full name", input: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", expectIP: "2001:db8::1", expectLen: 128},
+		{name: "zone name", input: "8.b.d.0.1.0.0.2.ip6.arpa", expectIP: "2001:db8::", expectLen: 32},
+		{name: "uppercase", input: "8.B.D.0.1.0.0.2.IP6.ARPA.", expectIP: "2001:db8::", expectLen: 32},
+		{name: "apex", input: "ip6.arpa.", expectIP: "::", expectLen: 0},
+		{name: "wrong tree", input: "1.2.0.192.in-addr.arpa.", expectError: true},
+		{name: "bad nibble", input: "10.b.d.0.1.0.0.2.ip6.arpa.", expectError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ip, plen, err := arpaNameToPrefix(tc.input)
+			if (err == nil) == tc.expectError {
+				t.Errorf("expected error %v, got %v", tc.expectError, err)
+			}
+			if err == nil {
+				if ip.String() != tc.expectIP || plen != tc.expectLen {
+					t.Errorf("expected %s/%d, got %s/%d", tc.expectIP, tc.expectLen, ip, plen)
+				}
+			}
+		})
+	}
+}
+
+func TestReverseTreeStats(t *testing.T) {
+	zone := `$TTL 3600
+@	IN	SOA	ns1.example.net. hostmaster.example.net. (
+		2024010101 ; serial
+		3600 900 604800 300 )
+	IN	NS	ns1.example.net.
+1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.1.0.0.0 PTR host1.example.net.
+2.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.1.0.0.0	IN PTR host2.example.net.
+1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.2.0.0.0 3600 IN PTR host3.example.net.
+f IN NS ns2.example.org.
+f IN NS ns3.example.org.
+1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.1.0.0.f PTR delegated.example.org.
+`
+	_, ipnet, _ := net.ParseCIDR("2001:db8:abcd::/48")
+	records, err := parseZoneFile(strings.NewReader(zone), reverseZoneName(ipnet.IP, 48))
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	stats := reverseTreeStats(ipnet, records)
+
+	if stats.PTRs != 4 {
+		t.Errorf("expected 4 PTRs, got %d", stats.PTRs)
+	}
+	if len(stats.PerSlash64) != 3 {
+		t.Errorf("expected 3 populated /64s, got %d: %v", len(stats.PerSlash64), stats.PerSlash64)
+	}
+	if got := stats.PerSlash64["2001:db8:abcd:1::/64"]; got != 2 {
+		t.Errorf("expected 2 PTRs in 2001:db8:abcd:1::/64, got %d", got)
+	}
+	if stats.Total64s.String() != "65536" {
+		t.Errorf("expected 65536 /64s, got %s", stats.Total64s)
+	}
+	if len(stats.Delegations) != 1 {
+		t.Fatalf("expected 1 delegation, got %d", len(stats.Delegations))
+	}
+	d := stats.Delegations[0]
+	if d.Prefix != "2001:db8:abcd:f000::/52" || d.PTRs != 1 || d.NameServer != "ns2.example.org.,ns3.example.org." {
+		t.Errorf("unexpected delegation %+v", d)
+	}
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// arpaZone is a reverse zone and the prefix it serves.
+type arpaZone struct {
+	Prefix string `json:"prefix"`
+	Zone   string `json:"zone"`
+}
+
+// arpaZonesResult is the set of reverse zones covering a prefix.
+type arpaZonesResult struct {
+	Prefix     string     `json:"prefix"`
+	ZoneLength int        `json:"zone_length"`
+	Zones      []arpaZone `json:"zones"`
+}
+
+// reverseZones lists the ip6.arpa zones needed to cover a prefix. Zones are cut
+// at nibbles, so a prefix off a nibble boundary, such as a /57, takes every
+// zone of the next boundary down inside it: eight /60 zones.
+func reverseZones(prefix string) (arpaZonesResult, error) {
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		return arpaZonesResult{}, err
+	}
+	zoneLen := (parent.Bits() + 3) / 4 * 4
+	result := arpaZonesResult{Prefix: parent.String(), ZoneLength: zoneLen}
+	for p := range subnetSeq(parent, zoneLen) {
+		result.Zones = append(result.Zones, arpaZone{Prefix: p.String(), Zone: reverseZoneName(prefixToIPNet(p).IP, zoneLen)})
+	}
+	return result, nil
+}
+
+// runArpaZones prints the reverse zones covering a prefix, one per line.
+func runArpaZones(prefix string) {
+	result, err := reverseZones(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if n := len(result.Zones); n > 1 {
+		statusf("%s is not on a nibble boundary: %d /%d zones cover it\n", result.Prefix, n, result.ZoneLength)
+	}
+	writeResult(result, func() {
+		for _, z := range result.Zones {
+			fmt.Printf("%-44s %s\n", z.Prefix, z.Zone)
+		}
+	})
+}
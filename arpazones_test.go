@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestReverseZones(t *testing.T) {
+	r, err := reverseZones("2001:db8::/57")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ZoneLength != 60 || len(r.Zones) != 8 {
+		t.Fatalf("got /%d with %d zones, want eight /60s", r.ZoneLength, len(r.Zones))
+	}
+	if z := r.Zones[7]; z.Prefix != "2001:db8:0:70::/60" || z.Zone != "7.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa." {
+		t.Errorf("last zone: got %+v", z)
+	}
+
+	r, err = reverseZones("2001:db8:0:80::/58")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Zones) != 4 || r.Zones[0].Zone != "8.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa." {
+		t.Errorf("/58: got %+v", r.Zones)
+	}
+
+	r, err = reverseZones("2001:db8::/48")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Zones) != 1 || r.Zones[0].Zone != "0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa." {
+		t.Errorf("nibble-aligned prefix: got %+v", r.Zones)
+	}
+
+	if _, err := reverseZones("2001:db8::/129"); err == nil {
+		t.Error("invalid prefix accepted")
+	}
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// conversion is the result of converting one input: what writeResult is given,
+// the sentence printed for a single input, and the line printed for each input
+// of a batch.
+type conversion struct {
+	Result any
+	Text   string
+	Line   string
+}
+
+// batchLine is the line of a batch for an input and what it converted to.
+func batchLine(input, output string) string {
+	return fmt.Sprintf("%-40s %s", input, output)
+}
+
+// conversionWorkers is how many goroutines convert the lines of a batch.
+var conversionWorkers = 1
+
+// batchSize is how many lines a worker converts at a time.
+const batchSize = 512
+
+// lineBatch is lines of a batch read together, converted by one worker.
+type lineBatch struct {
+	lines   []int
+	inputs  []string
+	results []conversion
+	errs    []error
+	done    chan struct{}
+}
+
+// convertLines converts each input read from r, one per line with blank
+// lines and '#' comments skipped, passing each conversion to emit and each
+// failure to fail with its line number, in the order read. With more than one
+// worker, batches of lines are converted at once by that many goroutines.
+func convertLines(r io.Reader, workers int, convert func(string) (conversion, error), emit func(conversion), fail func(line int, input string, err error)) error {
+	if workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", workers)
+	}
+	jobs := make(chan *lineBatch, workers)
+	ordered := make(chan *lineBatch, workers*2)
+	var readErr error
+	go func() {
+		defer close(jobs)
+		defer close(ordered)
+		scanner := bufio.NewScanner(r)
+		lineNo := 0
+		b := &lineBatch{done: make(chan struct{})}
+		send := func() {
+			ordered <- b
+			jobs <- b
+			b = &lineBatch{done: make(chan struct{})}
+		}
+		for scanner.Scan() {
+			lineNo++
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			b.lines = append(b.lines, lineNo)
+			b.inputs = append(b.inputs, fields[0])
+			if len(b.inputs) == batchSize {
+				send()
+			}
+		}
+		if len(b.inputs) > 0 {
+			send()
+		}
+		readErr = scanner.Err()
+	}()
+	for range workers {
+		go func() {
+			for b := range jobs {
+				b.results, b.errs = make([]conversion, len(b.inputs)), make([]error, len(b.inputs))
+				for i, input := range b.inputs {
+					b.results[i], b.errs[i] = convert(input)
+				}
+				close(b.done)
+			}
+		}()
+	}
+	for b := range ordered {
+		<-b.done
+		for i := range b.inputs {
+			if b.errs[i] != nil {
+				fail(b.lines[i], b.inputs[i], b.errs[i])
+				continue
+			}
+			emit(b.results[i])
+		}
+	}
+	return readErr
+}
+
+// runConversion converts an input and prints the result, or with an input of
+// "-", converts each line of stdin and prints a line for each, so an inventory
+// is converted in one pass, by conversionWorkers goroutines. Inputs that fail
+// are reported on stderr with their line numbers, and the exit status is then
+// 1.
+func runConversion(input string, convert func(string) (conversion, error)) {
+	if input != "-" {
+		c, err := convert(input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeResult(c.Result, func() {
+			fmt.Println(c.Text)
+		})
+		return
+	}
+
+	// JSON is one array; every other format streams a result per line.
+	results := []any{}
+	failed := false
+	err := convertLines(os.Stdin, conversionWorkers, convert, func(c conversion) {
+		if outputFormat == "json" {
+			results = append(results, c.Result)
+			return
+		}
+		writeResult(c.Result, func() {
+			fmt.Println(c.Line)
+		})
+	}, func(line int, input string, err error) {
+		fmt.Fprintf(os.Stderr, "line %d: %s: %v\n", line, input, err)
+		failed = true
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFormat == "json" {
+		writeResult(results, nil)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
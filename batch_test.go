@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConvertLines(t *testing.T) {
+	in := "192.0.2.1\n# comment\n\nbogus\n64:ff9b::c000:202 extra\n"
+	var got, failed []string
+	err := convertLines(strings.NewReader(in), 1, nat64Converter("64:ff9b::", 0), func(c conversion) {
+		got = append(got, strings.Join(strings.Fields(c.Line), " "))
+	}, func(line int, input string, err error) {
+		failed = append(failed, fmt.Sprintf("%d:%s", line, input))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "192.0.2.1 64:ff9b::c000:201|64:ff9b::c000:202 192.0.2.2"; strings.Join(got, "|") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+	if strings.Join(failed, " ") != "4:bogus" {
+		t.Errorf("failed %v, want [4:bogus]", failed)
+	}
+}
+
+func TestConverters(t *testing.T) {
+	tests := []struct {
+		convert   func(string) (conversion, error)
+		in, text  string
+		wantError bool
+	}{
+		{convertMAC, "00:11:22:33:44:55", "Link-local address: fe80::0211:22ff:fe33:4455", false},
+		{convertMAC, "fe80::211:22ff:fe33:4455", "MAC from link-local: 00:11:22:33:44:55", false},
+		{convertMAC, "2001:db8::211:22ff:fe33:4455", "Decoded MAC address: 00:11:22:33:44:55", false},
+		{convertMAC, "2001:db8::1", "", true},
+		{convertSLAAC, "2001:db8::211:22ff:fe33:4455", "Decoded MAC address: 00:11:22:33:44:55", false},
+		{nat64Converter("64:ff9b::", 0), "192.0.2.1", "Converted IPv4 to synthesized IPv6: 64:ff9b::c000:201", false},
+		{arpaConverter(64), "2001:db8::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0", false},
+		{arpaConverter(0), "nope", "", true},
+	}
+	for _, tt := range tests {
+		c, err := tt.convert(tt.in)
+		if (err != nil) != tt.wantError || c.Text != tt.text {
+			t.Errorf("%s: got %q, %v, want %q", tt.in, c.Text, err, tt.text)
+		}
+	}
+}
+
+func TestConvertLinesWorkers(t *testing.T) {
+	var in strings.Builder
+	for i := range 3000 {
+		if i%7 == 3 {
+			in.WriteString("bogus\n")
+			continue
+		}
+		fmt.Fprintf(&in, "10.0.%d.%d\n", i/256, i%256)
+	}
+	var sequential []string
+	for _, workers := range []int{1, 4} {
+		var got []string
+		err := convertLines(strings.NewReader(in.String()), workers, nat64Converter("64:ff9b::", 0), func(c conversion) {
+			got = append(got, c.Line)
+		}, func(line int, input string, err error) {
+			got = append(got, fmt.Sprintf("line %d failed", line))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 3000 {
+			t.Fatalf("%d workers: got %d lines, want 3000", workers, len(got))
+		}
+		if workers == 1 {
+			sequential = got
+			continue
+		}
+		if strings.Join(got, "\n") != strings.Join(sequential, "\n") {
+			t.Errorf("%d workers: output differs from one worker's", workers)
+		}
+	}
+	if err := convertLines(strings.NewReader(""), 0, nil, nil, nil); err == nil {
+		t.Error("0 workers accepted")
+	}
+}
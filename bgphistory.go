@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ripestatBaseURL is the RIPEstat Data API root; tests point it at a local server.
+var ripestatBaseURL = "https://stat.ripe.net/data"
+
+// routingHistory is the part of the RIPEstat routing-history response used here.
+type routingHistory struct {
+	Data struct {
+		ByOrigin []struct {
+			Origin   string `json:"origin"`
+			Prefixes []struct {
+				Prefix    string `json:"prefix"`
+				Timelines []struct {
+					StartTime string  `json:"starttime"`
+					EndTime   string  `json:"endtime"`
+					Peers     float64 `json:"full_peers_seeing"`
+				} `json:"timelines"`
+			} `json:"prefixes"`
+		} `json:"by_origin"`
+	} `json:"data"`
+}
+
+// announcement is one continuous period in which an origin AS announced a prefix.
+type announcement struct {
+	Prefix string
+	Origin string
+	Start  time.Time
+	End    time.Time
+}
+
+// bgpHistory summarizes the announcements of a prefix and its more-specifics.
+type bgpHistory struct {
+	Prefix        string
+	Exact         []announcement // sorted by start
+	MoreSpecifics []announcement // one entry per prefix and origin, first to last seen
+}
+
+// parseRIPEstatTime accepts the API's timestamps, which usually omit the zone.
+func parseRIPEstatTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", s)
+}
+
+// parseRoutingHistory turns a routing-history response into announcement periods,
+// separating the queried prefix from its more-specifics.
+func parseRoutingHistory(r io.Reader, prefix string) (*bgpHistory, error) {
+	var resp routingHistory
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid RIPEstat response: %v", err)
+	}
+	query, err := parseIPv6Prefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %v", err)
+	}
+	h := &bgpHistory{Prefix: query.String()}
+	for _, o := range resp.Data.ByOrigin {
+		for _, p := range o.Prefixes {
+			announced, err := parseIPv6Prefix(p.Prefix)
+			if err != nil {
+				continue // IPv4 or malformed entries
+			}
+			exact := announced.String() == query.String()
+			var first, last time.Time
+			for _, tl := range p.Timelines {
+				start, err := parseRIPEstatTime(tl.StartTime)
+				if err != nil {
+					return nil, err
+				}
+				end, err := parseRIPEstatTime(tl.EndTime)
+				if err != nil {
+					return nil, err
+				}
+				if exact {
+					h.Exact = append(h.Exact, announcement{Prefix: p.Prefix, Origin: o.Origin, Start: start, End: end})
+				}
+				if first.IsZero() || start.Before(first) {
+					first = start
+				}
+				if end.After(last) {
+					last = end
+				}
+			}
+			if !exact && !first.IsZero() && prefixWithin(announced, query) {
+				h.MoreSpecifics = append(h.MoreSpecifics, announcement{Prefix: announced.String(), Origin: o.Origin, Start: first, End: last})
+			}
+		}
+	}
+	sort.Slice(h.Exact, func(i, j int) bool { return h.Exact[i].Start.Before(h.Exact[j].Start) })
+	sort.Slice(h.MoreSpecifics, func(i, j int) bool { return h.MoreSpecifics[i].Start.Before(h.MoreSpecifics[j].Start) })
+	return h, nil
+}
+
+// originChanges lists the points at which a different origin took over the exact
+// prefix, and the periods in which two origins announced it at once (MOAS).
+func (h *bgpHistory) originChanges() (changes []string, moas []string) {
+	for i := 1; i < len(h.Exact); i++ {
+		prev, cur := h.Exact[i-1], h.Exact[i]
+		if cur.Origin != prev.Origin {
+			changes = append(changes, fmt.Sprintf("%s AS%s → AS%s", cur.Start.Format("2006-01-02"), prev.Origin, cur.Origin))
+		}
+	}
+	for i := range h.Exact {
+		for j := i + 1; j < len(h.Exact); j++ {
+			a, b := h.Exact[i], h.Exact[j]
+			if a.Origin != b.Origin && b.Start.Before(a.End) {
+				moas = append(moas, fmt.Sprintf("%s to %s AS%s and AS%s", b.Start.Format("2006-01-02"), minTime(a.End, b.End).Format("2006-01-02"), a.Origin, b.Origin))
+			}
+		}
+	}
+	return changes, moas
+}
+
+// minTime returns the earlier of two times.
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// fetchRoutingHistory queries RIPEstat for the routing history of a prefix.
+func fetchRoutingHistory(prefix string) (*bgpHistory, error) {
+	u := fmt.Sprintf("%s/routing-history/data.json?resource=%s&sourceapp=ipv6utils", ripestatBaseURL, url.QueryEscape(prefix))
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RIPEstat: %s", resp.Status)
+	}
+	return parseRoutingHistory(resp.Body, prefix)
+}
+
+// writeBGPHistory prints the first/last seen dates, origins, origin changes, and
+// more-specific announcements.
+func writeBGPHistory(w io.Writer, h *bgpHistory) {
+	fmt.Fprintf(w, "%-16s %s\n", "Prefix:", h.Prefix)
+	origins := map[string]bool{}
+	if len(h.Exact) == 0 {
+		fmt.Fprintf(w, "%-16s never seen in BGP\n", "Announced:")
+	} else {
+		first, last := h.Exact[0].Start, h.Exact[0].End
+		var originList []string
+		for _, a := range h.Exact {
+			if a.End.After(last) {
+				last = a.End
+			}
+			if !origins[a.Origin] {
+				origins[a.Origin] = true
+				originList = append(originList, "AS"+a.Origin)
+			}
+		}
+		fmt.Fprintf(w, "%-16s %s\n", "First seen:", first.Format("2006-01-02 15:04"))
+		fmt.Fprintf(w, "%-16s %s\n", "Last seen:", last.Format("2006-01-02 15:04"))
+		fmt.Fprintf(w, "%-16s %s\n", "Origins:", strings.Join(originList, ", "))
+		changes, moas := h.originChanges()
+		for _, c := range changes {
+			fmt.Fprintf(w, "%-16s %s\n", "Origin change:", c)
+		}
+		for _, m := range moas {
+			fmt.Fprintf(w, "%-16s %s\n", "MOAS:", m)
+		}
+	}
+	if len(h.MoreSpecifics) == 0 {
+		fmt.Fprintf(w, "%-16s none\n", "More-specifics:")
+		return
+	}
+	fmt.Fprintf(w, "%-16s %d\n", "More-specifics:", len(h.MoreSpecifics))
+	for _, a := range h.MoreSpecifics {
+		note := ""
+		if len(origins) > 0 && !origins[a.Origin] {
+			note = "  origin differs"
+		}
+		fmt.Fprintf(w, "  %-40s AS%-10s %s to %s%s\n", a.Prefix, a.Origin, a.Start.Format("2006-01-02"), a.End.Format("2006-01-02"), note)
+	}
+}
+
+// reportBGPHistory looks up and prints the announcement history of a prefix.
+func reportBGPHistory(prefix string) {
+	if _, err := parseIPv6Prefix(prefix); err != nil {
+		log.Fatalf("invalid prefix: %v", err)
+	}
+	h, err := fetchRoutingHistory(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeBGPHistory(os.Stdout, h)
+}
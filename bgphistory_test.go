@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseRoutingHistory(t *testing.T) {
+	f, err := os.Open("testdata/routing-history.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h, err := parseRoutingHistory(f, "2001:db8::/32")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.Exact) != 3 || h.Exact[1].Origin != "64511" {
+		t.Fatalf("unexpected exact announcements %+v", h.Exact)
+	}
+	if len(h.MoreSpecifics) != 2 || h.MoreSpecifics[0].Prefix != "2001:db8:100::/40" {
+		t.Fatalf("unexpected more-specifics %+v", h.MoreSpecifics)
+	}
+
+	changes, moas := h.originChanges()
+	wantChanges := []string{"2022-06-30 AS64500 → AS64511", "2023-01-10 AS64511 → AS64500"}
+	if strings.Join(changes, "|") != strings.Join(wantChanges, "|") {
+		t.Errorf("expected changes %v, got %v", wantChanges, changes)
+	}
+	if len(moas) != 1 || moas[0] != "2022-06-30 to 2022-06-30 AS64500 and AS64511" {
+		t.Errorf("unexpected MOAS periods %v", moas)
+	}
+
+	var buf bytes.Buffer
+	writeBGPHistory(&buf, h)
+	for _, want := range []string{
+		"First seen:      2021-03-01 00:00",
+		"Last seen:       2024-10-01 00:00",
+		"Origins:         AS64500, AS64511",
+		"2001:db8:100::/40                        AS64500      2022-02-01 to 2024-10-01\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestFetchRoutingHistory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/routing-history/data.json" || r.URL.Query().Get("resource") != "2001:db8:1::/48" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data": {"by_origin": []}}`))
+	}))
+	defer srv.Close()
+	saved := ripestatBaseURL
+	ripestatBaseURL = srv.URL
+	defer func() { ripestatBaseURL = saved }()
+
+	h, err := fetchRoutingHistory("2001:db8:1::/48")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	writeBGPHistory(&buf, h)
+	if !strings.Contains(buf.String(), "never seen in BGP") || !strings.Contains(buf.String(), "More-specifics:  none") {
+		t.Errorf("unexpected report:\n%s", buf.String())
+	}
+	if _, err := fetchRoutingHistory("2001:db8:2::/48"); err == nil {
+		t.Error("expected error for HTTP failure")
+	}
+}
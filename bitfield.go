@@ -0,0 +1,295 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemeField is one bit field of the subnet ID, with optional names for its values.
+type schemeField struct {
+	Name   string            `yaml:"name"`
+	Bits   int               `yaml:"bits"`
+	Values map[string]uint64 `yaml:"values"`
+
+	names map[uint64]string
+}
+
+// addressScheme lays structured identifiers out as consecutive bit fields after
+// a parent prefix, as written in a scheme file:
+//
+//	prefix: 2001:db8::/32
+//	fields:
+//	  - name: region
+//	    bits: 8
+//	    values: {emea: 1, amer: 2}
+//	  - name: site
+//	    bits: 8
+//	  - name: role
+//	    bits: 8
+//	    values: {users: 1, servers: 2, mgmt: 3}
+type addressScheme struct {
+	Prefix string        `yaml:"prefix"`
+	Fields []schemeField `yaml:"fields"`
+
+	parent    *net.IPNet
+	parentLen int
+}
+
+// parseAddressScheme reads and validates a YAML scheme. Unknown keys are rejected
+// so a misspelt setting is not silently ignored.
+func parseAddressScheme(r io.Reader) (*addressScheme, error) {
+	var s addressScheme
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid scheme: %v", err)
+	}
+	var err error
+	if s.parent, err = parseIPv6Prefix(s.Prefix); err != nil {
+		return nil, fmt.Errorf("prefix: %v", err)
+	}
+	s.parentLen, _ = s.parent.Mask.Size()
+	if len(s.Fields) == 0 {
+		return nil, fmt.Errorf("scheme has no fields")
+	}
+	total := s.parentLen
+	seen := map[string]bool{}
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		if f.Name == "" || seen[f.Name] {
+			return nil, fmt.Errorf("field %d: missing or duplicate name %q", i+1, f.Name)
+		}
+		seen[f.Name] = true
+		if f.Bits < 1 || f.Bits > 64 {
+			return nil, fmt.Errorf("field %s: bits must be 1-64", f.Name)
+		}
+		total += f.Bits
+		f.names = map[uint64]string{}
+		for name, v := range f.Values {
+			if f.Bits < 64 && v >= 1<<f.Bits {
+				return nil, fmt.Errorf("field %s: value %s=%d does not fit in %d bits", f.Name, name, v, f.Bits)
+			}
+			if other, dup := f.names[v]; dup {
+				return nil, fmt.Errorf("field %s: %s and %s both have value %d", f.Name, other, name, v)
+			}
+			f.names[v] = name
+		}
+	}
+	if total > 128 {
+		return nil, fmt.Errorf("fields end at bit %d, beyond 128", total)
+	}
+	return &s, nil
+}
+
+// loadAddressScheme reads a scheme file.
+func loadAddressScheme(path string) (*addressScheme, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := parseAddressScheme(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+// fieldValues resolves a field's value: a name from the scheme, a number, or a
+// numeric range such as 1-4.
+func (f schemeField) fieldValues(text string) ([]uint64, error) {
+	if v, ok := f.Values[text]; ok {
+		return []uint64{v}, nil
+	}
+	lo, hi, isRange := strings.Cut(text, "-")
+	first, err := strconv.ParseUint(lo, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("field %s: unknown value %q", f.Name, text)
+	}
+	last := first
+	if isRange {
+		if last, err = strconv.ParseUint(hi, 0, 64); err != nil || last < first {
+			return nil, fmt.Errorf("field %s: invalid range %q", f.Name, text)
+		}
+	}
+	if f.Bits < 64 && last >= 1<<f.Bits {
+		return nil, fmt.Errorf("field %s: %d does not fit in %d bits", f.Name, last, f.Bits)
+	}
+	var values []uint64
+	for v := first; v <= last; v++ {
+		values = append(values, v)
+		if v == last {
+			break // last may be the largest uint64
+		}
+	}
+	return values, nil
+}
+
+// label names a value for output: its scheme name, or the number.
+func (f schemeField) label(v uint64) string {
+	if name, ok := f.names[v]; ok {
+		return name
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// schemePrefix is a prefix built from, or decoded into, field values.
+type schemePrefix struct {
+	Net    *net.IPNet
+	Values []uint64
+}
+
+// encode builds the prefixes for comma-separated FIELD=VALUE assignments. Fields
+// must be given from the first without gaps; leaving out trailing fields yields
+// the shorter aggregate prefix. Ranges expand to every combination.
+func (s *addressScheme) encode(assignments string) ([]schemePrefix, error) {
+	given := map[string]string{}
+	for _, a := range strings.Split(assignments, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(a), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected FIELD=VALUE, got %q", a)
+		}
+		given[name] = value
+	}
+	var choices [][]uint64
+	for _, f := range s.Fields {
+		text, ok := given[f.Name]
+		if !ok {
+			break
+		}
+		delete(given, f.Name)
+		values, err := f.fieldValues(text)
+		if err != nil {
+			return nil, err
+		}
+		choices = append(choices, values)
+	}
+	for name := range given {
+		if len(choices) < len(s.Fields) && s.hasField(name) {
+			return nil, fmt.Errorf("field %s given without %s", name, s.Fields[len(choices)].Name)
+		}
+		return nil, fmt.Errorf("unknown field %q", name)
+	}
+
+	prefixLen := s.parentLen
+	for _, f := range s.Fields[:len(choices)] {
+		prefixLen += f.Bits
+	}
+	mask := net.CIDRMask(prefixLen, 128)
+	var out []schemePrefix
+	var walk func(values []uint64)
+	walk = func(values []uint64) {
+		if len(values) == len(choices) {
+			out = append(out, schemePrefix{Net: &net.IPNet{IP: s.place(values), Mask: mask}, Values: append([]uint64{}, values...)})
+			return
+		}
+		for _, v := range choices[len(values)] {
+			walk(append(values, v))
+		}
+	}
+	walk(nil)
+	return out, nil
+}
+
+// hasField reports whether the scheme defines a field of that name.
+func (s *addressScheme) hasField(name string) bool {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// place writes the values into the bit fields after the parent prefix.
+func (s *addressScheme) place(values []uint64) net.IP {
+	v := ipToBigInt(s.parent.IP)
+	shift := 128 - s.parentLen
+	for i, value := range values {
+		shift -= s.Fields[i].Bits
+		v.Or(v, new(big.Int).Lsh(new(big.Int).SetUint64(value), uint(shift)))
+	}
+	return bigIntToIP(v)
+}
+
+// decode reads every field of an address or prefix inside the parent.
+func (s *addressScheme) decode(input string) (schemePrefix, error) {
+	ip, _, err := parseIPv6WithOptionalPrefix(input)
+	if err != nil {
+		return schemePrefix{}, err
+	}
+	if !s.parent.Contains(ip) {
+		return schemePrefix{}, fmt.Errorf("%s is not inside %s", input, s.parent)
+	}
+	v := ipToBigInt(ip)
+	shift := 128 - s.parentLen
+	var values []uint64
+	for _, f := range s.Fields {
+		shift -= f.Bits
+		field := new(big.Int).Rsh(v, uint(shift))
+		field.And(field, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(f.Bits)), big.NewInt(1)))
+		values = append(values, field.Uint64())
+	}
+	mask := net.CIDRMask(128-shift, 128)
+	return schemePrefix{Net: &net.IPNet{IP: ip.Mask(mask), Mask: mask}, Values: values}, nil
+}
+
+// describe formats field values as "region=emea site=12 role=servers".
+func (s *addressScheme) describe(values []uint64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = s.Fields[i].Name + "=" + s.Fields[i].label(v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// name joins field values into a plan label such as "emea-12-servers".
+func (s *addressScheme) name(values []uint64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = s.Fields[i].label(v)
+	}
+	return strings.Join(parts, "-")
+}
+
+// runSchemeEncode prints the prefixes for the assignments as a prefix list.
+func runSchemeEncode(schemeFile string, assignments string) {
+	s, err := loadAddressScheme(schemeFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	prefixes, err := s.encode(assignments)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range prefixes {
+		fmt.Printf("%-44s %s\n", p.Net, s.name(p.Values))
+	}
+}
+
+// runSchemeDecode prints the field values of each comma-separated address or prefix.
+func runSchemeDecode(schemeFile string, inputs string) {
+	s, err := loadAddressScheme(schemeFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, input := range strings.Split(inputs, ",") {
+		input = strings.TrimSpace(input)
+		p, err := s.decode(input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%-40s %s (%s)\n", input, s.describe(p.Values), p.Net)
+	}
+}
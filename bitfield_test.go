@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAddressScheme(t *testing.T) {
+	cases := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"valid", "prefix: 2001:db8::/32\nfields:\n  - {name: region, bits: 8, values: {emea: 1}}\n", ""},
+		{"no fields", "prefix: 2001:db8::/32\n", "no fields"},
+		{"bad prefix", "prefix: 2001:db8::1/32\nfields:\n  - {name: a, bits: 8}\n", "prefix"},
+		{"duplicate field", "prefix: 2001:db8::/32\nfields:\n  - {name: a, bits: 8}\n  - {name: a, bits: 8}\n", "duplicate name"},
+		{"value too large", "prefix: 2001:db8::/32\nfields:\n  - {name: a, bits: 2, values: {x: 4}}\n", "does not fit"},
+		{"ambiguous value", "prefix: 2001:db8::/32\nfields:\n  - {name: a, bits: 2, values: {x: 1, y: 1}}\n", "both have value 1"},
+		{"too long", "prefix: 2001:db8::/32\nfields:\n  - {name: a, bits: 64}\n  - {name: b, bits: 33}\n", "beyond 128"},
+		{"unknown key", "prefix: 2001:db8::/32\nfield: []\n", "invalid scheme"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseAddressScheme(strings.NewReader(tc.yaml))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSchemeEncode(t *testing.T) {
+	s, err := loadAddressScheme("testdata/scheme.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		assignments string
+		want        []string
+		wantErr     string
+	}{
+		{"region=emea,site=12,role=servers", []string{"2001:db8:10c:200::/56 emea-12-servers"}, ""},
+		{"region=amer,site=1-2", []string{"2001:db8:201::/48 amer-1", "2001:db8:202::/48 amer-2"}, ""},
+		{"region=3", []string{"2001:db8:300::/40 apac"}, ""},
+		{"region=emea,role=mgmt", nil, "role given without site"},
+		{"region=mars", nil, "unknown value"},
+		{"region=emea,site=256", nil, "does not fit"},
+		{"region=emea,rack=1", nil, "unknown field"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.assignments, func(t *testing.T) {
+			prefixes, err := s.encode(tc.assignments)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, p := range prefixes {
+				got = append(got, p.Net.String()+" "+s.name(p.Values))
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSchemeDecode(t *testing.T) {
+	s, err := loadAddressScheme("testdata/scheme.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := s.decode("2001:db8:10c:325::25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.describe(p.Values); got != "region=emea site=12 role=mgmt" {
+		t.Errorf("got %q", got)
+	}
+	if p.Net.String() != "2001:db8:10c:300::/56" {
+		t.Errorf("got subnet %s", p.Net)
+	}
+	if _, err := s.decode("2001:db9::1"); err == nil {
+		t.Error("expected error for address outside the scheme prefix")
+	}
+}
@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// blocklistFeedMaxAge is how long a downloaded threat feed is used before refetching.
+const blocklistFeedMaxAge = 6 * time.Hour
+
+// hostResolver is the part of net.Resolver used for DNSBL queries.
+type hostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// blocklistFeed is a named list of listed prefixes, such as Spamhaus DROPv6.
+type blocklistFeed struct {
+	Name    string
+	Entries []prefixEntry
+}
+
+// blocklistHit records that an address or prefix appears on one source.
+type blocklistHit struct {
+	Source string
+	Detail string
+}
+
+// dnsblName is the DNSBL query name for ip: its 32 nibbles in reverse order,
+// followed by the list's zone.
+func dnsblName(ip net.IP, zone string) string {
+	return strings.TrimSuffix(reverseZoneName(ip, 128), "ip6.arpa.") + strings.TrimSuffix(zone, ".")
+}
+
+// checkDNSBL queries one DNSBL zone for ip and returns the 127.0.0.0/8 return codes
+// when it is listed. Codes in 127.255.255.0/24 are list errors (for example a
+// rate-limited or refused resolver) and are reported as such.
+func checkDNSBL(ctx context.Context, r hostResolver, ip net.IP, zone string) ([]string, error) {
+	addrs, err := r.LookupHost(ctx, dnsblName(ip, zone))
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var codes []string
+	for _, a := range addrs {
+		if strings.HasPrefix(a, "127.255.255.") {
+			return nil, fmt.Errorf("%s refused the query (%s)", zone, a)
+		}
+		if strings.HasPrefix(a, "127.") {
+			codes = append(codes, a)
+		}
+	}
+	return codes, nil
+}
+
+// readBlocklistFeed reads a threat feed of one prefix or address per line. Text
+// after ';' is kept as the listing's reference (as in the Spamhaus DROP format),
+// '#' starts a comment, and IPv4 entries are ignored.
+func readBlocklistFeed(r io.Reader) ([]prefixEntry, error) {
+	var out []prefixEntry
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		ref := ""
+		if i := strings.Index(line, ";"); i != -1 {
+			line, ref = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.Contains(fields[0], ":") {
+			continue
+		}
+		ip, prefixLen, err := parseIPv6WithOptionalPrefix(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		if prefixLen < 0 {
+			prefixLen = 128
+		}
+		mask := net.CIDRMask(prefixLen, 128)
+		if ref == "" {
+			ref = strings.Join(fields[1:], " ")
+		}
+		out = append(out, prefixEntry{Net: &net.IPNet{IP: ip.Mask(mask), Mask: mask}, Label: ref, Line: lineNo})
+	}
+	return out, scanner.Err()
+}
+
+// loadBlocklistFeeds reads feeds from local files or http(s) URLs; URLs are cached
+// for blocklistFeedMaxAge.
+func loadBlocklistFeeds(sources string) ([]blocklistFeed, error) {
+	var feeds []blocklistFeed
+	if sources == "" {
+		return nil, nil
+	}
+	for _, src := range strings.Split(sources, ",") {
+		path := src
+		if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+			var err error
+			if path, err = cachedDownload("feeds", src, blocklistFeedMaxAge, false); err != nil {
+				return nil, err
+			}
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := readBlocklistFeed(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", src, err)
+		}
+		feeds = append(feeds, blocklistFeed{Name: src, Entries: entries})
+	}
+	return feeds, nil
+}
+
+// checkBlocklists returns the hits for one entry. Feeds are matched on any overlap;
+// DNSBLs only list single addresses, so they are queried for /128 entries only.
+func checkBlocklists(ctx context.Context, r hostResolver, e prefixEntry, zones []string, feeds []blocklistFeed) ([]blocklistHit, error) {
+	var hits []blocklistHit
+	for _, feed := range feeds {
+		for _, listed := range feed.Entries {
+			if prefixesOverlap(e.Net, listed.Net) {
+				detail := listed.Net.String()
+				if listed.Label != "" {
+					detail += " " + listed.Label
+				}
+				hits = append(hits, blocklistHit{Source: feed.Name, Detail: detail})
+			}
+		}
+	}
+	if !e.Host() {
+		return hits, nil
+	}
+	for _, zone := range zones {
+		qctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		codes, err := checkDNSBL(qctx, r, e.Net.IP, zone)
+		cancel()
+		if err != nil {
+			return hits, err
+		}
+		if len(codes) > 0 {
+			hits = append(hits, blocklistHit{Source: zone, Detail: strings.Join(codes, ",")})
+		}
+	}
+	return hits, nil
+}
+
+// reportBlocklists checks an address or prefix, or every entry of a prefix list,
+// against the DNSBL zones and feeds and prints each entry annotated with its hits.
+func reportBlocklists(input string, zones string, feedSources string) {
+	var entries []prefixEntry
+	if ip, prefixLen, err := parseIPv6WithOptionalPrefix(input); err == nil {
+		if prefixLen < 0 {
+			prefixLen = 128
+		}
+		mask := net.CIDRMask(prefixLen, 128)
+		entries = []prefixEntry{{Net: &net.IPNet{IP: ip.Mask(mask), Mask: mask}}}
+	} else if entries, err = readPrefixFile(input); err != nil {
+		log.Fatal(err)
+	}
+	if zones == "" && feedSources == "" {
+		log.Fatal("give at least one DNSBL zone with -dnsbl or feed with -feed")
+	}
+	var zoneList []string
+	if zones != "" {
+		zoneList = strings.Split(zones, ",")
+	}
+	feeds, err := loadBlocklistFeeds(feedSources)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	listed := 0
+	for _, e := range entries {
+		name := e.Net.String()
+		if e.Host() {
+			name = e.Net.IP.String()
+		}
+		hits, err := checkBlocklists(context.Background(), net.DefaultResolver, e, zoneList, feeds)
+		if err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+		status := "clean"
+		if len(hits) > 0 {
+			listed++
+			parts := make([]string, len(hits))
+			for i, h := range hits {
+				parts[i] = fmt.Sprintf("%s (%s)", h.Source, h.Detail)
+			}
+			status = "listed: " + strings.Join(parts, "; ")
+		}
+		if e.Label != "" {
+			status += "  " + e.Label
+		}
+		fmt.Printf("%-40s %s\n", name, status)
+	}
+	if len(entries) > 1 {
+		fmt.Printf("\n%d of %d entries listed\n", listed, len(entries))
+	}
+}
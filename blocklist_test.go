@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeResolver answers DNSBL queries from a fixed table; other names do not exist.
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := f[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestDNSBLName(t *testing.T) {
+	got := dnsblName(net.ParseIP("2001:db8::1"), "dnsbl.example.")
+	want := "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.dnsbl.example"
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestCheckDNSBL(t *testing.T) {
+	listed := net.ParseIP("2001:db8::1")
+	refused := net.ParseIP("2001:db8::2")
+	r := fakeResolver{
+		dnsblName(listed, "dnsbl.example"):  {"127.0.0.2", "127.0.0.4"},
+		dnsblName(refused, "dnsbl.example"): {"127.255.255.254"},
+	}
+	codes, err := checkDNSBL(context.Background(), r, listed, "dnsbl.example")
+	if err != nil || strings.Join(codes, ",") != "127.0.0.2,127.0.0.4" {
+		t.Errorf("expected listing codes, got %v, %v", codes, err)
+	}
+	codes, err = checkDNSBL(context.Background(), r, net.ParseIP("2001:db8::3"), "dnsbl.example")
+	if err != nil || codes != nil {
+		t.Errorf("expected clean result, got %v, %v", codes, err)
+	}
+	if _, err := checkDNSBL(context.Background(), r, refused, "dnsbl.example"); err == nil {
+		t.Error("expected error for refused query")
+	}
+}
+
+func TestCheckBlocklists(t *testing.T) {
+	feedEntries, err := readBlocklistFeed(strings.NewReader(`; DROPv6
+3fff:0:bad::/48 ; SBL000001
+3fff:0:f00::/44 ; SBL000002
+192.0.2.0/24 ; SBL000003
+3fff:0:1::99  # single address, no reference
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feedEntries) != 3 {
+		t.Fatalf("expected 3 IPv6 feed entries, got %d", len(feedEntries))
+	}
+	feeds := []blocklistFeed{{Name: "drop", Entries: feedEntries}}
+	r := fakeResolver{dnsblName(net.ParseIP("3fff:0:1::99"), "dnsbl.example"): {"127.0.0.3"}}
+
+	cases := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"address in feed prefix", "3fff:0:bad::25", []string{"drop:3fff:0:bad::/48 SBL000001"}},
+		{"prefix inside feed prefix", "3fff:0:f0f::/48", []string{"drop:3fff:0:f00::/44 SBL000002"}},
+		{"prefix covering feed prefix", "3fff::/32", []string{"drop:3fff:0:bad::/48 SBL000001", "drop:3fff:0:f00::/44 SBL000002", "drop:3fff:0:1::99/128"}},
+		{"feed and DNSBL", "3fff:0:1::99", []string{"drop:3fff:0:1::99/128", "dnsbl.example:127.0.0.3"}},
+		{"clean", "3fff:0:1::80", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := readPrefixEntries(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			hits, err := checkBlocklists(context.Background(), r, entries[0], []string{"dnsbl.example"}, feeds)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, h := range hits {
+				got = append(got, h.Source+":"+h.Detail)
+			}
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
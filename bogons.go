@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"slices"
+)
+
+// bogon is a prefix that should never be seen on the public Internet, and why.
+type bogon struct {
+	Prefix netip.Prefix
+	Reason string
+}
+
+// MarshalJSON writes the prefix as bogonText does.
+func (b bogon) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Prefix string `json:"prefix"`
+		Reason string `json:"reason"`
+	}{bogonText(b.Prefix), b.Reason})
+}
+
+var (
+	// globalUnicast is the space IANA allocates to the RIRs from (RFC 4291).
+	globalUnicast = netip.MustParsePrefix("2000::/3")
+	// reservedByIETF holds the special-purpose entries at the bottom of the
+	// space, among them the globally reachable NAT64 prefix, so only its entries
+	// are bogons.
+	reservedByIETF = netip.MustParsePrefix("::/8")
+)
+
+// specialBogons returns the special-purpose entries that are not globally
+// reachable. An entry holding one that is, as 2001::/23 holds Teredo and AMT,
+// is left to its more specific entries.
+func specialBogons(entries []specialEntry) []bogon {
+	var out []bogon
+	for _, e := range entries {
+		if e.GloballyReachable == nil || *e.GloballyReachable {
+			continue
+		}
+		holdsReachable := slices.ContainsFunc(entries, func(r specialEntry) bool {
+			return r.Prefix.Bits() > e.Prefix.Bits() && e.Prefix.Contains(r.Prefix.Addr()) &&
+				(r.GloballyReachable == nil || *r.GloballyReachable)
+		})
+		if !holdsReachable {
+			out = append(out, bogon{e.Prefix, fmt.Sprintf("%s (%s)", e.Name, e.RFC)})
+		}
+	}
+	return out
+}
+
+// bogonList returns the space outside 2000::/3 and ::/8, the bogons of the
+// special-purpose registry not within it, and with full, the unallocated
+// prefixes of 2000::/3 those leave, in address order and not overlapping.
+func bogonList(entries []specialEntry, full []netip.Prefix) []bogon {
+	var list []bogon
+	outside := subtractPrefixes(netip.MustParsePrefix("::/0"), []netip.Prefix{globalUnicast, reservedByIETF})
+	for _, p := range outside {
+		list = append(list, bogon{p, "Outside global unicast (2000::/3)"})
+	}
+	var covered []netip.Prefix
+	for _, b := range specialBogons(entries) {
+		if !slices.ContainsFunc(outside, func(o netip.Prefix) bool { return o.Contains(b.Prefix.Addr()) }) {
+			list = append(list, b)
+			covered = append(covered, b.Prefix)
+		}
+	}
+	for _, f := range full {
+		if !globalUnicast.Overlaps(f) {
+			continue
+		}
+		for _, p := range subtractPrefixes(f, covered) {
+			list = append(list, bogon{p, "Unallocated (Team Cymru fullbogons)"})
+		}
+	}
+	slices.SortFunc(list, func(a, b bogon) int {
+		if c := a.Prefix.Addr().Compare(b.Prefix.Addr()); c != 0 {
+			return c
+		}
+		return a.Prefix.Bits() - b.Prefix.Bits()
+	})
+	return slices.CompactFunc(list, func(a, b bogon) bool { return a.Prefix == b.Prefix })
+}
+
+// readFullBogons reads a Team Cymru fullbogons list: one prefix per line, with
+// '#' comments.
+func readFullBogons(path string) ([]netip.Prefix, error) {
+	entries, err := readPrefixFile(path)
+	if err != nil {
+		return nil, err
+	}
+	prefixes := make([]netip.Prefix, len(entries))
+	for i, e := range entries {
+		prefixes[i] = entryPrefix(e)
+	}
+	return prefixes, nil
+}
+
+// bogonText is a bogon prefix as a router takes it, which for the IPv4-mapped
+// prefix is in hex rather than dotted quad.
+func bogonText(p netip.Prefix) string {
+	if p.Addr().Is4In6() && !explodedOutput {
+		b := p.Addr().As16()
+		return fmt.Sprintf("::ffff:%x:%x/%d", uint16(b[12])<<8|uint16(b[13]), uint16(b[14])<<8|uint16(b[15]), p.Bits())
+	}
+	return prefixText(p)
+}
+
+// writeBogonFilterIOS writes the bogons as an IOS prefix-list denying them and
+// every prefix within them.
+func writeBogonFilterIOS(w io.Writer, name string, list []bogon) {
+	for i, b := range list {
+		fmt.Fprintf(w, "ipv6 prefix-list %s seq %d deny %s le 128\n", name, (i+1)*5, bogonText(b.Prefix))
+	}
+	fmt.Fprintf(w, "ipv6 prefix-list %s seq %d permit ::/0 le 128\n", name, (len(list)+1)*5)
+}
+
+// writeBogonFilterJunos writes the bogons as a Junos policy rejecting them and
+// every prefix within them.
+func writeBogonFilterJunos(w io.Writer, name string, list []bogon) {
+	for _, b := range list {
+		fmt.Fprintf(w, "set policy-options policy-statement %s term bogons from route-filter %s orlonger\n", name, bogonText(b.Prefix))
+	}
+	fmt.Fprintf(w, "set policy-options policy-statement %s term bogons then reject\n", name)
+}
+
+// writeBogonFilterBIRD writes the bogons as a BIRD prefix set, matching them
+// and every prefix within them.
+func writeBogonFilterBIRD(w io.Writer, name string, list []bogon) {
+	fmt.Fprintf(w, "define %s = [\n", name)
+	for i, b := range list {
+		sep := ","
+		if i == len(list)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(w, "  %s+%s # %s\n", bogonText(b.Prefix), sep, b.Reason)
+	}
+	fmt.Fprintln(w, "];")
+}
+
+// bogonFilters maps -filter names to their writers.
+var bogonFilters = map[string]func(io.Writer, string, []bogon){
+	"ios":   writeBogonFilterIOS,
+	"junos": writeBogonFilterJunos,
+	"bird":  writeBogonFilterBIRD,
+}
+
+// runBogons prints the prefixes to filter at a network's edge: the
+// special-purpose prefixes that are not globally reachable and the space
+// outside 2000::/3, and with full, the unallocated prefixes of 2000::/3 from
+// Team Cymru's list, read from fullFile or the cache. The list is one prefix
+// per line, or with filter, a router filter named name.
+func runBogons(registry string, full bool, fullFile, filter, name string) {
+	write, ok := bogonFilters[filter]
+	if filter != "" && !ok {
+		log.Fatalf("unknown filter %q (want ios, junos, or bird)", filter)
+	}
+	entries, err := loadSpecialRegistry(registry)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var unallocated []netip.Prefix
+	if full || fullFile != "" {
+		if fullFile == "" {
+			paths, err := datasets["bogons"].fetch(false)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fullFile = paths[0]
+		}
+		if unallocated, err = readFullBogons(fullFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	list := bogonList(entries, unallocated)
+	writeResult(list, func() {
+		if write != nil {
+			write(os.Stdout, name, list)
+			return
+		}
+		for _, b := range list {
+			fmt.Println(bogonText(b.Prefix))
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func bogonPrefixes(list []bogon) string {
+	var s []string
+	for _, b := range list {
+		s = append(s, bogonText(b.Prefix))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestBogonList(t *testing.T) {
+	entries, err := loadSpecialRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := bogonPrefixes(bogonList(entries, nil))
+	want := "::/128 ::1/128 ::ffff:0:0/96 64:ff9b:1::/48 100::/8 200::/7 400::/6 800::/5 1000::/4 " +
+		"2001:2::/48 2001:db8::/32 3fff::/20 4000::/2 8000::/1"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	for _, reachable := range []string{"64:ff9b::1", "2001::1", "2002::1", "2001:1::1", "2620:4f:8000::1"} {
+		a := netip.MustParseAddr(reachable)
+		for _, b := range bogonList(entries, nil) {
+			if b.Prefix.Contains(a) {
+				t.Errorf("%s is globally reachable but within bogon %s", reachable, b.Prefix)
+			}
+		}
+	}
+}
+
+func TestBogonListFull(t *testing.T) {
+	entries, err := loadSpecialRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := []netip.Prefix{
+		netip.MustParsePrefix("::/8"),          // outside 2000::/3, ignored
+		netip.MustParsePrefix("2001:db8::/32"), // already a special bogon
+		netip.MustParsePrefix("3ffe::/16"),
+		netip.MustParsePrefix("3ffe::/16"),
+	}
+	var extra []string
+	for _, b := range bogonList(entries, full) {
+		if strings.HasPrefix(b.Reason, "Unallocated") {
+			extra = append(extra, b.Prefix.String())
+		}
+	}
+	if strings.Join(extra, " ") != "3ffe::/16" {
+		t.Errorf("unallocated: got %v, want [3ffe::/16]", extra)
+	}
+}
+
+func TestBogonFilters(t *testing.T) {
+	list := []bogon{
+		{netip.MustParsePrefix("2001:db8::/32"), "Documentation (RFC3849)"},
+		{netip.MustParsePrefix("fc00::/7"), "Unique-Local (RFC4193)"},
+	}
+	tests := map[string]string{
+		"ios": "ipv6 prefix-list B seq 5 deny 2001:db8::/32 le 128\n" +
+			"ipv6 prefix-list B seq 10 deny fc00::/7 le 128\n" +
+			"ipv6 prefix-list B seq 15 permit ::/0 le 128\n",
+		"junos": "set policy-options policy-statement B term bogons from route-filter 2001:db8::/32 orlonger\n" +
+			"set policy-options policy-statement B term bogons from route-filter fc00::/7 orlonger\n" +
+			"set policy-options policy-statement B term bogons then reject\n",
+		"bird": "define B = [\n  2001:db8::/32+, # Documentation (RFC3849)\n  fc00::/7+ # Unique-Local (RFC4193)\n];\n",
+	}
+	for name, want := range tests {
+		var buf bytes.Buffer
+		bogonFilters[name](&buf, "B", list)
+		if buf.String() != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", name, buf.String(), want)
+		}
+	}
+}
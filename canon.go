@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// addressCandidate matches text that may be an IPv6 address, with an optional
+// prefix length. Candidates are kept only if they parse, so times, MACs and
+// the like are left alone.
+var addressCandidate = regexp.MustCompile(`[0-9A-Fa-f]*:[0-9A-Fa-f:.]*(?:/[0-9]{1,3})?`)
+
+// rewrittenAddress is an address as written and as rewritten.
+type rewrittenAddress struct {
+	Line   int    `json:"line,omitempty"` // set when reading stdin
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+// canonicalForm is the RFC 5952 text of an address: lowercase, no leading
+// zeros, and the longest run of zero groups compressed.
+func canonicalForm(a netip.Addr) string {
+	return a.String()
+}
+
+// expandedForm is the fully expanded text of an address, 39 characters with
+// leading zeros, as ip6.arpa tooling and some vendor systems want it.
+func expandedForm(a netip.Addr) string {
+	return expandIPv6(a.AsSlice())
+}
+
+// rewriteAddress returns form(address) for an address, with its prefix length
+// if it has one, as rewritePrefix does.
+func rewriteAddress(s string, form func(netip.Addr) string) (string, error) {
+	ip, prefixLen, err := parseIPv6WithOptionalPrefix(s)
+	if err != nil {
+		return "", err
+	}
+	addr := netip.AddrFrom16([16]byte(ip.To16()))
+	if prefixLen >= 0 {
+		return rewritePrefix(addr, strconv.Itoa(prefixLen), form), nil
+	}
+	return form(addr), nil
+}
+
+// addressMatch is an IPv6 address found in text, with the prefix length or
+// zone written after it, if any.
+type addressMatch struct {
+	Start, End int // of the address and prefix length in the text
+	Addr       netip.Addr
+	Length     string // the prefix length as written, without the slash
+	Zone       string // as in fe80::1%eth0, after End
+}
+
+// findAddresses returns the IPv6 addresses in line. An address must stand
+// apart from the words around it, so "std::string" is not taken for one;
+// brackets, as in [2001:db8::1]:443, and punctuation may surround it.
+func findAddresses(line string) []addressMatch {
+	var found []addressMatch
+	for _, loc := range addressCandidate.FindAllStringIndex(line, -1) {
+		// A sentence may end just after an address.
+		text := strings.TrimRight(line[loc[0]:loc[1]], ".")
+		end := loc[0] + len(text)
+		if loc[0] > 0 && isWordByte(line[loc[0]-1]) || end < len(line) && isWordByte(line[end]) {
+			continue
+		}
+		addrText, length, hasLength := strings.Cut(text, "/")
+		if strings.Count(addrText, ":") < 2 {
+			continue
+		}
+		addr, err := netip.ParseAddr(addrText)
+		if err != nil || !addr.Is6() {
+			continue
+		}
+		m := addressMatch{Start: loc[0], End: end, Addr: addr, Length: length}
+		if !hasLength && end < len(line) && line[end] == '%' {
+			zone := line[end+1:]
+			if i := strings.IndexFunc(zone, func(r rune) bool { return r > 0x7f || !isWordByte(byte(r)) && r != '-' && r != '.' }); i >= 0 {
+				zone = zone[:i]
+			}
+			m.Zone = strings.TrimRight(zone, ".")
+		}
+		found = append(found, m)
+	}
+	return found
+}
+
+// rewriteAddresses replaces each IPv6 address in line with form(address),
+// keeping any prefix length and zone and everything around it, and returns
+// the new line and the addresses it found.
+func rewriteAddresses(line string, form func(netip.Addr) string) (string, []rewrittenAddress) {
+	var found []rewrittenAddress
+	var b strings.Builder
+	last := 0
+	for _, m := range findAddresses(line) {
+		rewritten := form(m.Addr)
+		if m.Length != "" {
+			rewritten = rewritePrefix(m.Addr, m.Length, form)
+		}
+		found = append(found, rewrittenAddress{Input: line[m.Start:m.End], Output: rewritten})
+		b.WriteString(line[last:m.Start])
+		b.WriteString(rewritten)
+		last = m.End
+	}
+	b.WriteString(line[last:])
+	return b.String(), found
+}
+
+// rewritePrefix returns form(addr) with the prefix length as written. A prefix
+// written without host bits, as a network is, is written without them after
+// the rewrite too.
+func rewritePrefix(addr netip.Addr, length string, form func(netip.Addr) string) string {
+	out := form(addr)
+	bits, err := strconv.Atoi(length)
+	if err != nil || bits > 128 || netip.PrefixFrom(addr, bits).Masked().Addr() != addr {
+		return out + "/" + length
+	}
+	if a, err := netip.ParseAddr(out); err == nil {
+		if masked := netip.PrefixFrom(a, bits).Masked().Addr(); masked != a {
+			out = addrText(masked)
+		}
+	}
+	return out + "/" + length
+}
+
+// isWordByte reports whether c can be part of a word next to an address.
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// rewriteStream copies r to w with every IPv6 address rewritten by form,
+// passing the addresses found to emit with their line numbers.
+func rewriteStream(r io.Reader, w io.Writer, form func(netip.Addr) string, emit func(rewrittenAddress)) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line, found := rewriteAddresses(scanner.Text(), form)
+		for _, c := range found {
+			c.Line = lineNo
+			emit(c)
+		}
+		if w != nil {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// runRewrite prints form(address), or with an address of "-", copies stdin
+// to stdout with every IPv6 address in it rewritten by form, to clean up
+// configuration files and inventories. In JSON and the other formats it lists
+// the addresses found instead.
+func runRewrite(address string, form func(netip.Addr) string) {
+	if address != "-" {
+		out, err := rewriteAddress(address, form)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result := rewrittenAddress{Input: address, Output: out}
+		writeResult(result, func() {
+			fmt.Println(result.Output)
+		})
+		return
+	}
+
+	if outputFormat == "text" && outputTemplate == nil {
+		out := bufio.NewWriter(os.Stdout)
+		err := rewriteStream(os.Stdin, out, form, func(rewrittenAddress) {})
+		if ferr := out.Flush(); err == nil {
+			err = ferr
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// JSON is one array; the other formats a result per address.
+	results := []rewrittenAddress{}
+	err := rewriteStream(os.Stdin, nil, form, func(c rewrittenAddress) {
+		if outputFormat == "json" {
+			results = append(results, c)
+			return
+		}
+		writeResult(c, nil)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFormat == "json" {
+		writeResult(results, nil)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewriteAddress(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"2001:0DB8:0000:0000:0000:0000:0000:0001", "2001:db8::1"},
+		{"2001:db8:0:0:1:0:0:1", "2001:db8::1:0:0:1"},
+		{"2001:db8:0:1:1:1:1:1", "2001:db8:0:1:1:1:1:1"},
+		{"2001:DB8::0:1/64", "2001:db8::1/64"},
+		{"::FFFF:192.0.2.1", "::ffff:192.0.2.1"},
+		{"0:0:0:0:0:0:0:0", "::"},
+	}
+	for _, tt := range tests {
+		got, err := rewriteAddress(tt.in, canonicalForm)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := rewriteAddress("2001:db8::g", canonicalForm); err == nil {
+		t.Error("invalid address accepted")
+	}
+	got, err := rewriteAddress("2001:db8::1/64", expandedForm)
+	if err != nil || got != "2001:0db8:0000:0000:0000:0000:0000:0001/64" {
+		t.Errorf("expanded: got %q, %v", got, err)
+	}
+}
+
+func TestRewriteAddresses(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{" ipv6 address 2001:DB8:0:0::1/64", " ipv6 address 2001:db8::1/64"},
+		{"server 2001:0db8::0053, 2001:db8:0:0:0:0:0:54;", "server 2001:db8::53, 2001:db8::54;"},
+		{"next hop is 2001:DB8::1.", "next hop is 2001:db8::1."},
+		{"fe80::0001%eth0", "fe80::1%eth0"},
+		{"at 10:30:00 mac 00:11:22:33:44:55", "at 10:30:00 mac 00:11:22:33:44:55"},
+		{"std::vector<int> v;", "std::vector<int> v;"},
+		{"no addresses here", "no addresses here"},
+	}
+	for _, tt := range tests {
+		got, _ := rewriteAddresses(tt.in, canonicalForm)
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	got, _ := rewriteAddresses("ns1 IN AAAA 2001:db8::53", expandedForm)
+	if got != "ns1 IN AAAA 2001:0db8:0000:0000:0000:0000:0000:0053" {
+		t.Errorf("expanded: got %q", got)
+	}
+}
+
+func TestRewriteStream(t *testing.T) {
+	var out strings.Builder
+	var found []rewrittenAddress
+	err := rewriteStream(strings.NewReader("a 2001:DB8::1\n\nb 2001:db8::2 2001:0db8::3\n"), &out, canonicalForm, func(c rewrittenAddress) {
+		found = append(found, c)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "a 2001:db8::1\n\nb 2001:db8::2 2001:db8::3\n" {
+		t.Errorf("got %q", out.String())
+	}
+	if len(found) != 3 || found[0].Line != 1 || found[2].Line != 3 || found[2].Input != "2001:0db8::3" {
+		t.Errorf("found %+v", found)
+	}
+}
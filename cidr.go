@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// cidrResult is an address range and the fewest prefixes covering it.
+type cidrResult struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Label    string   `json:"label,omitempty"`
+	Prefixes []string `json:"prefixes"`
+}
+
+// prefixRange is the addresses of a prefix.
+type prefixRange struct {
+	Prefix string `json:"prefix"`
+	First  string `json:"first"`
+	Last   string `json:"last"`
+	Count  string `json:"count"` // decimal, as it can exceed 64 bits
+	Bits   int    `json:"count_log2"`
+}
+
+// expandPrefix returns the first and last address of a prefix and how many it
+// holds, 2^(128-length).
+func expandPrefix(prefix string) (prefixRange, error) {
+	p, err := parseSubnetParent(prefix)
+	if err != nil {
+		return prefixRange{}, err
+	}
+	bits := 128 - p.Bits()
+	count := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	return prefixRange{Prefix: prefixText(p), First: addrText(p.Addr()), Last: addrText(lastAddr(p)), Count: count.String(), Bits: bits}, nil
+}
+
+// runRange prints the first and last address of a prefix and its size.
+func runRange(prefix string) {
+	r, err := expandPrefix(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeResult(r, func() {
+		count := r.Count + " = 2^" + fmt.Sprint(r.Bits)
+		if r.Bits >= 20 {
+			n, _ := new(big.Int).SetString(r.Count, 10)
+			count = formatSubnetCount(n)
+		}
+		fmt.Printf("First:     %s\n", r.First)
+		fmt.Printf("Last:      %s\n", r.Last)
+		fmt.Printf("Addresses: %s\n", count)
+	})
+}
+
+// parseIPv6Addr parses a single IPv6 address, without a prefix length.
+func parseIPv6Addr(s string) (netip.Addr, error) {
+	ip, length, err := parseIPv6WithOptionalPrefix(s)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if length >= 0 {
+		return netip.Addr{}, fmt.Errorf("want an address, not a prefix: %s", s)
+	}
+	return netip.AddrFrom16([16]byte(ip.To16())), nil
+}
+
+// rangeToCIDR returns the fewest prefixes covering the addresses from start to
+// end inclusive.
+func rangeToCIDR(start, end string) (cidrResult, error) {
+	from, err := parseIPv6Addr(start)
+	if err != nil {
+		return cidrResult{}, fmt.Errorf("invalid start address: %v", err)
+	}
+	to, err := parseIPv6Addr(end)
+	if err != nil {
+		return cidrResult{}, fmt.Errorf("invalid end address: %v", err)
+	}
+	if from.Compare(to) > 0 {
+		return cidrResult{}, fmt.Errorf("start %s is after end %s", from, to)
+	}
+	result := cidrResult{Start: addrText(from), End: addrText(to), Prefixes: []string{}}
+	for _, p := range rangePrefixes(addrRange{from, to}) {
+		result.Prefixes = append(result.Prefixes, prefixText(p))
+	}
+	return result, nil
+}
+
+// convertRanges converts each range read from r, one per line as "START END"
+// or "START-END" with an optional label after it, passing each result to emit.
+// Blank lines and '#' comments are skipped; a bad line stops it with an error
+// naming the line.
+func convertRanges(r io.Reader, emit func(cidrResult)) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		start, end, ok := strings.Cut(fields[0], "-")
+		rest := fields[1:]
+		if !ok {
+			if len(fields) < 2 {
+				return fmt.Errorf("line %d: want START END or START-END", lineNo)
+			}
+			end, rest = fields[1], fields[2:]
+		}
+		result, err := rangeToCIDR(start, end)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		result.Label = strings.Join(rest, " ")
+		emit(result)
+	}
+	return scanner.Err()
+}
+
+// runCIDR prints the fewest prefixes covering the range from start to end, or
+// with no range given, those of each range read from stdin as a prefix list
+// carrying the ranges' labels, ready for the other commands.
+func runCIDR(start, end string) {
+	if start != "-" {
+		result, err := rangeToCIDR(start, end)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeResult(result, func() {
+			for _, p := range result.Prefixes {
+				fmt.Println(p)
+			}
+		})
+		return
+	}
+
+	// JSON is one array; every other format streams a result per line.
+	var results []cidrResult
+	err := convertRanges(os.Stdin, func(r cidrResult) {
+		if outputFormat == "json" {
+			results = append(results, r)
+			return
+		}
+		writeResult(r, func() {
+			for _, p := range r.Prefixes {
+				fmt.Println(strings.TrimSpace(p + " " + r.Label))
+			}
+		})
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFormat == "json" {
+		if results == nil {
+			results = []cidrResult{}
+		}
+		writeResult(results, nil)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRangeToCIDR(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       string
+	}{
+		{"2001:db8::", "2001:db8::", "2001:db8::/128"},
+		{"2001:db8::", "2001:db8::ffff", "2001:db8::/112"},
+		{"2001:db8::", "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", "2001:db8::/32"},
+		{"2001:db8::1", "2001:db8::6", "2001:db8::1/128 2001:db8::2/127 2001:db8::4/127 2001:db8::6/128"},
+		{"2001:db8::100", "2001:db8::2ff", "2001:db8::100/120 2001:db8::200/120"},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "::/0"},
+	}
+	for _, tt := range tests {
+		got, err := rangeToCIDR(tt.start, tt.end)
+		if err != nil {
+			t.Errorf("%s-%s: %v", tt.start, tt.end, err)
+			continue
+		}
+		if s := strings.Join(got.Prefixes, " "); s != tt.want {
+			t.Errorf("%s-%s: got %s, want %s", tt.start, tt.end, s, tt.want)
+		}
+	}
+	for _, bad := range [][2]string{{"2001:db8::2", "2001:db8::1"}, {"2001:db8::/64", "2001:db8::1"}, {"2001:db8::", "192.0.2.1"}} {
+		if _, err := rangeToCIDR(bad[0], bad[1]); err == nil {
+			t.Errorf("%s-%s: no error", bad[0], bad[1])
+		}
+	}
+}
+
+func TestConvertRanges(t *testing.T) {
+	var got []string
+	err := convertRanges(strings.NewReader("# legacy\n2001:db8::-2001:db8::ff lab\n\n2001:db8::100 2001:db8::2ff office floor 2\n"), func(r cidrResult) {
+		got = append(got, strings.Join(r.Prefixes, ",")+"="+r.Label)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2001:db8::/120=lab 2001:db8::100/120,2001:db8::200/120=office floor 2"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("got %s, want %s", s, want)
+	}
+	err = convertRanges(strings.NewReader("2001:db8::1 2001:db8::2\n2001:db8::5\n"), func(cidrResult) {})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("got %v, want a line 2 error", err)
+	}
+}
+
+func TestExpandPrefix(t *testing.T) {
+	tests := []struct {
+		prefix, first, last, count string
+		bits                       int
+	}{
+		{"2001:db8::/64", "2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", "18446744073709551616", 64},
+		{"2001:db8::1/128", "2001:db8::1", "2001:db8::1", "1", 0},
+		{"2001:db8::/120", "2001:db8::", "2001:db8::ff", "256", 8},
+		{"::/0", "::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "340282366920938463463374607431768211456", 128},
+	}
+	for _, tt := range tests {
+		got, err := expandPrefix(tt.prefix)
+		if err != nil {
+			t.Errorf("%s: %v", tt.prefix, err)
+			continue
+		}
+		if got.First != tt.first || got.Last != tt.last || got.Count != tt.count || got.Bits != tt.bits {
+			t.Errorf("%s: got %+v", tt.prefix, got)
+		}
+	}
+	if _, err := expandPrefix("2001:db8::"); err == nil {
+		t.Error("prefix without a length accepted")
+	}
+}
@@ -0,0 +1,232 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// addressClasses are the kinds of address classify tells apart, most specific
+// first: the first whose prefix holds an address is its class.
+var addressClasses = []struct {
+	Prefix      netip.Prefix
+	Class       string
+	Description string
+}{
+	{netip.MustParsePrefix("::/128"), "unspecified", "Unspecified (::)"},
+	{netip.MustParsePrefix("::1/128"), "loopback", "Loopback (::1)"},
+	{netip.MustParsePrefix("::ffff:0:0/96"), "ipv4-mapped", "IPv4-Mapped (::ffff:0:0/96)"},
+	{netip.MustParsePrefix("64:ff9b::/96"), "nat64-wkp", "NAT64 Well-Known Prefix (64:ff9b::/96)"},
+	{netip.MustParsePrefix("64:ff9b:1::/48"), "nat64-local", "NAT64 Network-Specific (64:ff9b:1::/48)"},
+	{netip.MustParsePrefix("100::/64"), "discard", "Discard-Only (100::/64)"},
+	{netip.MustParsePrefix("2001::/32"), "teredo", "Teredo (2001:0000::/32)"},
+	{netip.MustParsePrefix("2001:db8::/32"), "documentation", "Documentation (2001:db8::/32)"},
+	{netip.MustParsePrefix("2002::/16"), "6to4", "6to4 (2002::/16)"},
+	{netip.MustParsePrefix("3fff::/20"), "documentation", "Documentation (3fff::/20)"},
+	{netip.MustParsePrefix("fc00::/7"), "ula", "Unique Local Address (ULA, fc00::/7)"},
+	{netip.MustParsePrefix("fe80::/10"), "link-local", "Link-Local (fe80::/10)"},
+	{netip.MustParsePrefix("ff00::/8"), "multicast", "Multicast (ff00::/8)"},
+	{netip.MustParsePrefix("2000::/3"), "gua", "Global Unicast (2000::/3)"},
+}
+
+// multicastScopes names the scopes of RFC 4291 section 2.7 and RFC 7346.
+var multicastScopes = map[byte]string{
+	0x1: "Interface-Local",
+	0x2: "Link-Local",
+	0x3: "Realm-Local",
+	0x4: "Admin-Local",
+	0x5: "Site-Local",
+	0x8: "Organization-Local",
+	0xe: "Global",
+}
+
+// addressClass is the kind of an address.
+type addressClass struct {
+	Address     string   `json:"address"`
+	Class       string   `json:"class"`
+	Scope       string   `json:"scope,omitempty"`       // of a multicast address
+	IPv4        string   `json:"ipv4,omitempty"`        // embedded in a 6to4 address
+	ISATAP      string   `json:"isatap_ipv4,omitempty"` // of an ISATAP interface ID
+	Contains    []string `json:"contains,omitempty"`    // classes lying inside a prefix
+	Description string   `json:"description"`
+	Geo         *geoInfo `json:"geo,omitempty"`   // with -geoip-db
+	Error       string   `json:"error,omitempty"` // the address did not parse
+}
+
+// classifyAddress returns the class of an address: gua, ula, link-local,
+// loopback, unspecified, multicast with its scope, ipv4-mapped, 6to4 with its
+// IPv4 address, teredo, documentation, nat64-wkp, nat64-local, discard, or
+// reserved for the rest. A unicast address with an ISATAP interface ID is
+// given that interface's IPv4 address too.
+func classifyAddress(a netip.Addr) addressClass {
+	c := addressClass{Address: a.String(), Class: "reserved", Description: "Reserved / Unknown"}
+	for _, k := range addressClasses {
+		if k.Prefix.Contains(a) {
+			c.Class, c.Description = k.Class, k.Description
+			break
+		}
+	}
+	if c.Class == "multicast" {
+		scope := a.As16()[1] & 0x0f
+		var ok bool
+		if c.Scope, ok = multicastScopes[scope]; !ok {
+			c.Scope = fmt.Sprintf("Unknown (0x%02x)", scope)
+		}
+		c.Description += ", Scope: " + c.Scope
+	}
+	if c.Class == "6to4" {
+		v4, _ := sixToFourIPv4(a)
+		c.IPv4 = v4.String()
+		c.Description += ", IPv4: " + c.IPv4
+	}
+	switch c.Class {
+	case "unspecified", "loopback", "multicast", "ipv4-mapped", "nat64-wkp":
+	default:
+		if v4, ok := isatapIPv4(a); ok {
+			c.ISATAP = v4.String()
+			c.Description += ", ISATAP IPv4: " + c.ISATAP
+		}
+	}
+	return c
+}
+
+// classifyPrefix returns the class of a prefix: that of the most specific
+// class holding all of it, with the classes lying inside it as Contains, so
+// 2001::/23 is gua containing teredo rather than teredo by its first address.
+// A prefix no one class holds, such as ::/0, is mixed. A scope or embedded
+// IPv4 address is given only when the prefix fixes all of its bits.
+func classifyPrefix(p netip.Prefix) addressClass {
+	p = p.Masked()
+	if p.IsSingleIP() {
+		return classifyAddress(p.Addr())
+	}
+	c := addressClass{Address: p.String(), Class: "reserved", Description: "Reserved / Unknown"}
+	held := false
+	for _, k := range addressClasses {
+		switch {
+		case !held && k.Prefix.Bits() <= p.Bits() && k.Prefix.Contains(p.Addr()):
+			c.Class, c.Description, held = k.Class, k.Description, true
+		case k.Prefix.Bits() > p.Bits() && p.Contains(k.Prefix.Addr()) && !slices.Contains(c.Contains, k.Class):
+			c.Contains = append(c.Contains, k.Class)
+		}
+	}
+	switch {
+	case !held && len(c.Contains) > 0:
+		c.Class, c.Description = "mixed", "Spans several classes"
+	case c.Class == "multicast" && p.Bits() >= 16, c.Class == "6to4" && p.Bits() >= 48:
+		a := classifyAddress(p.Addr())
+		c.Scope, c.IPv4, c.Description = a.Scope, a.IPv4, a.Description
+	}
+	if len(c.Contains) > 0 {
+		c.Description += ", contains: " + strings.Join(c.Contains, ", ")
+	}
+	return c
+}
+
+// classifyText classifies an address, or a prefix as classifyPrefix does.
+func classifyText(s string) addressClass {
+	ip, bits, err := parseIPv6WithOptionalPrefix(s)
+	if err != nil {
+		return addressClass{Address: s, Error: fmt.Sprintf("invalid address %q: %v", s, err)}
+	}
+	a := netip.AddrFrom16([16]byte(ip.To16()))
+	c := classifyAddress(a)
+	if bits >= 0 {
+		c = classifyPrefix(netip.PrefixFrom(a, bits))
+	}
+	c.Address = s
+	return c
+}
+
+// classifyAddresses classifies each address read from r, one per line with
+// blank lines and '#' comments skipped, passing each result to emit.
+func classifyAddresses(r io.Reader, emit func(addressClass)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		emit(classifyText(fields[0]))
+	}
+	return scanner.Err()
+}
+
+// annotateClass adds what geo knows of the address c was made from, or of the
+// first address of its prefix.
+func annotateClass(c *addressClass, geo geoAnnotator) error {
+	if len(geo) == 0 || c.Error != "" {
+		return nil
+	}
+	ip, _, err := parseIPv6WithOptionalPrefix(c.Address)
+	if err != nil {
+		return err
+	}
+	c.Geo, err = geo.annotate(netip.AddrFrom16([16]byte(ip.To16())))
+	return err
+}
+
+// geoSuffix is the text a -geoip-db annotation adds to the end of a line.
+func geoSuffix(g *geoInfo) string {
+	if g == nil {
+		return ""
+	}
+	return "  " + g.String()
+}
+
+// runClassify prints the class of an address, or with an address of "-", of
+// each address read from stdin, marking those that do not parse as invalid.
+// With geoDBs, MaxMind DB files, each is annotated with its country, city, and
+// ASN too.
+func runClassify(address, geoDBs string) {
+	geo, err := newGeoAnnotator(geoDBs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if address != "-" {
+		c := classifyText(address)
+		if c.Error != "" {
+			log.Fatal(c.Error)
+		}
+		if err := annotateClass(&c, geo); err != nil {
+			log.Fatal(err)
+		}
+		writeResult(c, func() {
+			fmt.Printf("%s  %s%s\n", c.Class, c.Description, geoSuffix(c.Geo))
+		})
+		return
+	}
+
+	// JSON is one array; every other format streams a result per line.
+	results := []addressClass{}
+	err = classifyAddresses(os.Stdin, func(c addressClass) {
+		if err := annotateClass(&c, geo); err != nil {
+			log.Fatal(err)
+		}
+		if outputFormat == "json" {
+			results = append(results, c)
+			return
+		}
+		writeResult(c, func() {
+			if c.Error != "" {
+				fmt.Printf("%s invalid\n", c.Address)
+				return
+			}
+			fmt.Printf("%-40s %-14s %s%s\n", c.Address, c.Class, c.Description, geoSuffix(c.Geo))
+		})
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFormat == "json" {
+		writeResult(results, nil)
+	}
+}
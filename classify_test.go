@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestClassifyAddress(t *testing.T) {
+	tests := []struct{ addr, class, scope string }{
+		{"2607:f8b0:4004:800::200e", "gua", ""},
+		{"fd12:3456::1", "ula", ""},
+		{"fe80::1", "link-local", ""},
+		{"::1", "loopback", ""},
+		{"::", "unspecified", ""},
+		{"ff02::1", "multicast", "Link-Local"},
+		{"ff3e::8000:1", "multicast", "Global"},
+		{"ff03::1", "multicast", "Realm-Local"},
+		{"::ffff:192.0.2.1", "ipv4-mapped", ""},
+		{"2002:c000:201::1", "6to4", ""},
+		{"2001:0:4136:e378::1", "teredo", ""},
+		{"2001:db8::1", "documentation", ""},
+		{"3fff:123::1", "documentation", ""},
+		{"64:ff9b::192.0.2.1", "nat64-wkp", ""},
+		{"64:ff9b:1::1", "nat64-local", ""},
+		{"100::1", "discard", ""},
+		{"4000::1", "reserved", ""},
+	}
+	for _, tt := range tests {
+		c := classifyAddress(netip.MustParseAddr(tt.addr))
+		if c.Class != tt.class || c.Scope != tt.scope {
+			t.Errorf("%s: got %s %q, want %s %q", tt.addr, c.Class, c.Scope, tt.class, tt.scope)
+		}
+	}
+	if c := classifyAddress(netip.MustParseAddr("2002:c000:201::1")); c.IPv4 != "192.0.2.1" || c.Description != "6to4 (2002::/16), IPv4: 192.0.2.1" {
+		t.Errorf("6to4: got %+v", c)
+	}
+}
+
+func TestClassifyPrefix(t *testing.T) {
+	tests := []struct{ prefix, class, scope, contains string }{
+		{"2001::/23", "gua", "", "teredo"},
+		{"2001::/32", "teredo", "", ""},
+		{"2001:0:4136::/48", "teredo", "", ""},
+		{"::/0", "mixed", "", "unspecified loopback ipv4-mapped nat64-wkp nat64-local discard teredo documentation 6to4 ula link-local multicast gua"},
+		{"2000::/3", "gua", "", "teredo documentation 6to4"},
+		{"ff02::/16", "multicast", "Link-Local", ""},
+		{"ff00::/8", "multicast", "", ""},
+		{"4000::/3", "reserved", "", ""},
+		{"2001:db8::1/64", "documentation", "", ""},
+		{"::1/128", "loopback", "", ""},
+	}
+	for _, tt := range tests {
+		c := classifyPrefix(netip.MustParsePrefix(tt.prefix))
+		if c.Class != tt.class || c.Scope != tt.scope || strings.Join(c.Contains, " ") != tt.contains {
+			t.Errorf("%s: got %s %q contains %v, want %s %q contains %q", tt.prefix, c.Class, c.Scope, c.Contains, tt.class, tt.scope, tt.contains)
+		}
+	}
+	if c := classifyPrefix(netip.MustParsePrefix("2002:c000:201::/48")); c.IPv4 != "192.0.2.1" {
+		t.Errorf("6to4 /48: got %+v", c)
+	}
+	if c := classifyPrefix(netip.MustParsePrefix("2002::/24")); c.IPv4 != "" {
+		t.Errorf("6to4 /24 has no one IPv4 address: got %+v", c)
+	}
+}
+
+func TestClassifyAddresses(t *testing.T) {
+	var got []string
+	err := classifyAddresses(strings.NewReader("# hosts\nfe80::1 router\n\n2001:db8::/32\nbogus\n"), func(c addressClass) {
+		if c.Error != "" {
+			got = append(got, c.Address+"=invalid")
+			return
+		}
+		got = append(got, c.Address+"="+c.Class)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(got, " "); s != "fe80::1=link-local 2001:db8::/32=documentation bogus=invalid" {
+		t.Errorf("got %s", s)
+	}
+}
+
+func TestClassifyGeoIP(t *testing.T) {
+	geo, err := newGeoAnnotator("testdata/geoip-test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ in, want string }{
+		{"2001:db8:2::1", "US AS64501 Example Transit"},
+		{"2001:db8:1::/64", "FR Paris AS64500 Example Net"},
+		{"fe80::1", "-"},
+	}
+	for _, tt := range tests {
+		c := classifyText(tt.in)
+		if err := annotateClass(&c, geo); err != nil || c.Geo == nil || c.Geo.String() != tt.want {
+			t.Errorf("%s: got %v, %v, want %s", tt.in, c.Geo, err, tt.want)
+		}
+	}
+	c := classifyText("2001:db8:2::1")
+	if err := annotateClass(&c, nil); err != nil || c.Geo != nil {
+		t.Errorf("without -geoip-db: got %v, %v", c.Geo, err)
+	}
+}
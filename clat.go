@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+)
+
+// clatAddress is an address of a packet 464XLAT translates, in each family,
+// and the prefix the IPv4 address is embedded in.
+type clatAddress struct {
+	IPv4   string `json:"ipv4"`
+	IPv6   string `json:"ipv6"`
+	Prefix string `json:"prefix"`
+}
+
+// clatTranslation is the source, and the destination if given, of a packet
+// translated by the CLAT of RFC 6877.
+type clatTranslation struct {
+	Source      clatAddress  `json:"source"`
+	Destination *clatAddress `json:"destination,omitempty"`
+}
+
+// clatTranslate translates an address of a packet between IPv4 and IPv6 as
+// RFC 7915 does with the addresses of RFC 6052: an IPv4 address is embedded in
+// prefix, and an IPv6 address has its IPv4 address extracted from it. With no
+// prefix, which only a source may lack, the length of the prefix an IPv6
+// address was translated under is detected.
+func clatTranslate(a netip.Addr, prefix netip.Prefix) (clatAddress, error) {
+	if a.Is4() {
+		if !prefix.IsValid() {
+			return clatAddress{}, errors.New("translating an IPv4 source needs the CLAT's prefix, given with -clat-prefix")
+		}
+		v6, err := synthesizeNAT64(prefix, a)
+		if err != nil {
+			return clatAddress{}, err
+		}
+		return clatAddress{IPv4: a.String(), IPv6: addrText(v6), Prefix: prefixText(prefix)}, nil
+	}
+	length := detectNAT64Length(a)
+	if prefix.IsValid() {
+		if !prefix.Contains(a) {
+			return clatAddress{}, fmt.Errorf("%s is not within %s", addrText(a), prefixText(prefix))
+		}
+		length = prefix.Bits()
+	}
+	v4, p, err := extractNAT64(a, length)
+	if err != nil {
+		return clatAddress{}, err
+	}
+	return clatAddress{IPv4: v4.String(), IPv6: addrText(a), Prefix: prefixText(p)}, nil
+}
+
+// translateCLAT translates the source, and the destination unless it is
+// invalid, of a packet crossing a CLAT: the source under the CLAT's own
+// prefix, and the destination under the PLAT's, the NAT64 prefix.
+func translateCLAT(source, destination netip.Addr, plat, clat netip.Prefix) (clatTranslation, error) {
+	var t clatTranslation
+	if destination.IsValid() && source.Is4() != destination.Is4() {
+		return t, errors.New("source and destination must both be IPv4 or both be IPv6")
+	}
+	var err error
+	if t.Source, err = clatTranslate(source, clat); err != nil {
+		return t, fmt.Errorf("source: %v", err)
+	}
+	if !destination.IsValid() {
+		return t, nil
+	}
+	d, err := clatTranslate(destination, plat)
+	if err != nil {
+		return t, fmt.Errorf("destination: %v", err)
+	}
+	t.Destination = &d
+	return t, nil
+}
+
+// parseCLATAddress reads an address of a packet, IPv4 or IPv6.
+func parseCLATAddress(s string) (netip.Addr, error) {
+	a, err := netip.ParseAddr(s)
+	if err != nil || a.Zone() != "" {
+		return netip.Addr{}, fmt.Errorf("invalid address %q", s)
+	}
+	return a.Unmap(), nil
+}
+
+// runCLAT prints the addresses a packet from source to destination has on the
+// other side of a 464XLAT CLAT (RFC 6877): the IPv6 source and destination of
+// a packet from an IPv4 client, or the IPv4 ones of a translated packet seen
+// in a capture. platPrefix is the NAT64 prefix; clatPrefix is the CLAT's own,
+// a /64 delegated for it or a /96 of one, which may be left out when
+// decomposing. The destination may be empty.
+func runCLAT(source, destination, platPrefix, clatPrefix string) {
+	plat, err := parseNAT64Prefix(platPrefix, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var clat netip.Prefix
+	if clatPrefix != "" {
+		if clat, err = parseNAT64Prefix(clatPrefix, 0); err != nil {
+			log.Fatal(err)
+		}
+	}
+	src, err := parseCLATAddress(source)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var dst netip.Addr
+	if destination != "" {
+		if dst, err = parseCLATAddress(destination); err != nil {
+			log.Fatal(err)
+		}
+	}
+	t, err := translateCLAT(src, dst, plat, clat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeResult(t, func() {
+		line := func(label, kind string, a clatAddress) {
+			if src.Is4() {
+				fmt.Printf("%-13s%s → %s (%s prefix %s)\n", label, a.IPv4, a.IPv6, kind, a.Prefix)
+				return
+			}
+			fmt.Printf("%-13s%s → %s (%s prefix %s)\n", label, a.IPv6, a.IPv4, kind, a.Prefix)
+		}
+		line("Source:", "CLAT", t.Source)
+		if t.Destination != nil {
+			line("Destination:", "PLAT", *t.Destination)
+		}
+	})
+}
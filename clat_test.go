@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestTranslateCLAT(t *testing.T) {
+	plat := netip.MustParsePrefix("64:ff9b::/96")
+	clat := netip.MustParsePrefix("2001:db8:aaaa:1::/64")
+	tests := []struct {
+		src, dst    string
+		clat        netip.Prefix
+		wantSrc     clatAddress
+		wantDstIPv6 string
+		wantDstIPv4 string
+	}{
+		{"192.0.0.2", "198.51.100.7", clat,
+			clatAddress{"192.0.0.2", "2001:db8:aaaa:1:c0:0:200:0", "2001:db8:aaaa:1::/64"}, "64:ff9b::c633:6407", "198.51.100.7"},
+		{"2001:db8:aaaa:1:c0:0:200:0", "64:ff9b::c633:6407", clat,
+			clatAddress{"192.0.0.2", "2001:db8:aaaa:1:c0:0:200:0", "2001:db8:aaaa:1::/64"}, "64:ff9b::c633:6407", "198.51.100.7"},
+		// Without the CLAT prefix, its length is detected.
+		{"2001:db8:aaaa:1:c0:0:200:0", "", netip.Prefix{},
+			clatAddress{"192.0.0.2", "2001:db8:aaaa:1:c0:0:200:0", "2001:db8:aaaa:1::/64"}, "", ""},
+		{"192.0.0.1", "", netip.MustParsePrefix("2001:db8:aaaa:1::/96"),
+			clatAddress{"192.0.0.1", "2001:db8:aaaa:1::c000:1", "2001:db8:aaaa:1::/96"}, "", ""},
+	}
+	for _, tt := range tests {
+		var dst netip.Addr
+		if tt.dst != "" {
+			dst = netip.MustParseAddr(tt.dst)
+		}
+		got, err := translateCLAT(netip.MustParseAddr(tt.src), dst, plat, tt.clat)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.src, tt.dst, err)
+			continue
+		}
+		if got.Source != tt.wantSrc {
+			t.Errorf("%s: source %+v, want %+v", tt.src, got.Source, tt.wantSrc)
+		}
+		switch {
+		case tt.dst == "" && got.Destination != nil:
+			t.Errorf("%s: unexpected destination %+v", tt.src, got.Destination)
+		case tt.dst != "" && (got.Destination == nil || got.Destination.IPv6 != tt.wantDstIPv6 || got.Destination.IPv4 != tt.wantDstIPv4):
+			t.Errorf("%s: destination %+v, want %s %s", tt.dst, got.Destination, tt.wantDstIPv4, tt.wantDstIPv6)
+		}
+	}
+}
+
+func TestTranslateCLATErrors(t *testing.T) {
+	plat := netip.MustParsePrefix("64:ff9b::/96")
+	clat := netip.MustParsePrefix("2001:db8:aaaa:1::/64")
+	tests := []struct{ src, dst string }{
+		{"192.0.0.2", "2001:db8::1"},                  // mixed families
+		{"2001:db8:bbbb::c000:2", ""},                 // outside the CLAT prefix
+		{"2001:db8:aaaa:1:c0:0:200:0", "2001:db8::1"}, // outside the PLAT prefix
+	}
+	for _, tt := range tests {
+		var dst netip.Addr
+		if tt.dst != "" {
+			dst = netip.MustParseAddr(tt.dst)
+		}
+		_, err := translateCLAT(netip.MustParseAddr(tt.src), dst, plat, clat)
+		if err == nil {
+			t.Errorf("%s %s: accepted", tt.src, tt.dst)
+		}
+	}
+	if _, err := translateCLAT(netip.MustParseAddr("192.0.0.2"), netip.Addr{}, plat, netip.Prefix{}); err == nil {
+		t.Error("IPv4 source translated without a CLAT prefix")
+	}
+}
@@ -0,0 +1,1360 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// subcommand is an operation with its own flags and help text, run as
+// "ipv6utils NAME [flags] [ARG]". The flat flags of earlier releases keep working
+// alongside.
+type subcommand struct {
+	Name    string
+	Args    string // positional argument, as shown in the usage line
+	Summary string
+	Setup   func(fs *flag.FlagSet) func(args []string) // registers flags, returns the action
+	// Subcommands are run as "ipv6utils NAME SUB [flags] [ARG]".
+	Subcommands []subcommand
+}
+
+// subcommands returns the subcommands in the order help lists them.
+func subcommands() []subcommand {
+	return []subcommand{
+		{"subnet", "PREFIX", "Split a prefix into subnets of a new length, or count them.", setupSubnet, nil},
+		{"nat64", "ADDRESS|-", "Synthesize an IPv6 address from IPv4 (RFC 6052), or extract the IPv4 address; with -, of each line of stdin.", setupNAT64, []subcommand{
+			{"check", "PREFIX", "Check that a prefix can be used for NAT64 under RFC 6052, and whether it is the Well-Known Prefix or a network-specific one.", setupNAT64Check, nil},
+		}},
+		{"mac", "MAC|ADDRESS|-", "Convert a MAC to its link-local address, or recover the MAC from a link-local or SLAAC address; with -, of each line of stdin.", setupMAC, []subcommand{
+			{"file", "FILE|-", "Convert every MAC in a file, such as a switch MAC table export, to its link-local and SLAAC addresses.", setupMACFile, nil},
+		}},
+		{"arpa", "ADDRESS|-", "Print the ip6.arpa reverse DNS name of an address, or with -, of each line of stdin.", setupArpa, []subcommand{
+			{"zones", "PREFIX", "List the nibble-aligned ip6.arpa zones covering a prefix, such as the eight /60 zones of a /57.", setupArpaZones, nil},
+			{"stats", "PREFIX", "Report how much of a prefix's ip6.arpa tree the -zone files populate.", setupArpaStats, nil},
+		}},
+		{"format", "ADDRESS", "Show every representation of an address.", setupFormat, []subcommand{
+			{"canon", "[ADDRESS]", "Print the RFC 5952 canonical form of an address, or make every address read from stdin canonical.", setupFormatCanon, nil},
+			{"expand", "[ADDRESS]", "Print an address fully expanded, or expand every address read from stdin.", setupFormatExpand, nil},
+			{"compress", "[ADDRESS]", "Print an address compressed, the same as canon, or compress every address read from stdin.", setupFormatCanon, nil},
+		}},
+		{"classify", "[ADDRESS]", "Print the class of an address, such as gua, ula, link-local, or multicast with its scope, or of each read from stdin.", setupClassify, nil},
+		{"extract", "[FILE...]", "Print each distinct IPv6 address and prefix found in files or stdin, such as logs, configurations, or HTML, in canonical form.", setupExtract, nil},
+		{"sortu", "[FILE...]", "Sort the addresses and prefixes of files or stdin in numeric order and drop duplicates, even for lists larger than memory.", setupSortUnique, nil},
+		{"sanitize", "", "Copy stdin to stdout with every global and unique local address moved into 2001:db8::/32, the same address always to the same one.", setupSanitize, nil},
+		{"anonymize", "[ADDRESS]", "Pseudonymize an address, or every address read from stdin, with a keyed hash that keeps addresses sharing a prefix together.", setupAnonymize, nil},
+		{"special", "ADDRESS|PREFIX", "Print the IANA special-purpose registry entries covering an address or prefix, with their RFCs and forwardable and global flags.", setupSpecial, nil},
+		{"bogons", "", "Print the prefixes that should never be seen on the public Internet, for ingress filters: one per line, as JSON, or as an IOS, Junos, or BIRD filter.", setupBogons, nil},
+		{"pref64", "PREFIX", "Print the PREF64 Router Advertisement option (RFC 8781) announcing a NAT64 prefix, as bytes or as radvd or Kea configuration.", setupPREF64, nil},
+		{"6to4", "IPV4|ADDRESS|-", "Give the 6to4 /48 of an IPv4 address, or the IPv4 address of a 6to4 address; with -, of each line of stdin.", setup6to4, nil},
+		{"isatap", "IPV4|ADDRESS|-", "Form the ISATAP address of an IPv4 address in a /64, or give the IPv4 address of an ISATAP address; with -, of each line of stdin.", setupISATAP, nil},
+		{"clat", "SOURCE [DESTINATION]", "Translate the addresses of a packet across a 464XLAT CLAT (RFC 6877): an IPv4 client's source into the CLAT prefix and destination into the PLAT prefix, or back from IPv6.", setupCLAT, nil},
+		{"dns64", "COMMAND", "Check what a DNS64 resolver synthesizes.", setupCommandGroup, []subcommand{
+			{"test", "HOSTNAME", "Resolve the A and AAAA records of a name, tell which AAAA records were synthesized and from which IPv4 address, and report the resolver's NAT64 prefix.", setupDNS64Test, nil},
+		}},
+		{"explain", "ADDRESS", "Take an address apart: its type, LIR prefix, network, subnet ID, interface ID and how it was formed, and reverse DNS name.", setupExplain, nil},
+		{"convert", "VALUE", "Print an address as a decimal integer, 64-bit halves, hex, and binary, or turn one of those back into an address.", setupConvert, nil},
+		{"lpm", "[ADDRESS]", "Print the most specific prefix of a -table holding an address, or each address read from stdin.", setupLPM, nil},
+		{"contains", "PREFIX [ADDRESS]", "Print whether an address or prefix is inside a prefix, or for each read from stdin; exits 1 if not.", setupContains, nil},
+		{"lint", "FILE", "Check a prefix list for invalid lines, host bits set, duplicates, and prefixes inside others; exits 1 on problems.", setupLint, nil},
+		{"intersect", "A B", "Print the address space two prefix lists share, with the entries of each holding it.", setupIntersect, nil},
+		{"setdiff", "A B", "Compare the address space of two prefix lists: only in A, only in B, and in both.", setupSetDiff, nil},
+		{"cidr", "[START END]", "Print the fewest prefixes covering an address range, or each range read from stdin.", setupCIDR, nil},
+		{"range", "PREFIX", "Print the first and last address of a prefix and how many it holds.", setupRange, nil},
+		{"nth", "[PREFIX]", "Print the address, or /n subnet, of a prefix at an -index, optionally counted from the end.", setupNth, nil},
+		{"locate", "PREFIX ADDRESS", "Print which /n subnet of a prefix an address belongs to, and its index.", setupLocate, nil},
+		{"math", "COMMAND", "Add an offset to an address, subtract one from it, or measure the distance between two.", setupCommandGroup, []subcommand{
+			{"add", "ADDRESS OFFSET", "Print the address OFFSET after ADDRESS; the offset is decimal or 0x hex.", setupMathAdd, nil},
+			{"sub", "ADDRESS OFFSET", "Print the address OFFSET before ADDRESS; the offset is decimal or 0x hex.", setupMathSub, nil},
+			{"distance", "A B", "Print how many addresses apart two addresses are, with the power of two.", setupMathDistance, nil},
+		}},
+		{"subtract", "PREFIX", "Print the space left in a prefix after removing others, as the fewest prefixes covering it.", setupSubtract, nil},
+		{"size", "[PREFIX]", "Find the prefix length that splits a prefix into at least -need subnets.", setupSize, nil},
+		{"neighbors", "PREFIX", "Show a prefix's siblings, parent, and position.", setupNeighbors, nil},
+		{"plan", "PLAN.yaml", "Generate a hierarchical address plan from a YAML description of its levels.", setupPlan, []subcommand{
+			{"validate", "PLAN", "Check a prefix-list plan for duplicates, overlaps, allocations outside its parent, and non-nibble-aligned prefixes.", setupPlanValidate, nil},
+			{"diff", "OLD NEW", "Report prefixes added, removed, resized, and renamed between two versions of a prefix-list plan.", setupPlanDiff, nil},
+			{"export", "PLAN FORMAT", "Render an annotated prefix-list plan (gw=, dns=, ntp=, vlan= per subnet) as kea, radvd, ios, or terraform configuration, or with an ipv6utils-export-FORMAT plugin.", setupPlanExport, nil},
+		}},
+		{"merge", "FIRST SECOND", "Merge two prefix-list plans into one, reporting overlaps and duplicate names.", setupMerge, nil},
+		{"gc", "PLAN", "Release expired allocations (expires=DATE in the label) by removing them from a plan file in place.", setupGC, nil},
+		{"audit", "FILE", "Audit a prefix list against the allocation policy in -rules.", setupAudit, nil},
+		{"stale", "FILE", "Report allocations of a prefix list that the -seen activity files have not shown active for -days.", setupStale, nil},
+		{"heatmap", "PREFIX FILE", "Render a usage heatmap of a prefix split into -n children from a file of allocated prefixes and active addresses.", setupHeatmap, nil},
+		{"scheme", "COMMAND", "Build and decode prefixes with a YAML bit-field scheme of named subnet-ID fields.", setupCommandGroup, []subcommand{
+			{"encode", "FIELD=VALUE,...", "Build prefixes from field values; values may be names, numbers, or ranges (site=1-4).", setupSchemeEncode, nil},
+			{"decode", "ADDRESS...", "Decode addresses or prefixes into their field values.", setupSchemeDecode, nil},
+		}},
+		{"vlan", "PREFIX VLANS", "Map VLAN IDs and ranges, such as 10,20,100-110, to the child subnets of a prefix.", setupVLAN, []subcommand{
+			{"decode", "PREFIX ADDRESS...", "Decode addresses or prefixes back to the VLAN IDs they carry.", setupVLANDecode, nil},
+		}},
+		{"vanity", "PREFIX WORD...", "Find child subnets of a prefix whose subnet IDs spell hex words.", setupVanity, []subcommand{
+			{"iid", "PREFIX WORD...", "Form addresses in a /64 whose interface IDs spell hex words, or ASCII with -ascii.", setupVanityIID, nil},
+		}},
+		{"pd-sim", "POOL", "Simulate a prefix-delegation pool and report utilization and fragmentation over time.", setupPDSim, nil},
+		{"alloc", "COMMAND", "Track allocations in a database and hand out free subnets.", setupCommandGroup, []subcommand{
+			{"next", "[PREFIX]", "Print the first free subnets of a new length not overlapping any allocation.", setupAllocNext, nil},
+			{"add", "PREFIX", "Record an allocation in the database.", setupAllocAdd, nil},
+			{"free", "PREFIX", "Release an allocation from the database.", setupAllocFree, nil},
+			{"list", "", "List the allocations in the database, with when each was made.", setupAllocList, nil},
+			{"import", "FILE", "Add the allocations of a prefix list to the database.", setupAllocImport, nil},
+			{"export", "", "Write the database as a prefix list.", setupAllocExport, nil},
+		}},
+		{"report", "COMMAND", "Report on how an address plan is used.", setupCommandGroup, []subcommand{
+			{"utilization", "[PREFIX]", "Compute the utilization and RFC 3194 HD-ratio of a prefix from its allocations.", setupReportUtilization, nil},
+		}},
+		{"leases", "COMMAND", "Read a DHCPv6 lease database as a prefix list, or reconcile it with a -plan.", setupCommandGroup, []subcommand{
+			{"kea", "FILE", "Read a Kea lease6 memfile CSV or lease6-get-all JSON export.", setupLeasesKea, nil},
+			{"dhcpd6", "FILE", "Read an ISC dhcpd6.leases file.", setupLeasesDhcpd6, nil},
+		}},
+		{"router-config", "FILE...", "List the interface subnets and static routes of saved IOS, FRR, or Junos configurations as a prefix list.", setupRouterConfig, nil},
+		{"ra-config", "FILE...", "List the prefixes and routes advertised by radvd.conf or systemd-networkd .network files as a prefix list.", setupRAConfig, nil},
+		{"radius", "FILE", "Generate RADIUS Framed-IPv6-Prefix and Delegated-IPv6-Prefix entries from a subscriber file (USER FRAMED DELEGATED per line).", setupRADIUS, nil},
+		{"stats", "[FILE]", "Summarize a large address list, or stdin: types, top /48s and /64s, interface-ID styles, unique prefixes.", setupStats, nil},
+		{"iid-score", "ADDRESS|FILE", "Score how predictable the interface ID of an address is, or of every address in a file.", setupIIDScore, nil},
+		{"fix", "[FILE]", "Correct typos in a list of addresses and prefixes, or stdin, writing canonical values and reporting changes on stderr.", setupFix, nil},
+		{"geoip", "ADDRESS|FILE", "Annotate an address, or every address in a file, with country, city, and ASN from -geoip-db.", setupGeoIP, nil},
+		{"blocklist", "ADDRESS|PREFIX|FILE", "Check an address or prefix, or every entry in a prefix list, against -dnsbl zones and -feed lists.", setupBlocklist, nil},
+		{"rir", "COMMAND", "Look up delegations in the RIR statistics.", setupCommandGroup, []subcommand{
+			{"lookup", "ADDRESS|PREFIX", "Show which RIR and economy an address or prefix was delegated to.", setupRIRLookup, nil},
+			{"country", "CC", "List the IPv6 prefixes delegated to an economy (ISO 3166 code).", setupRIRCountry, nil},
+		}},
+		{"bgp-history", "PREFIX", "Summarize the BGP announcement history of a prefix from RIPEstat.", setupBGPHistory, nil},
+		{"doctor", "", "Check this host's IPv6 health: addresses, default route, RAs, temporary addresses, DNS64, reachability; exits 1 on problems.", setupDoctor, nil},
+		{"discover", "INTERFACE", "Find live IPv6 neighbors on an interface with all-nodes pings and mDNS (ping needs root).", setupDiscover, nil},
+		{"data", "COMMAND", "Manage the cached datasets: rir, oui, iana-special, and bogons.", setupCommandGroup, []subcommand{
+			{"update", "[NAME...]", "Download fresh copies of the named datasets, or of all of them.", setupDataUpdate, nil},
+			{"status", "", "Show the cache directory and the age of each cached dataset.", setupDataStatus, nil},
+		}},
+	}
+}
+
+// legacyModes maps each flat flag of earlier releases that selects an operation
+// to the command replacing it. The flags still work, with a warning.
+var legacyModes = map[string]string{
+	"s":             "nat64",
+	"m":             "mac",
+	"local":         "mac",
+	"a":             "mac",
+	"mac-file":      "mac file",
+	"ip6.arpa":      "arpa",
+	"arpa-stats":    "arpa stats",
+	"format":        "format",
+	"f":             "format",
+	"count":         "subnet -c",
+	"c":             "subnet -c",
+	"neighbors":     "neighbors",
+	"export":        "plan export",
+	"merge":         "merge",
+	"gc":            "gc",
+	"audit":         "audit",
+	"stale":         "stale",
+	"heatmap":       "heatmap",
+	"scheme-encode": "scheme encode",
+	"scheme-decode": "scheme decode",
+	"vlan":          "vlan",
+	"vlan-decode":   "vlan decode",
+	"vanity":        "vanity",
+	"vanity-iid":    "vanity iid",
+	"pd-sim":        "pd-sim",
+	"kea-leases":    "leases kea",
+	"dhcpd6-leases": "leases dhcpd6",
+	"router-config": "router-config",
+	"ra-config":     "ra-config",
+	"radius":        "radius",
+	"stats":         "stats",
+	"iid-score":     "iid-score",
+	"fix":           "fix",
+	"geoip":         "geoip",
+	"blocklist":     "blocklist",
+	"rir-lookup":    "rir lookup",
+	"rir-country":   "rir country",
+	"rir-refresh":   "data update rir",
+	"bgp-history":   "bgp-history",
+	"doctor":        "doctor",
+	"discover":      "discover",
+	"update-data":   "data update",
+	"data-status":   "data status",
+}
+
+// structuredFormats are the -output-format values of commands printing their
+// results through writeResult, which also renders -template.
+var structuredFormats = []string{"json", "ndjson"}
+
+// subnetFormats are the -output-format values of commands generating subnets.
+var subnetFormats = []string{"json", "ndjson", "csv", "yaml", "sql", "sqlite", "xlsx"}
+
+// commandOutputFormats lists the -output-format values each command can write
+// besides text. Commands missing here write only text and reject the flag, and
+// -template, rather than ignore it.
+var commandOutputFormats = map[string][]string{
+	"subnet":             subnetFormats,
+	"plan":               subnetFormats,
+	"nat64":              structuredFormats,
+	"nat64 check":        structuredFormats,
+	"mac":                structuredFormats,
+	"mac file":           {"json", "ndjson", "csv"},
+	"arpa":               structuredFormats,
+	"arpa zones":         structuredFormats,
+	"format":             structuredFormats,
+	"format canon":       structuredFormats,
+	"format expand":      structuredFormats,
+	"format compress":    structuredFormats,
+	"classify":           structuredFormats,
+	"extract":            structuredFormats,
+	"sortu":              structuredFormats,
+	"sanitize":           structuredFormats,
+	"anonymize":          structuredFormats,
+	"special":            structuredFormats,
+	"bogons":             structuredFormats,
+	"pref64":             structuredFormats,
+	"6to4":               structuredFormats,
+	"isatap":             structuredFormats,
+	"clat":               structuredFormats,
+	"dns64 test":         structuredFormats,
+	"explain":            structuredFormats,
+	"convert":            structuredFormats,
+	"lpm":                structuredFormats,
+	"contains":           structuredFormats,
+	"lint":               structuredFormats,
+	"intersect":          structuredFormats,
+	"setdiff":            structuredFormats,
+	"cidr":               structuredFormats,
+	"range":              structuredFormats,
+	"nth":                structuredFormats,
+	"locate":             structuredFormats,
+	"math add":           structuredFormats,
+	"math sub":           structuredFormats,
+	"math distance":      structuredFormats,
+	"subtract":           structuredFormats,
+	"size":               structuredFormats,
+	"neighbors":          structuredFormats,
+	"plan validate":      structuredFormats,
+	"plan diff":          structuredFormats,
+	"alloc next":         structuredFormats,
+	"alloc list":         structuredFormats,
+	"report utilization": structuredFormats,
+	"stats":              structuredFormats,
+}
+
+// legacyFormatFlags are the flat flags of earlier releases that chose the
+// output format of a single operation; -output-format does it for every one.
+var legacyFormatFlags = []string{"mac-output", "stats-output"}
+
+// deprecateLegacyFlags marks the flat flags of fs that legacyModes replaces as
+// deprecated in their help.
+func deprecateLegacyFlags(fs *flag.FlagSet) {
+	for name, command := range legacyModes {
+		if f := fs.Lookup(name); f != nil {
+			f.Usage = fmt.Sprintf("%s. Deprecated: use \"ipv6utils %s\".", strings.TrimSuffix(f.Usage, "."), command)
+		}
+	}
+	for _, name := range legacyFormatFlags {
+		if f := fs.Lookup(name); f != nil {
+			f.Usage = strings.TrimSuffix(f.Usage, ".") + ". Deprecated: use -output-format."
+		}
+	}
+}
+
+// warnLegacyFlags warns about each deprecated flat flag given, and returns the
+// name of the command replacing the first one selecting an operation, or "" if
+// there is none.
+func warnLegacyFlags(fs *flag.FlagSet) string {
+	name := ""
+	fs.Visit(func(f *flag.Flag) {
+		if slices.Contains(legacyFormatFlags, f.Name) {
+			log.Printf("Warning: -%s is deprecated; use -output-format", f.Name)
+		}
+		command, ok := legacyModes[f.Name]
+		if !ok {
+			return
+		}
+		log.Printf("Warning: -%s is deprecated; use \"ipv6utils %s\"", f.Name, command)
+		if name == "" {
+			words := strings.Fields(command)
+			c, _ := findSubcommand(words[0])
+			c, _ = findNested(c, words[1:])
+			name = c.Name
+		}
+	})
+	return name
+}
+
+// findSubcommand looks a subcommand up by name.
+func findSubcommand(name string) (subcommand, bool) {
+	for _, c := range subcommands() {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return subcommand{}, false
+}
+
+// findNested resolves "NAME SUB" when args[0] names one of c's subcommands,
+// returning it, named "NAME SUB", and the arguments after SUB. Otherwise it
+// returns c and args unchanged.
+func findNested(c subcommand, args []string) (subcommand, []string) {
+	if len(args) == 0 {
+		return c, args
+	}
+	for _, sub := range c.Subcommands {
+		if sub.Name == args[0] {
+			sub.Name = c.Name + " " + sub.Name
+			return sub, args[1:]
+		}
+	}
+	return c, args
+}
+
+// writeSubcommandList prints the subcommands with their summaries.
+func writeSubcommandList(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range subcommands() {
+		fmt.Fprintf(w, "  %-13s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintln(w, "\nRun \"ipv6utils help COMMAND\" for a command's flags.")
+}
+
+// newSubcommandFlags builds the flag set of a subcommand, with the -stable,
+// -output-format, -template, and -watch flags every command shares, -manifest
+// on those writing an -o file, and usage text naming the positional argument.
+func newSubcommandFlags(c subcommand, errorHandling flag.ErrorHandling) (*flag.FlagSet, func([]string)) {
+	fs := flag.NewFlagSet(c.Name, errorHandling)
+	fs.BoolVar(&stableOutput, "stable", false, "Deterministic output for version control: no progress lines or log timestamps.")
+	formatUsage := "Result format: text only; this command rejects other formats and -template."
+	if formats := commandOutputFormats[c.Name]; len(formats) > 0 {
+		formatUsage = "Result format: text, " + strings.Join(formats, ", ") + "."
+	}
+	fs.StringVar(&outputFormat, "output-format", "text", formatUsage)
+	fs.StringVar(&outputTemplateText, "template", "", "Go text/template for each result, e.g. '{{.Index}} {{.Prefix}}'.")
+	watch := fs.Bool("watch", false, "Rerun the command whenever one of its input files (any argument or flag naming an existing file) changes.")
+	watchInterval := fs.Duration("watch-interval", time.Second, "How often -watch checks its input files for changes.")
+	run := c.Setup(fs)
+	outputFlag := fs.Lookup("o")
+	var manifest *bool
+	if outputFlag != nil {
+		manifest = fs.Bool("manifest", false, "Write OUTPUT.manifest.json next to the -o file with its SHA-256, size, line count, and the flags used.")
+	}
+	action := func(args []string) {
+		outputFile := ""
+		if outputFlag != nil {
+			outputFile = outputFlag.Value.String()
+		}
+		if *watch {
+			runWatch(commandParameters(fs, args), outputFile, *watchInterval)
+			return
+		}
+		withManifest := manifest != nil && *manifest
+		if withManifest && outputFile == "" {
+			log.Fatal("-manifest requires -o")
+		}
+		run(args)
+		if withManifest {
+			if err := writeManifest(outputFile, commandParameters(fs, args)); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: ipv6utils %s [flags] %s\n\n%s\n\n", c.Name, c.Args, c.Summary)
+		if len(c.Subcommands) > 0 {
+			fmt.Fprintln(out, "Commands:")
+			for _, sub := range c.Subcommands {
+				fmt.Fprintf(out, "  %-13s %s\n", sub.Name, sub.Summary)
+			}
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, "Flags:")
+		fs.PrintDefaults()
+	}
+	return fs, action
+}
+
+// runSubcommand runs "NAME [flags] [ARG...]" and reports whether NAME was a
+// subcommand (or help); otherwise the caller falls back to the flat flags.
+func runSubcommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if args[0] == "help" {
+		if len(args) > 1 {
+			if c, ok := findSubcommand(args[1]); ok {
+				c, _ = findNested(c, args[2:])
+				fs, _ := newSubcommandFlags(c, flag.ExitOnError)
+				fs.SetOutput(os.Stdout)
+				fs.Usage()
+				return true
+			}
+		}
+		fmt.Println("Usage: ipv6utils COMMAND [flags] ARG, or ipv6utils [flags]")
+		fmt.Println()
+		writeSubcommandList(os.Stdout)
+		return true
+	}
+	c, ok := findSubcommand(args[0])
+	if !ok {
+		return false
+	}
+	c, args = findNested(c, args[1:])
+	fs, action := newSubcommandFlags(c, flag.ExitOnError)
+	positional := parseInterspersed(fs, args)
+	if err := subcommandArgsError(c, len(positional)); err != nil {
+		fmt.Fprintf(os.Stderr, "ipv6utils %s: %v\n", c.Name, err)
+		fs.Usage()
+		os.Exit(2)
+	}
+	applyStableOutput()
+	if err := checkOutputFormat(c.Name); err != nil {
+		log.Fatal(err)
+	}
+	action(positional)
+	return true
+}
+
+// subcommandArgsError reports n positional arguments that are too many for c,
+// going by its Args: none when it is empty, one per word, or any number when
+// the last ends in "...", as FILE... and [FILE...] do.
+func subcommandArgsError(c subcommand, n int) error {
+	fields := strings.Fields(c.Args)
+	if len(fields) > 0 && strings.HasSuffix(strings.TrimRight(fields[len(fields)-1], "]"), "...") {
+		return nil
+	}
+	switch want := len(fields); {
+	case n <= want:
+		return nil
+	case want == 0:
+		return fmt.Errorf("takes no arguments, got %d", n)
+	case want == 1:
+		return fmt.Errorf("expected one %s, got %d arguments", c.Args, n)
+	}
+	return fmt.Errorf("expected %s, got %d arguments", c.Args, n)
+}
+
+// parseInterspersed parses flags given before or after the positional
+// arguments, so "subnet 3fff::/32 -n 48" works like "subnet -n 48 3fff::/32".
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args) // ExitOnError: never returns an error
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// requireArgs exits with the command's usage unless its n positional arguments
+// are all given.
+func requireArgs(fs *flag.FlagSet, args []string, n int) {
+	if len(args) < n {
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// addExplodedFlag registers -exploded on a command that prints addresses.
+func addExplodedFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&explodedOutput, "exploded", false, "Write addresses fully expanded, with leading zeros, as 2001:0db8:0000:...")
+}
+
+// addWorkersFlag registers -workers on a command that converts stdin a line at
+// a time.
+func addWorkersFlag(fs *flag.FlagSet) {
+	fs.IntVar(&conversionWorkers, "workers", 1, "Goroutines converting the lines of stdin at once; the output keeps the input's order.")
+}
+
+func setupSubnet(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	prefix := fs.String("p", "", "Prefix to split, if not given as the argument.")
+	newPrefixLength := fs.Int("n", 64, "New prefix length.")
+	limit := fs.Int("l", 0, "Limit the number of subnets displayed.")
+	countOnly := fs.Bool("c", false, "Display only the number of subnets.")
+	nibbleAlign := fs.String("nibble-align", "", "Move -n off a nibble boundary onto one: round-up (/57 to /60), round-down (/57 to /56), or strict to fail instead of warning.")
+	outputFile := fs.String("o", "", "File to save the subnets to.")
+	excludeFile := fs.String("exclude", "", "Prefix list of ranges to skip; entries past their expires= date are not skipped.")
+	fs.StringVar(excludeFile, "exclude-file", "", "Alias for -exclude.")
+	startIndex := fs.String("start-index", "", "0-based index of the first subnet, for paging with -l.")
+	startAt := fs.String("start-at", "", "Prefix or address of the first subnet.")
+	reserveFirst := fs.Int("reserve-first", 0, "Label the first N subnets RESERVED.")
+	reserveLast := fs.Int("reserve-last", 0, "Label the last N subnets RESERVED.")
+	reserveSkip := fs.Bool("reserve-skip", false, "Leave reserved subnets out instead of labelling them.")
+	nameTemplate := fs.String("name-template", "", "Go text/template naming each subnet, e.g. 'site-{{.Index}}'.")
+	namesFile := fs.String("names", "", "File of subnet names, one per line from index 0; later subnets fall back to -name-template.")
+	levels := fs.String("levels", "", "Prefix lengths above -n with their own sheet in xlsx output, e.g. 48,56.")
+	compress := fs.Bool("compress", false, "Gzip the output; implied when -o ends in .gz.")
+	columns := fs.String("columns", defaultSubnetColumns, "Columns with -output-format csv or xlsx: index, prefix, network, length, parent, last, gateway, name, nibble_aligned, reserved.")
+	sampleCount := fs.Int("sample", 0, "Pick this many subnets at random instead of listing them in order.")
+	seed := fs.Int64("seed", 0, "Random seed for -sample and -strategy random; required with -stable. 0 picks a new seed and prints it on stderr.")
+	strategy := fs.String("strategy", "", "Order to hand out subnets in, per RFC 3531: leftmost, rightmost (address order, the default), center, or random.")
+	resumeFile := fs.String("resume", "", "Cursor file to continue from and checkpoint to; requires -o.")
+	return func(args []string) {
+		if len(args) == 0 && *prefix != "" {
+			args = []string{*prefix}
+		}
+		requireArgs(fs, args, 1)
+		arg := args[0]
+		aligned, err := alignPrefixLength(*newPrefixLength, *nibbleAlign)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*newPrefixLength = aligned
+		if *countOnly {
+			runCount(arg, *newPrefixLength)
+			return
+		}
+		runSubnets(arg, *newPrefixLength, subnetOptions{
+			Limit:        *limit,
+			OutputFile:   *outputFile,
+			ExcludeFile:  *excludeFile,
+			StartIndex:   *startIndex,
+			StartAt:      *startAt,
+			Resume:       *resumeFile,
+			Sample:       *sampleCount,
+			SampleSeed:   *seed,
+			Strategy:     *strategy,
+			ReserveFirst: *reserveFirst,
+			ReserveLast:  *reserveLast,
+			SkipReserved: *reserveSkip,
+			Columns:      *columns,
+			Levels:       *levels,
+			NameTemplate: *nameTemplate,
+			NamesFile:    *namesFile,
+			Compress:     *compress,
+		})
+	}
+}
+
+func setupNAT64(fs *flag.FlagSet) func([]string) {
+	addWorkersFlag(fs)
+	prefix := fs.String("k", "64:ff9b::", "NAT64 prefix (RFC 6052), a /96 unless given a length, as 2001:db8:100::/40; when given, extracted addresses must lie within it.")
+	prefixLength := fs.Int("prefix-length", 0, "Length of the NAT64 prefix: 32, 40, 48, 56, 64, or 96; when extracting, detected if neither it nor -k is given.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		// Only a -k given decides how to extract; otherwise the length is detected.
+		given := ""
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "k" {
+				given = *prefix
+			}
+		})
+		runNAT64(args[0], given, *prefixLength)
+	}
+}
+
+func setupNAT64Check(fs *flag.FlagSet) func([]string) {
+	prefixLength := fs.Int("prefix-length", 0, "Length of the prefix, if not given with it; a /96 when neither is given.")
+	registry := fs.String("registry", "", "IANA special-purpose registry CSV to read instead of the cached or built-in copy.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runNAT64Check(args[0], *prefixLength, *registry)
+	}
+}
+
+func setupPREF64(fs *flag.FlagSet) func([]string) {
+	lifetime := fs.Int("lifetime", 1800, "Lifetime of the prefix in seconds, rounded up to a multiple of 8; at most 65528.")
+	config := fs.String("config", "", "Write configuration instead: radvd or kea.")
+	iface := fs.String("interface", "eth0", "Interface of the radvd configuration.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runPREF64(args[0], *lifetime, *config, *iface)
+	}
+}
+
+func setupDNS64Test(fs *flag.FlagSet) func([]string) {
+	resolver := fs.String("resolver", "", "DNS server to ask, an address or name with an optional port, instead of the system's resolver.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runDNS64Test(args[0], *resolver)
+	}
+}
+
+func setup6to4(fs *flag.FlagSet) func([]string) {
+	addWorkersFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runSixToFour(args[0])
+	}
+}
+
+func setupISATAP(fs *flag.FlagSet) func([]string) {
+	addWorkersFlag(fs)
+	prefix := fs.String("prefix", "fe80::/64", "The /64 to form ISATAP addresses in.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runISATAP(args[0], *prefix)
+	}
+}
+
+func setupCLAT(fs *flag.FlagSet) func([]string) {
+	plat := fs.String("plat", "64:ff9b::", "NAT64 prefix of the PLAT, a /96 unless given a length.")
+	clat := fs.String("clat-prefix", "", "The CLAT's own prefix, a /64 delegated for translation or a /96 of one; detected when decomposing if not given.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		destination := ""
+		if len(args) > 1 {
+			destination = args[1]
+		}
+		runCLAT(args[0], destination, *plat, *clat)
+	}
+}
+
+func setupMAC(fs *flag.FlagSet) func([]string) {
+	addWorkersFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runConversion(args[0], convertMAC)
+	}
+}
+
+func setupArpa(fs *flag.FlagSet) func([]string) {
+	addWorkersFlag(fs)
+	prefixLength := fs.Int("n", 0, "Zone prefix length: the name is given relative to that zone; 0 gives the full ip6.arpa name.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runArpa(args[0], *prefixLength)
+	}
+}
+
+func setupArpaZones(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runArpaZones(args[0])
+	}
+}
+
+func setupFormat(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		arg := args[0]
+		formatIPv6(arg)
+	}
+}
+
+func setupFormatCanon(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		address := "-"
+		if len(args) > 0 {
+			address = args[0]
+		}
+		runRewrite(address, canonicalForm)
+	}
+}
+
+func setupFormatExpand(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		address := "-"
+		if len(args) > 0 {
+			address = args[0]
+		}
+		runRewrite(address, expandedForm)
+	}
+}
+
+// addGeoIPDBFlag registers -geoip-db on a command that can annotate the
+// addresses it prints.
+func addGeoIPDBFlag(fs *flag.FlagSet) *string {
+	return fs.String("geoip-db", "", "Comma-separated MaxMind GeoIP2/GeoLite2 .mmdb files to annotate each address with its country, city, and ASN.")
+}
+
+func setupClassify(fs *flag.FlagSet) func([]string) {
+	geoDBs := addGeoIPDBFlag(fs)
+	return func(args []string) {
+		address := "-"
+		if len(args) > 0 {
+			address = args[0]
+		}
+		runClassify(address, *geoDBs)
+	}
+}
+
+func setupExtract(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	classify := fs.Bool("classify", false, "Print the class of each address, as classify does.")
+	aggregate := fs.Bool("aggregate", false, "Print the fewest prefixes covering the addresses and prefixes found instead.")
+	geoDBs := addGeoIPDBFlag(fs)
+	return func(args []string) {
+		runExtract(args, *classify, *aggregate, *geoDBs)
+	}
+}
+
+func setupSortUnique(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	chunk := fs.Int("chunk", 1<<20, "Lines to sort in memory at a time before spilling them to a temporary file.")
+	tmpDir := fs.String("tmpdir", "", "Directory for the temporary files (default the system's).")
+	return func(args []string) {
+		runSortUnique(args, *chunk, *tmpDir)
+	}
+}
+
+func setupSanitize(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	return func(args []string) {
+		runSanitize()
+	}
+}
+
+func setupAnonymize(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	key := fs.String("key", "", "Secret key; the same key gives the same pseudonyms.")
+	preserve := fs.Int("preserve-prefix", 0, "Number of leading bits to keep as they are, such as 32 for the provider or 48 for the site.")
+	return func(args []string) {
+		address := "-"
+		if len(args) > 0 {
+			address = args[0]
+		}
+		runAnonymize(address, *key, *preserve)
+	}
+}
+
+func setupSpecial(fs *flag.FlagSet) func([]string) {
+	addCacheDirFlag(fs)
+	addExplodedFlag(fs)
+	registry := fs.String("registry", "", "IANA special-purpose registry CSV to read instead of the cached or built-in copy.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runSpecial(args[0], *registry)
+	}
+}
+
+func setupBogons(fs *flag.FlagSet) func([]string) {
+	addCacheDirFlag(fs)
+	addExplodedFlag(fs)
+	registry := fs.String("registry", "", "IANA special-purpose registry CSV to read instead of the cached or built-in copy.")
+	full := fs.Bool("full", false, "Add the unallocated prefixes of 2000::/3 from Team Cymru's fullbogons list, downloaded into the cache.")
+	fullFile := fs.String("full-file", "", "Team Cymru fullbogons list to read instead of the cached copy; implies -full.")
+	filter := fs.String("filter", "", "Write a router filter instead: ios, junos, or bird.")
+	name := fs.String("name", "BOGONS-V6", "Name of the prefix-list, policy, or set of -filter.")
+	return func(args []string) {
+		requireArgs(fs, args, 0)
+		runBogons(*registry, *full, *fullFile, *filter, *name)
+	}
+}
+
+func setupExplain(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	lir := fs.Int("lir", 32, "Length of an LIR's allocation, shown as the LIR prefix.")
+	site := fs.Int("site", 48, "Length of the network of a /64, where its subnet ID starts.")
+	showBits := fs.Bool("binary", false, "Show the 128 bits by nibble, marking where the network, subnet ID, and interface ID meet.")
+	rirStats := fs.String("rir-stats", "", "Comma-separated RIR delegated-extended files to look up the registry delegation in.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runExplain(args[0], explainOptions{LIR: *lir, Site: *site, Bits: *showBits}, *rirStats)
+	}
+}
+
+func setupConvert(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	from := fs.String("from", "address", "Form of VALUE: address, int, hex, binary, or halves (HIGH,LOW).")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runConvert(args[0], *from)
+	}
+}
+
+func setupLPM(fs *flag.FlagSet) func([]string) {
+	table := fs.String("table", "", "Prefix list to match against, such as a routing table.")
+	return func(args []string) {
+		if *table == "" {
+			log.Fatal("-table is required")
+		}
+		address := "-"
+		if len(args) > 0 {
+			address = args[0]
+		}
+		runLPM(*table, address)
+	}
+}
+
+func setupContains(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		address := "-"
+		if len(args) > 1 {
+			address = args[1]
+		}
+		runContains(args[0], address)
+	}
+}
+
+func setupLint(fs *flag.FlagSet) func([]string) {
+	nested := fs.Bool("nested", false, "Allow prefixes inside others, for lists of aggregates and their parts.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runLint(args[0], *nested)
+	}
+}
+
+func setupIntersect(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runIntersect(args[0], args[1])
+	}
+}
+
+func setupSetDiff(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runSetDiff(args[0], args[1])
+	}
+}
+
+func setupSubtract(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	minus := fs.String("minus", "", "Comma-separated prefixes to remove.")
+	minusFile := fs.String("minus-file", "", "Prefix list of prefixes to remove, such as allocations; entries past their expires= date are not removed.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runSubtract(args[0], *minus, *minusFile)
+	}
+}
+
+func setupCIDR(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	return func(args []string) {
+		switch {
+		case len(args) == 0 || len(args) == 1 && args[0] == "-":
+			runCIDR("-", "")
+		case len(args) == 1:
+			start, end, ok := strings.Cut(args[0], "-")
+			if !ok {
+				requireArgs(fs, args, 2)
+			}
+			runCIDR(start, end)
+		default:
+			runCIDR(args[0], args[1])
+		}
+	}
+}
+
+func setupRange(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runRange(args[0])
+	}
+}
+
+func setupNth(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	prefix := fs.String("p", "", "Prefix to index into, if not given as the argument.")
+	index := fs.String("index", "", "0-based index, in decimal or 0x hex.")
+	newPrefixLength := fs.Int("n", 128, "Length of the subnet to return; 128 returns an address.")
+	fromEnd := fs.Bool("from-end", false, "Count from the last address or subnet.")
+	return func(args []string) {
+		if len(args) == 0 && *prefix != "" {
+			args = []string{*prefix}
+		}
+		requireArgs(fs, args, 1)
+		if *index == "" {
+			log.Fatal("-index is required")
+		}
+		runNth(args[0], *newPrefixLength, *index, *fromEnd)
+	}
+}
+
+func setupLocate(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	newPrefixLength := fs.Int("n", 64, "Length of the subnets the prefix is split into.")
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runLocate(args[0], *newPrefixLength, args[1])
+	}
+}
+
+func setupMathAdd(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runMath(args[0], args[1], false)
+	}
+}
+
+func setupMathSub(fs *flag.FlagSet) func([]string) {
+	addExplodedFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runMath(args[0], args[1], true)
+	}
+}
+
+func setupMathDistance(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runDistance(args[0], args[1])
+	}
+}
+
+func setupSize(fs *flag.FlagSet) func([]string) {
+	prefix := fs.String("p", "", "Prefix to split, if not given as the argument.")
+	need := fs.String("need", "", "Number of subnets needed.")
+	nibble := fs.Bool("nibble", false, "Round the length up to the next nibble boundary.")
+	return func(args []string) {
+		if len(args) == 0 && *prefix != "" {
+			args = []string{*prefix}
+		}
+		requireArgs(fs, args, 1)
+		if *need == "" {
+			log.Fatal("-need is required")
+		}
+		runSize(args[0], *need, *nibble)
+	}
+}
+
+func setupNeighbors(fs *flag.FlagSet) func([]string) {
+	parent := fs.Int("parent", -1, "Parent prefix length (default: the nearest nibble boundary above the prefix).")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		arg := args[0]
+		reportNeighbors(arg, *parent)
+	}
+}
+
+func setupPlan(fs *flag.FlagSet) func([]string) {
+	outputFile := fs.String("o", "", "File to save the plan to.")
+	compress := fs.Bool("compress", false, "Gzip the output; implied when -o ends in .gz.")
+	columns := fs.String("columns", "index,prefix,parent,name", "Columns with -output-format csv or xlsx: index, prefix, network, length, parent, last, gateway, name, nibble_aligned, reserved.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		arg := args[0]
+		runPlan(arg, planOptions{OutputFile: *outputFile, Columns: *columns, Compress: *compress})
+	}
+}
+
+func setupPlanValidate(fs *flag.FlagSet) func([]string) {
+	parent := fs.String("parent", "", "Prefix every allocation must be within.")
+	nested := fs.Bool("nested", false, "Allow allocations inside others, for plans listing each tier's aggregates.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		arg := args[0]
+		runPlanValidate(arg, *parent, *nested)
+	}
+}
+
+func setupPlanDiff(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runPlanDiff(args[0], args[1])
+	}
+}
+
+// setupCommandGroup is the action of a command that only groups subcommands:
+// it prints the usage listing them.
+func setupCommandGroup(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+func setupAllocNext(fs *flag.FlagSet) func([]string) {
+	prefix := fs.String("p", "", "Parent prefix to allocate from, if not given as the argument.")
+	newPrefixLength := fs.Int("n", 64, "Prefix length to allocate.")
+	allocatedFile := fs.String("allocated-file", "", "Prefix list of existing allocations; entries past their expires= date are free.")
+	count := fs.Int("count", 1, "Number of subnets to allocate.")
+	db := fs.String("db", "", "Allocation database whose allocations are also taken (default: env IPV6UTILS_ALLOC_DB, or "+defaultAllocDB+", when -claim is given).")
+	claim := fs.Bool("claim", false, "Record the subnets found in the database.")
+	name := fs.String("name", "", "Name of the subnets recorded with -claim.")
+	strategy := fs.String("strategy", "", "Order to allocate in, per RFC 3531: leftmost (spread across the parent), rightmost (address order, the default), center, or random.")
+	seed := fs.Int64("seed", 0, "Random seed for -strategy random; required with -stable. 0 picks a new seed and prints it on stderr.")
+	return func(args []string) {
+		if len(args) == 0 && *prefix != "" {
+			args = []string{*prefix}
+		}
+		requireArgs(fs, args, 1)
+		runAllocNext(args[0], *newPrefixLength, allocNextOptions{
+			AllocatedFile: *allocatedFile,
+			Count:         *count,
+			DB:            *db,
+			Claim:         *claim,
+			Name:          *name,
+			Strategy:      *strategy,
+			Seed:          *seed,
+		})
+	}
+}
+
+// allocDBFlag registers the -db flag of the alloc commands.
+func allocDBFlag(fs *flag.FlagSet) *string {
+	return fs.String("db", "", "Allocation database (default: env IPV6UTILS_ALLOC_DB, or "+defaultAllocDB+").")
+}
+
+func setupAllocAdd(fs *flag.FlagSet) func([]string) {
+	db := allocDBFlag(fs)
+	name := fs.String("name", "", "Name of the allocation; may carry plan metadata such as expires=2026-12-31.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runAllocAdd(*db, args[0], *name)
+	}
+}
+
+func setupAllocFree(fs *flag.FlagSet) func([]string) {
+	db := allocDBFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runAllocFree(*db, args[0])
+	}
+}
+
+func setupAllocList(fs *flag.FlagSet) func([]string) {
+	db := allocDBFlag(fs)
+	within := fs.String("within", "", "Only list allocations inside this prefix.")
+	return func(args []string) {
+		runAllocList(*db, *within)
+	}
+}
+
+func setupReportUtilization(fs *flag.FlagSet) func([]string) {
+	prefix := fs.String("p", "", "Parent prefix, if not given as the argument.")
+	unit := fs.Int("unit", 56, "Prefix length counted as one unit, such as the /56 or /48 assigned to an end site.")
+	allocatedFile := fs.String("allocated-file", "", "Prefix list of allocations; entries past their expires= date are free (default: the allocation database).")
+	db := allocDBFlag(fs)
+	threshold := fs.Float64("threshold", defaultHDThreshold, "HD-ratio at which the prefix counts as fully used, as in RIR policy.")
+	return func(args []string) {
+		if len(args) == 0 && *prefix != "" {
+			args = []string{*prefix}
+		}
+		requireArgs(fs, args, 1)
+		runUtilization(args[0], *unit, *allocatedFile, *db, *threshold)
+	}
+}
+
+func setupAllocImport(fs *flag.FlagSet) func([]string) {
+	db := allocDBFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runAllocImport(*db, args[0])
+	}
+}
+
+func setupAllocExport(fs *flag.FlagSet) func([]string) {
+	db := allocDBFlag(fs)
+	outputFile := fs.String("o", "", "File to save the prefix list to.")
+	return func(args []string) {
+		runAllocExport(*db, *outputFile)
+	}
+}
+
+func setupMACFile(fs *flag.FlagSet) func([]string) {
+	slaacPrefixes := fs.String("slaac-prefix", "", "Comma-separated /64 prefixes to derive SLAAC addresses in.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		convertMACFile(args[0], *slaacPrefixes)
+	}
+}
+
+func setupArpaStats(fs *flag.FlagSet) func([]string) {
+	zoneFiles := fs.String("zone", "", "Comma-separated reverse zone files.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportArpaStats(args[0], *zoneFiles)
+	}
+}
+
+func setupPlanExport(fs *flag.FlagSet) func([]string) {
+	outputFile := fs.String("o", "", "File to save the configuration to.")
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runPlanExport(args[0], args[1], *outputFile)
+	}
+}
+
+func setupMerge(fs *flag.FlagSet) func([]string) {
+	strategy := fs.String("strategy", "fail", "How to resolve conflicts: first, second, or fail.")
+	outputFile := fs.String("o", "", "File to save the merged plan to.")
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runMerge(args[0]+","+args[1], *strategy, *outputFile)
+	}
+}
+
+func setupGC(fs *flag.FlagSet) func([]string) {
+	dryRun := fs.Bool("dry-run", false, "List what would be released without changing the plan.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runPlanGC(args[0], *dryRun, time.Now())
+	}
+}
+
+func setupAudit(fs *flag.FlagSet) func([]string) {
+	rulesFile := fs.String("rules", "", "YAML allocation policy: allowed parents, forbidden ranges, naming, role sizes.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runPolicyAudit(args[0], *rulesFile)
+	}
+}
+
+func setupStale(fs *flag.FlagSet) func([]string) {
+	seenFiles := fs.String("seen", "", "Comma-separated activity files, ADDRESS TIMESTAMP per line.")
+	days := fs.Int("days", 90, "Days without activity before an allocation is reported.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportStaleAllocations(args[0], *seenFiles, *days)
+	}
+}
+
+func setupHeatmap(fs *flag.FlagSet) func([]string) {
+	newPrefixLength := fs.Int("n", 64, "Prefix length of the children drawn as cells.")
+	outputFile := fs.String("o", "", "File to save the heatmap to; a .svg name renders an image.")
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		renderHeatmap(args[0], *newPrefixLength, args[1], *outputFile)
+	}
+}
+
+// schemeFlag registers the -scheme flag of the scheme commands.
+func schemeFlag(fs *flag.FlagSet) *string {
+	return fs.String("scheme", "", "YAML bit-field scheme: the parent prefix and named subnet-ID fields.")
+}
+
+func setupSchemeEncode(fs *flag.FlagSet) func([]string) {
+	schemeFile := schemeFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		if *schemeFile == "" {
+			log.Fatal("scheme encode requires -scheme")
+		}
+		runSchemeEncode(*schemeFile, args[0])
+	}
+}
+
+func setupSchemeDecode(fs *flag.FlagSet) func([]string) {
+	schemeFile := schemeFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		if *schemeFile == "" {
+			log.Fatal("scheme decode requires -scheme")
+		}
+		runSchemeDecode(*schemeFile, strings.Join(args, ","))
+	}
+}
+
+// vlanFlags registers the -n and -encoding flags of the vlan commands.
+func vlanFlags(fs *flag.FlagSet) (*int, *string) {
+	return fs.Int("n", 64, "Prefix length of the VLAN subnets."),
+		fs.String("encoding", "decimal", "How VLAN IDs are embedded: decimal (VLAN 120 -> :120:) or hex (VLAN 120 -> :78:).")
+}
+
+func setupVLAN(fs *flag.FlagSet) func([]string) {
+	newPrefixLength, encoding := vlanFlags(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runVLANSubnets(args[0], *newPrefixLength, args[1], *encoding)
+	}
+}
+
+func setupVLANDecode(fs *flag.FlagSet) func([]string) {
+	newPrefixLength, encoding := vlanFlags(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runVLANDecode(args[0], *newPrefixLength, strings.Join(args[1:], ","), *encoding)
+	}
+}
+
+func setupVanity(fs *flag.FlagSet) func([]string) {
+	newPrefixLength := fs.Int("n", 64, "Prefix length of the subnets.")
+	group := fs.Int("group", 0, "Place the words right-aligned in this 16-bit group (1-8). 0 tries every position.")
+	budget := fs.Int("budget", 1000, "Maximum number of candidates to produce.")
+	limit := fs.Int("l", 0, "Limit the number of subnets displayed.")
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runVanity(args[0], *newPrefixLength, args[1:], *group, *budget, *limit)
+	}
+}
+
+func setupVanityIID(fs *flag.FlagSet) func([]string) {
+	ascii := fs.Bool("ascii", false, "Encode the words as ASCII bytes instead of hex.")
+	return func(args []string) {
+		requireArgs(fs, args, 2)
+		runVanityIIDs(args[0], args[1:], *ascii)
+	}
+}
+
+func setupPDSim(fs *flag.FlagSet) func([]string) {
+	cfg := pdSimConfig{}
+	fs.IntVar(&cfg.DelegLen, "size", 56, "Per-customer delegation size.")
+	fs.IntVar(&cfg.Customers, "customers", 0, "Customers holding a delegation at the start.")
+	fs.Float64Var(&cfg.Growth, "growth", 0.02, "Net customer growth per period, as a fraction.")
+	fs.Float64Var(&cfg.Churn, "churn", 0.01, "Fraction of customers leaving and being replaced per period.")
+	fs.BoolVar(&cfg.Sticky, "sticky", false, "Hold released delegations for -hold periods before reuse (sticky assignment).")
+	fs.IntVar(&cfg.Hold, "hold", 3, "Periods a released delegation is held with -sticky.")
+	fs.IntVar(&cfg.Periods, "periods", 36, "Number of periods to simulate.")
+	fs.Int64Var(&cfg.Seed, "seed", 1, "Random seed for departures.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		cfg.Pool = args[0]
+		runPDSimulation(cfg)
+	}
+}
+
+// leasesFlags registers the -all and -plan flags of the leases commands.
+func leasesFlags(fs *flag.FlagSet) (*bool, *string) {
+	return fs.Bool("all", false, "Include expired, released, and abandoned leases, with their state."),
+		fs.String("plan", "", "Prefix list of planned ranges (or plugin:NAME[:ARG]): report each lease's range and fail on leases outside the plan.")
+}
+
+func setupLeasesKea(fs *flag.FlagSet) func([]string) {
+	all, plan := leasesFlags(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportLeases(args[0], readKeaLeases, *all, *plan)
+	}
+}
+
+func setupLeasesDhcpd6(fs *flag.FlagSet) func([]string) {
+	all, plan := leasesFlags(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportLeases(args[0], parseDhcpd6Leases, *all, *plan)
+	}
+}
+
+func setupRouterConfig(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		importRouterConfigs(strings.Join(args, ","))
+	}
+}
+
+func setupRAConfig(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		importRAConfigs(strings.Join(args, ","))
+	}
+}
+
+func setupRADIUS(fs *flag.FlagSet) func([]string) {
+	format := fs.String("format", "users", "Entries to write: users (FreeRADIUS users file) or sql (radreply INSERTs).")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		exportRADIUS(args[0], *format)
+	}
+}
+
+func setupStats(fs *flag.FlagSet) func([]string) {
+	top := fs.Int("top", 10, "Number of covering /48s and /64s listed.")
+	return func(args []string) {
+		path := "-"
+		if len(args) > 0 {
+			path = args[0]
+		}
+		reportAddressStats(path, *top)
+	}
+}
+
+func setupIIDScore(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportIIDScores(args[0])
+	}
+}
+
+func setupFix(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		path := "-"
+		if len(args) > 0 {
+			path = args[0]
+		}
+		runFix(path)
+	}
+}
+
+func setupGeoIP(fs *flag.FlagSet) func([]string) {
+	geoDBs := addGeoIPDBFlag(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportGeoIP(args[0], *geoDBs)
+	}
+}
+
+func setupBlocklist(fs *flag.FlagSet) func([]string) {
+	zones := fs.String("dnsbl", "", "Comma-separated DNSBL zones to query.")
+	feeds := fs.String("feed", "", "Comma-separated threat feeds: files or http(s) URLs of prefixes.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportBlocklists(args[0], *zones, *feeds)
+	}
+}
+
+// addCacheDirFlag registers -cache-dir on a command that reads the cached
+// datasets. It leaves cacheRoot alone unless given.
+func addCacheDirFlag(fs *flag.FlagSet) {
+	fs.Func("cache-dir", "Directory for downloaded datasets (default: ipv6utils under the user cache directory; env IPV6UTILS_CACHE_DIR).", func(dir string) error {
+		cacheRoot = dir
+		return nil
+	})
+}
+
+// rirFlags registers the flags of the rir commands.
+func rirFlags(fs *flag.FlagSet) (*string, *bool) {
+	addCacheDirFlag(fs)
+	return fs.String("stats", "", "Comma-separated delegated-extended files to use instead of the downloaded cache."),
+		fs.Bool("refresh", false, "Download fresh RIR statistics into the cache first.")
+}
+
+func setupRIRLookup(fs *flag.FlagSet) func([]string) {
+	files, refresh := rirFlags(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportRIRLookup(args[0], *files, *refresh)
+	}
+}
+
+func setupRIRCountry(fs *flag.FlagSet) func([]string) {
+	files, refresh := rirFlags(fs)
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportRIRCountry(args[0], *files, *refresh)
+	}
+}
+
+func setupBGPHistory(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		reportBGPHistory(args[0])
+	}
+}
+
+func setupDoctor(fs *flag.FlagSet) func([]string) {
+	return func(args []string) {
+		if !runDoctor(os.Stdout) {
+			os.Exit(1)
+		}
+	}
+}
+
+func setupDiscover(fs *flag.FlagSet) func([]string) {
+	addCacheDirFlag(fs)
+	timeout := fs.Duration("timeout", 3*time.Second, "How long to wait for replies.")
+	return func(args []string) {
+		requireArgs(fs, args, 1)
+		runDiscovery(args[0], *timeout)
+	}
+}
+
+func setupDataUpdate(fs *flag.FlagSet) func([]string) {
+	addCacheDirFlag(fs)
+	return func(args []string) {
+		names := "all"
+		if len(args) > 0 {
+			names = strings.Join(args, ",")
+		}
+		updateDatasets(names)
+	}
+}
+
+func setupDataStatus(fs *flag.FlagSet) func([]string) {
+	addCacheDirFlag(fs)
+	return func(args []string) {
+		reportDatasetStatus()
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	cases := []struct {
+		args       string
+		positional string
+		n          string
+	}{
+		{"3fff::/32 -n 48", "3fff::/32", "48"},
+		{"-n 48 3fff::/32", "3fff::/32", "48"},
+		{"-n 40 3fff::/32 -l 5", "3fff::/32", "40"},
+		{"3fff::/32 extra", "3fff::/32,extra", "64"},
+		{"", "", "64"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.args, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			n := fs.Int("n", 64, "")
+			fs.Int("l", 0, "")
+			got := parseInterspersed(fs, strings.Fields(tc.args))
+			if strings.Join(got, ",") != tc.positional || fs.Lookup("n").Value.String() != tc.n {
+				t.Errorf("got positional %v, n=%d", got, *n)
+			}
+		})
+	}
+}
+
+func TestSubcommands(t *testing.T) {
+	seen := map[string]bool{}
+	for _, c := range subcommands() {
+		if seen[c.Name] || c.Name == "help" {
+			t.Errorf("duplicate or reserved subcommand name %q", c.Name)
+		}
+		seen[c.Name] = true
+		if strings.HasPrefix(c.Name, "-") {
+			t.Errorf("subcommand %q would be taken for a flag", c.Name)
+		}
+		fs, action := newSubcommandFlags(c, flag.ContinueOnError)
+		if action == nil || fs.Lookup("stable") == nil {
+			t.Errorf("%s: missing action or -stable flag", c.Name)
+		}
+		var buf bytes.Buffer
+		fs.SetOutput(&buf)
+		fs.Usage()
+		if !strings.HasPrefix(buf.String(), "Usage: ipv6utils "+c.Name+" [flags] "+c.Args+"\n") {
+			t.Errorf("%s: unexpected usage %q", c.Name, buf.String())
+		}
+	}
+	if _, ok := findSubcommand("subnet"); !ok {
+		t.Error("subnet subcommand not found")
+	}
+	if _, ok := findSubcommand("-p"); ok {
+		t.Error("flags must not resolve to subcommands")
+	}
+}
+
+func TestRunSubcommandFallsBack(t *testing.T) {
+	for _, args := range [][]string{nil, {"-p", "3fff::/32"}, {"unknown"}} {
+		if runSubcommand(args) {
+			t.Errorf("runSubcommand(%q) claimed a legacy command line", args)
+		}
+	}
+}
+
+func TestFindNested(t *testing.T) {
+	plan, ok := findSubcommand("plan")
+	if !ok {
+		t.Fatal("plan subcommand not found")
+	}
+	c, rest := findNested(plan, []string{"validate", "plan.txt", "-nested"})
+	if c.Name != "plan validate" || strings.Join(rest, " ") != "plan.txt -nested" {
+		t.Errorf("got %q with %v", c.Name, rest)
+	}
+	c, rest = findNested(plan, []string{"plan.yaml"})
+	if c.Name != "plan" || len(rest) != 1 {
+		t.Errorf("got %q with %v", c.Name, rest)
+	}
+	for _, sub := range plan.Subcommands {
+		fs, action := newSubcommandFlags(sub, flag.ContinueOnError)
+		if action == nil || fs.Lookup("stable") == nil {
+			t.Errorf("plan %s: missing action or -stable flag", sub.Name)
+		}
+	}
+}
+
+func TestLegacyModes(t *testing.T) {
+	for flagName, command := range legacyModes {
+		words := strings.Fields(command)
+		c, ok := findSubcommand(words[0])
+		if !ok {
+			t.Errorf("-%s: no %q subcommand", flagName, words[0])
+			continue
+		}
+		c, rest := findNested(c, words[1:])
+		if c.Args == "COMMAND" {
+			t.Errorf("-%s: %q names a command group, not a command", flagName, command)
+		}
+		for _, arg := range rest {
+			if strings.HasPrefix(arg, "-") && subcommandFlag(c, strings.TrimLeft(arg, "-")) == nil {
+				t.Errorf("-%s: %q has no flag %s", flagName, c.Name, arg)
+			}
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("vanity", "", "Comma-separated hex words.")
+	fs.String("prefix", "", "IPv6 prefix.")
+	deprecateLegacyFlags(fs)
+	if got := fs.Lookup("vanity").Usage; got != `Comma-separated hex words. Deprecated: use "ipv6utils vanity".` {
+		t.Errorf("unexpected -vanity usage %q", got)
+	}
+	if got := fs.Lookup("prefix").Usage; got != "IPv6 prefix." {
+		t.Errorf("-prefix should not be marked deprecated, got %q", got)
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("count", false, "Count subnets.")
+	fs.String("rir-refresh", "", "Refresh.")
+	fs.String("stats-output", "text", "Alias for -output-format with -stats.")
+	deprecateLegacyFlags(fs)
+	if got := fs.Lookup("stats-output").Usage; got != "Alias for -output-format with -stats. Deprecated: use -output-format." {
+		t.Errorf("unexpected -stats-output usage %q", got)
+	}
+	fs.Parse([]string{"-stats-output", "json", "-rir-refresh", "x", "-count"})
+	if got := warnLegacyFlags(fs); got != "subnet" {
+		t.Errorf("expected the command of -count, got %q", got)
+	}
+}
+
+func TestCommandOutputFormats(t *testing.T) {
+	for name := range commandOutputFormats {
+		words := strings.Fields(name)
+		c, ok := findSubcommand(words[0])
+		if ok {
+			c, _ = findNested(c, words[1:])
+		}
+		if !ok || c.Name != name {
+			t.Errorf("%q is not a command", name)
+		}
+	}
+}
+
+// subcommandFlag looks up a flag of subcommand c.
+func subcommandFlag(c subcommand, name string) *flag.Flag {
+	fs, _ := newSubcommandFlags(c, flag.ContinueOnError)
+	return fs.Lookup(name)
+}
+
+func TestWriteSubcommandList(t *testing.T) {
+	var buf bytes.Buffer
+	writeSubcommandList(&buf)
+	for _, c := range subcommands() {
+		if !strings.Contains(buf.String(), "\n  "+c.Name+" ") {
+			t.Errorf("%s missing from the command list", c.Name)
+		}
+	}
+	if strings.Contains(buf.String(), "flags below") {
+		t.Errorf("command list still points at the flat flags:\n%s", buf.String())
+	}
+}
+
+func TestRunLegacyModeSubcommands(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"vanity", "3fff::/32", "cafe", "beef", "-n", "48"}, "3fff:0:cafe::/48\n3fff:0:beef::/48\n"},
+		{[]string{"vanity", "iid", "3fff:0:1::/64", "mail", "-ascii"}, "3fff:0:1::6d61:696c\n"},
+		{[]string{"vlan", "decode", "2001:db8::/48", "2001:db8:0:120::1"}, "VLAN 120 (2001:db8:0:120::/64)"},
+		{[]string{"rir", "country", "FR", "-stats", "testdata/delegated-extended.txt"}, "2a01:e00::/26\n"},
+		{[]string{"stats", "testdata/clients.txt", "-top", "1", "-output-format", "json"}, `"total": 11`},
+		{[]string{"gc", "testdata/plan-lab.txt", "-dry-run"}, "released 3fff:0:1:f000::/56"},
+		{[]string{"leases", "kea", "testdata/kea-leases6.csv"}, "3fff:0:1:10::1:100/128"},
+		{[]string{"geoip", "2001:db8:1::1", "-geoip-db", "testdata/geoip-test.mmdb"}, "FR Paris AS64500 Example Net"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			got := captureStdout(t, func() { runSubcommandArgs(t, tt.args...) })
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("expected %q in output, got:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSubcommandManifest(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "merged.txt")
+	captureStdout(t, func() {
+		runSubcommandArgs(t, "merge", "testdata/plan-acme.txt", "testdata/plan-globex.txt", "-strategy", "first", "-o", out, "-manifest", "-stable")
+	})
+	data, err := os.ReadFile(manifestPath(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m outputManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Parameters["args"] != "testdata/plan-acme.txt,testdata/plan-globex.txt" || m.Parameters["strategy"] != "first" || m.Lines == 0 {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+	if fs, _ := newSubcommandFlags(subcommand{Name: "doctor", Setup: setupDoctor}, flag.ContinueOnError); fs.Lookup("manifest") != nil {
+		t.Error("-manifest offered on a command without -o")
+	}
+}
+
+// captureStdout returns what f writes to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	return <-done
+}
+
+// runSubcommandArgs parses a command line as runSubcommand does, failing the
+// test where it would exit, and runs the command.
+func runSubcommandArgs(t *testing.T, args ...string) {
+	t.Helper()
+	c, ok := findSubcommand(args[0])
+	if !ok {
+		t.Fatalf("%s subcommand not found", args[0])
+	}
+	c, rest := findNested(c, args[1:])
+	fs, action := newSubcommandFlags(c, flag.ContinueOnError)
+	positional := parseInterspersed(fs, rest)
+	if err := subcommandArgsError(c, len(positional)); err != nil {
+		t.Fatalf("%s: %v", strings.Join(args, " "), err)
+	}
+	action(positional)
+}
+
+func TestSubcommandArgsError(t *testing.T) {
+	tests := []struct {
+		args string
+		n    int
+		ok   bool
+	}{
+		{"PREFIX", 1, true},
+		{"PREFIX", 2, false},
+		{"OLD NEW", 2, true},
+		{"OLD NEW", 3, false},
+		{"[FILE...]", 0, true},
+		{"[FILE...]", 3, true},
+		{"PREFIX [FILE...]", 4, true},
+		{"", 0, true},
+		{"", 1, false},
+	}
+	for _, tt := range tests {
+		if err := subcommandArgsError(subcommand{Name: "test", Args: tt.args}, tt.n); (err == nil) != tt.ok {
+			t.Errorf("%q with %d arguments: got %v", tt.args, tt.n, err)
+		}
+	}
+	if err := subcommandArgsError(subcommand{Name: "sanitize"}, 1); err == nil || err.Error() != "takes no arguments, got 1" {
+		t.Errorf("no Args: got %v", err)
+	}
+}
+
+func TestSortUniqueTwoFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("2001:db8::10\n2001:db8::9\n"), 0o644)
+	os.WriteFile(b, []byte("2001:db8::9\n2001:db8::a\n"), 0o644)
+	got := captureStdout(t, func() { runSubcommandArgs(t, "sortu", a, b) })
+	if want := "2001:db8::9\n2001:db8::a\n2001:db8::10\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
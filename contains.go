@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// containsResult is whether an address, or a prefix, lies inside a prefix.
+type containsResult struct {
+	Prefix    string `json:"prefix"`
+	Address   string `json:"address"`
+	Contained bool   `json:"contained"`
+}
+
+// prefixContains reports whether parent holds the address or prefix s in its
+// entirety.
+func prefixContains(parent netip.Prefix, s string) (bool, error) {
+	ip, bits, err := parseIPv6WithOptionalPrefix(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid address or prefix %q: %v", s, err)
+	}
+	if bits < 0 {
+		bits = 128
+	}
+	return bits >= parent.Bits() && parent.Contains(netip.AddrFrom16([16]byte(ip.To16()))), nil
+}
+
+// checkContains checks each address or prefix read from r, one per line and
+// with blank lines and '#' comments skipped, passing each result to emit and
+// each line that does not parse to fail with its line number. It returns
+// whether every line parsed and was contained.
+func checkContains(parent netip.Prefix, r io.Reader, emit func(containsResult), fail func(line int, input string, err error)) (bool, error) {
+	all := true
+	lineNo := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		contained, err := prefixContains(parent, fields[0])
+		if err != nil {
+			fail(lineNo, fields[0], err)
+			all = false
+			continue
+		}
+		all = all && contained
+		emit(containsResult{Prefix: parent.String(), Address: fields[0], Contained: contained})
+	}
+	return all, scanner.Err()
+}
+
+// runContains prints true or false for whether address lies inside prefix, or
+// with an address of "-", for each address read from stdin, reporting those
+// that do not parse on stderr with their line numbers. It exits with status 1
+// unless all parse and are inside, so scripts can test it directly.
+func runContains(prefix, address string) {
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if address != "-" {
+		contained, err := prefixContains(parent, address)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeResult(containsResult{Prefix: parent.String(), Address: address, Contained: contained}, func() {
+			fmt.Println(contained)
+		})
+		if !contained {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// JSON is one array; every other format streams a result per line.
+	var results []containsResult
+	all, err := checkContains(parent, os.Stdin, func(r containsResult) {
+		if outputFormat == "json" {
+			results = append(results, r)
+			return
+		}
+		writeResult(r, func() {
+			fmt.Printf("%s %t\n", r.Address, r.Contained)
+		})
+	}, func(line int, input string, err error) {
+		fmt.Fprintf(os.Stderr, "line %d: %v\n", line, err)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFormat == "json" {
+		if results == nil {
+			results = []containsResult{}
+		}
+		writeResult(results, nil)
+	}
+	if !all {
+		os.Exit(1)
+	}
+}
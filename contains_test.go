@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestPrefixContains(t *testing.T) {
+	parent := netip.MustParsePrefix("2001:db8::/32")
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"2001:db8:1::5", true},
+		{"2001:db8::", true},
+		{"2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", true},
+		{"2001:db9::", false},
+		{"2001:db8:1::/48", true},
+		{"2001:db8::/32", true},
+		{"2001:db8::/31", false},
+	}
+	for _, tt := range tests {
+		got, err := prefixContains(parent, tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+	if _, err := prefixContains(parent, "nope"); err == nil {
+		t.Error("invalid address accepted")
+	}
+}
+
+func TestCheckContains(t *testing.T) {
+	parent := netip.MustParsePrefix("2001:db8::/32")
+	var got, failed []string
+	fail := func(line int, input string, err error) {
+		failed = append(failed, fmt.Sprintf("%d:%s", line, input))
+	}
+	all, err := checkContains(parent, strings.NewReader("2001:db8::1 host\n\n# comment\n3fff::1\nbogus invalid\n"), func(r containsResult) {
+		if r.Contained {
+			got = append(got, r.Address+"=in")
+		} else {
+			got = append(got, r.Address+"=out")
+		}
+	}, fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all || strings.Join(got, " ") != "2001:db8::1=in 3fff::1=out" {
+		t.Errorf("got %v, all %v", got, all)
+	}
+	if strings.Join(failed, " ") != "5:bogus" {
+		t.Errorf("failed lines: got %v, want 5:bogus", failed)
+	}
+
+	// A line that does not parse fails the run even when the rest are inside.
+	failed = nil
+	all, _ = checkContains(parent, strings.NewReader("2001:db8::1\nbogus\n"), func(containsResult) {}, fail)
+	if all || len(failed) != 1 {
+		t.Errorf("invalid line: all %v, failed %v", all, failed)
+	}
+	all, _ = checkContains(parent, strings.NewReader("2001:db8::1\n2001:db8:5::/48\n"), func(containsResult) {}, fail)
+	if !all {
+		t.Error("all contained: got false")
+	}
+}
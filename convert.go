@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/big"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// addressNumbers is an address as the numbers it is stored as.
+type addressNumbers struct {
+	Address string `json:"address"`
+	Integer string `json:"integer"` // decimal, as it can exceed 64 bits
+	High    uint64 `json:"high"`    // the first 64 bits
+	Low     uint64 `json:"low"`     // the last 64 bits, the interface ID
+	Hex     string `json:"hex"`
+	Binary  string `json:"binary"`
+}
+
+// numbersOf returns the numeric forms of an address.
+func numbersOf(a netip.Addr) addressNumbers {
+	b := a.As16()
+	high, low := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	return addressNumbers{
+		Address: addrText(a),
+		Integer: ipToBigInt(b[:]).String(),
+		High:    high,
+		Low:     low,
+		Hex:     fmt.Sprintf("%016x%016x", high, low),
+		Binary:  fmt.Sprintf("%064b%064b", high, low),
+	}
+}
+
+// parseAddressNumber reads an address from one of its numeric forms: from is
+// int (a decimal integer), hex (up to 32 digits, with or without 0x), binary
+// (up to 128 digits, with or without 0b, ignoring the colons of format's
+// output), or halves (the high and low 64 bits in decimal, as HIGH,LOW). An
+// empty from takes an address.
+func parseAddressNumber(s, from string) (netip.Addr, error) {
+	var n *big.Int
+	var ok bool
+	switch from {
+	case "", "address":
+		return parseIPv6Addr(s)
+	case "int":
+		n, ok = new(big.Int).SetString(s, 10)
+	case "hex":
+		n, ok = new(big.Int).SetString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"), 16)
+	case "binary":
+		n, ok = new(big.Int).SetString(strings.ReplaceAll(strings.TrimPrefix(s, "0b"), ":", ""), 2)
+	case "halves":
+		h, l, found := strings.Cut(s, ",")
+		high, herr := strconv.ParseUint(strings.TrimSpace(h), 10, 64)
+		low, lerr := strconv.ParseUint(strings.TrimSpace(l), 10, 64)
+		if !found || herr != nil || lerr != nil {
+			return netip.Addr{}, fmt.Errorf("invalid halves %q: want HIGH,LOW as two 64-bit decimal integers", s)
+		}
+		var b [16]byte
+		binary.BigEndian.PutUint64(b[:8], high)
+		binary.BigEndian.PutUint64(b[8:], low)
+		return netip.AddrFrom16(b), nil
+	default:
+		return netip.Addr{}, fmt.Errorf("unknown form %q (want address, int, hex, binary, or halves)", from)
+	}
+	if !ok || n.Sign() < 0 {
+		return netip.Addr{}, fmt.Errorf("invalid %s value %q", from, s)
+	}
+	if n.BitLen() > 128 {
+		return netip.Addr{}, fmt.Errorf("%s value %q is more than 128 bits", from, s)
+	}
+	return netip.AddrFrom16([16]byte(bigIntToIP(n))), nil
+}
+
+// runConvert prints an address, or one given in the numeric form from, as a
+// decimal integer, two 64-bit halves, hex, and binary, for storing addresses in
+// databases that lack an IPv6 type.
+func runConvert(value, from string) {
+	a, err := parseAddressNumber(value, from)
+	if err != nil {
+		log.Fatal(err)
+	}
+	n := numbersOf(a)
+	writeResult(n, func() {
+		fmt.Printf("%-10s%s\n", "Address:", n.Address)
+		fmt.Printf("%-10s%s\n", "Integer:", n.Integer)
+		fmt.Printf("%-10s%d\n", "High:", n.High)
+		fmt.Printf("%-10s%d\n", "Low:", n.Low)
+		fmt.Printf("%-10s%s\n", "Hex:", n.Hex)
+		fmt.Printf("%-10s%s\n", "Binary:", n.Binary)
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestNumbersOf(t *testing.T) {
+	n := numbersOf(netip.MustParseAddr("2001:db8::1"))
+	if n.Integer != "42540766411282592856903984951653826561" || n.High != 0x20010db800000000 || n.Low != 1 {
+		t.Errorf("got %+v", n)
+	}
+	if n.Hex != "20010db8000000000000000000000001" {
+		t.Errorf("hex: got %s", n.Hex)
+	}
+	if len(n.Binary) != 128 || !strings.HasPrefix(n.Binary, "0010000000000001") || !strings.HasSuffix(n.Binary, "01") {
+		t.Errorf("binary: got %s", n.Binary)
+	}
+}
+
+func TestParseAddressNumber(t *testing.T) {
+	want := netip.MustParseAddr("2001:db8::1")
+	tests := []struct{ value, from string }{
+		{"2001:db8::1", "address"},
+		{"42540766411282592856903984951653826561", "int"},
+		{"20010db8000000000000000000000001", "hex"},
+		{"0x20010DB8000000000000000000000001", "hex"},
+		{"2306139568115548160,1", "halves"},
+		{numbersOf(want).Binary, "binary"},
+		{binaryIPv6(want.AsSlice()), "binary"},
+	}
+	for _, tt := range tests {
+		got, err := parseAddressNumber(tt.value, tt.from)
+		if err != nil || got != want {
+			t.Errorf("%s %s: got %v, %v", tt.from, tt.value, got, err)
+		}
+	}
+	if got, err := parseAddressNumber("1", "int"); err != nil || got != netip.MustParseAddr("::1") {
+		t.Errorf("int 1: got %v, %v", got, err)
+	}
+	for _, bad := range []struct{ value, from string }{
+		{"-1", "int"},
+		{"340282366920938463463374607431768211456", "int"},
+		{"12g", "hex"},
+		{"102", "binary"},
+		{"1", "halves"},
+		{"18446744073709551616,0", "halves"},
+		{"1", "octal"},
+	} {
+		if _, err := parseAddressNumber(bad.value, bad.from); err == nil {
+			t.Errorf("%s %s: no error", bad.from, bad.value)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dataset is an external data source kept in the cache: one or more files under
+// the same cache subdirectory, refreshed once they are older than MaxAge.
+type dataset struct {
+	Name        string
+	Description string
+	URLs        []string
+	MaxAge      time.Duration
+}
+
+// datasets are the external datasets the tool downloads, by name.
+var datasets = map[string]dataset{
+	"rir": {
+		Name:        "rir",
+		Description: "RIR delegated-extended statistics",
+		URLs:        rirURLs(),
+		MaxAge:      rirStatsMaxAge,
+	},
+	"oui": {
+		Name:        "oui",
+		Description: "IEEE MA-L (OUI) registry",
+		URLs:        []string{"https://standards-oui.ieee.org/oui/oui.csv"},
+		MaxAge:      30 * 24 * time.Hour,
+	},
+	"iana-special": {
+		Name:        "iana-special",
+		Description: "IANA IPv6 Special-Purpose Address Registry",
+		URLs:        []string{"https://www.iana.org/assignments/iana-ipv6-special-registry/iana-ipv6-special-registry-1.csv"},
+		MaxAge:      30 * 24 * time.Hour,
+	},
+	"bogons": {
+		Name:        "bogons",
+		Description: "Team Cymru IPv6 full bogons",
+		URLs:        []string{"https://www.team-cymru.org/Services/Bogons/fullbogons-ipv6.txt"},
+		MaxAge:      24 * time.Hour,
+	},
+}
+
+// rirURLs lists the statistics URL of every RIR.
+func rirURLs() []string {
+	urls := make([]string, len(rirStatsURLs))
+	for i, rir := range rirStatsURLs {
+		urls[i] = rir.URL
+	}
+	return urls
+}
+
+// datasetNames returns the known dataset names in order.
+func datasetNames() []string {
+	names := make([]string, 0, len(datasets))
+	for name := range datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectDatasets resolves a comma-separated list of dataset names, or "all".
+func selectDatasets(list string) ([]dataset, error) {
+	if list == "all" {
+		list = strings.Join(datasetNames(), ",")
+	}
+	var out []dataset
+	for _, name := range strings.Split(list, ",") {
+		d, ok := datasets[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown dataset %q (want all or %s)", name, strings.Join(datasetNames(), ", "))
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// fetch returns the cached paths of the dataset's files, downloading any that are
+// missing or stale, or all of them when refresh is set.
+func (d dataset) fetch(refresh bool) ([]string, error) {
+	var paths []string
+	for _, url := range d.URLs {
+		path, err := cachedDownload(d.Name, url, d.MaxAge, refresh)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", d.Name, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// cached returns the cached paths of the dataset's files, however old, without
+// downloading anything, for readers with a built-in copy to fall back on or that
+// can do without the data. It reports false unless every file is cached.
+func (d dataset) cached() ([]string, bool) {
+	dir, err := cacheDir(d.Name)
+	if err != nil {
+		return nil, false
+	}
+	var paths []string
+	for _, url := range d.URLs {
+		path := filepath.Join(dir, cacheFileName(url))
+		if _, err := os.Stat(path); err != nil {
+			return nil, false
+		}
+		paths = append(paths, path)
+	}
+	return paths, true
+}
+
+// datasetState describes the cached copy of a dataset. The age is that of its
+// oldest file; Missing counts files never downloaded.
+type datasetState struct {
+	Dataset dataset
+	Dir     string
+	Age     time.Duration
+	Missing int
+}
+
+// Status summarizes the state as fresh, stale, partial, or missing.
+func (s datasetState) Status() string {
+	switch {
+	case s.Missing == len(s.Dataset.URLs):
+		return "missing"
+	case s.Missing > 0:
+		return "partial"
+	case s.Age > s.Dataset.MaxAge:
+		return "stale"
+	}
+	return "fresh"
+}
+
+// inspectDataset looks at the cached files of a dataset without downloading.
+func inspectDataset(d dataset, now time.Time) (datasetState, error) {
+	dir, err := cacheDir(d.Name)
+	if err != nil {
+		return datasetState{}, err
+	}
+	s := datasetState{Dataset: d, Dir: dir}
+	for _, url := range d.URLs {
+		info, err := os.Stat(filepath.Join(dir, cacheFileName(url)))
+		if err != nil {
+			s.Missing++
+			continue
+		}
+		if age := now.Sub(info.ModTime()); age > s.Age {
+			s.Age = age
+		}
+	}
+	return s, nil
+}
+
+// shortDuration formats a duration to the two largest of days, hours, and
+// minutes, e.g. "30d", "1d4h", "3h12m".
+func shortDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days, hours, minutes := int(d/(24*time.Hour)), int(d%(24*time.Hour)/time.Hour), int(d%time.Hour/time.Minute)
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%dd", days)
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// writeDatasetStatus prints one line per dataset with its state and age.
+func writeDatasetStatus(w io.Writer, states []datasetState) {
+	for _, s := range states {
+		age := "-"
+		if s.Missing < len(s.Dataset.URLs) {
+			age = shortDuration(s.Age)
+		}
+		fmt.Fprintf(w, "%-14s %-8s age %-6s max %-4s %s\n", s.Dataset.Name, s.Status(), age, shortDuration(s.Dataset.MaxAge), s.Dataset.Description)
+	}
+}
+
+// updateDatasets downloads fresh copies of the listed datasets.
+func updateDatasets(list string) {
+	selected, err := selectDatasets(list)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, d := range selected {
+		if _, err := d.fetch(true); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// reportDatasetStatus prints the cache directory and the state of every dataset.
+func reportDatasetStatus() {
+	var states []datasetState
+	for _, name := range datasetNames() {
+		s, err := inspectDataset(datasets[name], time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		states = append(states, s)
+	}
+	fmt.Printf("Cache:           %s\n", filepath.Dir(states[0].Dir))
+	writeDatasetStatus(os.Stdout, states)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelectDatasets(t *testing.T) {
+	all, err := selectDatasets("all")
+	if err != nil || len(all) != len(datasets) {
+		t.Fatalf("all: got %d datasets, %v", len(all), err)
+	}
+	some, err := selectDatasets("rir, oui")
+	if err != nil || len(some) != 2 || some[0].Name != "rir" || some[1].Name != "oui" {
+		t.Errorf("got %+v, %v", some, err)
+	}
+	if _, err := selectDatasets("rir,geoip"); err == nil || !strings.Contains(err.Error(), `unknown dataset "geoip"`) {
+		t.Errorf("expected unknown dataset error, got %v", err)
+	}
+}
+
+func TestShortDuration(t *testing.T) {
+	cases := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * 24 * time.Hour, "30d"},
+		{28 * time.Hour, "1d4h"},
+		{3*time.Hour + 12*time.Minute + 20*time.Second, "3h12m"},
+		{24 * time.Hour, "1d"},
+		{6 * time.Hour, "6h"},
+		{40 * time.Second, "1m"},
+	}
+	for _, tc := range cases {
+		if got := shortDuration(tc.d); got != tc.want {
+			t.Errorf("shortDuration(%s) = %s, want %s", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestDatasetFetchAndStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testDownload))
+	}))
+	defer srv.Close()
+	defer func(root string) { cacheRoot = root }(cacheRoot)
+	cacheRoot = t.TempDir()
+
+	d := dataset{Name: "test", URLs: []string{srv.URL + "/a.txt", srv.URL + "/b.txt"}, MaxAge: time.Hour}
+	now := time.Now()
+	if s, err := inspectDataset(d, now); err != nil || s.Status() != "missing" {
+		t.Fatalf("before fetch: %s, %v", s.Status(), err)
+	}
+	paths, err := d.fetch(false)
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("fetch: %v, %v", paths, err)
+	}
+	if !strings.HasPrefix(paths[0], filepath.Join(cacheRoot, "test")) {
+		t.Errorf("dataset not cached under -cache-dir: %s", paths[0])
+	}
+	if s, _ := inspectDataset(d, now); s.Status() != "fresh" {
+		t.Errorf("after fetch: %s", s.Status())
+	}
+	old := now.Add(-2 * time.Hour)
+	if err := os.Chtimes(paths[1], old, old); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := inspectDataset(d, now)
+	if s.Status() != "stale" {
+		t.Errorf("after aging: %s", s.Status())
+	}
+	os.Remove(paths[0])
+	s, _ = inspectDataset(d, now)
+	if s.Status() != "partial" {
+		t.Errorf("after removing a file: %s", s.Status())
+	}
+	var buf bytes.Buffer
+	writeDatasetStatus(&buf, []datasetState{s})
+	if want := "test           partial  age 2h     max 1h"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestDatasetCached(t *testing.T) {
+	defer func(root string) { cacheRoot = root }(cacheRoot)
+	cacheRoot = t.TempDir()
+	d := dataset{Name: "test", URLs: []string{"https://example.com/a.csv", "https://example.com/b.csv"}, MaxAge: time.Hour}
+	if _, ok := d.cached(); ok {
+		t.Fatal("nothing cached yet: got ok")
+	}
+	dir := filepath.Join(cacheRoot, "test")
+	os.WriteFile(filepath.Join(dir, cacheFileName(d.URLs[0])), []byte("a"), 0o644)
+	if _, ok := d.cached(); ok {
+		t.Error("one of two files cached: got ok")
+	}
+	os.WriteFile(filepath.Join(dir, cacheFileName(d.URLs[1])), []byte("b"), 0o644)
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(filepath.Join(dir, cacheFileName(d.URLs[1])), old, old)
+	paths, ok := d.cached()
+	if !ok || len(paths) != 2 || filepath.Base(paths[1]) != cacheFileName(d.URLs[1]) {
+		t.Errorf("stale but complete cache: got %v, %v", paths, ok)
+	}
+}
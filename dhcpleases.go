@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dhcpLease is one address or prefix binding from a DHCPv6 server's lease database.
+type dhcpLease struct {
+	Kind  string // "ia-na", "ia-ta", or "ia-pd"
+	Net   *net.IPNet
+	State string
+	Ends  time.Time // zero when the lease never expires
+}
+
+// Active reports whether the lease is bound at the given time.
+func (l dhcpLease) Active(now time.Time) bool {
+	return l.State == "active" && (l.Ends.IsZero() || l.Ends.After(now))
+}
+
+// confTokens splits a brace-structured file (dhcpd leases, radvd.conf) into words,
+// quoted strings, and the punctuation '{', '}', and ';'. Comments run from '#' to
+// the end of the line.
+func confTokens(data string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case c == '{' || c == '}' || c == ';':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(data) && data[j] != '"'; j++ {
+				if data[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(data) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, data[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(data) && !strings.ContainsRune(" \t\r\n{};#\"", rune(data[j])) {
+				j++
+			}
+			tokens = append(tokens, data[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// confStatement is a parsed "words ... ;" or "words ... { body }" statement.
+type confStatement struct {
+	Words []string
+	Body  []confStatement
+}
+
+// parseConfStatements parses statements until the closing brace (or end of input
+// at the top level), returning them and the position after the brace.
+func parseConfStatements(tokens []string, pos int, nested bool) ([]confStatement, int, error) {
+	var stmts []confStatement
+	var words []string
+	for pos < len(tokens) {
+		tok := tokens[pos]
+		pos++
+		switch tok {
+		case ";":
+			if len(words) > 0 {
+				stmts = append(stmts, confStatement{Words: words})
+			}
+			words = nil
+		case "{":
+			body, next, err := parseConfStatements(tokens, pos, true)
+			if err != nil {
+				return nil, 0, err
+			}
+			stmts = append(stmts, confStatement{Words: words, Body: body})
+			words, pos = nil, next
+		case "}":
+			if !nested {
+				return nil, 0, fmt.Errorf("unexpected '}'")
+			}
+			return stmts, pos, nil
+		default:
+			words = append(words, tok)
+		}
+	}
+	if nested {
+		return nil, 0, fmt.Errorf("missing '}'")
+	}
+	return stmts, pos, nil
+}
+
+// parseBraceConfig tokenizes and parses a whole brace-structured file.
+func parseBraceConfig(data string) ([]confStatement, error) {
+	tokens, err := confTokens(data)
+	if err != nil {
+		return nil, err
+	}
+	stmts, _, err := parseConfStatements(tokens, 0, false)
+	return stmts, err
+}
+
+// parseLeaseTime reads an ISC lease time: "never", "epoch SECONDS; # comment",
+// or "WEEKDAY YYYY/MM/DD HH:MM:SS" in UTC.
+func parseLeaseTime(words []string) (time.Time, error) {
+	switch {
+	case len(words) == 1 && words[0] == "never":
+		return time.Time{}, nil
+	case len(words) == 2 && words[0] == "epoch":
+		var secs int64
+		if _, err := fmt.Sscan(words[1], &secs); err != nil {
+			return time.Time{}, fmt.Errorf("invalid epoch time %q", words[1])
+		}
+		return time.Unix(secs, 0).UTC(), nil
+	case len(words) == 3:
+		return time.Parse("2006/01/02 15:04:05", words[1]+" "+words[2])
+	}
+	return time.Time{}, fmt.Errorf("invalid lease time %q", strings.Join(words, " "))
+}
+
+// parseDhcpd6Leases reads an ISC dhcpd6.leases file. The file is an append-only
+// journal, so a later entry for the same address or prefix replaces earlier ones;
+// the result is sorted by address.
+func parseDhcpd6Leases(r io.Reader) ([]dhcpLease, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	stmts, err := parseBraceConfig(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var leases []dhcpLease
+	for _, ia := range stmts {
+		if len(ia.Words) == 0 || (ia.Words[0] != "ia-na" && ia.Words[0] != "ia-ta" && ia.Words[0] != "ia-pd") {
+			continue
+		}
+		for _, binding := range ia.Body {
+			if len(binding.Words) != 2 || (binding.Words[0] != "iaaddr" && binding.Words[0] != "iaprefix") {
+				continue
+			}
+			ip, prefixLen, err := parseIPv6WithOptionalPrefix(binding.Words[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", strings.Join(binding.Words, " "), err)
+			}
+			if prefixLen < 0 {
+				prefixLen = 128
+			}
+			mask := net.CIDRMask(prefixLen, 128)
+			lease := dhcpLease{Kind: ia.Words[0], Net: &net.IPNet{IP: ip.Mask(mask), Mask: mask}}
+			for _, attr := range binding.Body {
+				w := attr.Words
+				switch {
+				case len(w) == 3 && w[0] == "binding" && w[1] == "state":
+					lease.State = w[2]
+				case len(w) > 1 && w[0] == "ends":
+					if lease.Ends, err = parseLeaseTime(w[1:]); err != nil {
+						return nil, fmt.Errorf("%s: %v", lease.Net, err)
+					}
+				}
+			}
+			leases = append(leases, lease)
+		}
+	}
+	return latestLeases(leases), nil
+}
+
+// latestLeases keeps the last lease for each address or prefix, sorted by address.
+func latestLeases(leases []dhcpLease) []dhcpLease {
+	latest := map[string]dhcpLease{}
+	for _, l := range leases {
+		latest[l.Net.String()] = l
+	}
+	out := make([]dhcpLease, 0, len(latest))
+	for _, l := range latest {
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return ipToBigInt(out[i].Net.IP).Cmp(ipToBigInt(out[j].Net.IP)) < 0
+	})
+	return out
+}
+
+// writeLeasePrefixList prints leases as a prefix list, so they can be given to
+// -exclude, -heatmap, -stale, or -audit. Only leases active at now are written
+// unless all is set.
+func writeLeasePrefixList(w io.Writer, leases []dhcpLease, now time.Time, all bool) int {
+	n := 0
+	for _, l := range leases {
+		if !all && !l.Active(now) {
+			continue
+		}
+		label := l.Kind
+		if all {
+			label += " " + l.State
+		}
+		if !l.Ends.IsZero() {
+			label += " ends " + l.Ends.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%-44s %s\n", l.Net, label)
+		n++
+	}
+	return n
+}
+
+// reportLeases reads a lease database with parse and either prints it as a prefix
+// list or, when planFile is given, reconciles the leases against that prefix list
+// and fails if any lie outside it. Only active leases are considered unless all is set.
+func reportLeases(path string, parse func(io.Reader) ([]dhcpLease, error), all bool, planFile string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	leases, err := parse(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	if planFile == "" {
+		n := writeLeasePrefixList(os.Stdout, leases, time.Now(), all)
+		if !stableOutput {
+			fmt.Fprintf(os.Stderr, "%d of %d lease(s) written\n", n, len(leases))
+		}
+		return
+	}
+	plan, err := readPrefixFile(planFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var current []dhcpLease
+	now := time.Now()
+	for _, l := range leases {
+		if all || l.Active(now) {
+			current = append(current, l)
+		}
+	}
+	if outside := writeLeaseReconciliation(os.Stdout, reconcileLeases(current, plan)); outside > 0 {
+		log.Fatalf("%d of %d lease(s) outside planned ranges", outside, len(current))
+	}
+	statusf("%d lease(s) within planned ranges\n", len(current))
+}
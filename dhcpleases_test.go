@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDhcpd6Leases(t *testing.T) {
+	f, err := os.Open("testdata/dhcpd6.leases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	leases, err := parseDhcpd6Leases(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(leases) != 4 {
+		t.Fatalf("expected 4 leases, got %+v", leases)
+	}
+	// The second entry for ::1:101 replaces the expired one.
+	if l := leases[1]; l.Net.String() != "3fff:0:1:10::1:101/128" || l.State != "active" || l.Ends.Hour() != 21 {
+		t.Errorf("expected the later entry to win, got %+v", l)
+	}
+	if l := leases[2]; l.Kind != "ia-pd" || l.Net.String() != "3fff:0:1:100::/56" || !l.Ends.IsZero() {
+		t.Errorf("unexpected delegated prefix %+v", l)
+	}
+	if l := leases[3]; !l.Ends.Equal(time.Date(2026, 10, 13, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected epoch end time %v", l.Ends)
+	}
+
+	var buf bytes.Buffer
+	n := writeLeasePrefixList(&buf, leases, time.Date(2026, 10, 13, 20, 30, 0, 0, time.UTC), false)
+	want := "3fff:0:1:10::1:101/128                       ia-na ends 2026-10-13T21:00:00Z\n" +
+		"3fff:0:1:100::/56                            ia-pd\n"
+	if n != 2 || buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+	entries, err := readPrefixEntries(&buf)
+	if err != nil || len(entries) != 2 || entries[1].Label != "ia-pd" {
+		t.Errorf("expected output to read back as a prefix list, got %+v: %v", entries, err)
+	}
+}
+
+func TestParseDhcpd6LeasesErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"unterminated string", `ia-na "abc {`},
+		{"missing brace", `ia-na "x" { iaaddr 3fff::1 { binding state active; }`},
+		{"stray brace", `}`},
+		{"bad address", `ia-na "x" { iaaddr 3fff::g { } }`},
+		{"bad time", `ia-na "x" { iaaddr 3fff::1 { ends soon; } }`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseDhcpd6Leases(strings.NewReader(tc.input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
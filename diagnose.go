@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// parseError explains why a textual address or prefix was rejected and where.
+type parseError struct {
+	Input      string
+	Pos        int // byte offset of the offending character
+	Reason     string
+	Suggestion string // likely intended value, if a typo correction applies
+}
+
+// Error renders the reason followed by the input with a caret under the offending
+// character, and the suggested correction when there is one.
+func (e *parseError) Error() string {
+	msg := fmt.Sprintf("%s\n  %s\n  %s^", e.Reason, e.Input, strings.Repeat(" ", e.Pos))
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf("\n  did you mean %s?", e.Suggestion)
+	}
+	return msg
+}
+
+// parseIPv6Text parses an IPv6 address strictly, returning the address and the text
+// offset at which each of its eight 16-bit groups starts (groups elided by "::" point
+// at the "::"). Unlike net.ParseIP it reports exactly what is wrong.
+func parseIPv6Text(s string) (net.IP, [8]int, *parseError) {
+	var pos [8]int
+	fail := func(p int, format string, a ...any) (net.IP, [8]int, *parseError) {
+		return nil, pos, &parseError{Input: s, Pos: p, Reason: fmt.Sprintf(format, a...)}
+	}
+	if s == "" {
+		return fail(0, "empty address")
+	}
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'f', r >= 'A' && r <= 'F', r == ':', r == '.':
+		case r == '%':
+			return fail(i, "zone index (%s) is not allowed here", s[i:])
+		case r == '/':
+			return fail(i, "unexpected prefix length")
+		case r >= 'g' && r <= 'z', r >= 'G' && r <= 'Z':
+			return fail(i, "invalid hex digit '%c'", r)
+		default:
+			return fail(i, "invalid character %q", r)
+		}
+	}
+	if !strings.Contains(s, ":") {
+		if strings.Contains(s, ".") {
+			return fail(0, "%s is an IPv4 address, not IPv6", s)
+		}
+		return fail(len(s), "missing ':' separators")
+	}
+	if i := strings.Index(s, ":::"); i != -1 {
+		return fail(i+2, "too many colons")
+	}
+	first := strings.Index(s, "::")
+	if first != -1 {
+		if second := strings.Index(s[first+2:], "::"); second != -1 {
+			return fail(first+2+second, "'::' may only appear once")
+		}
+	}
+	if s[0] == ':' && first != 0 {
+		return fail(0, "leading ':' must be part of '::'")
+	}
+	if s[len(s)-1] == ':' && first != len(s)-2 {
+		return fail(len(s)-1, "trailing ':' must be part of '::'")
+	}
+
+	// Split into the groups before and after "::", remembering offsets.
+	type group struct {
+		text string
+		at   int
+	}
+	split := func(part string, offset int) []group {
+		if part == "" {
+			return nil
+		}
+		var gs []group
+		at := offset
+		for _, g := range strings.Split(part, ":") {
+			gs = append(gs, group{g, at})
+			at += len(g) + 1
+		}
+		return gs
+	}
+	var head, tail []group
+	if first == -1 {
+		head = split(s, 0)
+	} else {
+		head = split(s[:first], 0)
+		tail = split(s[first+2:], first+2)
+	}
+
+	var values []uint16
+	var starts []int
+	all := append(append([]group{}, head...), tail...)
+	for i, g := range all {
+		if strings.Contains(g.text, ".") {
+			if i != len(all)-1 {
+				return fail(g.at, "embedded IPv4 address must be the last group")
+			}
+			v4, err := parseDottedQuad(g.text)
+			if err != nil {
+				return fail(g.at+err.Pos, "%s", err.Reason)
+			}
+			values = append(values, uint16(v4[0])<<8|uint16(v4[1]), uint16(v4[2])<<8|uint16(v4[3]))
+			starts = append(starts, g.at, g.at)
+			continue
+		}
+		if g.text == "" {
+			return fail(g.at, "empty group")
+		}
+		if len(g.text) > 4 {
+			return fail(g.at+4, "group %q is longer than 4 hex digits", g.text)
+		}
+		v, _ := strconv.ParseUint(g.text, 16, 16)
+		values = append(values, uint16(v))
+		starts = append(starts, g.at)
+	}
+
+	// An embedded IPv4 address counts as two groups.
+	headGroups := len(head)
+	if first == -1 {
+		if len(values) != 8 {
+			if len(values) > 8 {
+				return fail(starts[8], "too many groups (%d); an address has 8", len(values))
+			}
+			return fail(len(s), "only %d groups and no '::'; an address has 8", len(values))
+		}
+	} else if len(values) > 7 {
+		return fail(first, "'::' must stand for at least one group, but %d groups are already present", len(values))
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	missing := 8 - len(values)
+	for i := 0; i < 8; i++ {
+		var v uint16
+		switch {
+		case i < headGroups:
+			v, pos[i] = values[i], starts[i]
+		case i < headGroups+missing:
+			pos[i] = first
+		default:
+			v, pos[i] = values[i-missing], starts[i-missing]
+		}
+		ip[i*2] = byte(v >> 8)
+		ip[i*2+1] = byte(v)
+	}
+	return ip, pos, nil
+}
+
+// parseDottedQuad parses the IPv4 tail of a mixed-notation address.
+func parseDottedQuad(s string) ([4]byte, *parseError) {
+	var out [4]byte
+	parts := strings.Split(s, ".")
+	if len(parts) != 4 {
+		return out, &parseError{Pos: 0, Reason: fmt.Sprintf("embedded IPv4 address %q needs 4 octets", s)}
+	}
+	at := 0
+	for i, p := range parts {
+		if p == "" || strings.ContainsAny(p, "abcdefABCDEF") {
+			return out, &parseError{Pos: at, Reason: fmt.Sprintf("invalid IPv4 octet %q", p)}
+		}
+		if len(p) > 1 && p[0] == '0' {
+			return out, &parseError{Pos: at, Reason: fmt.Sprintf("IPv4 octet %q has a leading zero", p)}
+		}
+		v, err := strconv.Atoi(p)
+		if err != nil || v > 255 {
+			return out, &parseError{Pos: at, Reason: fmt.Sprintf("IPv4 octet %q is larger than 255", p)}
+		}
+		out[i] = byte(v)
+		at += len(p) + 1
+	}
+	return out, nil
+}
+
+// diagnoseIPv6 explains why an address that net.ParseIP rejected is invalid. If the
+// strict parser finds nothing specific, the input itself is returned as the error.
+func diagnoseIPv6(s string) error {
+	if _, _, err := parseIPv6Text(s); err != nil {
+		if fixed, _, ferr := fixIPv6Input(s); ferr == nil {
+			err.Suggestion = fixed
+		}
+		return err
+	}
+	return fmt.Errorf("%s", s)
+}
+
+// parseIPv6Prefix strictly parses an IPv6 prefix in CIDR notation. Errors point at the
+// offending character and cover malformed addresses, missing or out-of-range lengths,
+// and host bits set beyond the prefix length. Near misses carry a suggested correction.
+func parseIPv6Prefix(s string) (*net.IPNet, error) {
+	ipnet, err := parsePrefixStrict(s)
+	if perr, ok := err.(*parseError); ok {
+		if fixed, _, ferr := fixIPv6Input(s); ferr == nil && strings.Contains(fixed, "/") {
+			perr.Suggestion = fixed
+		}
+	}
+	return ipnet, err
+}
+
+// parsePrefixStrict is parseIPv6Prefix without typo suggestions.
+func parsePrefixStrict(s string) (*net.IPNet, error) {
+	slash := strings.LastIndex(s, "/")
+	if slash == -1 {
+		if _, _, err := parseIPv6Text(s); err != nil {
+			return nil, err
+		}
+		return nil, &parseError{Input: s, Pos: len(s), Reason: "missing prefix length (e.g. /64)"}
+	}
+	ip, groupPos, perr := parseIPv6Text(s[:slash])
+	if perr != nil {
+		perr.Input = s
+		return nil, perr
+	}
+	lenText := s[slash+1:]
+	if lenText == "" {
+		return nil, &parseError{Input: s, Pos: slash + 1, Reason: "missing prefix length after '/'"}
+	}
+	for i, r := range lenText {
+		if r < '0' || r > '9' {
+			return nil, &parseError{Input: s, Pos: slash + 1 + i, Reason: fmt.Sprintf("invalid prefix length %q", lenText)}
+		}
+	}
+	prefixLen, err := strconv.Atoi(lenText)
+	if err != nil || prefixLen > 128 {
+		return nil, &parseError{Input: s, Pos: slash + 1, Reason: fmt.Sprintf("prefix length %s exceeds 128", lenText)}
+	}
+	mask := net.CIDRMask(prefixLen, 128)
+	network := ip.Mask(mask)
+	if !network.Equal(ip) {
+		bit := prefixLen
+		for ; bit < 128; bit++ {
+			if ip[bit/8]&(0x80>>(bit%8)) != 0 {
+				break
+			}
+		}
+		return nil, &parseError{
+			Input:  s,
+			Pos:    groupPos[bit/16],
+			Reason: fmt.Sprintf("host bits set beyond /%d", prefixLen),
+		}
+	}
+	return &net.IPNet{IP: network, Mask: mask}, nil
+}
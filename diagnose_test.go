@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseIPv6Text(t *testing.T) {
+	valid := []string{
+		"::", "::1", "1::", "2001:db8::1", "2001:DB8::A", "fe80::aabb:ccff:fedd:eeff",
+		"2001:db8:0:0:0:0:0:1", "2001:db8:1:2:3:4:5::", "::ffff:192.0.2.1", "64:ff9b::192.0.2.33",
+		"1:2:3:4:5:6:1.2.3.4",
+	}
+	for _, s := range valid {
+		t.Run(s, func(t *testing.T) {
+			got, _, err := parseIPv6Text(s)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(net.ParseIP(s)) {
+				t.Errorf("expected %s, got %s", net.ParseIP(s), got)
+			}
+		})
+	}
+}
+
+func TestParseIPv6Prefix(t *testing.T) {
+	cases := []struct {
+		name         string
+		input        string
+		expect       string
+		expectPos    int
+		expectReason string
+	}{
+		{name: "valid", input: "2001:db8::/32", expect: "2001:db8::/32"},
+		{name: "valid /0", input: "::/0", expect: "::/0"},
+		{name: "valid /128", input: "2001:db8::1/128", expect: "2001:db8::1/128"},
+		{name: "bad hex digit", input: "2001:db8::g/32", expectPos: 10, expectReason: "invalid hex digit 'g'"},
+		{name: "double ::", input: "2001::db8::/32", expectPos: 9, expectReason: "'::' may only appear once"},
+		{name: "triple colon", input: "2001:db8:::/32", expectPos: 10, expectReason: "too many colons"},
+		{name: "group too long", input: "2001:db8:12345::/48", expectPos: 13, expectReason: "longer than 4 hex digits"},
+		{name: "missing length", input: "2001:db8::", expectPos: 10, expectReason: "missing prefix length"},
+		{name: "empty length", input: "2001:db8::/", expectPos: 11, expectReason: "missing prefix length after '/'"},
+		{name: "length too large", input: "2001:db8::/129", expectPos: 11, expectReason: "exceeds 128"},
+		{name: "non-numeric length", input: "2001:db8::/3x", expectPos: 12, expectReason: "invalid prefix length"},
+		{name: "host bits set", input: "2001:db8:1:2::/32", expectPos: 9, expectReason: "host bits set beyond /32"},
+		{name: "host bits in elided run point at ::", input: "2001:db8::1/32", expectPos: 10, expectReason: "host bits set beyond /32"},
+		{name: "too many groups", input: "1:2:3:4:5:6:7:8:9/64", expectPos: 16, expectReason: "too many groups"},
+		{name: "too few groups", input: "1:2:3:4:5:6:7/64", expectPos: 13, expectReason: "only 7 groups"},
+		{name: ":: with 8 groups", input: "1:2:3:4::5:6:7:8/64", expectPos: 7, expectReason: "at least one group"},
+		{name: "leading colon", input: ":1::/16", expectPos: 0, expectReason: "leading ':'"},
+		{name: "trailing colon", input: "1::1:/64", expectPos: 4, expectReason: "trailing ':'"},
+		{name: "zone index", input: "fe80::%eth0/64", expectPos: 6, expectReason: "zone index"},
+		{name: "IPv4 prefix", input: "192.0.2.0/24", expectPos: 0, expectReason: "IPv4 address, not IPv6"},
+		{name: "bad embedded IPv4", input: "::ffff:192.0.2.256/128", expectPos: 15, expectReason: "larger than 255"},
+		{name: "embedded IPv4 not last", input: "::1.2.3.4:1/128", expectPos: 2, expectReason: "must be the last group"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseIPv6Prefix(tc.input)
+			if tc.expectReason == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got.String() != tc.expect {
+					t.Errorf("expected %s, got %s", tc.expect, got)
+				}
+				return
+			}
+			var perr *parseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected *parseError, got %v", err)
+			}
+			if perr.Pos != tc.expectPos {
+				t.Errorf("expected position %d, got %d\n%v", tc.expectPos, perr.Pos, perr)
+			}
+			if !strings.Contains(perr.Reason, tc.expectReason) {
+				t.Errorf("expected reason containing %q, got %q", tc.expectReason, perr.Reason)
+			}
+		})
+	}
+}
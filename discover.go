@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+	dnsTypeA          = 1
+	dnsTypePTR        = 12
+	dnsTypeAAAA       = 28
+	dnsClassINUnicast = 0x8001 // class IN with the mDNS "unicast response" bit
+)
+
+// discoveredHost is a neighbor that answered at least one probe.
+type discoveredHost struct {
+	IP      net.IP
+	Sources []string // probes it answered: "icmp", "mdns"
+	Name    string   // mDNS host name, when one was announced
+}
+
+// echoRequest builds an ICMPv6 echo request. The kernel fills in the checksum for
+// ICMPv6 raw sockets (RFC 3542), so it is left zero.
+func echoRequest(id, seq uint16) []byte {
+	b := make([]byte, 8, 8+len("ipv6utils"))
+	b[0] = icmpv6EchoRequest
+	binary.BigEndian.PutUint16(b[4:], id)
+	binary.BigEndian.PutUint16(b[6:], seq)
+	return append(b, "ipv6utils"...)
+}
+
+// isEchoReply reports whether b is an echo reply to a request with the given id.
+func isEchoReply(b []byte, id uint16) bool {
+	return len(b) >= 8 && b[0] == icmpv6EchoReply && binary.BigEndian.Uint16(b[4:]) == id
+}
+
+// mdnsServicesQuery builds an mDNS query for the DNS-SD service enumeration name,
+// asking for unicast responses so replies come straight back to our socket.
+func mdnsServicesQuery() []byte {
+	b := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0} // id, flags, qd=1
+	for _, label := range strings.Split("_services._dns-sd._udp.local", ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint16(b, dnsTypePTR)
+	return binary.BigEndian.AppendUint16(b, dnsClassINUnicast)
+}
+
+// readDNSName decodes a possibly compressed name at off, returning it and the
+// offset after it in the original position.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, fmt.Errorf("name runs past end of message")
+		}
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			if next == -1 {
+				next = off + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case l&0xc0 == 0xc0:
+			if off+1 >= len(msg) || jumps > 16 {
+				return "", 0, fmt.Errorf("invalid compression pointer")
+			}
+			if next == -1 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			jumps++
+		default:
+			if off+1+l > len(msg) {
+				return "", 0, fmt.Errorf("label runs past end of message")
+			}
+			labels = append(labels, string(msg[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
+
+// mdnsHostName returns the owner name of the first A or AAAA record in an mDNS
+// response, which is the responder's host name (e.g. "printer.local").
+func mdnsHostName(msg []byte) string {
+	if len(msg) < 12 {
+		return ""
+	}
+	qd := int(binary.BigEndian.Uint16(msg[4:]))
+	rrs := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+	off := 12
+	for range qd {
+		_, next, err := readDNSName(msg, off)
+		if err != nil {
+			return ""
+		}
+		off = next + 4
+	}
+	for range rrs {
+		name, next, err := readDNSName(msg, off)
+		if err != nil || next+10 > len(msg) {
+			return ""
+		}
+		typ := binary.BigEndian.Uint16(msg[next:])
+		rdlen := int(binary.BigEndian.Uint16(msg[next+8:]))
+		if typ == dnsTypeAAAA || typ == dnsTypeA {
+			return name
+		}
+		off = next + 10 + rdlen
+	}
+	return ""
+}
+
+// neighborSet collects probe answers, merging replies from the same address.
+type neighborSet struct {
+	mu    sync.Mutex
+	hosts map[string]*discoveredHost
+}
+
+// add records that ip answered the named probe.
+func (s *neighborSet) add(ip net.IP, source string, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hosts == nil {
+		s.hosts = map[string]*discoveredHost{}
+	}
+	h, ok := s.hosts[ip.String()]
+	if !ok {
+		h = &discoveredHost{IP: ip}
+		s.hosts[ip.String()] = h
+	}
+	if !slices.Contains(h.Sources, source) {
+		h.Sources = append(h.Sources, source)
+	}
+	if h.Name == "" {
+		h.Name = strings.TrimSuffix(name, ".")
+	}
+}
+
+// sorted returns the neighbors ordered by address.
+func (s *neighborSet) sorted() []*discoveredHost {
+	var out []*discoveredHost
+	for _, h := range s.hosts {
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool { return ipToBigInt(out[i].IP).Cmp(ipToBigInt(out[j].IP)) < 0 })
+	return out
+}
+
+// pingAllNodes sends echo requests to ff02::1 on the interface and records every
+// reply until the deadline. It needs a raw ICMPv6 socket, so root or CAP_NET_RAW.
+func pingAllNodes(iface string, deadline time.Time, found *neighborSet) error {
+	conn, err := net.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return fmt.Errorf("ICMPv6 socket (needs root or CAP_NET_RAW): %v", err)
+	}
+	defer conn.Close()
+	id := uint16(time.Now().UnixNano())
+	dst := &net.IPAddr{IP: net.ParseIP("ff02::1"), Zone: iface}
+	for seq := range uint16(3) {
+		if _, err := conn.WriteTo(echoRequest(id, seq), dst); err != nil {
+			return err
+		}
+	}
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil // deadline reached
+		}
+		if addr, ok := from.(*net.IPAddr); ok && isEchoReply(buf[:n], id) {
+			found.add(addr.IP, "icmp", "")
+		}
+	}
+}
+
+// queryMDNS asks ff02::fb for its services and records every responder.
+func queryMDNS(iface string, deadline time.Time, found *neighborSet) error {
+	conn, err := net.ListenPacket("udp6", "[::]:0")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	dst := &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: 5353, Zone: iface}
+	if _, err := conn.WriteTo(mdnsServicesQuery(), dst); err != nil {
+		return err
+	}
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil
+		}
+		if addr, ok := from.(*net.UDPAddr); ok {
+			found.add(addr.IP, "mdns", mdnsHostName(buf[:n]))
+		}
+	}
+}
+
+// describeNeighbor runs the address through the existing analysis: the MAC is
+// recovered from EUI-64 interface IDs, its vendor looked up in ouis, and the
+// interface ID is classified. Without a MAC both are "-".
+func describeNeighbor(h *discoveredHost, ouis ouiRegistry) (mac, vendor, pattern string) {
+	score := scoreIID(h.IP)
+	mac, vendor = "-", "-"
+	if score.Pattern == patternEUI64 {
+		if m, err := decodeMACFromSLAAC(h.IP.String()); err == nil {
+			mac, vendor = m, ouis.vendor(m)
+		}
+	}
+	return mac, vendor, score.Pattern
+}
+
+// runDiscovery probes the link attached to iface and prints every neighbor
+// found, with the vendor of each recovered MAC from the cached OUI registry.
+func runDiscovery(iface string, timeout time.Duration) {
+	if _, err := net.InterfaceByName(iface); err != nil {
+		log.Fatal(err)
+	}
+	deadline := time.Now().Add(timeout)
+	found := &neighborSet{}
+	var wg sync.WaitGroup
+	for _, probe := range []func(string, time.Time, *neighborSet) error{pingAllNodes, queryMDNS} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := probe(iface, deadline, found); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	hosts := found.sorted()
+	ouis := loadCachedOUIRegistry()
+	for _, h := range hosts {
+		mac, vendor, pattern := describeNeighbor(h, ouis)
+		name := h.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Printf("%-40s %-10s %-17s %-15s %-15s %s\n", h.IP, strings.Join(h.Sources, ","), mac, pattern, name, vendor)
+	}
+	statusf("%d neighbor(s) on %s\n", len(hosts), iface)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestEchoRequest(t *testing.T) {
+	req := echoRequest(0x1234, 2)
+	if req[0] != icmpv6EchoRequest || req[4] != 0x12 || req[5] != 0x34 || req[7] != 2 {
+		t.Errorf("unexpected echo request % x", req)
+	}
+	reply := append([]byte{}, req...)
+	reply[0] = icmpv6EchoReply
+	if !isEchoReply(reply, 0x1234) {
+		t.Error("expected reply to match")
+	}
+	if isEchoReply(reply, 0x1235) || isEchoReply(req, 0x1234) || isEchoReply(reply[:6], 0x1234) {
+		t.Error("expected other ids, requests, and short packets not to match")
+	}
+}
+
+func TestMDNSServicesQuery(t *testing.T) {
+	q := mdnsServicesQuery()
+	name, next, err := readDNSName(q, 12)
+	if err != nil || name != "_services._dns-sd._udp.local" {
+		t.Fatalf("unexpected question name %q: %v", name, err)
+	}
+	if !bytes.Equal(q[next:], []byte{0, dnsTypePTR, 0x80, 0x01}) {
+		t.Errorf("unexpected question type/class % x", q[next:])
+	}
+}
+
+func TestMDNSHostName(t *testing.T) {
+	// A response with one PTR answer and an AAAA additional record whose owner
+	// name is compressed against the PTR target.
+	msg := []byte{0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 1}
+	msg = append(msg, 9)
+	msg = append(msg, "_services"...)
+	msg = append(msg, 7)
+	msg = append(msg, "_dns-sd"...)
+	msg = append(msg, 4)
+	msg = append(msg, "_udp"...)
+	msg = append(msg, 5)
+	msg = append(msg, "local"...)
+	msg = append(msg, 0)
+	msg = append(msg, 0, dnsTypePTR, 0, 1, 0, 0, 0x11, 0x94)
+	target := len(msg) + 2
+	rdata := append([]byte{7}, "printer"...)
+	rdata = append(rdata, 0xc0, 35) // "local" at offset 35
+	msg = append(msg, 0, byte(len(rdata)))
+	msg = append(msg, rdata...)
+	msg = append(msg, 0xc0, byte(target))
+	msg = append(msg, 0, dnsTypeAAAA, 0x80, 1, 0, 0, 0, 120, 0, 16)
+	msg = append(msg, net.ParseIP("fe80::1")...)
+
+	if got := mdnsHostName(msg); got != "printer.local" {
+		t.Errorf("expected printer.local, got %q", got)
+	}
+	if got := mdnsHostName(msg[:20]); got != "" {
+		t.Errorf("expected no name from truncated message, got %q", got)
+	}
+	loop := []byte{0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0xc0, 12}
+	if _, _, err := readDNSName(loop, 12); err == nil {
+		t.Error("expected error for compression loop")
+	}
+}
+
+func TestNeighborSet(t *testing.T) {
+	var s neighborSet
+	s.add(net.ParseIP("fe80::211:22ff:fe33:4455"), "icmp", "")
+	s.add(net.ParseIP("fe80::1"), "mdns", "router.local.")
+	s.add(net.ParseIP("fe80::211:22ff:fe33:4455"), "mdns", "nas.local.")
+	s.add(net.ParseIP("fe80::211:22ff:fe33:4455"), "icmp", "")
+
+	hosts := s.sorted()
+	if len(hosts) != 2 || hosts[0].IP.String() != "fe80::1" || hosts[0].Name != "router.local" {
+		t.Fatalf("unexpected neighbors %+v", hosts)
+	}
+	h := hosts[1]
+	if len(h.Sources) != 2 || h.Sources[0] != "icmp" || h.Sources[1] != "mdns" || h.Name != "nas.local" {
+		t.Errorf("unexpected merged neighbor %+v", h)
+	}
+	ouis := ouiRegistry{"001122": "CIMSYS Inc"}
+	mac, vendor, pattern := describeNeighbor(h, ouis)
+	if mac != "00:11:22:33:44:55" || vendor != "CIMSYS Inc" || pattern != patternEUI64 {
+		t.Errorf("expected EUI-64 MAC and vendor, got %s %q %s", mac, vendor, pattern)
+	}
+	if _, vendor, _ := describeNeighbor(h, nil); vendor != "unknown" {
+		t.Errorf("expected unknown vendor without the OUI registry, got %q", vendor)
+	}
+	if mac, vendor, _ := describeNeighbor(hosts[0], ouis); mac != "-" || vendor != "-" {
+		t.Errorf("expected no MAC for fe80::1, got %s %s", mac, vendor)
+	}
+}
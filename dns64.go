@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ipLookup is net.Resolver's LookupIP, replaced in tests.
+type ipLookup func(ctx context.Context, network, host string) ([]net.IP, error)
+
+// dns64Answer is an AAAA record of a name, and the IPv4 address and NAT64
+// prefix it was synthesized from, if it was.
+type dns64Answer struct {
+	IPv6        string `json:"ipv6"`
+	Synthesized bool   `json:"synthesized"`
+	IPv4        string `json:"ipv4,omitempty"`
+	Prefix      string `json:"prefix,omitempty"`
+}
+
+// dns64Report is what a resolver answered for a name, and what that says of its
+// DNS64.
+type dns64Report struct {
+	Host        string        `json:"host"`
+	Resolver    string        `json:"resolver"`
+	A           []string      `json:"a"`
+	AAAA        []dns64Answer `json:"aaaa"`
+	Prefixes    []string      `json:"prefixes"` // the resolver's NAT64 prefixes, from ipv4only.arpa
+	Synthesized bool          `json:"synthesized"`
+}
+
+// lookupAddrs returns the addresses of host for network, ip4 or ip6, with a
+// name having none of them not an error.
+func lookupAddrs(ctx context.Context, lookup ipLookup, network, host string) ([]netip.Addr, error) {
+	ips, err := lookup(ctx, network, host)
+	if dnsErr := (*net.DNSError)(nil); errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var addrs []netip.Addr
+	for _, ip := range ips {
+		if a, ok := netip.AddrFromSlice(ip); ok {
+			addrs = append(addrs, a.Unmap())
+		}
+	}
+	return addrs, nil
+}
+
+// testDNS64 asks for the A and AAAA records of host, and for the AAAA records
+// of ipv4only.arpa to learn the NAT64 prefixes the resolver synthesizes with.
+// An AAAA record is synthesized when it lies within one of those prefixes, or
+// when it embeds one of the name's IPv4 addresses under a prefix of any RFC
+// 6052 length, which finds the prefix of a resolver that does not synthesize
+// for ipv4only.arpa.
+func testDNS64(ctx context.Context, lookup ipLookup, host string) (dns64Report, error) {
+	r := dns64Report{Host: host, A: []string{}, AAAA: []dns64Answer{}, Prefixes: []string{}}
+	v4s, err := lookupAddrs(ctx, lookup, "ip4", host)
+	if err != nil {
+		return r, err
+	}
+	v6s, err := lookupAddrs(ctx, lookup, "ip6", host)
+	if err != nil {
+		return r, err
+	}
+	if len(v4s) == 0 && len(v6s) == 0 {
+		return r, fmt.Errorf("%s has no A or AAAA records", host)
+	}
+	// A resolver without DNS64 may fail to resolve ipv4only.arpa at all.
+	wka, _ := lookupAddrs(ctx, lookup, "ip6", "ipv4only.arpa")
+	var prefixes []netip.Prefix
+	for _, a := range wka {
+		if _, p, ok := findNAT64(a, func(v4 netip.Addr) bool { return slices.Contains(ipv4OnlyARPA, v4) }); ok && !slices.Contains(prefixes, p) {
+			prefixes = append(prefixes, p)
+			r.Prefixes = append(r.Prefixes, prefixText(p))
+		}
+	}
+
+	for _, a := range v4s {
+		r.A = append(r.A, a.String())
+	}
+	for _, a := range v6s {
+		answer := dns64Answer{IPv6: addrText(a)}
+		v4, p, ok := findNAT64(a, func(v4 netip.Addr) bool { return slices.Contains(v4s, v4) })
+		for _, known := range prefixes {
+			if known.Contains(a) {
+				v4, _, _ = extractNAT64(a, known.Bits())
+				p, ok = known, true
+				break
+			}
+		}
+		if ok {
+			answer.Synthesized, answer.IPv4, answer.Prefix = true, v4.String(), prefixText(p)
+			r.Synthesized = true
+		}
+		r.AAAA = append(r.AAAA, answer)
+	}
+	return r, nil
+}
+
+// dnsServer returns the address to send queries to for a -resolver of an
+// address, a host name, or either with a port, on port 53 unless given.
+func dnsServer(resolver string) string {
+	if a, err := netip.ParseAddr(resolver); err == nil {
+		return net.JoinHostPort(a.String(), "53")
+	}
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(resolver, "53")
+}
+
+// newResolver returns the system's resolver, or with a server, one sending its
+// queries there.
+func newResolver(server string) *net.Resolver {
+	if server == "" {
+		return net.DefaultResolver
+	}
+	addr := dnsServer(server)
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// runDNS64Test prints what a resolver, the system's or server, answers for a
+// name: its IPv4 addresses, its IPv6 addresses and which of them DNS64
+// synthesized, from which IPv4 address, and the NAT64 prefix the resolver
+// uses.
+func runDNS64Test(host, server string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r, err := testDNS64(ctx, newResolver(server).LookupIP, host)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Resolver = "system"
+	if server != "" {
+		r.Resolver = dnsServer(server)
+	}
+	writeResult(r, func() {
+		fmt.Printf("%-14s%s\n", "Host:", r.Host)
+		fmt.Printf("%-14s%s\n", "Resolver:", r.Resolver)
+		for _, a := range r.A {
+			fmt.Printf("%-14s%s\n", "A:", a)
+		}
+		for _, a := range r.AAAA {
+			if a.Synthesized {
+				fmt.Printf("%-14s%s, synthesized from %s under %s\n", "AAAA:", a.IPv6, a.IPv4, a.Prefix)
+				continue
+			}
+			fmt.Printf("%-14s%s\n", "AAAA:", a.IPv6)
+		}
+		prefix := "none found for ipv4only.arpa"
+		if len(r.Prefixes) > 0 {
+			prefix = strings.Join(r.Prefixes, ", ")
+		}
+		fmt.Printf("%-14s%s\n", "NAT64 prefix:", prefix)
+		switch {
+		case r.Synthesized:
+			fmt.Printf("%-14s%s\n", "DNS64:", "synthesized the AAAA records")
+		case len(r.Prefixes) > 0 && len(r.AAAA) > 0:
+			fmt.Printf("%-14s%s\n", "DNS64:", "present; the name has AAAA records of its own")
+		case len(r.Prefixes) > 0:
+			fmt.Printf("%-14s%s\n", "DNS64:", "present, but synthesized no AAAA records for the name")
+		default:
+			fmt.Printf("%-14s%s\n", "DNS64:", "not detected")
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+// fakeDNS answers lookups from a table of name and network to addresses, with
+// names not in it not found.
+func fakeDNS(records map[string][]string) ipLookup {
+	return func(ctx context.Context, network, host string) ([]net.IP, error) {
+		addrs, ok := records[network+" "+host]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		var ips []net.IP
+		for _, a := range addrs {
+			ips = append(ips, net.ParseIP(a))
+		}
+		return ips, nil
+	}
+}
+
+func TestTestDNS64(t *testing.T) {
+	lookup := fakeDNS(map[string][]string{
+		"ip4 www.example.net":    {"192.0.2.1"},
+		"ip6 www.example.net":    {"64:ff9b::c000:201"},
+		"ip4 dual.example.net":   {"192.0.2.7"},
+		"ip6 dual.example.net":   {"2001:db8:7::7"},
+		"ip6 ipv4only.arpa":      {"64:ff9b::c000:aa", "64:ff9b::c000:ab"},
+		"ip4 v4only.example.net": {"198.51.100.7"},
+	})
+	r, err := testDNS64(context.Background(), lookup, "www.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Synthesized || len(r.AAAA) != 1 || r.AAAA[0].IPv4 != "192.0.2.1" || r.AAAA[0].Prefix != "64:ff9b::/96" {
+		t.Errorf("www.example.net: got %+v", r)
+	}
+	if len(r.Prefixes) != 1 || r.Prefixes[0] != "64:ff9b::/96" {
+		t.Errorf("prefixes: got %v", r.Prefixes)
+	}
+
+	r, err = testDNS64(context.Background(), lookup, "dual.example.net")
+	if err != nil || r.Synthesized || len(r.AAAA) != 1 || r.AAAA[0].Synthesized {
+		t.Errorf("dual.example.net: got %+v, %v", r, err)
+	}
+
+	r, err = testDNS64(context.Background(), lookup, "v4only.example.net")
+	if err != nil || r.Synthesized || len(r.AAAA) != 0 || len(r.A) != 1 {
+		t.Errorf("v4only.example.net: got %+v, %v", r, err)
+	}
+
+	if _, err := testDNS64(context.Background(), lookup, "nx.example.net"); err == nil {
+		t.Error("name without records accepted")
+	}
+}
+
+func TestTestDNS64NetworkSpecific(t *testing.T) {
+	// A /56 prefix, and a resolver that does not synthesize for ipv4only.arpa.
+	lookup := fakeDNS(map[string][]string{
+		"ip4 www.example.net": {"192.0.2.33"},
+		"ip6 www.example.net": {"2001:db8:122:3c0:0:221::"},
+	})
+	r, err := testDNS64(context.Background(), lookup, "www.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Synthesized || r.AAAA[0].IPv4 != "192.0.2.33" || r.AAAA[0].Prefix != "2001:db8:122:300::/56" || len(r.Prefixes) != 0 {
+		t.Errorf("got %+v", r)
+	}
+}
+
+func TestDNSServer(t *testing.T) {
+	tests := map[string]string{
+		"2001:4860:4860::6464":        "[2001:4860:4860::6464]:53",
+		"[2001:4860:4860::6464]:5353": "[2001:4860:4860::6464]:5353",
+		"192.0.2.53":                  "192.0.2.53:53",
+		"ns.example.net":              "ns.example.net:53",
+		"ns.example.net:5353":         "ns.example.net:5353",
+	}
+	for in, want := range tests {
+		if got := dnsServer(in); got != want {
+			t.Errorf("%s: got %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Doctor finding severities.
+const (
+	doctorOK   = "OK"
+	doctorInfo = "INFO"
+	doctorWarn = "WARN"
+	doctorFail = "FAIL"
+)
+
+// Linux route and address flags from <linux/route.h> and <linux/if_addr.h>.
+const (
+	rtfReject      = 0x0200
+	rtfAddrconf    = 0x40000
+	ifaFTemporary  = 0x01
+	ifaFDadFailed  = 0x08
+	ifaFDeprecated = 0x20
+	ifaFTentative  = 0x40
+)
+
+// doctorTargets are dialled to test IPv6 reachability: public DNS resolvers that
+// accept TCP on port 53.
+var doctorTargets = []string{
+	"[2001:4860:4860::8888]:53",
+	"[2606:4700:4700::1111]:53",
+	"[2620:fe::fe]:53",
+}
+
+// doctorFinding is the outcome of one health check.
+type doctorFinding struct {
+	Check  string
+	Status string
+	Detail string
+	Advice string // what to do about a WARN or FAIL
+}
+
+// kernelRoute is one entry of /proc/net/ipv6_route.
+type kernelRoute struct {
+	Dest    *net.IPNet
+	NextHop net.IP
+	Metric  uint32
+	Flags   uint32
+	Iface   string
+}
+
+// kernelAddr is one entry of /proc/net/if_inet6.
+type kernelAddr struct {
+	IP    net.IP
+	Len   int
+	Scope int
+	Flags int
+	Iface string
+}
+
+// parseProcHexIP decodes the 32-hex-digit addresses used in /proc/net files.
+func parseProcHexIP(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != net.IPv6len {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	return net.IP(b), nil
+}
+
+// parseIPv6Routes reads the Linux IPv6 routing table from /proc/net/ipv6_route.
+func parseIPv6Routes(r io.Reader) ([]kernelRoute, error) {
+	var routes []kernelRoute
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) < 10 {
+			continue
+		}
+		dest, err := parseProcHexIP(f[0])
+		if err != nil {
+			return nil, err
+		}
+		destLen, _ := strconv.ParseUint(f[1], 16, 8)
+		nextHop, err := parseProcHexIP(f[4])
+		if err != nil {
+			return nil, err
+		}
+		metric, _ := strconv.ParseUint(f[5], 16, 32)
+		flags, _ := strconv.ParseUint(f[8], 16, 32)
+		routes = append(routes, kernelRoute{
+			Dest:    &net.IPNet{IP: dest, Mask: net.CIDRMask(int(destLen), 128)},
+			NextHop: nextHop,
+			Metric:  uint32(metric),
+			Flags:   uint32(flags),
+			Iface:   f[9],
+		})
+	}
+	return routes, scanner.Err()
+}
+
+// parseIfInet6 reads the interface addresses from /proc/net/if_inet6.
+func parseIfInet6(r io.Reader) ([]kernelAddr, error) {
+	var addrs []kernelAddr
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) < 6 {
+			continue
+		}
+		ip, err := parseProcHexIP(f[0])
+		if err != nil {
+			return nil, err
+		}
+		prefixLen, _ := strconv.ParseUint(f[2], 16, 8)
+		scope, _ := strconv.ParseUint(f[3], 16, 8)
+		flags, _ := strconv.ParseUint(f[4], 16, 32)
+		addrs = append(addrs, kernelAddr{IP: ip, Len: int(prefixLen), Scope: int(scope), Flags: int(flags), Iface: f[5]})
+	}
+	return addrs, scanner.Err()
+}
+
+// isULA reports whether ip is a unique local address (fc00::/7).
+func isULA(ip net.IP) bool {
+	return ip.To16()[0]&0xfe == 0xfc
+}
+
+// checkAddresses reports whether the host has usable global addresses.
+func checkAddresses(addrs []kernelAddr) doctorFinding {
+	var gua, ula []string
+	broken := 0
+	for _, a := range addrs {
+		if a.Scope != 0 || a.Iface == "lo" {
+			continue
+		}
+		if a.Flags&(ifaFDadFailed|ifaFTentative) != 0 {
+			broken++
+			continue
+		}
+		if isULA(a.IP) {
+			ula = append(ula, a.IP.String())
+		} else if a.Flags&ifaFDeprecated == 0 {
+			gua = append(gua, a.IP.String())
+		}
+	}
+	switch {
+	case len(gua) > 0:
+		return doctorFinding{Check: "Global address", Status: doctorOK, Detail: strings.Join(gua, ", ")}
+	case len(ula) > 0:
+		return doctorFinding{Check: "Global address", Status: doctorWarn, Detail: "only ULA: " + strings.Join(ula, ", "),
+			Advice: "ULA is not routed on the Internet; check that the router advertises a global prefix"}
+	case broken > 0:
+		return doctorFinding{Check: "Global address", Status: doctorFail, Detail: fmt.Sprintf("%d address(es) failed or pending DAD", broken),
+			Advice: "duplicate address detection failed; look for an address conflict on the link"}
+	}
+	return doctorFinding{Check: "Global address", Status: doctorFail, Detail: "none",
+		Advice: "no global IPv6 address; check that RAs with a prefix (or DHCPv6) reach this host"}
+}
+
+// defaultRoutes returns the usable ::/0 routes.
+func defaultRoutes(routes []kernelRoute) []kernelRoute {
+	var out []kernelRoute
+	for _, r := range routes {
+		if ones, _ := r.Dest.Mask.Size(); ones == 0 && r.Flags&rtfReject == 0 && r.Iface != "lo" {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// checkDefaultRoute reports the default routes and whether they were learned from
+// router advertisements.
+func checkDefaultRoute(routes []kernelRoute) []doctorFinding {
+	defaults := defaultRoutes(routes)
+	if len(defaults) == 0 {
+		return []doctorFinding{
+			{Check: "Default route", Status: doctorFail, Detail: "none",
+				Advice: "no IPv6 default route; the router may not be sending RAs, or accept_ra is disabled"},
+			{Check: "Router advertisements", Status: doctorWarn, Detail: "no RA-learned route",
+				Advice: "check accept_ra (must be 2 when forwarding is on) and that RAs are not filtered"},
+		}
+	}
+	var parts []string
+	fromRA := false
+	for _, r := range defaults {
+		parts = append(parts, fmt.Sprintf("via %s on %s", r.NextHop, r.Iface))
+		fromRA = fromRA || r.Flags&rtfAddrconf != 0
+	}
+	findings := []doctorFinding{{Check: "Default route", Status: doctorOK, Detail: strings.Join(parts, "; ")}}
+	if fromRA {
+		findings = append(findings, doctorFinding{Check: "Router advertisements", Status: doctorOK, Detail: "default route learned from RA"})
+	} else {
+		findings = append(findings, doctorFinding{Check: "Router advertisements", Status: doctorInfo, Detail: "default route is static or from DHCP/VPN, not RA"})
+	}
+	return findings
+}
+
+// checkTempAddresses reports whether RFC 8981 temporary addresses are in use on
+// the interfaces that have global addresses. useTempAddr maps interface names to
+// their use_tempaddr sysctl.
+func checkTempAddresses(addrs []kernelAddr, useTempAddr map[string]int) doctorFinding {
+	temps := 0
+	var disabled []string
+	seen := map[string]bool{}
+	for _, a := range addrs {
+		if a.Scope != 0 || a.Iface == "lo" || isULA(a.IP) {
+			continue
+		}
+		if a.Flags&ifaFTemporary != 0 {
+			temps++
+		}
+		if v, ok := useTempAddr[a.Iface]; ok && v <= 0 && !seen[a.Iface] {
+			seen[a.Iface] = true
+			disabled = append(disabled, a.Iface)
+		}
+	}
+	switch {
+	case temps > 0:
+		return doctorFinding{Check: "Temporary addresses", Status: doctorOK, Detail: fmt.Sprintf("%d in use", temps)}
+	case len(disabled) > 0:
+		return doctorFinding{Check: "Temporary addresses", Status: doctorWarn, Detail: "disabled on " + strings.Join(disabled, ", "),
+			Advice: fmt.Sprintf("clients should prefer temporary addresses: sysctl -w net.ipv6.conf.%s.use_tempaddr=2", disabled[0])}
+	}
+	return doctorFinding{Check: "Temporary addresses", Status: doctorInfo, Detail: "none"}
+}
+
+// checkDNS64 looks for a DNS64 resolver by asking for AAAA records of
+// ipv4only.arpa (RFC 7050), and reports the NAT64 prefix it synthesizes with.
+func checkDNS64(ctx context.Context, lookup func(ctx context.Context, network, host string) ([]net.IP, error)) doctorFinding {
+	ips, err := lookup(ctx, "ip6", "ipv4only.arpa")
+	if err != nil || len(ips) == 0 {
+		return doctorFinding{Check: "DNS64", Status: doctorOK, Detail: "not detected"}
+	}
+	for _, ip := range ips {
+		v4 := ip.To16()[12:]
+		if (v4[0] == 192 && v4[1] == 0 && v4[2] == 0 && (v4[3] == 170 || v4[3] == 171)) && ip.To4() == nil {
+			prefix := &net.IPNet{IP: networkAddress(ip, 96), Mask: net.CIDRMask(96, 128)}
+			return doctorFinding{Check: "DNS64", Status: doctorInfo, Detail: fmt.Sprintf("present, NAT64 prefix %s", prefix)}
+		}
+	}
+	return doctorFinding{Check: "DNS64", Status: doctorWarn, Detail: fmt.Sprintf("ipv4only.arpa returned unexpected %s", ips[0]),
+		Advice: "the NAT64 prefix is not a /96 or the resolver rewrites answers; check the DNS64 configuration"}
+}
+
+// checkReachability dials each target and reports how many answered.
+func checkReachability(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), targets []string) doctorFinding {
+	var ok, failed []string
+	for _, t := range targets {
+		dctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		conn, err := dial(dctx, "tcp6", t)
+		cancel()
+		if err != nil {
+			failed = append(failed, t)
+			continue
+		}
+		conn.Close()
+		ok = append(ok, t)
+	}
+	switch {
+	case len(failed) == 0:
+		return doctorFinding{Check: "Reachability", Status: doctorOK, Detail: fmt.Sprintf("%d/%d targets reachable", len(ok), len(targets))}
+	case len(ok) > 0:
+		return doctorFinding{Check: "Reachability", Status: doctorWarn, Detail: fmt.Sprintf("unreachable: %s", strings.Join(failed, ", ")),
+			Advice: "partial reachability usually means a path or filtering problem upstream"}
+	}
+	return doctorFinding{Check: "Reachability", Status: doctorFail, Detail: "no IPv6 targets reachable",
+		Advice: "check the default route, upstream filtering, and PMTU (ICMPv6 Packet Too Big must not be blocked)"}
+}
+
+// readSysctlInts reads an integer sysctl for every interface under
+// /proc/sys/net/ipv6/conf.
+func readSysctlInts(name string) map[string]int {
+	out := map[string]int{}
+	dirs, _ := filepath.Glob("/proc/sys/net/ipv6/conf/*")
+	for _, d := range dirs {
+		b, err := os.ReadFile(filepath.Join(d, name))
+		if err != nil {
+			continue
+		}
+		if v, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil {
+			out[filepath.Base(d)] = v
+		}
+	}
+	return out
+}
+
+// runDoctor inspects the local host and prints a finding per check. Route and
+// address checks read Linux /proc files and are skipped on other systems. It
+// returns false when any check failed.
+func runDoctor(w io.Writer) bool {
+	var findings []doctorFinding
+	ctx := context.Background()
+
+	addrFile, addrErr := os.Open("/proc/net/if_inet6")
+	routeFile, routeErr := os.Open("/proc/net/ipv6_route")
+	if addrErr != nil || routeErr != nil {
+		findings = append(findings, doctorFinding{Check: "Local configuration", Status: doctorInfo, Detail: "skipped (needs Linux /proc/net)"})
+	} else {
+		addrs, err := parseIfInet6(addrFile)
+		if err == nil {
+			findings = append(findings, checkAddresses(addrs))
+		}
+		routes, err := parseIPv6Routes(routeFile)
+		if err == nil {
+			findings = append(findings, checkDefaultRoute(routes)...)
+		}
+		findings = append(findings, checkTempAddresses(addrs, readSysctlInts("use_tempaddr")))
+	}
+	if addrFile != nil {
+		addrFile.Close()
+	}
+	if routeFile != nil {
+		routeFile.Close()
+	}
+
+	findings = append(findings, checkDNS64(ctx, net.DefaultResolver.LookupIP))
+	findings = append(findings, checkReachability(ctx, (&net.Dialer{}).DialContext, doctorTargets))
+
+	healthy := true
+	for _, f := range findings {
+		fmt.Fprintf(w, "[%-4s] %-22s %s\n", f.Status, f.Check, f.Detail)
+		if f.Advice != "" {
+			fmt.Fprintf(w, "       → %s\n", f.Advice)
+		}
+		healthy = healthy && f.Status != doctorFail
+	}
+	return healthy
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+const testIPv6Routes = `fd000000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00450003     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 00000000000000000000000000000000 ffffffff 00000001 00000000 00200200       lo
+`
+
+const testIfInet6 = `fe8000000000000000fc00fffe000001 04 40 20 80     eth0
+00000000000000000000000000000001 01 80 10 80       lo
+20010db8000000010211 22fffe334455 04 40 00 00     eth0
+`
+
+func TestParseIPv6Routes(t *testing.T) {
+	routes, err := parseIPv6Routes(strings.NewReader(testIPv6Routes))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(routes) != 3 || routes[0].Dest.String() != "fd00::/64" || routes[1].NextHop.String() != "fe80::1" {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+	defaults := defaultRoutes(routes)
+	if len(defaults) != 1 || defaults[0].Iface != "eth0" {
+		t.Errorf("expected the lo reject route to be ignored, got %+v", defaults)
+	}
+	findings := checkDefaultRoute(routes)
+	if findings[0].Status != doctorOK || findings[0].Detail != "via fe80::1 on eth0" || findings[1].Status != doctorOK {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+	if f := checkDefaultRoute(routes[2:]); f[0].Status != doctorFail {
+		t.Errorf("expected failure without a default route, got %+v", f)
+	}
+}
+
+func TestCheckAddresses(t *testing.T) {
+	if _, err := parseIfInet6(strings.NewReader(testIfInet6)); err == nil {
+		t.Error("expected error for malformed address")
+	}
+	cases := []struct {
+		name   string
+		lines  string
+		status string
+	}{
+		{"global", "20010db800000001021122fffe334455 04 40 00 00 eth0", doctorOK},
+		{"deprecated only", "20010db800000001021122fffe334455 04 40 00 20 eth0", doctorFail},
+		{"ULA only", "fd000000000000000000000000000002 04 40 00 80 eth0", doctorWarn},
+		{"DAD failed", "20010db800000001021122fffe334455 04 40 00 08 eth0", doctorFail},
+		{"link-local only", "fe8000000000000000fc00fffe000001 04 40 20 80 eth0", doctorFail},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			addrs, err := parseIfInet6(strings.NewReader(tc.lines))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f := checkAddresses(addrs); f.Status != tc.status {
+				t.Errorf("expected %s, got %+v", tc.status, f)
+			}
+		})
+	}
+}
+
+func TestCheckTempAddresses(t *testing.T) {
+	addrs, _ := parseIfInet6(strings.NewReader("20010db800000001021122fffe334455 04 40 00 00 eth0\n"))
+	f := checkTempAddresses(addrs, map[string]int{"eth0": 0})
+	if f.Status != doctorWarn || !strings.Contains(f.Advice, "net.ipv6.conf.eth0.use_tempaddr=2") {
+		t.Errorf("unexpected finding %+v", f)
+	}
+	temp, _ := parseIfInet6(strings.NewReader("20010db8000000018d3b4f2e91a7c6b5 04 40 00 01 eth0\n"))
+	if f := checkTempAddresses(append(addrs, temp...), map[string]int{"eth0": 2}); f.Status != doctorOK {
+		t.Errorf("unexpected finding %+v", f)
+	}
+}
+
+func TestCheckDNS64(t *testing.T) {
+	answer := func(ips ...string) func(context.Context, string, string) ([]net.IP, error) {
+		return func(ctx context.Context, network, host string) ([]net.IP, error) {
+			if host != "ipv4only.arpa" || network != "ip6" {
+				t.Errorf("unexpected query %s %s", network, host)
+			}
+			if len(ips) == 0 {
+				return nil, errors.New("no such host")
+			}
+			var out []net.IP
+			for _, s := range ips {
+				out = append(out, net.ParseIP(s))
+			}
+			return out, nil
+		}
+	}
+	if f := checkDNS64(context.Background(), answer()); f.Detail != "not detected" {
+		t.Errorf("unexpected finding %+v", f)
+	}
+	if f := checkDNS64(context.Background(), answer("64:ff9b::c000:aa")); f.Detail != "present, NAT64 prefix 64:ff9b::/96" {
+		t.Errorf("unexpected finding %+v", f)
+	}
+	if f := checkDNS64(context.Background(), answer("2001:db8::1")); f.Status != doctorWarn {
+		t.Errorf("unexpected finding %+v", f)
+	}
+}
+
+func TestCheckReachability(t *testing.T) {
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == "[2001:db8::1]:53" {
+			c1, c2 := net.Pipe()
+			c2.Close()
+			return c1, nil
+		}
+		return nil, errors.New("unreachable")
+	}
+	cases := []struct {
+		targets []string
+		status  string
+	}{
+		{[]string{"[2001:db8::1]:53"}, doctorOK},
+		{[]string{"[2001:db8::1]:53", "[2001:db8::2]:53"}, doctorWarn},
+		{[]string{"[2001:db8::2]:53"}, doctorFail},
+	}
+	for _, tc := range cases {
+		if f := checkReachability(context.Background(), dial, tc.targets); f.Status != tc.status {
+			t.Errorf("%v: expected %s, got %+v", tc.targets, tc.status, f)
+		}
+	}
+}
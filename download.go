@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// cacheRoot, when set by -cache-dir or IPV6UTILS_CACHE_DIR, replaces the default
+// cache location, e.g. with a directory shared by a team or prepared for offline use.
+var cacheRoot string
+
+// cacheDir returns (and creates) a directory for downloaded data under the user
+// cache directory, e.g. ~/.cache/ipv6utils/rir on Linux, or under cacheRoot.
+func cacheDir(sub string) (string, error) {
+	root := cacheRoot
+	if root == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		root = filepath.Join(dir, "ipv6utils")
+	}
+	dir := filepath.Join(root, sub)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// cacheFileName derives a stable file name for a URL, keeping the last path
+// element readable and prefixing a short hash so different sources never collide.
+func cacheFileName(rawURL string) string {
+	base := "download"
+	if u, err := url.Parse(rawURL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		base = path.Base(u.Path)
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return fmt.Sprintf("%x-%s", sum[:4], base)
+}
+
+// downloadFile fetches url into path. The file is replaced only once the download
+// has completed, so a failure leaves any previous copy intact.
+func downloadFile(url, path string) error {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// cachedDownload returns the path of a cached copy of url, fetching it when it is
+// missing, older than maxAge, or refresh is set. If fetching fails and a stale
+// copy exists, the stale copy is used with a warning.
+func cachedDownload(sub, url string, maxAge time.Duration, refresh bool) (string, error) {
+	dir, err := cacheDir(sub)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, cacheFileName(url))
+	info, statErr := os.Stat(path)
+	if !refresh && statErr == nil && time.Since(info.ModTime()) <= maxAge {
+		return path, nil
+	}
+	statusf("Fetching %s\n", url)
+	if err := downloadFile(url, path); err != nil {
+		if statErr != nil {
+			return "", err
+		}
+		log.Printf("using cached copy of %s: %v", url, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDownload = "2001:db8::/32 ; test\n"
+
+func TestDownloadFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testDownload))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "data")
+	if err := downloadFile(srv.URL+"/data", path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != testDownload {
+		t.Errorf("cached file does not match download: %v", err)
+	}
+	if err := downloadFile(srv.URL+"/missing", path); err == nil {
+		t.Error("expected error for 404")
+	}
+	if data, _ := os.ReadFile(path); string(data) != testDownload {
+		t.Error("failed download must not replace the cached file")
+	}
+}
+
+func TestCacheFileName(t *testing.T) {
+	a := cacheFileName("https://example.net/feeds/drop_v6.txt")
+	b := cacheFileName("https://example.org/feeds/drop_v6.txt")
+	if a == b {
+		t.Errorf("different URLs must not share a cache file: %s", a)
+	}
+	if !strings.HasSuffix(a, "-drop_v6.txt") || len(a) != len("01234567-drop_v6.txt") {
+		t.Errorf("unexpected cache file name %s", a)
+	}
+	if got := cacheFileName("https://example.net/"); !strings.HasSuffix(got, "-download") {
+		t.Errorf("unexpected cache file name %s", got)
+	}
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Interface-ID patterns, loosely following the categories in RFC 7707 section 4.1.
+const (
+	patternLowByte      = "low-byte"
+	patternEmbeddedIPv4 = "embedded-IPv4"
+	patternISATAP       = "ISATAP"
+	patternEUI64        = "EUI-64"
+	patternWordy        = "wordy"
+	patternLowEntropy   = "low-entropy"
+	patternRandomized   = "randomized"
+)
+
+// hexWords are common hex-spellable words seen in hand-assigned interface IDs.
+var hexWords = []string{"dead", "beef", "cafe", "babe", "face", "feed", "f00d", "c0de", "fade", "deaf", "bead", "c0ffee", "d00d", "1337"}
+
+// iidScore describes how predictable an address's interface ID is. Score runs from
+// 0 (trivially guessable) to 100 (indistinguishable from random).
+type iidScore struct {
+	Address    string
+	Score      int
+	Pattern    string
+	Entropy    float64
+	Sequential bool
+}
+
+// nibbleEntropy returns the Shannon entropy, in bits per nibble, of the 16 nibbles
+// of a 64-bit interface ID. The maximum for 16 samples is 4.0.
+func nibbleEntropy(iid []byte) float64 {
+	counts := make(map[rune]int)
+	nibbles := hex.EncodeToString(iid)
+	for _, c := range nibbles {
+		counts[c]++
+	}
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(nibbles))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// isDecimalGroups reports whether all four 16-bit groups read as decimal octets when
+// printed in hex, e.g. ::192:0:2:1 for 192.0.2.1.
+func isDecimalGroups(iid []byte) bool {
+	for i := 0; i < 4; i++ {
+		g := fmt.Sprintf("%x", binary.BigEndian.Uint16(iid[i*2:]))
+		var v int
+		if strings.ContainsAny(g, "abcdef") || len(g) > 3 {
+			return false
+		}
+		fmt.Sscanf(g, "%d", &v)
+		if v > 255 {
+			return false
+		}
+	}
+	return true
+}
+
+// containsHexWord reports whether the interface ID spells one of the common hex words.
+func containsHexWord(iid []byte) bool {
+	nibbles := hex.EncodeToString(iid)
+	for _, w := range hexWords {
+		if strings.Contains(nibbles, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// scoreIID classifies the interface ID (low 64 bits) of an address and rates how
+// predictable it is.
+func scoreIID(ip net.IP) iidScore {
+	b := ip.To16()
+	iid := b[8:16]
+	v := binary.BigEndian.Uint64(iid)
+	entropy := nibbleEntropy(iid)
+	s := iidScore{Address: ip.String(), Entropy: entropy}
+
+	switch {
+	case v <= 0xffff:
+		s.Pattern, s.Score = patternLowByte, 5
+	case isISATAPIID(iid):
+		s.Pattern, s.Score = patternISATAP, 10
+	case iid[3] == 0xff && iid[4] == 0xfe:
+		s.Pattern, s.Score = patternEUI64, 20
+	case containsHexWord(iid):
+		s.Pattern, s.Score = patternWordy, 25
+	case v>>32 == 0 || isDecimalGroups(iid):
+		s.Pattern, s.Score = patternEmbeddedIPv4, 10
+	default:
+		s.Score = int(math.Round(entropy / 4 * 100))
+		s.Pattern = patternRandomized
+		if entropy < 2.5 {
+			s.Pattern = patternLowEntropy
+			if s.Score > 40 {
+				s.Score = 40
+			}
+		}
+	}
+	return s
+}
+
+// scoreInventory scores every address and additionally flags interface IDs that sit
+// close to another one in the same /64 (delta ≤ 16), which is what a scanner walking
+// sequential assignments would find first. Sequential addresses score at most 10.
+func scoreInventory(ips []net.IP) []iidScore {
+	scores := make([]iidScore, len(ips))
+	bySubnet := map[string][]int{}
+	for i, ip := range ips {
+		scores[i] = scoreIID(ip)
+		key := string(ip.To16()[:8])
+		bySubnet[key] = append(bySubnet[key], i)
+	}
+	for _, idx := range bySubnet {
+		sort.Slice(idx, func(a, b int) bool {
+			return binary.BigEndian.Uint64(ips[idx[a]].To16()[8:]) < binary.BigEndian.Uint64(ips[idx[b]].To16()[8:])
+		})
+		for j := 1; j < len(idx); j++ {
+			prev := binary.BigEndian.Uint64(ips[idx[j-1]].To16()[8:])
+			cur := binary.BigEndian.Uint64(ips[idx[j]].To16()[8:])
+			if cur-prev <= 16 {
+				scores[idx[j-1]].Sequential = true
+				scores[idx[j]].Sequential = true
+			}
+		}
+	}
+	for i := range scores {
+		if scores[i].Sequential && scores[i].Score > 10 {
+			scores[i].Score = 10
+		}
+	}
+	return scores
+}
+
+// readAddressList reads one IPv6 address per line, skipping blank lines and '#' comments.
+// An optional prefix length on an entry is ignored.
+func readAddressList(r io.Reader) ([]net.IP, error) {
+	var ips []net.IP
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ip, _, err := parseIPv6WithOptionalPrefix(strings.Fields(line)[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, scanner.Err()
+}
+
+// reportIIDScores scores a single address, or every address in a file, and prints
+// per-address results followed by a summary for inventories.
+func reportIIDScores(input string) {
+	var ips []net.IP
+	if ip, _, err := parseIPv6WithOptionalPrefix(input); err == nil {
+		ips = []net.IP{ip}
+	} else {
+		f, err := os.Open(input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ips, err = readAddressList(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", input, err)
+		}
+	}
+
+	scores := scoreInventory(ips)
+	patterns := map[string]int{}
+	total, sequential := 0, 0
+	for _, s := range scores {
+		pattern := s.Pattern
+		if s.Sequential {
+			pattern += ",sequential"
+			sequential++
+		}
+		fmt.Printf("%-40s %3d  %s\n", s.Address, s.Score, pattern)
+		patterns[s.Pattern]++
+		total += s.Score
+	}
+	if len(scores) < 2 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%-16s%d\n", "Addresses:", len(scores))
+	fmt.Printf("%-16s%.1f\n", "Mean score:", float64(total)/float64(len(scores)))
+	fmt.Printf("%-16s%d\n", "Sequential:", sequential)
+	fmt.Println("Patterns:")
+	names := make([]string, 0, len(patterns))
+	for p := range patterns {
+		names = append(names, p)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if patterns[names[i]] != patterns[names[j]] {
+			return patterns[names[i]] > patterns[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for _, p := range names {
+		fmt.Printf("  %-16s%d\n", p, patterns[p])
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestScoreIID(t *testing.T) {
+	cases := []struct {
+		name          string
+		input         string
+		expectPattern string
+		minScore      int
+		maxScore      int
+	}{
+		{name: "low byte", input: "2001:db8::1", expectPattern: patternLowByte, maxScore: 5},
+		{name: "low byte 16 bits", input: "2001:db8::beef", expectPattern: patternLowByte, maxScore: 5},
+		{name: "embedded IPv4 hex", input: "2001:db8::c000:201", expectPattern: patternEmbeddedIPv4, maxScore: 10},
+		{name: "embedded IPv4 decimal groups", input: "2001:db8::192:0:2:10", expectPattern: patternEmbeddedIPv4, maxScore: 10},
+		{name: "ISATAP", input: "2001:db8::5efe:c000:201", expectPattern: patternISATAP, maxScore: 10},
+		{name: "ISATAP universal", input: "2001:db8::200:5efe:c000:201", expectPattern: patternISATAP, maxScore: 10},
+		{name: "EUI-64", input: "2001:db8::211:22ff:fe33:4455", expectPattern: patternEUI64, maxScore: 20},
+		{name: "wordy", input: "2001:db8::dead:beef", expectPattern: patternWordy, maxScore: 25},
+		{name: "low entropy", input: "2001:db8::1111:1111:1111:2222", expectPattern: patternLowEntropy, maxScore: 40},
+		{name: "randomized", input: "2001:db8::8d3b:4f2e:91a7:c6b5", expectPattern: patternRandomized, minScore: 80, maxScore: 100},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := scoreIID(net.ParseIP(tc.input))
+			if got.Pattern != tc.expectPattern {
+				t.Errorf("expected pattern %s, got %s", tc.expectPattern, got.Pattern)
+			}
+			if got.Score < tc.minScore || got.Score > tc.maxScore {
+				t.Errorf("expected score in [%d, %d], got %d", tc.minScore, tc.maxScore, got.Score)
+			}
+		})
+	}
+}
+
+func TestScoreInventory(t *testing.T) {
+	list := `# inventory
+2001:db8:1::8d3b:4f2e:91a7:c600
+2001:db8:1::8d3b:4f2e:91a7:c605/64
+
+2001:db8:2::8d3b:4f2e:91a7:c6b5
+`
+	ips, err := readAddressList(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 3 {
+		t.Fatalf("expected 3 addresses, got %d", len(ips))
+	}
+	scores := scoreInventory(ips)
+	if !scores[0].Sequential || !scores[1].Sequential {
+		t.Errorf("expected neighbouring interface IDs in the same /64 to be sequential: %+v", scores[:2])
+	}
+	if scores[0].Score > 10 {
+		t.Errorf("expected sequential score to be capped at 10, got %d", scores[0].Score)
+	}
+	if scores[2].Sequential {
+		t.Errorf("expected an address in a different /64 not to be sequential: %+v", scores[2])
+	}
+
+	if _, err := readAddressList(strings.NewReader("2001:db8::1\nnot-an-address\n")); err == nil {
+		t.Errorf("expected error for invalid line")
+	}
+}
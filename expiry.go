@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// parseExpiry reads an expires= value: an RFC 3339 time, or a date, which
+// expires at the start of that day in UTC.
+func parseExpiry(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry %q (want YYYY-MM-DD or RFC 3339)", value)
+	}
+	return t, nil
+}
+
+// entryExpiry returns when a plan entry's allocation expires, from an
+// expires= word in its label. ok is false for permanent allocations.
+func entryExpiry(e prefixEntry) (expires time.Time, ok bool, err error) {
+	for _, word := range strings.Fields(e.Label) {
+		if value, found := strings.CutPrefix(word, "expires="); found {
+			t, err := parseExpiry(value)
+			if err != nil {
+				return time.Time{}, false, fmt.Errorf("line %d: %v", e.Line, err)
+			}
+			return t, true, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// splitExpired separates the entries whose allocation has expired at now.
+func splitExpired(entries []prefixEntry, now time.Time) (active, expired []prefixEntry, err error) {
+	for _, e := range entries {
+		t, ok, err := entryExpiry(e)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok && !now.Before(t) {
+			expired = append(expired, e)
+		} else {
+			active = append(active, e)
+		}
+	}
+	return active, expired, nil
+}
+
+// removeLines returns data without the given 1-based line numbers, keeping every
+// other line, comments included, exactly as written.
+func removeLines(data string, drop map[int]bool) string {
+	lines := strings.SplitAfter(data, "\n")
+	var sb strings.Builder
+	for i, line := range lines {
+		if !drop[i+1] {
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
+
+// writeReleased prints each released allocation with its label, which includes
+// the expiry.
+func writeReleased(w io.Writer, released []prefixEntry) {
+	for _, e := range released {
+		fmt.Fprintf(w, "released %-44s %s\n", e.Net, e.Label)
+	}
+}
+
+// runPlanGC releases expired allocations by removing their lines from the plan
+// file in place. With dryRun the file is left alone.
+func runPlanGC(planFile string, dryRun bool, now time.Time) {
+	data, err := os.ReadFile(planFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	entries, err := readPrefixEntries(strings.NewReader(string(data)))
+	if err != nil {
+		log.Fatalf("%s: %v", planFile, err)
+	}
+	_, expired, err := splitExpired(entries, now)
+	if err != nil {
+		log.Fatalf("%s: %v", planFile, err)
+	}
+	writeReleased(os.Stdout, expired)
+	if dryRun || len(expired) == 0 {
+		return
+	}
+	drop := map[int]bool{}
+	for _, e := range expired {
+		drop[e.Line] = true
+	}
+	info, err := os.Stat(planFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(planFile, []byte(removeLines(string(data), drop)), info.Mode().Perm()); err != nil {
+		log.Fatal(err)
+	}
+	statusf("%d expired allocation(s) released from %s\n", len(expired), planFile)
+}
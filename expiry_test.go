@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryExpiry(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("3fff:0:1:f000::/56")
+	cases := []struct {
+		label   string
+		ok      bool
+		want    string
+		wantErr bool
+	}{
+		{"hackathon expires=2026-10-01", true, "2026-10-01T00:00:00Z", false},
+		{"demo expires=2027-02-01T18:00:00-06:00 vlan=40", true, "2027-02-02T00:00:00Z", false},
+		{"permanent", false, "", false},
+		{"bad expires=next-week", false, "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.label, func(t *testing.T) {
+			got, ok, err := entryExpiry(prefixEntry{Net: ipnet, Label: tc.label, Line: 7})
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "line 7") {
+					t.Fatalf("expected line 7 error, got %v", err)
+				}
+				return
+			}
+			if err != nil || ok != tc.ok {
+				t.Fatalf("got ok=%v, %v", ok, err)
+			}
+			if ok && got.UTC().Format(time.RFC3339) != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got.UTC().Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestSplitExpired(t *testing.T) {
+	entries, err := readPrefixFile("testdata/plan-lab.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		now     string
+		expired int
+	}{
+		{"2026-09-30T23:59:59Z", 0},
+		{"2026-10-01T00:00:00Z", 1},
+		{"2027-03-01T00:00:00Z", 2},
+	}
+	for _, tc := range cases {
+		now, _ := time.Parse(time.RFC3339, tc.now)
+		active, expired, err := splitExpired(entries, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expired) != tc.expired || len(active)+len(expired) != len(entries) {
+			t.Errorf("at %s: %d active, %d expired", tc.now, len(active), len(expired))
+		}
+	}
+}
+
+func TestRunPlanGC(t *testing.T) {
+	data, err := os.ReadFile("testdata/plan-lab.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "plan.txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	now, _ := time.Parse(time.RFC3339, "2026-12-01T00:00:00Z")
+	runPlanGC(path, true, now)
+	if after, _ := os.ReadFile(path); string(after) != string(data) {
+		t.Error("dry run changed the plan")
+	}
+	runPlanGC(path, false, now)
+	after, _ := os.ReadFile(path)
+	want := strings.Replace(string(data), "3fff:0:1:f000::/56     hackathon expires=2026-10-01\n", "", 1)
+	if string(after) != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, after)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode changed to %v", info.Mode().Perm())
+	}
+}
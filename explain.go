@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// addressPart is a run of bits of an address with one role: the network it
+// is routed by, the subnet ID, and the interface ID, or the host part of a
+// prefix longer than /64.
+type addressPart struct {
+	Name string `json:"name"`
+	From int    `json:"from"` // first bit
+	To   int    `json:"to"`   // bit after the last
+}
+
+// bitRow is one 16-bit group of an address in binary.
+type bitRow struct {
+	From  int      `json:"from"`
+	Bits  string   `json:"bits"` // by nibble, with | where a part ends
+	Hex   string   `json:"hex"`
+	Parts []string `json:"parts"`
+}
+
+// addressExplanation takes an address apart.
+type addressExplanation struct {
+	Address         string          `json:"address"`
+	PrefixLength    int             `json:"prefix_length"`
+	Type            string          `json:"type"`
+	IPv4            string          `json:"ipv4,omitempty"`        // embedded in a 6to4 address
+	ISATAP          string          `json:"isatap_ipv4,omitempty"` // of an ISATAP interface ID
+	LIRPrefix       string          `json:"lir_prefix,omitempty"`
+	Registry        *registryPrefix `json:"registry,omitempty"`
+	Network         string          `json:"network"`
+	SubnetID        string          `json:"subnet_id,omitempty"`
+	InterfaceID     string          `json:"interface_id,omitempty"`
+	InterfaceIDType string          `json:"interface_id_type,omitempty"`
+	MAC             string          `json:"mac,omitempty"` // from an EUI-64 interface ID
+	Host            string          `json:"host,omitempty"`
+	ReverseDNS      string          `json:"reverse_dns"`
+	Parts           []addressPart   `json:"parts"`
+	Bits            []bitRow        `json:"bits,omitempty"`
+}
+
+// registryPrefix is the RIR delegation holding an address.
+type registryPrefix struct {
+	RIR     string `json:"rir"`
+	Prefix  string `json:"prefix"`
+	Economy string `json:"economy"`
+	Status  string `json:"status"`
+}
+
+// explainOptions are how explainAddress reads an address.
+type explainOptions struct {
+	LIR         int             // length of an LIR's allocation
+	Site        int             // length of the network of a /64
+	Bits        bool            // include the bits
+	Delegations []rirDelegation // RIR statistics to look the address up in, if any
+}
+
+// interfaceIDType names how an interface ID was most likely formed, from its
+// pattern as scoreIID sees it: from a MAC by SLAAC, at random for privacy
+// (RFC 4941) or stable-privacy (RFC 8064) addresses, or by hand.
+func interfaceIDType(pattern string) string {
+	switch pattern {
+	case patternEUI64:
+		return "EUI-64, from a MAC"
+	case patternRandomized:
+		return "privacy or stable random"
+	case patternISATAP:
+		return "ISATAP"
+	}
+	return "manual (" + pattern + ")"
+}
+
+// splitAddressBits divides the 128 bits of an address with a prefix length
+// into its parts. Up to /64 the network ends at the prefix length, or for a
+// /64 itself, at site (the /48 of an end site, usually), and the subnet ID
+// runs up to the interface ID in the last 64 bits. A longer prefix, such as
+// the /127 of a point-to-point link, is network and host.
+func splitAddressBits(prefixLen, site int) []addressPart {
+	if prefixLen > 64 {
+		return []addressPart{{"network", 0, prefixLen}, {"host", prefixLen, 128}}
+	}
+	network := prefixLen
+	if prefixLen == 64 {
+		network = min(site, 64)
+	}
+	parts := []addressPart{{"network", 0, network}, {"subnet", network, 64}, {"interface ID", 64, 128}}
+	return slices.DeleteFunc(parts, func(p addressPart) bool { return p.To == p.From })
+}
+
+// bitRows writes an address in binary, a row per 16-bit group, grouped by
+// nibble and with a | where each part ends inside a group.
+func bitRows(a netip.Addr, parts []addressPart) []bitRow {
+	b := a.As16()
+	rows := make([]bitRow, 8)
+	for i := range rows {
+		from := i * 16
+		var sb strings.Builder
+		for bit := from; bit < from+16; bit++ {
+			if bit > from {
+				switch {
+				case isPartBoundary(parts, bit):
+					sb.WriteString(" | ")
+				case bit%4 == 0:
+					sb.WriteByte(' ')
+				}
+			}
+			sb.WriteByte('0' + byte(addrBit(a, bit)))
+		}
+		row := bitRow{From: from, Bits: sb.String(), Hex: fmt.Sprintf("%02x%02x", b[i*2], b[i*2+1])}
+		for _, p := range parts {
+			if p.From < from+16 && p.To > from {
+				row.Parts = append(row.Parts, p.Name)
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// isPartBoundary reports whether a part starts at bit.
+func isPartBoundary(parts []addressPart, bit int) bool {
+	for _, p := range parts {
+		if p.From == bit {
+			return true
+		}
+	}
+	return false
+}
+
+// explainAddress takes an address apart; with no prefix length it is taken
+// to be in a /64.
+func explainAddress(input string, opts explainOptions) (addressExplanation, error) {
+	ip, prefixLen, err := parseIPv6WithOptionalPrefix(input)
+	if err != nil {
+		return addressExplanation{}, err
+	}
+	if prefixLen < 0 {
+		prefixLen = 64
+	}
+	if opts.Site < 0 || opts.Site > 64 {
+		return addressExplanation{}, fmt.Errorf("site length must be between 0 and 64, got %d", opts.Site)
+	}
+	if opts.LIR < 0 || opts.LIR > 64 {
+		return addressExplanation{}, fmt.Errorf("LIR length must be between 0 and 64, got %d", opts.LIR)
+	}
+	a := netip.AddrFrom16([16]byte(ip.To16()))
+	arpa, err := ipv6ToArpa(ip.String(), 0)
+	if err != nil {
+		return addressExplanation{}, err
+	}
+	class := classifyAddress(a)
+	e := addressExplanation{
+		Address:      addrText(a),
+		PrefixLength: prefixLen,
+		Type:         class.Description,
+		IPv4:         class.IPv4,
+		ISATAP:       class.ISATAP,
+		ReverseDNS:   arpa,
+		Parts:        splitAddressBits(prefixLen, opts.Site),
+	}
+	for _, p := range e.Parts {
+		switch p.Name {
+		case "network":
+			e.Network = prefixText(netip.PrefixFrom(a, p.To).Masked())
+			if opts.LIR < p.To {
+				e.LIRPrefix = prefixText(netip.PrefixFrom(a, opts.LIR).Masked())
+			}
+		case "subnet":
+			id := ipToBigInt(networkAddress(ip, p.To))
+			id.Rsh(id, uint(128-p.To)).And(id, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(p.To-p.From)), big.NewInt(1)))
+			e.SubnetID = fmt.Sprintf("%0*x", (p.To-p.From+3)/4, id)
+		case "interface ID":
+			e.InterfaceID = ipv6String(hostSuffix(ip, p.From))
+			pattern := scoreIID(ip).Pattern
+			e.InterfaceIDType = interfaceIDType(pattern)
+			if pattern == patternEUI64 {
+				e.MAC, _ = decodeMACFromSLAAC(ip.String())
+			}
+		case "host":
+			e.Host = ipv6String(hostSuffix(ip, p.From))
+		}
+	}
+	if opts.Delegations != nil {
+		if d, ok := lookupRIRDelegation(opts.Delegations, prefixToIPNet(netip.PrefixFrom(a, 128))); ok {
+			e.Registry = &registryPrefix{RIR: d.Registry, Prefix: d.Net.String(), Economy: d.CC, Status: d.Status}
+		}
+	}
+	if opts.Bits {
+		e.Bits = bitRows(a, e.Parts)
+	}
+	return e, nil
+}
+
+// runExplain prints the parts of an address and what they say about it, and
+// with -binary, its bits. RIR statistics are read only when files are given,
+// so it works offline.
+func runExplain(input string, opts explainOptions, rirFiles string) {
+	if rirFiles != "" {
+		delegations, err := loadRIRStats(rirFiles, false)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.Delegations = delegations
+	}
+	e, err := explainAddress(input, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeResult(e, func() {
+		line := func(name, value string) {
+			if value != "" {
+				fmt.Printf("%-20s%s\n", name+":", value)
+			}
+		}
+		line("Address", fmt.Sprintf("%s/%d", e.Address, e.PrefixLength))
+		line("Type", e.Type)
+		if r := e.Registry; r != nil {
+			line("Registry", fmt.Sprintf("%s %s (%s, %s)", r.RIR, r.Prefix, r.Economy, r.Status))
+		}
+		line("LIR prefix", e.LIRPrefix)
+		line("Network", e.Network)
+		line("Subnet ID", e.SubnetID)
+		line("Interface ID", e.InterfaceID)
+		line("Interface ID type", e.InterfaceIDType)
+		line("MAC", e.MAC)
+		line("Host", e.Host)
+		line("Reverse DNS", e.ReverseDNS)
+		if len(e.Bits) == 0 {
+			return
+		}
+		fmt.Println()
+		for _, r := range e.Bits {
+			fmt.Printf("%3d-%-3d  %-21s  %s  %s\n", r.From, r.From+15, r.Bits, r.Hex, strings.Join(r.Parts, " | "))
+		}
+	})
+}
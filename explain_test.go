@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExplainAddress(t *testing.T) {
+	tests := []struct {
+		input                            string
+		site                             int
+		network, subnet, iid, host, bits string
+	}{
+		{"2001:db8:abcd:12::1/64", 48, "2001:db8:abcd::/48", "0012", "::1", "", ""},
+		{"2001:db8:abcd:12::1", 32, "2001:db8::/32", "abcd0012", "::1", "", ""},
+		{"2001:db8:abcd:12c0::1/57", 48, "2001:db8:abcd:1280::/57", "40", "::1", "", "0001 0010 1 | 100 0000"},
+		{"2001:db8:abcd:12::/56", 48, "2001:db8:abcd::/56", "12", "::", "", "0000 0000 | 0001 0010"},
+		{"2001:db8::1/127", 48, "2001:db8::/127", "", "", "::1", "0000 0000 0000 000 | 1"},
+		{"2001:db8::1/64", 64, "2001:db8::/64", "", "::1", "", ""},
+	}
+	for _, tt := range tests {
+		e, err := explainAddress(tt.input, explainOptions{LIR: 32, Site: tt.site, Bits: true})
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if e.Network != tt.network || e.SubnetID != tt.subnet || e.InterfaceID != tt.iid || e.Host != tt.host {
+			t.Errorf("%s: got %s %s %s %s", tt.input, e.Network, e.SubnetID, e.InterfaceID, e.Host)
+		}
+		if tt.bits == "" {
+			continue
+		}
+		found := false
+		for _, r := range e.Bits {
+			found = found || r.Bits == tt.bits && strings.Contains(strings.Join(r.Parts, ","), ",")
+		}
+		if !found {
+			t.Errorf("%s: no row %q spanning two parts in %+v", tt.input, tt.bits, e.Bits)
+		}
+	}
+	if _, err := explainAddress("2001:db8::1", explainOptions{LIR: 32, Site: 65}); err == nil {
+		t.Error("site length 65 accepted")
+	}
+}
+
+func TestExplainAddressDetails(t *testing.T) {
+	f, err := os.Open("testdata/delegated-extended.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delegations, err := readRIRStats(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := explainAddress("2001:610:abcd:12:0211:22ff:fe33:4455/64", explainOptions{LIR: 32, Site: 48, Delegations: delegations})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.LIRPrefix != "2001:610::/32" || e.InterfaceIDType != "EUI-64, from a MAC" || e.MAC != "00:11:22:33:44:55" {
+		t.Errorf("got %+v", e)
+	}
+	if e.Registry == nil || e.Registry.RIR != "ripencc" || e.Registry.Economy != "NL" {
+		t.Errorf("registry: got %+v", e.Registry)
+	}
+	if !strings.HasSuffix(e.ReverseDNS, ".0.1.6.0.1.0.0.2.ip6.arpa.") {
+		t.Errorf("reverse DNS: got %s", e.ReverseDNS)
+	}
+
+	tests := []struct{ input, iidType string }{
+		{"2001:db8::1", "manual (low-byte)"},
+		{"2001:db8::8a3f:c21d:5e90:b7e4", "privacy or stable random"},
+		{"2001:db8::dead:beef", "manual (wordy)"},
+	}
+	for _, tt := range tests {
+		e, err := explainAddress(tt.input, explainOptions{LIR: 32, Site: 48})
+		if err != nil || e.InterfaceIDType != tt.iidType {
+			t.Errorf("%s: got %q, %v, want %q", tt.input, e.InterfaceIDType, err, tt.iidType)
+		}
+		if e.Registry != nil {
+			t.Errorf("%s: registry without statistics", tt.input)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// extractedAddress is an address found in text, in canonical form, and how
+// many times it was seen.
+type extractedAddress struct {
+	Address  string   `json:"address"`
+	Count    int      `json:"count"`
+	Class    string   `json:"class,omitempty"`
+	Contains []string `json:"contains,omitempty"` // classes inside a prefix
+	Geo      *geoInfo `json:"geo,omitempty"`      // with -geoip-db
+}
+
+// extractor collects the distinct addresses found in text, in the order they
+// are first seen.
+type extractor struct {
+	seen  map[string]int // index in found
+	found []extractedAddress
+	addrs []netip.Prefix // each address or prefix, for aggregating
+}
+
+func newExtractor() *extractor {
+	return &extractor{seen: map[string]int{}}
+}
+
+// scan reads r, adding every IPv6 address and prefix in it: a prefix is kept
+// with its length, as written, and a zone with its address.
+func (x *extractor) scan(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // long lines of HTML or JSON
+	for scanner.Scan() {
+		for _, m := range findAddresses(scanner.Text()) {
+			text, p := addrText(m.Addr), netip.PrefixFrom(m.Addr, 128)
+			switch {
+			case m.Length != "":
+				text = rewritePrefix(m.Addr, m.Length, addrText)
+				if q, err := netip.ParsePrefix(m.Addr.String() + "/" + m.Length); err == nil {
+					p = q.Masked()
+				}
+			case m.Zone != "":
+				text += "%" + m.Zone
+			}
+			if i, ok := x.seen[text]; ok {
+				x.found[i].Count++
+				continue
+			}
+			x.seen[text] = len(x.found)
+			x.found = append(x.found, extractedAddress{Address: text, Count: 1})
+			x.addrs = append(x.addrs, p)
+		}
+	}
+	return scanner.Err()
+}
+
+// aggregate returns the fewest prefixes covering every address and prefix
+// found.
+func (x *extractor) aggregate() []string {
+	entries := make([]prefixEntry, len(x.addrs))
+	for i, p := range x.addrs {
+		entries[i] = prefixEntry{Net: prefixToIPNet(p)}
+	}
+	out := []string{}
+	for _, r := range excludedRanges(entries) {
+		for _, p := range rangePrefixes(r) {
+			out = append(out, prefixText(p))
+		}
+	}
+	return out
+}
+
+// runExtract prints every distinct IPv6 address and prefix in the files, or
+// stdin, once, in canonical form and in the order first seen: from logs,
+// configurations, HTML, anything. With classify each is given its class, with
+// geoDBs, MaxMind DB files, its country, city, and ASN, and with aggregate the
+// fewest prefixes covering them are printed instead.
+func runExtract(files []string, classify, aggregate bool, geoDBs string) {
+	geo, err := newGeoAnnotator(geoDBs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	x := newExtractor()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, file := range files {
+		r := io.Reader(os.Stdin)
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			r = f
+		}
+		if err := x.scan(r); err != nil {
+			log.Fatalf("%s: %v", file, err)
+		}
+	}
+
+	if aggregate {
+		prefixes := x.aggregate()
+		writeResult(prefixes, func() {
+			for _, p := range prefixes {
+				fmt.Println(p)
+			}
+		})
+		return
+	}
+	for i, p := range x.addrs {
+		if classify {
+			c := classifyPrefix(p)
+			x.found[i].Class, x.found[i].Contains = c.Class, c.Contains
+		}
+		if x.found[i].Geo, err = geo.annotate(p.Addr()); err != nil {
+			log.Fatal(err)
+		}
+	}
+	found := x.found
+	if found == nil {
+		found = []extractedAddress{}
+	}
+	writeResult(found, func() {
+		for _, a := range found {
+			switch {
+			case a.Geo != nil && classify:
+				line := fmt.Sprintf("%-40s %-14s %s", a.Address, a.Class, a.Geo)
+				if len(a.Contains) > 0 {
+					line += "  contains " + strings.Join(a.Contains, ", ")
+				}
+				fmt.Println(line)
+			case a.Geo != nil:
+				fmt.Printf("%-40s %s\n", a.Address, a.Geo)
+			case classify && len(a.Contains) > 0:
+				fmt.Printf("%-40s %s, contains %s\n", a.Address, a.Class, strings.Join(a.Contains, ", "))
+			case classify:
+				fmt.Printf("%-40s %s\n", a.Address, a.Class)
+			default:
+				fmt.Println(a.Address)
+			}
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindAddresses(t *testing.T) {
+	tests := []struct{ line, want string }{
+		{`<a href="http://[2001:DB8::1]:8080/">`, "2001:db8::1"},
+		{"ping fe80::1%eth0.", "fe80::1%eth0"},
+		{"ping fe80::1%en0: reply", "fe80::1%en0"},
+		{"route 2001:db8:1::/48 via 2001:db8::1", "2001:db8:1::/48 2001:db8::1"},
+		{"10:02:11 00:11:22:33:44:55 std::string", ""},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range findAddresses(tt.line) {
+			s := m.Addr.String()
+			if m.Length != "" {
+				s += "/" + m.Length
+			}
+			if m.Zone != "" {
+				s += "%" + m.Zone
+			}
+			got = append(got, s)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%q: got %v, want %s", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	x := newExtractor()
+	in := "from 2001:DB8::1 to [2001:db8::2]:443\nfrom 2001:db8:0::1 to fe80::1%eth0\nroute 2001:db8:1::/48\n"
+	if err := x.scan(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, a := range x.found {
+		got = append(got, a.Address)
+	}
+	if want := "2001:db8::1 2001:db8::2 fe80::1%eth0 2001:db8:1::/48"; strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+	if x.found[0].Count != 2 {
+		t.Errorf("2001:db8::1 seen %d times, want 2", x.found[0].Count)
+	}
+	if got, want := strings.Join(x.aggregate(), " "), "2001:db8::1/128 2001:db8::2/128 2001:db8:1::/48 fe80::1/128"; got != want {
+		t.Errorf("aggregate: got %s, want %s", got, want)
+	}
+}
+
+func TestExtractTwoFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	os.WriteFile(a, []byte("from 2001:db8::1 to 2001:db8::2\n"), 0o644)
+	os.WriteFile(b, []byte("from 2001:db8::2 to 2001:db8::3\n"), 0o644)
+	got := captureStdout(t, func() { runSubcommandArgs(t, "extract", a, b) })
+	if want := "2001:db8::1\n2001:db8::2\n2001:db8::3\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExtractClassifyPrefix(t *testing.T) {
+	got := captureStdout(t, func() { runSubcommandArgs(t, "extract", "-classify", "testdata/notes.txt") })
+	if !strings.Contains(got, "2001::/23                                gua, contains teredo\n") {
+		t.Errorf("2001::/23 not classified by the range holding it:\n%s", got)
+	}
+}
+
+func TestExtractGeoIP(t *testing.T) {
+	got := captureStdout(t, func() {
+		runSubcommandArgs(t, "extract", "-classify", "-geoip-db", "testdata/geoip-test.mmdb", "testdata/notes.txt")
+	})
+	for _, want := range []string{
+		"2001:db8:1::/48                          documentation  FR Paris AS64500 Example Net\n",
+		"2001:db8::1                              documentation  -\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
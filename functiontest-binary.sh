@@ -54,6 +54,249 @@ echo "Testing IPv6 format display for IPv4-mapped (shows IPv4-in-IPv6 line)..."
 echo "Testing IPv6 format display for ULA with prefix..."
 ./ipv6utils -f fd12:3456:789a::1/48
 
+echo "Testing reverse DNS tree statistics..."
+./ipv6utils arpa stats 3fff:0:abcd::/48 -zone testdata/3fff-0-abcd.zone
+
+echo "Testing vanity subnet search..."
+./ipv6utils vanity 3fff::/32 cafe beef f00d -n 48 -l 5
+
+echo "Testing vanity interface IDs..."
+./ipv6utils vanity iid 3fff:0:1::/64 cafe:f00d c0ffee
+
+echo "Testing ASCII vanity interface IDs..."
+./ipv6utils vanity iid 3fff:0:1::/64 mail www -ascii
+
+echo "Testing interface-ID scoring for an inventory..."
+./ipv6utils iid-score testdata/inventory.txt
+
+echo "Testing subnet usage heatmap..."
+./ipv6utils heatmap 3fff:0:1::/48 testdata/usage.txt -n 56
+
+echo "Testing stale allocation report..."
+./ipv6utils stale testdata/usage.txt -seen testdata/activity.txt -days 365
+
+echo "Testing stable output mode..."
+./ipv6utils subnet 3fff:0::/32 -n 40 -l 5 -stable
+
+echo "Testing typo correction..."
+./ipv6utils fix testdata/typos.txt
+
+echo "Testing bulk MAC conversion..."
+./ipv6utils mac file testdata/mactable.txt -slaac-prefix 2001:db8:10::/64 -output-format csv
+
+echo "Testing PD pool simulation..."
+./ipv6utils pd-sim 2001:db8::/44 -size 56 -customers 3000 -periods 6 -sticky
+
+echo "Testing allocation policy audit (violations expected)..."
+./ipv6utils audit testdata/allocations.txt -rules testdata/policy.yaml
+
+echo "Testing RIR delegation lookup..."
+./ipv6utils rir lookup 2a01:e34:1234::/48 -stats testdata/delegated-extended.txt
+
+echo "Testing RIR per-country list..."
+./ipv6utils rir country FR -stats testdata/delegated-extended.txt
+
+echo "Testing blocklist feed checking..."
+./ipv6utils blocklist testdata/abuse.txt -feed testdata/drop_v6.txt
+
+echo "Testing bulk address statistics..."
+./ipv6utils stats testdata/clients.txt -output-format json
+
+echo "Testing RADIUS export..."
+./ipv6utils radius testdata/subscribers.txt -format sql
+
+echo "Testing DHCPv6 lease import..."
+./ipv6utils leases dhcpd6 testdata/dhcpd6.leases -all
+
+echo "Testing subnet generation with exclusions..."
+./ipv6utils subnet 3fff:0::/32 -n 48 -l 5 -exclude testdata/allocations.txt
+
+echo "Testing Kea lease import..."
+./ipv6utils leases kea testdata/kea-leases6.csv
+
+echo "Testing radvd/networkd import..."
+./ipv6utils ra-config testdata/radvd.conf testdata/lan.network
+
+echo "Testing router configuration import..."
+./ipv6utils router-config testdata/configs/edge1.cfg testdata/configs/core1.conf
+
+echo "Testing plan merge..."
+./ipv6utils merge testdata/plan-acme.txt testdata/plan-globex.txt -strategy first
+
+echo "Testing plan export to Kea..."
+./ipv6utils plan export testdata/plan-services.txt kea
+
+echo "Testing VLAN subnet mapping..."
+./ipv6utils vlan 2001:db8::/48 10,120,200-201
+
+echo "Testing bit-field scheme encoding..."
+./ipv6utils scheme encode -scheme testdata/scheme.yaml region=emea,site=12-13,role=servers
+
+echo "Testing output manifest..."
+./ipv6utils subnet 3fff:0::/32 -n 36 -o subnets.txt -manifest -stable
+
+echo "Testing expired allocation release (dry run)..."
+./ipv6utils gc testdata/plan-lab.txt -dry-run
+
+echo "Testing prefix neighbors..."
+./ipv6utils neighbors 2001:db8:0:120::/64 -parent 48
+
+echo "Testing subnet subcommand..."
+./ipv6utils subnet 3fff::/32 -n 40 -l 5
+
+echo "Testing nat64 subcommand..."
+./ipv6utils nat64 192.0.2.1
+
+echo "Subnets from a start index"
+./ipv6utils subnet 2001:db8::/32 -start-index 1000000 -l 3
+
+echo "Random sample of subnets"
+./ipv6utils subnet 2001:db8::/32 -sample 3 -seed 7
+
+echo "Reserve first and last subnets"
+./ipv6utils subnet 2001:db8::/62 -reserve-first 1 -reserve-last 1
+
+echo "JSON output for NAT64 synthesis"
+./ipv6utils nat64 192.0.2.1 -output-format json
+
+echo "JSON output for subnet generation"
+./ipv6utils subnet 2001:db8::/60 -l 2 -output-format json
+
+echo "CSV output for subnet generation"
+./ipv6utils subnet 2001:db8::/62 -reserve-first 1 -output-format csv -columns index,prefix,gateway,name
+
+echo "YAML output for subnet generation"
+./ipv6utils subnet 2001:db8::/63 -reserve-last 1 -output-format yaml
+
+echo "Template output for subnets"
+./ipv6utils subnet 2001:db8:0:10::/62 -template 'interface Vlan{{.Index}} ipv6 address {{.Gateway}}/{{.Length}}'
+
+echo "Testing subnet names..."
+./ipv6utils subnet 2001:db8::/62 -reserve-first 1 -name-template 'site-{{.Index}}'
+
+echo "Testing hierarchical plan..."
+./ipv6utils plan testdata/plan-schema.yaml
+
+echo "Testing plan validation..."
+./ipv6utils plan validate testdata/plan-invalid.txt -parent 3fff::/32
+
+echo "Testing plan diff..."
+./ipv6utils plan diff testdata/plan-v1.txt testdata/plan-v2.txt
+
+echo "Testing next-available allocation..."
+./ipv6utils alloc next -p 3fff::/32 -n 48 -allocated-file testdata/allocations.txt -count 2
+
+echo "Allocation database list"
+./ipv6utils alloc list -db /tmp/ipv6utils-ft-alloc.json
+
+echo "Sparse allocation with -strategy leftmost"
+./ipv6utils subnet 3fff::/32 -n 36 -strategy leftmost -l 4
+
+echo "HD-ratio utilization report"
+./ipv6utils report utilization 3fff::/32 -unit 48 -allocated-file testdata/allocations.txt
+
+echo "Prefix length for a subnet count"
+./ipv6utils size -p 2001:db8::/48 -need 600 -nibble
+
+echo "Nibble-align a new prefix length"
+./ipv6utils subnet 2001:db8::/48 -n 57 -nibble-align round-up -c
+
+echo "Reverse zones covering a non-nibble prefix"
+./ipv6utils arpa zones 2001:db8::/57
+
+echo "Prefix subtraction"
+./ipv6utils subtract 2001:db8::/32 -minus 2001:db8:dead::/48,2001:db8:beef::/48
+
+echo "Prefix list intersection"
+./ipv6utils intersect testdata/announced.txt testdata/allocations.txt
+
+echo "Prefix list set difference"
+./ipv6utils setdiff testdata/router-routes.txt testdata/allocations.txt
+
+echo "Prefix list lint"
+./ipv6utils lint testdata/allocations.txt
+
+echo "Containment check"
+./ipv6utils contains 2001:db8::/32 2001:db8:1::5
+
+echo "Longest-prefix match"
+./ipv6utils lpm -table testdata/router-routes.txt 3fff:0:1:9000::5
+
+echo "Testing range to CIDR conversion..."
+./ipv6utils cidr 2001:db8::1 2001:db8::6
+
+echo "Testing prefix range expansion..."
+./ipv6utils range 2001:db8:5::/48
+
+echo "Testing address arithmetic..."
+./ipv6utils math add 2001:db8::1 +0x1000
+
+echo "Testing nth address in a prefix..."
+./ipv6utils nth -p 2001:db8:1::/64 -index 42
+
+echo "Testing subnet lookup for an address..."
+./ipv6utils locate 2001:db8::/48 2001:db8:0:2a::1234
+
+echo "Testing address distance..."
+./ipv6utils math distance 2001:db8::1 2001:db8:0:1::1
+
+echo "Testing RFC 5952 canonicalization..."
+./ipv6utils format canon 2001:0DB8:0000:0000:0000:0000:0000:0001
+
+echo "Testing fully expanded format..."
+./ipv6utils format expand 2001:db8::1
+
+echo "Testing numeric address conversion..."
+./ipv6utils convert 2001:db8::1
+
+echo "Testing address explanation with binary view..."
+./ipv6utils explain 2001:db8:abcd:12::1/64 -binary
+
+echo "Testing address explanation..."
+./ipv6utils explain 2001:610:abcd:12:0211:22ff:fe33:4455 -rir-stats testdata/delegated-extended.txt
+
+echo "Testing address classification..."
+./ipv6utils classify ff02::1:ff00:1
+
+echo "Testing special-purpose registry lookup..."
+./ipv6utils special 2001::1
+
+echo "Testing bogon list as a BIRD filter..."
+./ipv6utils bogons -filter bird
+
+echo "Testing sanitize..."
+echo " ipv6 address 2606:4700:10:5::1/64" | ./ipv6utils sanitize
+
+echo "Testing anonymize..."
+./ipv6utils anonymize -key test -preserve-prefix 48 2606:4700:10:5::1
+
+echo "Testing extract..."
+./ipv6utils extract -classify testdata/notes.txt
+
+echo "Testing sortu..."
+./ipv6utils sortu testdata/inventory.txt
+
+echo "Testing nat64 batch conversion..."
+printf '192.0.2.1\n198.51.100.7\n' | ./ipv6utils nat64 -workers 2 -
+
+echo "Testing NAT64 synthesis under a /40 prefix..."
+./ipv6utils nat64 -k 2001:db8:100::/40 192.0.2.33
+
+echo "Testing nat64 check..."
+./ipv6utils nat64 check 64:ff9b:1:ab00::/56
+
+echo "Testing pref64..."
+./ipv6utils pref64 -config radvd 64:ff9b::/96
+
+echo "Testing clat..."
+./ipv6utils clat -clat-prefix 2001:db8:aaaa:1::/64 192.0.0.2 198.51.100.7
+
+echo "Testing 6to4..."
+./ipv6utils 6to4 192.0.2.1
+
+echo "Testing isatap..."
+./ipv6utils isatap -prefix 2001:db8:0:10::/64 192.0.2.1
+
 echo "Testing version flag..."
 ./ipv6utils -version
 
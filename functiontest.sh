@@ -1,62 +1,305 @@
 #!/bin/bash
 
 echo "Testing IPv4 to IPv6 conversion..."
-go run ipv6utils.go -s 100.64.1.1
+go run . -s 100.64.1.1
 
 echo "Testing IPv6 to IPv4 conversion..."
-go run ipv6utils.go -s 64:ff9b::c0a8:101
+go run . -s 64:ff9b::c0a8:101
 
 echo "Testing SLAAC MAC address decoding..."
-go run ipv6utils.go -m 3fff:0::0200:5eff:fe00:5325
+go run . -m 3fff:0::0200:5eff:fe00:5325
 
 echo "Testing subnet generation..."
-go run ipv6utils.go -p 3fff:0::/32 -n 40 -l 5
+go run . -p 3fff:0::/32 -n 40 -l 5
 
 echo "Testing prefix count..."
-go run ipv6utils.go -p 3fff:0::/32 -n 40 -c
+go run . -p 3fff:0::/32 -n 40 -c
 
 echo "Testing output to file..."
-go run ipv6utils.go -p 3fff:0::/32 -n 36 -o subnets.txt
+go run . -p 3fff:0::/32 -n 36 -o subnets.txt
 cat subnets.txt
 
 echo "Testing alias flags..."
-go run ipv6utils.go -p 3fff:0::/32 -n 40 -l 5
-#go run ipv6utils.go -prefix 3fff:0::/32 -new-prefix-length 40 -limit 5
+go run . -p 3fff:0::/32 -n 40 -l 5
+#go run . -prefix 3fff:0::/32 -new-prefix-length 40 -limit 5
 
 echo "Testing link MAC to local decoder..."
-go run ipv6utils.go -local 00:11:22:33:44:55
+go run . -local 00:11:22:33:44:55
 
 echo "Testing link local to MAC decoder..."
-go run ipv6utils.go -local fe80::0211:22ff:fe33:4455 
+go run . -local fe80::0211:22ff:fe33:4455 
 
 echo "Testing DNS PTR generation on /56 boundary..."
-go run ipv6utils.go -ip6.arpa 3fff:0:abcd::0211:22ff:fe33:4455 -n 56
+go run . -ip6.arpa 3fff:0:abcd::0211:22ff:fe33:4455 -n 56
 
 echo "Testing DNS PTR generation..."
-go run ipv6utils.go -ip6.arpa 3fff:0:abcd::0211:22ff:fe33:4455 -n 0
+go run . -ip6.arpa 3fff:0:abcd::0211:22ff:fe33:4455 -n 0
 
 echo "Testing IPv6 format display (no prefix)..."
-go run ipv6utils.go -f 2001:db8::1
+go run . -f 2001:db8::1
 
 echo "Testing IPv6 format display with /48 prefix (network range + host ID)..."
-go run ipv6utils.go -format 2001:db8::1/48
+go run . -format 2001:db8::1/48
 
 echo "Testing IPv6 format display with /64 prefix..."
-go run ipv6utils.go -f fe80::aabb:ccff:fedd:eeff/64
+go run . -f fe80::aabb:ccff:fedd:eeff/64
 
 echo "Testing IPv6 format display for loopback..."
-go run ipv6utils.go -f ::1
+go run . -f ::1
 
 echo "Testing IPv6 format display for IPv4-mapped (shows IPv4-in-IPv6 line)..."
-go run ipv6utils.go -f ::ffff:192.0.2.1
+go run . -f ::ffff:192.0.2.1
 
 echo "Testing IPv6 format display for ULA with prefix..."
-go run ipv6utils.go -f fd12:3456:789a::1/48
+go run . -f fd12:3456:789a::1/48
+
+echo "Testing reverse DNS tree statistics..."
+go run . arpa stats 3fff:0:abcd::/48 -zone testdata/3fff-0-abcd.zone
+
+echo "Testing vanity subnet search..."
+go run . vanity 3fff::/32 cafe beef f00d -n 48 -l 5
+
+echo "Testing vanity interface IDs..."
+go run . vanity iid 3fff:0:1::/64 cafe:f00d c0ffee
+
+echo "Testing ASCII vanity interface IDs..."
+go run . vanity iid 3fff:0:1::/64 mail www -ascii
+
+echo "Testing interface-ID scoring for an inventory..."
+go run . iid-score testdata/inventory.txt
+
+echo "Testing subnet usage heatmap..."
+go run . heatmap 3fff:0:1::/48 testdata/usage.txt -n 56
+
+echo "Testing stale allocation report..."
+go run . stale testdata/usage.txt -seen testdata/activity.txt -days 365
+
+echo "Testing stable output mode..."
+go run . subnet 3fff:0::/32 -n 40 -l 5 -stable
+
+echo "Testing typo correction..."
+go run . fix testdata/typos.txt
+
+echo "Testing bulk MAC conversion..."
+go run . mac file testdata/mactable.txt -slaac-prefix 2001:db8:10::/64 -output-format csv
+
+echo "Testing PD pool simulation..."
+go run . pd-sim 2001:db8::/44 -size 56 -customers 3000 -periods 6 -sticky
+
+echo "Testing allocation policy audit (violations expected)..."
+go run . audit testdata/allocations.txt -rules testdata/policy.yaml
+
+echo "Testing RIR delegation lookup..."
+go run . rir lookup 2a01:e34:1234::/48 -stats testdata/delegated-extended.txt
+
+echo "Testing RIR per-country list..."
+go run . rir country FR -stats testdata/delegated-extended.txt
+
+echo "Testing blocklist feed checking..."
+go run . blocklist testdata/abuse.txt -feed testdata/drop_v6.txt
+
+echo "Testing bulk address statistics..."
+go run . stats testdata/clients.txt -output-format json
+
+echo "Testing RADIUS export..."
+go run . radius testdata/subscribers.txt -format sql
+
+echo "Testing DHCPv6 lease import..."
+go run . leases dhcpd6 testdata/dhcpd6.leases -all
+
+echo "Testing subnet generation with exclusions..."
+go run . subnet 3fff:0::/32 -n 48 -l 5 -exclude testdata/allocations.txt
+
+echo "Testing Kea lease import..."
+go run . leases kea testdata/kea-leases6.csv
+
+echo "Testing radvd/networkd import..."
+go run . ra-config testdata/radvd.conf testdata/lan.network
+
+echo "Testing router configuration import..."
+go run . router-config testdata/configs/edge1.cfg testdata/configs/core1.conf
+
+echo "Testing plan merge..."
+go run . merge testdata/plan-acme.txt testdata/plan-globex.txt -strategy first
+
+echo "Testing plan export to Kea..."
+go run . plan export testdata/plan-services.txt kea
+
+echo "Testing VLAN subnet mapping..."
+go run . vlan 2001:db8::/48 10,120,200-201
+
+echo "Testing bit-field scheme encoding..."
+go run . scheme encode -scheme testdata/scheme.yaml region=emea,site=12-13,role=servers
+
+echo "Testing output manifest..."
+go run . subnet 3fff:0::/32 -n 36 -o subnets.txt -manifest -stable
+
+echo "Testing expired allocation release (dry run)..."
+go run . gc testdata/plan-lab.txt -dry-run
+
+echo "Testing prefix neighbors..."
+go run . neighbors 2001:db8:0:120::/64 -parent 48
+
+echo "Testing subnet subcommand..."
+go run . subnet 3fff::/32 -n 40 -l 5
+
+echo "Testing nat64 subcommand..."
+go run . nat64 192.0.2.1
+
+echo "Subnets from a start index"
+go run . subnet 2001:db8::/32 -start-index 1000000 -l 3
+
+echo "Random sample of subnets"
+go run . subnet 2001:db8::/32 -sample 3 -seed 7
+
+echo "Reserve first and last subnets"
+go run . subnet 2001:db8::/62 -reserve-first 1 -reserve-last 1
+
+echo "JSON output for NAT64 synthesis"
+go run . nat64 192.0.2.1 -output-format json
+
+echo "JSON output for subnet generation"
+go run . subnet 2001:db8::/60 -l 2 -output-format json
+
+echo "CSV output for subnet generation"
+go run . subnet 2001:db8::/62 -reserve-first 1 -output-format csv -columns index,prefix,gateway,name
+
+echo "YAML output for subnet generation"
+go run . subnet 2001:db8::/63 -reserve-last 1 -output-format yaml
+
+echo "Template output for subnets"
+go run . subnet 2001:db8:0:10::/62 -template 'interface Vlan{{.Index}} ipv6 address {{.Gateway}}/{{.Length}}'
+
+echo "Testing subnet names..."
+go run . subnet 2001:db8::/62 -reserve-first 1 -name-template 'site-{{.Index}}'
+
+echo "Testing hierarchical plan..."
+go run . plan testdata/plan-schema.yaml
+
+echo "Testing plan validation..."
+go run . plan validate testdata/plan-invalid.txt -parent 3fff::/32
+
+echo "Testing plan diff..."
+go run . plan diff testdata/plan-v1.txt testdata/plan-v2.txt
+
+echo "Testing next-available allocation..."
+go run . alloc next -p 3fff::/32 -n 48 -allocated-file testdata/allocations.txt -count 2
+
+echo "Allocation database list"
+go run . alloc list -db /tmp/ipv6utils-ft-alloc.json
+
+echo "Sparse allocation with -strategy leftmost"
+go run . subnet 3fff::/32 -n 36 -strategy leftmost -l 4
+
+echo "HD-ratio utilization report"
+go run . report utilization 3fff::/32 -unit 48 -allocated-file testdata/allocations.txt
+
+echo "Prefix length for a subnet count"
+go run . size -p 2001:db8::/48 -need 600 -nibble
+
+echo "Nibble-align a new prefix length"
+go run . subnet 2001:db8::/48 -n 57 -nibble-align round-up -c
+
+echo "Reverse zones covering a non-nibble prefix"
+go run . arpa zones 2001:db8::/57
+
+echo "Prefix subtraction"
+go run . subtract 2001:db8::/32 -minus 2001:db8:dead::/48,2001:db8:beef::/48
+
+echo "Prefix list intersection"
+go run . intersect testdata/announced.txt testdata/allocations.txt
+
+echo "Prefix list set difference"
+go run . setdiff testdata/router-routes.txt testdata/allocations.txt
+
+echo "Prefix list lint"
+go run . lint testdata/allocations.txt
+
+echo "Containment check"
+go run . contains 2001:db8::/32 2001:db8:1::5
+
+echo "Longest-prefix match"
+go run . lpm -table testdata/router-routes.txt 3fff:0:1:9000::5
+
+echo "Testing range to CIDR conversion..."
+go run . cidr 2001:db8::1 2001:db8::6
+
+echo "Testing prefix range expansion..."
+go run . range 2001:db8:5::/48
+
+echo "Testing address arithmetic..."
+go run . math add 2001:db8::1 +0x1000
+
+echo "Testing nth address in a prefix..."
+go run . nth -p 2001:db8:1::/64 -index 42
+
+echo "Testing subnet lookup for an address..."
+go run . locate 2001:db8::/48 2001:db8:0:2a::1234
+
+echo "Testing address distance..."
+go run . math distance 2001:db8::1 2001:db8:0:1::1
+
+echo "Testing RFC 5952 canonicalization..."
+go run . format canon 2001:0DB8:0000:0000:0000:0000:0000:0001
+
+echo "Testing fully expanded format..."
+go run . format expand 2001:db8::1
+
+echo "Testing numeric address conversion..."
+go run . convert 2001:db8::1
+
+echo "Testing address explanation with binary view..."
+go run . explain 2001:db8:abcd:12::1/64 -binary
+
+echo "Testing address explanation..."
+go run . explain 2001:610:abcd:12:0211:22ff:fe33:4455 -rir-stats testdata/delegated-extended.txt
+
+echo "Testing address classification..."
+go run . classify ff02::1:ff00:1
+
+echo "Testing special-purpose registry lookup..."
+go run . special 2001::1
+
+echo "Testing bogon list as a BIRD filter..."
+go run . bogons -filter bird
+
+echo "Testing sanitize..."
+echo " ipv6 address 2606:4700:10:5::1/64" | go run . sanitize
+
+echo "Testing anonymize..."
+go run . anonymize -key test -preserve-prefix 48 2606:4700:10:5::1
+
+echo "Testing extract..."
+go run . extract -classify testdata/notes.txt
+
+echo "Testing sortu..."
+go run . sortu testdata/inventory.txt
+
+echo "Testing nat64 batch conversion..."
+printf '192.0.2.1\n198.51.100.7\n' | go run . nat64 -workers 2 -
+
+echo "Testing NAT64 synthesis under a /40 prefix..."
+go run . nat64 -k 2001:db8:100::/40 192.0.2.33
+
+echo "Testing nat64 check..."
+go run . nat64 check 64:ff9b:1:ab00::/56
+
+echo "Testing pref64..."
+go run . pref64 -config radvd 64:ff9b::/96
+
+echo "Testing clat..."
+go run . clat -clat-prefix 2001:db8:aaaa:1::/64 192.0.0.2 198.51.100.7
+
+echo "Testing 6to4..."
+go run . 6to4 192.0.2.1
+
+echo "Testing isatap..."
+go run . isatap -prefix 2001:db8:0:10::/64 192.0.2.1
 
 echo "Testing version flag..."
-go run ipv6utils.go -version
+go run . -version
 
 echo "Testing version alias flag..."
-go run ipv6utils.go -v
+go run . -v
 
 echo "All tests completed."
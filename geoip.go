@@ -0,0 +1,413 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// mmdbMetadataMarker precedes the metadata map at the end of a MaxMind DB file.
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// mmdbReader looks up addresses in a MaxMind DB (GeoIP2/GeoLite2 .mmdb) file.
+type mmdbReader struct {
+	Path         string
+	DatabaseType string
+	buf          []byte
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	dataStart    uint
+}
+
+// geoInfo is the subset of GeoIP2 City, Country, and ASN records shown by ipv6utils.
+type geoInfo struct {
+	Country string `json:"country,omitempty"`
+	City    string `json:"city,omitempty"`
+	ASN     uint   `json:"asn,omitempty"`
+	ASOrg   string `json:"as_org,omitempty"`
+}
+
+// openMMDB reads a MaxMind DB file into memory.
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newMMDBReader(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	r.Path = path
+	return r, nil
+}
+
+// newMMDBReader parses the metadata of an in-memory MaxMind DB.
+func newMMDBReader(buf []byte) (*mmdbReader, error) {
+	i := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if i == -1 {
+		return nil, fmt.Errorf("not a MaxMind DB file")
+	}
+	metaStart := uint(i + len(mmdbMetadataMarker))
+	d := &mmdbDecoder{buf: buf[metaStart:]}
+	v, _, err := d.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata: %v", err)
+	}
+	meta, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid metadata")
+	}
+	uintField := func(key string) uint {
+		n, _ := meta[key].(uint64)
+		return uint(n)
+	}
+	r := &mmdbReader{
+		buf:        buf,
+		nodeCount:  uintField("node_count"),
+		recordSize: uintField("record_size"),
+		ipVersion:  uintField("ip_version"),
+	}
+	r.DatabaseType, _ = meta["database_type"].(string)
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 6 {
+		return nil, fmt.Errorf("database is IPv%d only", r.ipVersion)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	r.dataStart = treeSize + 16
+	if r.dataStart > uint(i) {
+		return nil, fmt.Errorf("search tree larger than file")
+	}
+	return r, nil
+}
+
+// readRecord returns the left (bit 0) or right (bit 1) record of a tree node.
+func (r *mmdbReader) readRecord(node uint, bit uint) uint {
+	size := r.recordSize / 4 // bytes per node
+	b := r.buf[node*size : (node+1)*size]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// lookup walks the search tree for ip and decodes the record it leads to, returning
+// nil when the database has no data for the address.
+func (r *mmdbReader) lookup(ip net.IP) (map[string]any, error) {
+	ip = ip.To16()
+	node := uint(0)
+	for i := 0; i < 128 && node < r.nodeCount; i++ {
+		bit := uint(ip[i/8]>>(7-i%8)) & 1
+		node = r.readRecord(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, fmt.Errorf("search tree ended inside the tree")
+	}
+	offset := node - r.nodeCount - 16
+	d := &mmdbDecoder{buf: r.buf[r.dataStart:]}
+	v, _, err := d.decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := v.(map[string]any)
+	return m, nil
+}
+
+// mmdbDecoder decodes values from the MaxMind DB data section format.
+type mmdbDecoder struct {
+	buf []byte
+}
+
+// decode returns the value at offset and the offset just past it.
+func (d *mmdbDecoder) decode(offset uint) (any, uint, error) {
+	if offset >= uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("offset %d beyond data section", offset)
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ := uint(ctrl >> 5)
+	if typ == 1 {
+		ptr, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(ptr)
+		return v, next, err
+	}
+	if typ == 0 {
+		if offset >= uint(len(d.buf)) {
+			return nil, 0, fmt.Errorf("truncated extended type")
+		}
+		typ = 7 + uint(d.buf[offset])
+		offset++
+	}
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return nil, 0, fmt.Errorf("truncated size")
+		}
+		var extra uint
+		for _, b := range d.buf[offset : offset+n] {
+			extra = extra<<8 | uint(b)
+		}
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + extra
+		case 30:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+
+	switch typ {
+	case 7: // map
+		m := make(map[string]any, size)
+		for range size {
+			k, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key is not a string")
+			}
+			v, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			offset = next
+		}
+		return m, offset, nil
+	case 11: // array
+		a := make([]any, 0, size)
+		for range size {
+			v, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			offset = next
+		}
+		return a, offset, nil
+	case 14: // boolean, value held in the size
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("value extends beyond data section")
+	}
+	b := d.buf[offset : offset+size]
+	next := offset + size
+	switch typ {
+	case 2: // UTF-8 string
+		return string(b), next, nil
+	case 3: // double
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case 15: // float
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case 4, 10: // bytes, uint128
+		return append([]byte(nil), b...), next, nil
+	case 5, 6, 9: // uint16, uint32, uint64
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, next, nil
+	case 8: // int32
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		if size == 4 {
+			return int64(int32(n)), next, nil
+		}
+		return int64(n), next, nil
+	case 12, 13: // data cache container, end marker
+		return nil, next, nil
+	}
+	return nil, 0, fmt.Errorf("unknown data type %d", typ)
+}
+
+// pointer decodes a pointer whose control byte is ctrl, returning the target offset
+// and the offset following the pointer.
+func (d *mmdbDecoder) pointer(ctrl byte, offset uint) (uint, uint, error) {
+	n := uint(ctrl>>3)&0x3 + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, fmt.Errorf("truncated pointer")
+	}
+	b := d.buf[offset : offset+n]
+	var p uint
+	if n < 4 {
+		p = uint(ctrl & 0x7)
+	}
+	for _, c := range b {
+		p = p<<8 | uint(c)
+	}
+	switch n {
+	case 2:
+		p += 2048
+	case 3:
+		p += 526336
+	}
+	return p, offset + n, nil
+}
+
+// mmdbPath follows a chain of map keys through a decoded record.
+func mmdbPath(v any, keys ...string) any {
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+// geoLookup merges what each database knows about ip; a City or Country database
+// supplies location and an ASN database the autonomous system.
+func geoLookup(dbs []*mmdbReader, ip net.IP) (geoInfo, error) {
+	var info geoInfo
+	for _, db := range dbs {
+		rec, err := db.lookup(ip)
+		if err != nil {
+			return info, fmt.Errorf("%s: %v", db.Path, err)
+		}
+		if rec == nil {
+			continue
+		}
+		if cc, ok := mmdbPath(rec, "country", "iso_code").(string); ok && info.Country == "" {
+			info.Country = cc
+		}
+		if city, ok := mmdbPath(rec, "city", "names", "en").(string); ok && info.City == "" {
+			info.City = city
+		}
+		if asn, ok := mmdbPath(rec, "autonomous_system_number").(uint64); ok && info.ASN == 0 {
+			info.ASN = uint(asn)
+		}
+		if org, ok := mmdbPath(rec, "autonomous_system_organization").(string); ok && info.ASOrg == "" {
+			info.ASOrg = org
+		}
+	}
+	return info, nil
+}
+
+// String renders the annotation as "CC City AS64500 Org", omitting unknown parts.
+func (g geoInfo) String() string {
+	var parts []string
+	if g.Country != "" {
+		parts = append(parts, g.Country)
+	}
+	if g.City != "" {
+		parts = append(parts, g.City)
+	}
+	if g.ASN != 0 {
+		parts = append(parts, fmt.Sprintf("AS%d", g.ASN))
+	}
+	if g.ASOrg != "" {
+		parts = append(parts, g.ASOrg)
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
+
+// openGeoIPDatabases opens each comma-separated database path.
+func openGeoIPDatabases(paths string) ([]*mmdbReader, error) {
+	var dbs []*mmdbReader
+	for _, p := range strings.Split(paths, ",") {
+		db, err := openMMDB(p)
+		if err != nil {
+			return nil, err
+		}
+		dbs = append(dbs, db)
+	}
+	return dbs, nil
+}
+
+// geoAnnotator looks addresses up for the -geoip-db of classify and extract.
+// With no databases it annotates nothing.
+type geoAnnotator []*mmdbReader
+
+// newGeoAnnotator opens the comma-separated databases, if any are given.
+func newGeoAnnotator(paths string) (geoAnnotator, error) {
+	if paths == "" {
+		return nil, nil
+	}
+	return openGeoIPDatabases(paths)
+}
+
+// annotate returns what the databases know of a, or nil without databases.
+func (g geoAnnotator) annotate(a netip.Addr) (*geoInfo, error) {
+	if len(g) == 0 {
+		return nil, nil
+	}
+	info, err := geoLookup(g, net.IP(a.AsSlice()))
+	return &info, err
+}
+
+// reportGeoIP annotates an address, or every address in a file, with country, city,
+// and ASN from the given databases.
+func reportGeoIP(input string, dbPaths string) {
+	if dbPaths == "" {
+		log.Fatal("a GeoIP2 database must be given with -geoip-db")
+	}
+	dbs, err := openGeoIPDatabases(dbPaths)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var ips []net.IP
+	if ip, _, err := parseIPv6WithOptionalPrefix(input); err == nil {
+		ips = []net.IP{ip}
+	} else {
+		f, err := os.Open(input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ips, err = readAddressList(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", input, err)
+		}
+	}
+	for _, ip := range ips {
+		info, err := geoLookup(dbs, ip)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%-40s %s\n", ip, info)
+	}
+}
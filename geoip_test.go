@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// mmdbTestWriter builds small MaxMind DB files with 24-bit records for tests.
+type mmdbTestWriter struct {
+	nodes [][2]int // >0 child node, <0 -(data offset+1), 0 no data
+	data  bytes.Buffer
+}
+
+func mmdbString(s string) []byte {
+	if len(s) >= 29 {
+		return append([]byte{2<<5 | 29, byte(len(s) - 29)}, s...)
+	}
+	return append([]byte{2<<5 | byte(len(s))}, s...)
+}
+
+func mmdbUint32(n uint32) []byte {
+	return []byte{6<<5 | 4, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+}
+
+func mmdbMap(pairs ...[]byte) []byte {
+	out := []byte{7<<5 | byte(len(pairs)/2)}
+	for _, p := range pairs {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// add stores value in the data section and returns its offset.
+func (w *mmdbTestWriter) add(value []byte) int {
+	off := w.data.Len()
+	w.data.Write(value)
+	return off
+}
+
+// insert points prefix at the data at off.
+func (w *mmdbTestWriter) insert(prefix string, off int) {
+	_, ipnet, _ := net.ParseCIDR(prefix)
+	ones, _ := ipnet.Mask.Size()
+	if len(w.nodes) == 0 {
+		w.nodes = append(w.nodes, [2]int{})
+	}
+	n := 0
+	for i := 0; i < ones; i++ {
+		bit := int(ipnet.IP[i/8]>>(7-i%8)) & 1
+		if i == ones-1 {
+			w.nodes[n][bit] = -(off + 1)
+			return
+		}
+		if w.nodes[n][bit] <= 0 {
+			w.nodes = append(w.nodes, [2]int{})
+			w.nodes[n][bit] = len(w.nodes) - 1
+		}
+		n = w.nodes[n][bit]
+	}
+}
+
+func (w *mmdbTestWriter) bytes(databaseType string, ipVersion uint32) []byte {
+	var out bytes.Buffer
+	count := len(w.nodes)
+	for _, node := range w.nodes {
+		for _, rec := range node {
+			v := count
+			if rec > 0 {
+				v = rec
+			} else if rec < 0 {
+				v = count + 16 - rec - 1
+			}
+			out.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(w.data.Bytes())
+	out.Write(mmdbMetadataMarker)
+	out.Write(mmdbMap(
+		mmdbString("node_count"), mmdbUint32(uint32(count)),
+		mmdbString("record_size"), mmdbUint32(24),
+		mmdbString("ip_version"), mmdbUint32(ipVersion),
+		mmdbString("database_type"), mmdbString(databaseType),
+	))
+	return out.Bytes()
+}
+
+func TestMMDBLookup(t *testing.T) {
+	var city mmdbTestWriter
+	fr := city.add(mmdbMap(
+		mmdbString("country"), mmdbMap(mmdbString("iso_code"), mmdbString("FR")),
+		mmdbString("city"), mmdbMap(mmdbString("names"), mmdbMap(mmdbString("en"), mmdbString("Paris"))),
+	))
+	// A record that reuses the country map above through a 11-bit pointer, and
+	// carries extended-type values (boolean, uint64) the decoder must skip over.
+	ptr := []byte{1<<5 | byte(fr>>8), byte(fr)}
+	reuse := city.add(mmdbMap(
+		mmdbString("is_anycast"), []byte{0<<5 | 1, 7},
+		mmdbString("geoname_id"), []byte{0<<5 | 2, 2, 0x12, 0x34},
+		mmdbString("registered"), ptr,
+	))
+	city.insert("2001:db8:1::/48", fr)
+	city.insert("2001:db8:2::/48", reuse)
+	cityDB, err := newMMDBReader(city.bytes("GeoIP2-City", 6))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var asn mmdbTestWriter
+	asn.insert("2001:db8::/32", asn.add(mmdbMap(
+		mmdbString("autonomous_system_number"), mmdbUint32(64500),
+		mmdbString("autonomous_system_organization"), mmdbString("Example Net"),
+	)))
+	asnDB, err := newMMDBReader(asn.bytes("GeoLite2-ASN", 6))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cityDB.DatabaseType != "GeoIP2-City" {
+		t.Errorf("unexpected database type %q", cityDB.DatabaseType)
+	}
+
+	rec, err := cityDB.lookup(net.ParseIP("2001:db8:2::1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec["is_anycast"] != true || rec["geoname_id"] != uint64(0x1234) {
+		t.Errorf("unexpected extended values %v", rec)
+	}
+	if mmdbPath(rec, "registered", "country", "iso_code") != "FR" {
+		t.Errorf("pointer not followed: %v", rec)
+	}
+
+	cases := []struct {
+		ip   string
+		want string
+	}{
+		{"2001:db8:1::25", "FR Paris AS64500 Example Net"},
+		{"2001:db8:ffff::1", "AS64500 Example Net"},
+		{"2001:db9::1", "-"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.ip, func(t *testing.T) {
+			info, err := geoLookup([]*mmdbReader{cityDB, asnDB}, net.ParseIP(tc.ip))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if info.String() != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, info.String())
+			}
+		})
+	}
+}
+
+func TestNewMMDBReaderErrors(t *testing.T) {
+	if _, err := newMMDBReader([]byte("not a database")); err == nil {
+		t.Error("expected error for missing metadata")
+	}
+	var w mmdbTestWriter
+	w.insert("2001:db8::/32", w.add(mmdbMap()))
+	if _, err := newMMDBReader(w.bytes("test", 4)); err == nil || err.Error() != "database is IPv4 only" {
+		t.Errorf("expected error for IPv4-only database, got %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// heatmapMaxCells caps the number of cells drawn; larger expansions are bucketed.
+const heatmapMaxCells = 4096
+
+// heatmapCell is one square of a subnet heatmap. When a cell stands for a single
+// child subnet, Allocated and Active tell how it is used; otherwise Fraction gives
+// the share of children in the bucket that are in use.
+type heatmapCell struct {
+	Start     net.IP
+	Fraction  float64
+	Allocated bool
+	Active    bool
+}
+
+// heatmap is the rendered layout of a parent prefix split into children.
+type heatmap struct {
+	Parent        *net.IPNet
+	ChildLen      int
+	ChildrenShift int // each cell covers 2^ChildrenShift children
+	Cells         []heatmapCell
+}
+
+// indexInterval is an inclusive range of child subnet indexes.
+type indexInterval struct {
+	lo, hi *big.Int
+}
+
+// mergeIntervals sorts and coalesces overlapping or adjacent intervals.
+func mergeIntervals(in []indexInterval) []indexInterval {
+	sort.Slice(in, func(i, j int) bool { return in[i].lo.Cmp(in[j].lo) < 0 })
+	var out []indexInterval
+	for _, iv := range in {
+		if n := len(out); n > 0 {
+			next := new(big.Int).Add(out[n-1].hi, big.NewInt(1))
+			if iv.lo.Cmp(next) <= 0 {
+				if iv.hi.Cmp(out[n-1].hi) > 0 {
+					out[n-1].hi = iv.hi
+				}
+				continue
+			}
+		}
+		out = append(out, indexInterval{lo: new(big.Int).Set(iv.lo), hi: new(big.Int).Set(iv.hi)})
+	}
+	return out
+}
+
+// childInterval returns the range of child indexes under parent that entry overlaps,
+// or false when the two do not intersect.
+func childInterval(parent *net.IPNet, childLen int, entry *net.IPNet) (indexInterval, bool) {
+	parentLen, _ := parent.Mask.Size()
+	entryLen, _ := entry.Mask.Size()
+	pFirst := ipToBigInt(networkAddress(parent.IP.To16(), parentLen))
+	pLast := ipToBigInt(lastAddress(parent.IP.To16(), parentLen))
+	eFirst := ipToBigInt(networkAddress(entry.IP.To16(), entryLen))
+	eLast := ipToBigInt(lastAddress(entry.IP.To16(), entryLen))
+
+	lo, hi := pFirst, pLast
+	if eFirst.Cmp(lo) > 0 {
+		lo = eFirst
+	}
+	if eLast.Cmp(hi) < 0 {
+		hi = eLast
+	}
+	if lo.Cmp(hi) > 0 {
+		return indexInterval{}, false
+	}
+	shift := uint(128 - childLen)
+	base := new(big.Int).Rsh(pFirst, shift)
+	return indexInterval{
+		lo: new(big.Int).Sub(new(big.Int).Rsh(lo, shift), base),
+		hi: new(big.Int).Sub(new(big.Int).Rsh(hi, shift), base),
+	}, true
+}
+
+// bucketCoverage adds, for every bucket of 2^shift children, how many children the
+// merged intervals cover.
+func bucketCoverage(intervals []indexInterval, shift uint, cells int) []*big.Int {
+	covered := make([]*big.Int, cells)
+	for i := range covered {
+		covered[i] = new(big.Int)
+	}
+	for _, iv := range intervals {
+		first := new(big.Int).Rsh(iv.lo, shift).Int64()
+		last := new(big.Int).Rsh(iv.hi, shift).Int64()
+		for b := first; b <= last; b++ {
+			bLo := new(big.Int).Lsh(big.NewInt(b), shift)
+			bHi := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(b+1), shift), big.NewInt(1))
+			lo, hi := iv.lo, iv.hi
+			if bLo.Cmp(lo) > 0 {
+				lo = bLo
+			}
+			if bHi.Cmp(hi) < 0 {
+				hi = bHi
+			}
+			n := new(big.Int).Sub(hi, lo)
+			covered[b].Add(covered[b], n.Add(n, big.NewInt(1)))
+		}
+	}
+	return covered
+}
+
+// subnetHeatmap lays out the children of prefix at newPrefixLength and marks those
+// overlapped by entries. Prefix entries count as allocated, bare addresses as
+// seen-active. Expansions with more than maxCells children are bucketed.
+func subnetHeatmap(prefix string, newPrefixLength int, entries []prefixEntry, maxCells int) (*heatmap, error) {
+	ipnet, err := parseIPv6Prefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %v", err)
+	}
+	currentPrefixLength, _ := ipnet.Mask.Size()
+	if newPrefixLength <= currentPrefixLength || newPrefixLength > 128 {
+		return nil, fmt.Errorf("new prefix length must be larger than the current prefix length")
+	}
+
+	delta := newPrefixLength - currentPrefixLength
+	cellBits := delta
+	for cellBits > 0 && 1<<cellBits > maxCells {
+		cellBits--
+	}
+	shift := uint(delta - cellBits)
+	cells := 1 << cellBits
+
+	var all, allocated, active []indexInterval
+	for _, e := range entries {
+		iv, ok := childInterval(ipnet, newPrefixLength, e.Net)
+		if !ok {
+			continue
+		}
+		all = append(all, iv)
+		if e.Host() {
+			active = append(active, iv)
+		} else {
+			allocated = append(allocated, iv)
+		}
+	}
+	used := bucketCoverage(mergeIntervals(all), shift, cells)
+	allocUsed := bucketCoverage(mergeIntervals(allocated), shift, cells)
+	activeUsed := bucketCoverage(mergeIntervals(active), shift, cells)
+
+	hm := &heatmap{Parent: ipnet, ChildLen: newPrefixLength, ChildrenShift: int(shift)}
+	bucketSize := new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), shift))
+	start := ipnet.IP.Mask(ipnet.Mask).To16()
+	step := new(big.Int).Lsh(big.NewInt(1), uint(128-newPrefixLength)+shift)
+	for i := 0; i < cells; i++ {
+		frac, _ := new(big.Float).Quo(new(big.Float).SetInt(used[i]), bucketSize).Float64()
+		hm.Cells = append(hm.Cells, heatmapCell{
+			Start:     start,
+			Fraction:  frac,
+			Allocated: allocUsed[i].Sign() > 0,
+			Active:    activeUsed[i].Sign() > 0,
+		})
+		start = addBigIntToIP(start, step)
+	}
+	return hm, nil
+}
+
+// cellGlyph picks the text character for a cell.
+func (hm *heatmap) cellGlyph(c heatmapCell) byte {
+	if hm.ChildrenShift == 0 {
+		switch {
+		case c.Allocated:
+			return '#'
+		case c.Active:
+			return 'o'
+		default:
+			return '.'
+		}
+	}
+	switch {
+	case c.Fraction == 0:
+		return '.'
+	case c.Fraction < 0.25:
+		return '-'
+	case c.Fraction < 0.5:
+		return '+'
+	case c.Fraction < 0.75:
+		return '*'
+	case c.Fraction < 1:
+		return 'x'
+	default:
+		return '#'
+	}
+}
+
+// rowWidth returns how many cells are drawn per row.
+func (hm *heatmap) rowWidth() int {
+	if len(hm.Cells) <= 256 {
+		return 16
+	}
+	return 64
+}
+
+// renderHeatmapText draws the heatmap as rows of characters labelled with the first
+// child prefix of each row.
+func renderHeatmapText(w io.Writer, hm *heatmap) {
+	fmt.Fprintf(w, "%s → /%d", hm.Parent, hm.ChildLen)
+	if hm.ChildrenShift > 0 {
+		fmt.Fprintf(w, " (%d children per cell)", 1<<hm.ChildrenShift)
+	}
+	fmt.Fprintln(w)
+	width := hm.rowWidth()
+	for row := 0; row < len(hm.Cells); row += width {
+		end := min(row+width, len(hm.Cells))
+		line := make([]byte, 0, width)
+		for _, c := range hm.Cells[row:end] {
+			line = append(line, hm.cellGlyph(c))
+		}
+		fmt.Fprintf(w, "%-40s %s\n", fmt.Sprintf("%s/%d", hm.Cells[row].Start, hm.ChildLen), line)
+	}
+	if hm.ChildrenShift == 0 {
+		fmt.Fprintln(w, "Legend: # allocated  o seen-active  . free")
+	} else {
+		fmt.Fprintln(w, "Legend: . 0%  - <25%  + <50%  * <75%  x <100%  # 100% of children in use")
+	}
+}
+
+// heatmapColor maps a cell to an SVG fill colour.
+func (hm *heatmap) heatmapColor(c heatmapCell) string {
+	if hm.ChildrenShift == 0 {
+		switch {
+		case c.Allocated:
+			return "#1f77b4"
+		case c.Active:
+			return "#ff7f0e"
+		default:
+			return "#eeeeee"
+		}
+	}
+	if c.Fraction == 0 {
+		return "#eeeeee"
+	}
+	// Blend from light to dark blue as usage rises.
+	r := int(198 - 167*c.Fraction)
+	g := int(219 - 100*c.Fraction)
+	b := int(239 - 59*c.Fraction)
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// renderHeatmapSVG draws the heatmap as an SVG grid, with each cell's prefix and
+// usage in a tooltip.
+func renderHeatmapSVG(w io.Writer, hm *heatmap) {
+	const size, gap, margin = 12, 1, 20
+	width := hm.rowWidth()
+	rows := (len(hm.Cells) + width - 1) / width
+	svgW := margin*2 + width*(size+gap)
+	svgH := margin*2 + rows*(size+gap)
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\">\n", svgW, svgH+margin)
+	fmt.Fprintf(w, "  <text x=\"%d\" y=\"%d\" font-family=\"monospace\" font-size=\"12\">%s → /%d</text>\n", margin, margin-6, hm.Parent, hm.ChildLen)
+	for i, c := range hm.Cells {
+		x := margin + (i%width)*(size+gap)
+		y := margin + (i/width)*(size+gap)
+		title := fmt.Sprintf("%s/%d", c.Start, hm.ChildLen)
+		if hm.ChildrenShift > 0 {
+			title += fmt.Sprintf(" (+%d): %.1f%% used", (1<<hm.ChildrenShift)-1, c.Fraction*100)
+		} else if c.Allocated {
+			title += ": allocated"
+		} else if c.Active {
+			title += ": seen-active"
+		}
+		fmt.Fprintf(w, "  <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"><title>%s</title></rect>\n",
+			x, y, size, size, hm.heatmapColor(c), title)
+	}
+	fmt.Fprintln(w, "</svg>")
+}
+
+// renderHeatmap reads the usage file and writes a text heatmap to stdout, or an SVG
+// heatmap when outputFile ends in .svg.
+func renderHeatmap(prefix string, newPrefixLength int, usageFile string, outputFile string) {
+	entries, err := readPrefixFile(usageFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	hm, err := subnetHeatmap(prefix, newPrefixLength, entries, heatmapMaxCells)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFile == "" {
+		renderHeatmapText(os.Stdout, hm)
+		return
+	}
+	f, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if strings.HasSuffix(strings.ToLower(outputFile), ".svg") {
+		renderHeatmapSVG(f, hm)
+	} else {
+		renderHeatmapText(f, hm)
+	}
+	statusf("Heatmap saved to %s\n", outputFile)
+}
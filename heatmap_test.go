@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSubnetHeatmap(t *testing.T) {
+	entries, err := readPrefixEntries(strings.NewReader(`
+2001:db8::/50          allocated first quarter
+2001:db8:0:c000::1     host seen in the last quarter
+2001:db8:1::/48        outside the parent
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("one cell per child", func(t *testing.T) {
+		hm, err := subnetHeatmap("2001:db8::/48", 52, entries, heatmapMaxCells)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(hm.Cells) != 16 || hm.ChildrenShift != 0 {
+			t.Fatalf("expected 16 unbucketed cells, got %d (shift %d)", len(hm.Cells), hm.ChildrenShift)
+		}
+		var glyphs []byte
+		for _, c := range hm.Cells {
+			glyphs = append(glyphs, hm.cellGlyph(c))
+		}
+		if got := string(glyphs); got != "####........o..." {
+			t.Errorf("expected ####........o..., got %s", got)
+		}
+		if hm.Cells[12].Start.String() != "2001:db8:0:c000::" {
+			t.Errorf("unexpected cell start %s", hm.Cells[12].Start)
+		}
+	})
+
+	t.Run("bucketed cells", func(t *testing.T) {
+		hm, err := subnetHeatmap("2001:db8::/48", 64, entries, 16)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(hm.Cells) != 16 || hm.ChildrenShift != 12 {
+			t.Fatalf("expected 16 cells of 4096 children, got %d (shift %d)", len(hm.Cells), hm.ChildrenShift)
+		}
+		if hm.Cells[0].Fraction != 1 {
+			t.Errorf("expected first cell fully used, got %v", hm.Cells[0].Fraction)
+		}
+		if f := hm.Cells[12].Fraction; f <= 0 || f >= 0.25 {
+			t.Errorf("expected cell 12 lightly used, got %v", f)
+		}
+		if hm.Cells[5].Fraction != 0 {
+			t.Errorf("expected cell 5 free, got %v", hm.Cells[5].Fraction)
+		}
+	})
+
+	t.Run("SVG output", func(t *testing.T) {
+		hm, _ := subnetHeatmap("2001:db8::/48", 52, entries, heatmapMaxCells)
+		var buf bytes.Buffer
+		renderHeatmapSVG(&buf, hm)
+		if got := strings.Count(buf.String(), "<rect "); got != 16 {
+			t.Errorf("expected 16 rects, got %d", got)
+		}
+		if !strings.Contains(buf.String(), "2001:db8:0:c000::/52: seen-active") {
+			t.Errorf("expected seen-active tooltip in SVG output")
+		}
+	})
+
+	t.Run("invalid length", func(t *testing.T) {
+		if _, err := subnetHeatmap("2001:db8::/48", 48, entries, heatmapMaxCells); err == nil {
+			t.Errorf("expected error for non-increasing prefix length")
+		}
+	})
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"net/netip"
+	"slices"
+)
+
+// prefixOverlap is address space two prefix lists share, with the most specific
+// entry of each list holding it.
+type prefixOverlap struct {
+	Prefix string         `json:"prefix"`
+	A      *planDiffEntry `json:"a"`
+	B      *planDiffEntry `json:"b"`
+}
+
+// intersectResult is the JSON form of an intersection.
+type intersectResult struct {
+	A        string          `json:"a"`
+	B        string          `json:"b"`
+	Overlaps []prefixOverlap `json:"overlaps"`
+}
+
+// intersectPrefixLists returns the address space in both lists, in address
+// order. Two prefixes either nest or are disjoint, so where an entry of one
+// list lies inside an entry of the other, the inner one is shared; it is left
+// out when it lies inside a shared prefix already reported.
+func intersectPrefixLists(a, b []prefixEntry) []prefixOverlap {
+	type side struct {
+		e    prefixEntry
+		inA  bool
+		pref netip.Prefix
+	}
+	var all []side
+	for _, e := range a {
+		all = append(all, side{e, true, entryPrefix(e)})
+	}
+	for _, e := range b {
+		all = append(all, side{e, false, entryPrefix(e)})
+	}
+	// In address order with shorter prefixes first, an entry follows every
+	// entry containing it.
+	slices.SortStableFunc(all, func(x, y side) int {
+		if c := x.pref.Addr().Compare(y.pref.Addr()); c != 0 {
+			return c
+		}
+		return cmp.Compare(x.pref.Bits(), y.pref.Bits())
+	})
+
+	var overlaps []prefixOverlap
+	var last netip.Prefix   // the last shared prefix reported
+	var lastInA bool        // whether it was reported for an entry of a
+	var openA, openB []side // entries of each list containing the current one
+	pop := func(open []side, p netip.Prefix) []side {
+		for len(open) > 0 && !open[len(open)-1].pref.Contains(p.Addr()) {
+			open = open[:len(open)-1]
+		}
+		return open
+	}
+	for _, s := range all {
+		openA, openB = pop(openA, s.pref), pop(openB, s.pref)
+		other := openB
+		if !s.inA {
+			other = openA
+		}
+		switch {
+		case len(other) == 0:
+		case s.pref == last && s.inA != lastInA:
+			// The same prefix is in both lists: it is the most specific
+			// entry of its list holding the prefix just reported.
+			if o := &overlaps[len(overlaps)-1]; s.inA {
+				o.A = newPlanDiffEntry(s.e)
+			} else {
+				o.B = newPlanDiffEntry(s.e)
+			}
+		case !last.IsValid() || last.Bits() > s.pref.Bits() || !last.Contains(s.pref.Addr()):
+			o := prefixOverlap{Prefix: s.pref.String(), A: newPlanDiffEntry(s.e), B: newPlanDiffEntry(other[len(other)-1].e)}
+			if !s.inA {
+				o.A, o.B = o.B, o.A
+			}
+			overlaps = append(overlaps, o)
+			last, lastInA = s.pref, s.inA
+		}
+		if s.inA {
+			openA = append(openA, s)
+		} else {
+			openB = append(openB, s)
+		}
+	}
+	return overlaps
+}
+
+// runIntersect prints the address space two prefix lists share, with the
+// entries of each that hold it.
+func runIntersect(pathA, pathB string) {
+	a, err := readPrefixFile(pathA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := readPrefixFile(pathB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	result := intersectResult{A: pathA, B: pathB, Overlaps: intersectPrefixLists(a, b)}
+	if result.Overlaps == nil {
+		result.Overlaps = []prefixOverlap{}
+	}
+	writeResult(result, func() {
+		name := func(e *planDiffEntry) string {
+			if e.Label == "" {
+				return fmt.Sprintf("%s (line %d)", e.Prefix, e.Line)
+			}
+			return fmt.Sprintf("%s %s (line %d)", e.Prefix, e.Label, e.Line)
+		}
+		for _, o := range result.Overlaps {
+			fmt.Printf("%-44s %s & %s\n", o.Prefix, name(o.A), name(o.B))
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIntersectPrefixLists(t *testing.T) {
+	a, err := readPrefixEntries(strings.NewReader(`2001:db8::/32 announced
+3fff:0:1::/48 site
+3fff:0:9::/48 lab
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := readPrefixEntries(strings.NewReader(`2001:db8:1::/48 internal
+2001:db8:1:5::/64 inside-internal
+3fff::/40 region
+3fff:0:1::/48 site-again
+4000::/16 elsewhere
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range intersectPrefixLists(a, b) {
+		got = append(got, o.Prefix+" "+o.A.Label+"&"+o.B.Label)
+	}
+	// 2001:db8:1:5::/64 is inside 2001:db8:1::/48, already reported.
+	want := "2001:db8:1::/48 announced&internal|3fff:0:1::/48 site&site-again|3fff:0:9::/48 lab&region"
+	if strings.Join(got, "|") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+	if o := intersectPrefixLists(a, nil); len(o) != 0 {
+		t.Errorf("intersection with an empty list: got %v", o)
+	}
+}
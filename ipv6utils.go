@@ -4,72 +4,146 @@
 package main
 
 import (
-	"bytes"
 	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"net"
+	"net/netip"
 	"os"
 	"slices"
-	"sort"
 	"strings"
+	"time"
 )
 
 // version is set at build time via -ldflags "-X main.version=<tag>".
 var version = "dev"
 
+// stableOutput suppresses progress and status lines and log timestamps, so that
+// identical input always yields byte-identical output. Set by -stable.
+var stableOutput bool
+
+// statusf prints a progress or status line unless -stable is set, or results
+// are structured or templated, which the line would corrupt.
+func statusf(format string, a ...any) {
+	if !stableOutput && outputFormat == "text" && outputTemplate == nil {
+		fmt.Printf(format, a...)
+	}
+}
+
+// applyStableOutput drops log timestamps once -stable has been parsed.
+func applyStableOutput() {
+	if stableOutput {
+		log.SetFlags(0)
+	}
+}
+
 // isNibbleAligned checks whether the prefix length is on a nibble boundary (multiple of 4).
 func isNibbleAligned(prefixLength int) bool {
 	return prefixLength%4 == 0
 }
 
+// Modes of -nibble-align.
+const (
+	nibbleRoundUp   = "round-up"   // a /57 becomes a /60
+	nibbleRoundDown = "round-down" // a /57 becomes a /56
+	nibbleStrict    = "strict"     // a /57 is an error
+)
+
+// alignPrefixLength applies a -nibble-align mode to a new prefix length. An
+// empty mode leaves the length as it is, for the generator to warn about.
+func alignPrefixLength(prefixLength int, mode string) (int, error) {
+	aligned := prefixLength
+	switch mode {
+	case "":
+		return prefixLength, nil
+	case nibbleRoundUp:
+		aligned = (prefixLength + 3) / 4 * 4
+	case nibbleRoundDown:
+		aligned = prefixLength / 4 * 4
+	case nibbleStrict:
+		if !isNibbleAligned(prefixLength) {
+			return 0, fmt.Errorf("new prefix length /%d is not on a nibble boundary", prefixLength)
+		}
+	default:
+		return 0, fmt.Errorf("unknown -nibble-align %q: want round-up, round-down, or strict", mode)
+	}
+	if aligned != prefixLength {
+		statusf("Rounded /%d to /%d\n", prefixLength, aligned)
+	}
+	return aligned, nil
+}
+
 // countSubnets calculates how many subnets would be generated from the original prefix to the new length.
-func countSubnets(prefix string, newPrefixLength int) (int, error) {
-	_, ipnet, err := net.ParseCIDR(prefix)
+// The count is exact for any difference in length, up to 2^128.
+func countSubnets(prefix string, newPrefixLength int) (*big.Int, error) {
+	ipnet, err := parseIPv6Prefix(prefix)
 	if err != nil {
-		return 0, fmt.Errorf("invalid prefix: %v", err)
+		return nil, fmt.Errorf("invalid prefix: %v", err)
 	}
 	currentPrefixLength, _ := ipnet.Mask.Size()
 	if newPrefixLength <= currentPrefixLength {
-		return 0, fmt.Errorf("new prefix length must be larger than the current prefix length")
+		return nil, fmt.Errorf("new prefix length must be larger than the current prefix length")
+	}
+	if newPrefixLength > 128 {
+		return nil, fmt.Errorf("new prefix length must be at most 128")
 	}
-	return 1 << (newPrefixLength - currentPrefixLength), nil
+	return new(big.Int).Lsh(big.NewInt(1), uint(newPrefixLength-currentPrefixLength)), nil
+}
+
+// formatSubnetCount prints a subnet count, adding the power of two and an
+// approximation once it is too long to read at a glance, e.g.
+// "9223372036854775808 = 2^63 (9.2e18)".
+func formatSubnetCount(count *big.Int) string {
+	bits := count.BitLen() - 1
+	if bits < 20 {
+		return count.String()
+	}
+	approx := strings.Replace(new(big.Float).SetInt(count).Text('e', 1), "e+", "e", 1)
+	return fmt.Sprintf("%s = 2^%d (%s)", count, bits, approx)
 }
 
-// generateSubnets produces subnets of a specified length from a base prefix with optional output limiting.
-func generateSubnets(prefix string, newPrefixLength int, limit int) ([]string, error) {
-	_, ipnet, err := net.ParseCIDR(prefix)
+// streamSubnets passes each subnet to emit, in address order or the given
+// order, as it is generated, so memory use does not grow with the number of
+// subnets. It begins at the 0-based index start (nil for the first subnet),
+// stops after limit subnets (0 for no limit) or at the first error from emit,
+// and returns how many subnets were emitted.
+func streamSubnets(prefix string, newPrefixLength int, start *big.Int, order subnetOrder, limit int, exclude []prefixEntry, emit func(netip.Prefix) error) (int, error) {
+	parent, err := parseSubnetParent(prefix)
 	if err != nil {
-		return nil, fmt.Errorf("invalid prefix: %v", err)
+		return 0, err
 	}
 	if !isNibbleAligned(newPrefixLength) {
 		log.Println("Warning: new prefix length is not on a nibble boundary")
 	}
-	currentPrefixLength, _ := ipnet.Mask.Size()
+	currentPrefixLength := parent.Bits()
 	if newPrefixLength <= currentPrefixLength {
-		return nil, fmt.Errorf("new prefix length must be larger than the current prefix length")
+		return 0, fmt.Errorf("new prefix length must be larger than the current prefix length")
+	}
+	if newPrefixLength > 128 {
+		return 0, fmt.Errorf("new prefix length must be at most 128")
 	}
-	subnetCount := 1 << (newPrefixLength - currentPrefixLength)
-	fmt.Printf("Generating %d prefixes...\n", subnetCount)
-	subnets := []string{}
-	prefixIP := ipnet.IP.Mask(ipnet.Mask)
-	increment := big.NewInt(1)
-	increment.Lsh(increment, uint(128-newPrefixLength))
-	for i := 0; i < subnetCount; i++ {
-		subnets = append(subnets, fmt.Sprintf("%s/%d", prefixIP, newPrefixLength))
-		prefixIP = addBigIntToIP(prefixIP, increment)
-		if limit > 0 && len(subnets) >= limit {
-			break
+	subnetCount := new(big.Int).Lsh(big.NewInt(1), uint(newPrefixLength-currentPrefixLength))
+	statusf("Generating %s prefixes...\n", formatSubnetCount(subnetCount))
+	emitted := 0
+	for subnet := range limitSubnets(orderedSubnetSeq(parent, newPrefixLength, start, exclude, order), limit) {
+		if err := emit(subnet); err != nil {
+			return emitted, err
 		}
+		emitted++
 	}
-	sort.Slice(subnets, func(i, j int) bool {
-		ip1 := net.ParseIP(strings.Split(subnets[i], "/")[0])
-		ip2 := net.ParseIP(strings.Split(subnets[j], "/")[0])
-		return bytes.Compare(ip1, ip2) < 0
-	})
-	return subnets, nil
+	return emitted, nil
+}
+
+// overlapsAny reports whether subnet overlaps any of the entries.
+func overlapsAny(subnet *net.IPNet, entries []prefixEntry) bool {
+	for _, e := range entries {
+		if prefixesOverlap(subnet, e.Net) {
+			return true
+		}
+	}
+	return false
 }
 
 // addBigIntToIP adds a big integer to an IPv6 address and returns the resulting IP.
@@ -85,40 +159,75 @@ func addBigIntToIP(ip net.IP, value *big.Int) net.IP {
 	return newIP
 }
 
-// synthesizedToIPv4 converts an RFC 6052 synthesized IPv6 address to its embedded IPv4 address.
-func synthesizedToIPv4(synthesizedAddr string) (string, error) {
+// ipToBigInt returns the 128-bit integer value of an IPv6 address.
+func ipToBigInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+// bigIntToIP converts a 128-bit integer back into an IPv6 address.
+func bigIntToIP(value *big.Int) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	value.FillBytes(ip)
+	return ip
+}
+
+// synthesizedToIPv4 converts an RFC 6052 synthesized IPv6 address to its embedded
+// IPv4 address, and returns the NAT64 prefix it was under. With a prefix the
+// address must lie within it, and is decoded with its length, or prefixLength
+// if not 0; with neither, the length is detected with detectNAT64Length.
+func synthesizedToIPv4(synthesizedAddr string, prefix string, prefixLength int) (string, netip.Prefix, error) {
 	ip := net.ParseIP(synthesizedAddr)
 	if ip == nil || ip.To16() == nil {
-		return "", fmt.Errorf("invalid RFC 6052 synthesized address")
+		return "", netip.Prefix{}, fmt.Errorf("invalid RFC 6052 synthesized address: %v", diagnoseIPv6(synthesizedAddr))
+	}
+	a := netip.AddrFrom16([16]byte(ip.To16()))
+	if prefix != "" {
+		p, err := parseNAT64Prefix(prefix, prefixLength)
+		if err != nil {
+			return "", netip.Prefix{}, err
+		}
+		if !p.Contains(a) {
+			return "", netip.Prefix{}, fmt.Errorf("%s is not within the NAT64 prefix %s", synthesizedAddr, p)
+		}
+		prefixLength = p.Bits()
 	}
-	ipv4 := ip[12:16]
-	if len(ipv4) != 4 {
-		return "", fmt.Errorf("not a valid synthesized IPv6 address containing IPv4")
+	if prefixLength == 0 {
+		prefixLength = detectNAT64Length(a)
+	}
+	v4, p, err := extractNAT64(a, prefixLength)
+	if err != nil {
+		return "", netip.Prefix{}, err
 	}
-	return fmt.Sprintf("%d.%d.%d.%d", ipv4[0], ipv4[1], ipv4[2], ipv4[3]), nil
+	return v4.String(), p, nil
 }
 
-// ipv4ToSynthesized converts an IPv4 address into an RFC 6052 synthesized IPv6 address using the provided prefix.
-func ipv4ToSynthesized(ipv4Addr string, prefix string) (string, error) {
+// ipv4ToSynthesized converts an IPv4 address into an RFC 6052 synthesized IPv6
+// address under the provided prefix, a /96 unless it has a length or
+// prefixLength is not 0, and returns the prefix as used.
+func ipv4ToSynthesized(ipv4Addr string, prefix string, prefixLength int) (string, netip.Prefix, error) {
 	ip := net.ParseIP(ipv4Addr)
 	if ip == nil || ip.To4() == nil {
-		return "", fmt.Errorf("invalid IPv4 address")
+		return "", netip.Prefix{}, fmt.Errorf("invalid IPv4 address")
 	}
-	prefixIP := net.ParseIP(prefix)
-	if prefixIP == nil {
-		return "", fmt.Errorf("invalid IPv6 prefix")
+	if net.ParseIP(strings.Split(prefix, "/")[0]) == nil {
+		return "", netip.Prefix{}, fmt.Errorf("invalid IPv6 prefix: %v", diagnoseIPv6(prefix))
 	}
-	ipv6Addr := make(net.IP, net.IPv6len)
-	copy(ipv6Addr, prefixIP.To16())
-	copy(ipv6Addr[12:], ip.To4())
-	return ipv6Addr.String(), nil
+	p, err := parseNAT64Prefix(prefix, prefixLength)
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	a, err := synthesizeNAT64(p, netip.AddrFrom4([4]byte(ip.To4())))
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	return a.String(), p, nil
 }
 
 // decodeMACFromSLAAC extracts a MAC address from a given SLAAC IPv6 address.
 func decodeMACFromSLAAC(ipv6 string) (string, error) {
 	ip := net.ParseIP(ipv6)
 	if ip == nil || ip.To16() == nil {
-		return "", fmt.Errorf("invalid SLAAC IPv6 address")
+		return "", fmt.Errorf("invalid SLAAC IPv6 address: %v", diagnoseIPv6(ipv6))
 	}
 	interfaceID := ip[8:]
 	if len(interfaceID) < 8 || interfaceID[3] != 0xFF || interfaceID[4] != 0xFE {
@@ -179,7 +288,7 @@ func linkLocalToMAC(ipv6 string) (string, error) {
 func ipv6ToArpa(ipv6 string, prefixLength int) (string, error) {
 	ip := net.ParseIP(ipv6)
 	if ip == nil || ip.To16() == nil {
-		return "", fmt.Errorf("Invalid IP address: %s", ipv6)
+		return "", fmt.Errorf("Invalid IP address: %v", diagnoseIPv6(ipv6))
 	}
 	if prefixLength < 0 || prefixLength > 128 {
 		return "", fmt.Errorf("Invalid prefix length: %d", prefixLength)
@@ -225,7 +334,7 @@ func parseIPv6WithOptionalPrefix(input string) (net.IP, int, error) {
 
 	ip := net.ParseIP(addr)
 	if ip == nil {
-		return nil, -1, fmt.Errorf("invalid IPv6 address: %s", addr)
+		return nil, -1, fmt.Errorf("invalid IPv6 address: %v", diagnoseIPv6(addr))
 	}
 	ip = ip.To16()
 	if ip == nil {
@@ -248,6 +357,12 @@ func compressIPv6(ip net.IP) string {
 	return ip.String()
 }
 
+// ipv6String returns the RFC 5952 text of an address, keeping IPv4-mapped addresses
+// in IPv6 form (::ffff:192.0.2.1) where net.IP.String would print bare IPv4.
+func ipv6String(ip net.IP) string {
+	return netip.AddrFrom16([16]byte(ip.To16())).String()
+}
+
 // uppercaseIPv6 returns the compressed form in uppercase.
 func uppercaseIPv6(ip net.IP) string {
 	return strings.ToUpper(ip.String())
@@ -277,96 +392,7 @@ func binaryIPv6(ip net.IP) string {
 
 // classifyIPv6 returns a human-readable string describing the address type.
 func classifyIPv6(ip net.IP) string {
-	b := ip.To16()
-
-	// Unspecified (::)
-	if ip.Equal(net.IPv6unspecified) {
-		return "Unspecified (::)"
-	}
-	// Loopback (::1)
-	if ip.Equal(net.IPv6loopback) {
-		return "Loopback (::1)"
-	}
-	// IPv4-Mapped (::ffff:0:0/96)
-	allZero := true
-	for i := 0; i < 10; i++ {
-		if b[i] != 0 {
-			allZero = false
-			break
-		}
-	}
-	if allZero && b[10] == 0xff && b[11] == 0xff {
-		return "IPv4-Mapped (::ffff:0:0/96)"
-	}
-	// NAT64 Well-Known Prefix (64:ff9b::/96)
-	if b[0] == 0x00 && b[1] == 0x64 && b[2] == 0xff && b[3] == 0x9b &&
-		b[4] == 0x00 && b[5] == 0x00 && b[6] == 0x00 && b[7] == 0x00 &&
-		b[8] == 0x00 && b[9] == 0x00 && b[10] == 0x00 && b[11] == 0x00 {
-		return "NAT64 Well-Known Prefix (64:ff9b::/96)"
-	}
-	// NAT64 Network-Specific (64:ff9b:1::/48)
-	if b[0] == 0x00 && b[1] == 0x64 && b[2] == 0xff && b[3] == 0x9b &&
-		b[4] == 0x00 && b[5] == 0x01 {
-		return "NAT64 Network-Specific (64:ff9b:1::/48)"
-	}
-	// Discard-Only (100::/64)
-	if b[0] == 0x01 && b[1] == 0x00 &&
-		b[2] == 0x00 && b[3] == 0x00 && b[4] == 0x00 && b[5] == 0x00 &&
-		b[6] == 0x00 && b[7] == 0x00 {
-		return "Discard-Only (100::/64)"
-	}
-	// Teredo (2001:0000::/32)
-	if b[0] == 0x20 && b[1] == 0x01 && b[2] == 0x00 && b[3] == 0x00 {
-		return "Teredo (2001:0000::/32)"
-	}
-	// Documentation (2001:db8::/32)
-	if b[0] == 0x20 && b[1] == 0x01 && b[2] == 0x0d && b[3] == 0xb8 {
-		return "Documentation (2001:db8::/32)"
-	}
-	// 6to4 (2002::/16)
-	if b[0] == 0x20 && b[1] == 0x02 {
-		return "6to4 (2002::/16)"
-	}
-	// Documentation (3fff::/20) per RFC 9637
-	if b[0] == 0x3f && b[1] == 0xff && b[2]&0xf0 == 0x00 {
-		return "Documentation (3fff::/20)"
-	}
-	// Unique Local Address (fc00::/7)
-	if b[0]&0xfe == 0xfc {
-		return "Unique Local Address (ULA, fc00::/7)"
-	}
-	// Link-Local (fe80::/10)
-	if b[0] == 0xfe && b[1]&0xc0 == 0x80 {
-		return "Link-Local (fe80::/10)"
-	}
-	// Multicast (ff00::/8) with scope
-	if b[0] == 0xff {
-		scope := b[1] & 0x0f
-		scopeStr := ""
-		switch scope {
-		case 0x01:
-			scopeStr = "Interface-Local"
-		case 0x02:
-			scopeStr = "Link-Local"
-		case 0x04:
-			scopeStr = "Admin-Local"
-		case 0x05:
-			scopeStr = "Site-Local"
-		case 0x08:
-			scopeStr = "Organization-Local"
-		case 0x0e:
-			scopeStr = "Global"
-		default:
-			scopeStr = fmt.Sprintf("Unknown (0x%02x)", scope)
-		}
-		return fmt.Sprintf("Multicast (ff00::/8), Scope: %s", scopeStr)
-	}
-	// Global Unicast (2000::/3)
-	if b[0]&0xe0 == 0x20 {
-		return "Global Unicast (2000::/3)"
-	}
-
-	return "Reserved / Unknown"
+	return classifyAddress(netip.AddrFrom16([16]byte(ip.To16()))).Description
 }
 
 // networkAddress returns the network address (host bits zeroed) for the given IP and prefix length.
@@ -471,46 +497,82 @@ func formatIPv6(input string) {
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	pfxSuffix := ""
+	arpa, err := ipv6ToArpa(ip.String(), 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f := addressFormats{
+		Expanded:     expandIPv6(ip),
+		Compressed:   compressIPv6(ip),
+		Uppercase:    uppercaseIPv6(ip),
+		URL:          urlIPv6(ip),
+		Dotted:       dottedIPv6(ip),
+		Binary:       binaryIPv6(ip),
+		ReverseDNS:   arpa,
+		Type:         classifyIPv6(ip),
+		IPv4InIPv6:   mixedNotation(ip),
+		Permutations: compressionPermutations(ip),
+	}
 	if prefixLen >= 0 {
-		pfxSuffix = fmt.Sprintf("/%d", prefixLen)
+		f.PrefixLength = &prefixLen
+		f.Network = expandIPv6(networkAddress(ip, prefixLen))
+		f.HostID = compressIPv6(hostSuffix(ip, prefixLen))
+		f.Last = expandIPv6(lastAddress(ip, prefixLen))
 	}
+	writeResult(f, func() { writeAddressFormats(f) })
+}
 
-	fmt.Printf("%-16s%s%s\n", "Expanded:", expandIPv6(ip), pfxSuffix)
-	fmt.Printf("%-16s%s%s\n", "Compressed:", compressIPv6(ip), pfxSuffix)
-	fmt.Printf("%-16s%s\n", "Uppercase:", uppercaseIPv6(ip))
-	fmt.Printf("%-16s%s\n", "URL format:", urlIPv6(ip))
-	fmt.Printf("%-16s%s\n", "Dotted:", dottedIPv6(ip))
-	fmt.Printf("%-16s%s\n", "Binary:", binaryIPv6(ip))
+// addressFormats holds every representation of an address that formatIPv6
+// shows. The network fields are set only when a prefix length was given.
+type addressFormats struct {
+	Expanded     string   `json:"expanded"`
+	Compressed   string   `json:"compressed"`
+	Uppercase    string   `json:"uppercase"`
+	URL          string   `json:"url"`
+	Dotted       string   `json:"dotted"`
+	Binary       string   `json:"binary"`
+	ReverseDNS   string   `json:"reverse_dns"`
+	Type         string   `json:"type"`
+	IPv4InIPv6   string   `json:"ipv4_in_ipv6,omitempty"`
+	PrefixLength *int     `json:"prefix_length,omitempty"`
+	Network      string   `json:"network,omitempty"`
+	HostID       string   `json:"host_id,omitempty"`
+	Last         string   `json:"last,omitempty"`
+	Permutations []string `json:"permutations"`
+}
 
-	arpa, err := ipv6ToArpa(ip.String(), 0)
-	if err != nil {
-		log.Fatal(err)
+// writeAddressFormats prints the representations as text.
+func writeAddressFormats(f addressFormats) {
+	pfxSuffix := ""
+	if f.PrefixLength != nil {
+		pfxSuffix = fmt.Sprintf("/%d", *f.PrefixLength)
 	}
-	fmt.Printf("%-16s%s\n", "Reverse DNS:", arpa)
-	fmt.Printf("%-16s%s\n", "Address Type:", classifyIPv6(ip))
 
-	if mixed := mixedNotation(ip); mixed != "" {
-		fmt.Printf("%-16s%s\n", "IPv4-in-IPv6:", mixed)
+	fmt.Printf("%-16s%s%s\n", "Expanded:", f.Expanded, pfxSuffix)
+	fmt.Printf("%-16s%s%s\n", "Compressed:", f.Compressed, pfxSuffix)
+	fmt.Printf("%-16s%s\n", "Uppercase:", f.Uppercase)
+	fmt.Printf("%-16s%s\n", "URL format:", f.URL)
+	fmt.Printf("%-16s%s\n", "Dotted:", f.Dotted)
+	fmt.Printf("%-16s%s\n", "Binary:", f.Binary)
+	fmt.Printf("%-16s%s\n", "Reverse DNS:", f.ReverseDNS)
+	fmt.Printf("%-16s%s\n", "Address Type:", f.Type)
+
+	if f.IPv4InIPv6 != "" {
+		fmt.Printf("%-16s%s\n", "IPv4-in-IPv6:", f.IPv4InIPv6)
 	}
 
-	if prefixLen >= 0 {
-		netAddr := networkAddress(ip, prefixLen)
-		lastAddr := lastAddress(ip, prefixLen)
-		hostID := hostSuffix(ip, prefixLen)
+	if f.PrefixLength != nil {
 		fmt.Println()
-		fmt.Printf("%-16s%s/%d\n", "Network:", expandIPv6(netAddr), prefixLen)
-		fmt.Printf("%-16s%s/%d\n", "Host ID:", compressIPv6(hostID), prefixLen)
-		fmt.Printf("%-16s%s -\n", "Network range:", expandIPv6(netAddr))
-		fmt.Printf("%-16s%s\n", "", expandIPv6(lastAddr))
+		fmt.Printf("%-16s%s%s\n", "Network:", f.Network, pfxSuffix)
+		fmt.Printf("%-16s%s%s\n", "Host ID:", f.HostID, pfxSuffix)
+		fmt.Printf("%-16s%s -\n", "Network range:", f.Network)
+		fmt.Printf("%-16s%s\n", "", f.Last)
 	}
 
-	perms := compressionPermutations(ip)
-	if len(perms) > 0 {
+	if len(f.Permutations) > 0 {
 		fmt.Println()
 		fmt.Println("Compression permutations:")
-		for _, p := range perms {
+		for _, p := range f.Permutations {
 			fmt.Printf("  %s\n", p)
 		}
 	}
@@ -520,25 +582,131 @@ func main() {
 	prefix := flag.String("prefix", "64:ff9b::", "IPv6 prefix for synthesis. (alias: -p)")
 	newPrefixLength := flag.Int("new-prefix-length", 40, "New prefix length for subnet allocation. (alias: -n)")
 	outputFile := flag.String("output", "", "File to save the output subnets. (alias: -o)")
-	source := flag.String("s", "", "Source address for conversion.")
-	macInput := flag.String("m", "", "SLAAC IPv6 address to decode MAC from.")
-	linkLocal := flag.String("local", "", "Link-local MAC or IPv6 to convert. (alias: -a)")
+	source := flag.String("s", "", "Source address for conversion (\"-\" for one per line of stdin).")
+	macInput := flag.String("m", "", "SLAAC IPv6 address to decode MAC from (\"-\" for one per line of stdin).")
+	linkLocal := flag.String("local", "", "Link-local MAC or IPv6 to convert (\"-\" for one per line of stdin). (alias: -a)")
 	nonWellKnownPrefix := flag.String("k", "64:ff9b::", "Non-well-known prefix for RFC 6052 conversion.")
 	limit := flag.Int("l", 0, "Limit the number of subnets displayed.")
 	countOnly := flag.Bool("count", false, "Display only the number of generated prefixes. (alias: -c)")
-	ip6arpa := flag.String("ip6.arpa", "", "Generate a reverse ip6.arpa name for an IPv6 address. Uses -new-prefix-length as zone context.")
+	nibbleAlign := flag.String("nibble-align", "", "Move a -new-prefix-length off a nibble boundary onto one: round-up (/57 to /60), round-down (/57 to /56), or strict to fail instead of warning.")
+	ip6arpa := flag.String("ip6.arpa", "", "Generate a reverse ip6.arpa name for an IPv6 address (\"-\" for one per line of stdin). Uses -new-prefix-length as zone context.")
 	format := flag.String("format", "", "Display all format representations of an IPv6 address. (alias: -f)")
 	showVersion := flag.Bool("version", false, "Print version and exit. (alias: -v)")
+	flag.BoolVar(&stableOutput, "stable", false, "Deterministic output for version control: no progress lines or log timestamps.")
+	flag.IntVar(&conversionWorkers, "workers", 1, "Goroutines converting the lines of stdin at once for -s, -m, -local, and -ip6.arpa given \"-\"; the output keeps the input's order.")
+	flag.BoolVar(&explodedOutput, "exploded", false, "Write generated subnets fully expanded, with leading zeros, as 2001:0db8:0000:...")
+	flag.StringVar(&outputFormat, "output-format", "text", "Result format for subnets, counts, conversions, -format, -neighbors, and -stats: text, json, or ndjson; subnets also take csv, yaml, sql, sqlite (into the -o database), and xlsx, and -mac-file takes csv. Operations writing only text reject it.")
+	nameTemplate := flag.String("name-template", "", "Go text/template naming each generated subnet, e.g. 'site-{{.Index}}'; the name is carried into every output format.")
+	namesFile := flag.String("names", "", "File of subnet names, one per line, naming the subnets from index 0; subnets past the end fall back to -name-template.")
+	levels := flag.String("levels", "", "Comma-separated prefix lengths between the parent and -n that get their own sheet in xlsx output, e.g. 48,56.")
+	compress := flag.Bool("compress", false, "Gzip the generated subnets; implied when -o ends in .gz.")
+	flag.StringVar(&outputTemplateText, "template", "", "Go text/template for each generated subnet or conversion result, e.g. '{{.Index}} {{.Prefix}} vlan{{.Index}}'.")
+	columns := flag.String("columns", defaultSubnetColumns, "Comma-separated CSV columns for generated subnets: index, prefix, network, length, parent, last, gateway, name, nibble_aligned, reserved.")
+	arpaStatsPrefix := flag.String("arpa-stats", "", "Report how much of a prefix's ip6.arpa tree is populated. Requires -zone.")
+	zoneFiles := flag.String("zone", "", "Comma-separated reverse zone files for -arpa-stats.")
+	vanity := flag.String("vanity", "", "Comma-separated hex words to search for in child subnet IDs. Uses -p and -n.")
+	vanityGroup := flag.Int("vanity-group", 0, "Place vanity words right-aligned in this 16-bit group (1-8). 0 tries every position.")
+	vanityBudget := flag.Int("vanity-budget", 1000, "Maximum number of vanity candidates to produce.")
+	vanityIIDs := flag.String("vanity-iid", "", "Comma-separated hex words for interface IDs, combined with the /64 given by -p.")
+	vanityASCII := flag.Bool("vanity-ascii", false, "Encode -vanity-iid words as ASCII bytes instead of hex.")
+	heatmapFile := flag.String("heatmap", "", "Render a usage heatmap of -p split into -n children from a file of allocated prefixes and active addresses.")
+	staleFile := flag.String("stale", "", "Report allocations from this prefix list that have not been seen active. Requires -seen.")
+	seenFiles := flag.String("seen", "", "Comma-separated activity files (ADDRESS TIMESTAMP per line) for -stale.")
+	staleDays := flag.Int("stale-days", 90, "Days without activity before -stale reports an allocation.")
+	fixFile := flag.String("fix", "", "Correct typos in a file of addresses/prefixes (\"-\" for stdin), writing canonical values and reporting changes on stderr.")
+	iidScoreInput := flag.String("iid-score", "", "Score interface-ID predictability of an address, or of every address in a file.")
+	macFile := flag.String("mac-file", "", "Convert every MAC in a file (e.g. a switch MAC table export, \"-\" for stdin) to link-local and SLAAC addresses.")
+	slaacPrefixes := flag.String("slaac-prefix", "", "Comma-separated /64 prefixes to derive SLAAC addresses in for -mac-file.")
+	flag.StringVar(&outputFormat, "mac-output", "text", "Alias for -output-format with -mac-file.")
+	dhcpd6Leases := flag.String("dhcpd6-leases", "", "Convert an ISC dhcpd6.leases file to a prefix list of active leased addresses and delegated prefixes.")
+	neighbors := flag.String("neighbors", "", "Show the previous and next sibling of a prefix, its parent, and its position among the parent's children.")
+	neighborsParent := flag.Int("neighbors-parent", -1, "Parent prefix length for -neighbors (default: the nearest nibble boundary above the prefix).")
+	gcPlan := flag.String("gc", "", "Release expired allocations (expires=DATE in the label) by removing them from this plan file in place.")
+	gcDryRun := flag.Bool("gc-dry-run", false, "List what -gc would release without changing the plan.")
+	updateData := flag.String("update-data", "", "Download fresh copies of cached datasets: all, or comma-separated names (rir, oui, iana-special, bogons).")
+	dataStatus := flag.Bool("data-status", false, "Show the cache directory and the age of each cached dataset.")
+	flag.StringVar(&cacheRoot, "cache-dir", os.Getenv("IPV6UTILS_CACHE_DIR"), "Directory for downloaded datasets (default: ipv6utils under the user cache directory; env IPV6UTILS_CACHE_DIR).")
+	watch := flag.Bool("watch", false, "Rerun the command whenever one of its input files (any flag naming an existing file) changes.")
+	watchInterval := flag.Duration("watch-interval", time.Second, "How often -watch checks its input files for changes.")
+	manifest := flag.Bool("manifest", false, "Write OUTPUT.manifest.json next to the -o file with its SHA-256, size, line count, and the flags used.")
+	schemeFile := flag.String("scheme", "", "YAML bit-field scheme (parent prefix and named subnet-ID fields) for -scheme-encode and -scheme-decode.")
+	schemeEncode := flag.String("scheme-encode", "", "Build prefixes from FIELD=VALUE,... using -scheme; values may be names, numbers, or ranges (site=1-4).")
+	schemeDecode := flag.String("scheme-decode", "", "Comma-separated addresses or prefixes to decode into -scheme field values.")
+	vlanList := flag.String("vlan", "", "Comma-separated VLAN IDs and ranges (e.g. 10,20,100-110) to map to child subnets of -p at -n.")
+	vlanDecode := flag.String("vlan-decode", "", "Comma-separated addresses or prefixes to decode back to VLAN IDs, using -p and -n.")
+	vlanEncoding := flag.String("vlan-encoding", "decimal", "How -vlan and -vlan-decode embed VLAN IDs: decimal (VLAN 120 -> :120:) or hex (VLAN 120 -> :78:).")
+	exportFormat := flag.String("export", "", "Render the annotated -plan (gw=, dns=, ntp=, vlan= per subnet) as kea, radvd, ios, or terraform configuration, or with an ipv6utils-export-FORMAT plugin.")
+	mergeFiles := flag.String("merge", "", "Merge two plan files (FIRST,SECOND prefix lists) into one, reporting overlaps and duplicate names. Writes to -o or stdout.")
+	mergeStrategy := flag.String("merge-strategy", "fail", "How -merge resolves conflicts: first, second, or fail.")
+	routerConfigs := flag.String("router-config", "", "Comma-separated saved IOS, FRR, or Junos configurations to list interface subnets and static routes from, as a prefix list.")
+	raConfigs := flag.String("ra-config", "", "Comma-separated radvd.conf or systemd-networkd .network files to list advertised prefixes and routes from, as a prefix list.")
+	keaLeases := flag.String("kea-leases", "", "Read a Kea lease6 memfile CSV or lease6-get-all JSON export; prints a prefix list, or reconciles with -plan.")
+	leasePlan := flag.String("plan", "", "Prefix list of planned ranges (or plugin:NAME[:ARG] to read one from an ipv6utils-plan-NAME plugin): input to -export, and with -dhcpd6-leases or -kea-leases, report each lease's range and fail on leases outside the plan.")
+	leasesAll := flag.Bool("leases-all", false, "Include expired, released, and abandoned leases, with their state.")
+	startIndex := flag.String("start-index", "", "Begin subnet generation at this 0-based subnet index, for paging through a large expansion with -l.")
+	reserveFirst := flag.Int("reserve-first", 0, "Label the first N generated subnets RESERVED, e.g. for infrastructure or anycast.")
+	reserveLast := flag.Int("reserve-last", 0, "Label the last N subnets of the expansion RESERVED.")
+	reserveSkip := flag.Bool("reserve-skip", false, "Leave the -reserve-first and -reserve-last subnets out instead of labelling them.")
+	sampleCount := flag.Int("sample", 0, "Pick this many subnets of -n at random from -p instead of listing them in order, without enumerating the expansion.")
+	sampleSeed := flag.Int64("sample-seed", 0, "Random seed for -sample and -strategy random, to repeat a run; required with -stable. 0 picks a new seed and prints it on stderr.")
+	strategy := flag.String("strategy", "", "Order to hand out subnets in, per RFC 3531: leftmost (spread across the parent), rightmost (address order, the default), center, or random.")
+	resumeFile := flag.String("resume", "", "Cursor file for resumable subnet generation to -o: continue after the last subnet it records, and checkpoint to it as subnets are written.")
+	startAt := flag.String("start-at", "", "Begin subnet generation at the subnet holding this prefix or address.")
+	excludeFile := flag.String("exclude", "", "Prefix list of ranges (e.g. from -dhcpd6-leases) to skip when generating subnets; entries past their expires= date are not skipped.")
+	radiusFile := flag.String("radius", "", "Generate RADIUS Framed-IPv6-Prefix/Delegated-IPv6-Prefix entries from a subscriber file (USER FRAMED DELEGATED per line).")
+	radiusFormat := flag.String("radius-format", "users", "Output format for -radius: users (FreeRADIUS users file) or sql (radreply INSERTs).")
+	statsFile := flag.String("stats", "", "Summarize a large address list (\"-\" for stdin): types, top /48s and /64s, interface-ID styles, unique prefixes.")
+	statsTop := flag.Int("stats-top", 10, "Number of covering /48s and /64s listed by -stats.")
+	flag.StringVar(&outputFormat, "stats-output", "text", "Alias for -output-format with -stats.")
+	doctor := flag.Bool("doctor", false, "Check this host's IPv6 health: addresses, default route, RAs, temporary addresses, DNS64, reachability.")
+	discoverIface := flag.String("discover", "", "Find live IPv6 neighbors on an interface with all-nodes pings and mDNS (ping needs root).")
+	discoverTimeout := flag.Duration("discover-timeout", 3*time.Second, "How long -discover waits for replies.")
+	bgpHistoryPrefix := flag.String("bgp-history", "", "Summarize the BGP announcement history of a prefix from RIPEstat.")
+	geoipInput := flag.String("geoip", "", "Annotate an address, or every address in a file, with country, city, and ASN from -geoip-db.")
+	geoipDB := flag.String("geoip-db", "", "Comma-separated MaxMind GeoIP2/GeoLite2 .mmdb files (City, Country, and/or ASN).")
+	blocklistInput := flag.String("blocklist", "", "Check an address or prefix, or every entry in a prefix list, against -dnsbl zones and -feed lists.")
+	dnsblZones := flag.String("dnsbl", "", "Comma-separated DNSBL zones to query for -blocklist.")
+	feedSources := flag.String("feed", "", "Comma-separated threat feeds (files or http(s) URLs of prefixes) for -blocklist.")
+	rirLookup := flag.String("rir-lookup", "", "Show which RIR and economy an address or prefix was delegated to.")
+	rirCountry := flag.String("rir-country", "", "List the IPv6 prefixes delegated to an economy (ISO 3166 code).")
+	rirStatsFiles := flag.String("rir-stats", "", "Comma-separated delegated-extended files to use instead of the downloaded cache.")
+	rirRefresh := flag.Bool("rir-refresh", false, "Download fresh RIR statistics into the cache.")
+	auditFile := flag.String("audit", "", "Audit a prefix list against the allocation policy in -rules.")
+	rulesFile := flag.String("rules", "", "YAML allocation policy (allowed parents, forbidden ranges, naming, role sizes) for -audit.")
+	pdSimPool := flag.String("pd-sim", "", "Simulate a prefix-delegation pool and report utilization and fragmentation over time.")
+	pdSize := flag.Int("pd-size", 56, "Per-customer delegation size for -pd-sim.")
+	pdCustomers := flag.Int("pd-customers", 0, "Customers holding a delegation at the start of -pd-sim.")
+	pdGrowth := flag.Float64("pd-growth", 0.02, "Net customer growth per period for -pd-sim, as a fraction.")
+	pdChurn := flag.Float64("pd-churn", 0.01, "Fraction of customers leaving and being replaced per period for -pd-sim.")
+	pdSticky := flag.Bool("pd-sticky", false, "Hold released delegations for -pd-hold periods before reuse (sticky assignment).")
+	pdHold := flag.Int("pd-hold", 3, "Periods a released delegation is held with -pd-sticky.")
+	pdPeriods := flag.Int("pd-periods", 36, "Number of periods to simulate with -pd-sim.")
+	pdSeed := flag.Int64("pd-seed", 1, "Random seed for -pd-sim departures.")
 
 	flag.StringVar(prefix, "p", "64:ff9b::", "Alias for -prefix")
 	flag.IntVar(newPrefixLength, "n", 40, "Alias for -new-prefix-length")
 	flag.StringVar(outputFile, "o", "", "Alias for -output")
+	flag.StringVar(excludeFile, "exclude-file", "", "Alias for -exclude")
 	flag.BoolVar(countOnly, "c", false, "Alias for -count")
 	flag.StringVar(linkLocal, "a", "", "Alias for -local")
 	flag.StringVar(format, "f", "", "Alias for -format")
 	flag.BoolVar(showVersion, "v", false, "Alias for -version")
+	deprecateLegacyFlags(flag.CommandLine)
+
+	if runSubcommand(os.Args[1:]) {
+		return
+	}
 
 	flag.Parse()
+	legacyCommand := warnLegacyFlags(flag.CommandLine)
+	applyStableOutput()
+	command := legacyCommand
+	if command == "" {
+		command = "subnet"
+	}
+	if err := checkOutputFormat(command); err != nil {
+		log.Fatal(err)
+	}
 
 	if *showVersion {
 		fmt.Printf("ipv6utils %s\n", version)
@@ -546,102 +714,525 @@ func main() {
 	}
 
 	if flag.NFlag() == 0 {
-		fmt.Println("Usage:")
+		fmt.Println("Usage: ipv6utils COMMAND [flags] ARG, or ipv6utils [flags]")
+		fmt.Println()
+		writeSubcommandList(os.Stdout)
+		fmt.Println()
+		fmt.Println("Flags of earlier releases, kept as deprecated aliases of the commands above:")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
+	if *watch {
+		runWatch(setFlags(flag.CommandLine), *outputFile, *watchInterval)
+		return
+	}
+
+	if *manifest {
+		if *outputFile == "" {
+			log.Fatal("-manifest requires -o")
+		}
+		// Deferred so it runs after the mode has written and closed the output file.
+		defer func() {
+			if err := writeManifest(*outputFile, setFlags(flag.CommandLine)); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
 	if *format != "" {
 		formatIPv6(*format)
 		return
 	}
 
-	if *macInput != "" {
-		mac, err := decodeMACFromSLAAC(*macInput)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Println("Decoded MAC address:", mac)
+	if *arpaStatsPrefix != "" {
+		reportArpaStats(*arpaStatsPrefix, *zoneFiles)
 		return
 	}
 
-	if *linkLocal != "" {
-		if ip := net.ParseIP(*linkLocal); ip != nil && ip.To16() != nil && strings.HasPrefix(*linkLocal, "fe80") {
-			mac, err := linkLocalToMAC(*linkLocal)
-			if err != nil {
-				log.Fatal(err)
-			}
-			fmt.Println("MAC from link-local:", mac)
+	if *vanity != "" {
+		runVanity(*prefix, *newPrefixLength, strings.Split(*vanity, ","), *vanityGroup, *vanityBudget, *limit)
+		return
+	}
+
+	if *vanityIIDs != "" {
+		runVanityIIDs(*prefix, strings.Split(*vanityIIDs, ","), *vanityASCII)
+		return
+	}
+
+	if *heatmapFile != "" {
+		renderHeatmap(*prefix, *newPrefixLength, *heatmapFile, *outputFile)
+		return
+	}
+
+	if *staleFile != "" {
+		reportStaleAllocations(*staleFile, *seenFiles, *staleDays)
+		return
+	}
+
+	if *fixFile != "" {
+		runFix(*fixFile)
+		return
+	}
+
+	if *iidScoreInput != "" {
+		reportIIDScores(*iidScoreInput)
+		return
+	}
+
+	if *schemeEncode != "" || *schemeDecode != "" {
+		if *schemeFile == "" {
+			log.Fatal("-scheme-encode and -scheme-decode require -scheme")
+		}
+		if *schemeEncode != "" {
+			runSchemeEncode(*schemeFile, *schemeEncode)
 		} else {
-			ll, err := macToLinkLocal(*linkLocal)
-			if err != nil {
-				log.Fatal(err)
-			}
-			fmt.Println("Link-local address:", ll)
+			runSchemeDecode(*schemeFile, *schemeDecode)
 		}
 		return
 	}
 
+	if *vlanList != "" {
+		runVLANSubnets(*prefix, *newPrefixLength, *vlanList, *vlanEncoding)
+		return
+	}
+
+	if *vlanDecode != "" {
+		runVLANDecode(*prefix, *newPrefixLength, *vlanDecode, *vlanEncoding)
+		return
+	}
+
+	if *exportFormat != "" {
+		runPlanExport(*leasePlan, *exportFormat, *outputFile)
+		return
+	}
+
+	if *mergeFiles != "" {
+		runMerge(*mergeFiles, *mergeStrategy, *outputFile)
+		return
+	}
+
+	if *routerConfigs != "" {
+		importRouterConfigs(*routerConfigs)
+		return
+	}
+
+	if *raConfigs != "" {
+		importRAConfigs(*raConfigs)
+		return
+	}
+
+	if *keaLeases != "" {
+		reportLeases(*keaLeases, readKeaLeases, *leasesAll, *leasePlan)
+		return
+	}
+
+	if *dhcpd6Leases != "" {
+		reportLeases(*dhcpd6Leases, parseDhcpd6Leases, *leasesAll, *leasePlan)
+		return
+	}
+
+	if *radiusFile != "" {
+		exportRADIUS(*radiusFile, *radiusFormat)
+		return
+	}
+
+	if *statsFile != "" {
+		reportAddressStats(*statsFile, *statsTop)
+		return
+	}
+
+	if *doctor {
+		if !runDoctor(os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *discoverIface != "" {
+		runDiscovery(*discoverIface, *discoverTimeout)
+		return
+	}
+
+	if *bgpHistoryPrefix != "" {
+		reportBGPHistory(*bgpHistoryPrefix)
+		return
+	}
+
+	if *geoipInput != "" {
+		reportGeoIP(*geoipInput, *geoipDB)
+		return
+	}
+
+	if *blocklistInput != "" {
+		reportBlocklists(*blocklistInput, *dnsblZones, *feedSources)
+		return
+	}
+
+	if *rirLookup != "" {
+		reportRIRLookup(*rirLookup, *rirStatsFiles, *rirRefresh)
+		return
+	}
+
+	if *rirCountry != "" {
+		reportRIRCountry(*rirCountry, *rirStatsFiles, *rirRefresh)
+		return
+	}
+
+	if *neighbors != "" {
+		reportNeighbors(*neighbors, *neighborsParent)
+		return
+	}
+
+	if *gcPlan != "" {
+		runPlanGC(*gcPlan, *gcDryRun, time.Now())
+		return
+	}
+
+	if *updateData != "" {
+		updateDatasets(*updateData)
+		return
+	}
+
+	if *dataStatus {
+		reportDatasetStatus()
+		return
+	}
+
+	if *rirRefresh {
+		updateDatasets("rir")
+		return
+	}
+
+	if *auditFile != "" {
+		runPolicyAudit(*auditFile, *rulesFile)
+		return
+	}
+
+	if *pdSimPool != "" {
+		runPDSimulation(pdSimConfig{
+			Pool:      *pdSimPool,
+			DelegLen:  *pdSize,
+			Customers: *pdCustomers,
+			Growth:    *pdGrowth,
+			Churn:     *pdChurn,
+			Sticky:    *pdSticky,
+			Hold:      *pdHold,
+			Periods:   *pdPeriods,
+			Seed:      *pdSeed,
+		})
+		return
+	}
+
+	if *macFile != "" {
+		convertMACFile(*macFile, *slaacPrefixes)
+		return
+	}
+
+	if *macInput != "" {
+		runMACDecode(*macInput)
+		return
+	}
+
+	if *linkLocal != "" {
+		runLinkLocal(*linkLocal)
+		return
+	}
+
 	if *source != "" {
-		ip := net.ParseIP(*source)
+		// Only a -k given decides how to extract; otherwise the length is detected.
+		prefix := *nonWellKnownPrefix
+		if _, ok := setFlags(flag.CommandLine)["k"]; !ok {
+			prefix = ""
+		}
+		runNAT64(*source, prefix, 0)
+		return
+	}
+
+	if *ip6arpa != "" {
+		runArpa(*ip6arpa, *newPrefixLength)
+		return
+	}
+
+	if legacyCommand == "" {
+		log.Print("Warning: subnet generation with the flat flags is deprecated; use \"ipv6utils subnet\"")
+	}
+	aligned, err := alignPrefixLength(*newPrefixLength, *nibbleAlign)
+	if err != nil {
+		log.Fatal(err)
+	}
+	*newPrefixLength = aligned
+
+	if *countOnly {
+		runCount(*prefix, *newPrefixLength)
+		return
+	}
+
+	runSubnets(*prefix, *newPrefixLength, subnetOptions{
+		Limit:        *limit,
+		OutputFile:   *outputFile,
+		ExcludeFile:  *excludeFile,
+		StartIndex:   *startIndex,
+		StartAt:      *startAt,
+		Resume:       *resumeFile,
+		Sample:       *sampleCount,
+		SampleSeed:   *sampleSeed,
+		Strategy:     *strategy,
+		ReserveFirst: *reserveFirst,
+		ReserveLast:  *reserveLast,
+		SkipReserved: *reserveSkip,
+		Columns:      *columns,
+		Levels:       *levels,
+		NameTemplate: *nameTemplate,
+		NamesFile:    *namesFile,
+		Compress:     *compress,
+	})
+}
+
+// convertSLAAC recovers the MAC address embedded in a SLAAC (EUI-64) address.
+func convertSLAAC(addr string) (conversion, error) {
+	mac, err := decodeMACFromSLAAC(addr)
+	if err != nil {
+		return conversion{}, err
+	}
+	return conversion{macResult{MAC: mac, Address: addr}, "Decoded MAC address: " + mac, batchLine(addr, mac)}, nil
+}
+
+// convertLinkLocal converts a MAC to its link-local address, or a link-local
+// address back to its MAC.
+func convertLinkLocal(input string) (conversion, error) {
+	if ip := net.ParseIP(input); ip != nil && ip.To16() != nil && strings.HasPrefix(input, "fe80") {
+		mac, err := linkLocalToMAC(input)
+		if err != nil {
+			return conversion{}, err
+		}
+		return conversion{macResult{MAC: mac, Address: input}, "MAC from link-local: " + mac, batchLine(input, mac)}, nil
+	}
+	ll, err := macToLinkLocal(input)
+	if err != nil {
+		return conversion{}, err
+	}
+	return conversion{macResult{MAC: input, Address: ll}, "Link-local address: " + ll, batchLine(input, ll)}, nil
+}
+
+// convertMAC converts a MAC to its link-local address, or recovers the MAC
+// from a link-local or SLAAC address, whichever the input is.
+func convertMAC(input string) (conversion, error) {
+	if ip := net.ParseIP(input); ip != nil && !ip.IsLinkLocalUnicast() {
+		return convertSLAAC(input)
+	}
+	return convertLinkLocal(input)
+}
+
+// nat64Converter synthesizes an IPv6 address from an IPv4 one under prefix,
+// the Well-Known Prefix if empty, or extracts the IPv4 address from a
+// synthesized IPv6 one, which must lie within prefix if given. A prefixLength
+// other than 0 gives the length of the prefix in both directions; otherwise
+// it is the prefix's own, or /96, and detected when extracting with no prefix.
+func nat64Converter(prefix string, prefixLength int) func(string) (conversion, error) {
+	return func(source string) (conversion, error) {
+		ip := net.ParseIP(source)
 		if ip == nil {
-			log.Fatalf("Invalid IP address: %s", *source)
+			return conversion{}, fmt.Errorf("Invalid IP address: %s", source)
 		}
 		if ip.To4() != nil {
-			synthesizedAddr, err := ipv4ToSynthesized(*source, *nonWellKnownPrefix)
-			if err != nil {
-				log.Fatal(err)
+			synthesisPrefix := prefix
+			if synthesisPrefix == "" {
+				synthesisPrefix = wellKnownNAT64.Addr().String()
 			}
-			fmt.Println("Converted IPv4 to synthesized IPv6:", synthesizedAddr)
-		} else {
-			ipv4Addr, err := synthesizedToIPv4(*source)
+			synthesizedAddr, p, err := ipv4ToSynthesized(source, synthesisPrefix, prefixLength)
 			if err != nil {
-				log.Fatal(err)
+				return conversion{}, err
 			}
-			fmt.Println("Converted synthesized IPv6 to IPv4:", ipv4Addr)
+			return conversion{
+				nat64Result{IPv4: source, IPv6: synthesizedAddr, Prefix: p.String()},
+				"Converted IPv4 to synthesized IPv6: " + synthesizedAddr,
+				batchLine(source, synthesizedAddr),
+			}, nil
 		}
-		return
-	}
-
-	if *ip6arpa != "" {
-		arpa, err := ipv6ToArpa(*ip6arpa, *newPrefixLength)
+		ipv4Addr, p, err := synthesizedToIPv4(source, prefix, prefixLength)
 		if err != nil {
-			log.Fatal(err)
+			return conversion{}, err
 		}
-		fmt.Println(arpa)
-		return
+		text := "Converted synthesized IPv6 to IPv4: " + ipv4Addr
+		if p.Bits() != 96 {
+			text += fmt.Sprintf(" (prefix %s)", p)
+		}
+		return conversion{
+			nat64Result{IPv4: ipv4Addr, IPv6: source, Prefix: p.String()},
+			text,
+			batchLine(source, ipv4Addr),
+		}, nil
 	}
+}
 
-	if *countOnly {
-		count, err := countSubnets(*prefix, *newPrefixLength)
+// arpaConverter gives the ip6.arpa name of an address, relative to a zone of
+// prefixLength.
+func arpaConverter(prefixLength int) func(string) (conversion, error) {
+	return func(addr string) (conversion, error) {
+		arpa, err := ipv6ToArpa(addr, prefixLength)
 		if err != nil {
-			log.Fatal(err)
+			return conversion{}, err
 		}
-		fmt.Printf("Number of prefixes: %d\n", count)
-		return
+		return conversion{arpaResult{Address: addr, ZoneLength: prefixLength, Name: arpa}, arpa, batchLine(addr, arpa)}, nil
 	}
+}
 
-	subnets, err := generateSubnets(*prefix, *newPrefixLength, *limit)
+// runMACDecode prints the MAC address embedded in a SLAAC (EUI-64) address,
+// or of each read from stdin.
+func runMACDecode(addr string) {
+	runConversion(addr, convertSLAAC)
+}
+
+// runLinkLocal converts a MAC to its link-local address, or a link-local
+// address back to its MAC, or each read from stdin.
+func runLinkLocal(input string) {
+	runConversion(input, convertLinkLocal)
+}
+
+// runNAT64 synthesizes an IPv6 address from an IPv4 one under prefix, or
+// extracts the IPv4 address from a synthesized IPv6 one, or each read from
+// stdin. An empty prefix is the Well-Known Prefix when synthesizing, and
+// detected when extracting.
+func runNAT64(source string, prefix string, prefixLength int) {
+	runConversion(source, nat64Converter(prefix, prefixLength))
+}
+
+// runArpa prints the ip6.arpa name of an address, or of each read from stdin.
+func runArpa(addr string, prefixLength int) {
+	runConversion(addr, arpaConverter(prefixLength))
+}
+
+// runCount prints how many subnets of newPrefixLength prefix holds.
+func runCount(prefix string, newPrefixLength int) {
+	count, err := countSubnets(prefix, newPrefixLength)
 	if err != nil {
 		log.Fatal(err)
 	}
+	writeResult(countResult{Prefix: prefix, NewLength: newPrefixLength, Count: count.String()}, func() {
+		fmt.Printf("Number of prefixes: %s\n", formatSubnetCount(count))
+	})
+}
 
-	if *outputFile != "" {
-		outputFileHandle, err := os.Create(*outputFile)
-		if err != nil {
+// subnetOptions are the settings of a subnet generation run beyond the prefix
+// and new length.
+type subnetOptions struct {
+	Limit       int
+	OutputFile  string
+	ExcludeFile string
+	StartIndex  string // 0-based index of the first subnet, in decimal
+	StartAt     string // prefix or address of the first subnet
+	Resume      string // cursor file to continue from and checkpoint to
+	Sample      int    // pick this many subnets at random instead
+	SampleSeed  int64  // 0 for a new seed each run; also seeds Strategy random
+	Strategy    string // RFC 3531 allocation order; empty for address order
+	// ReserveFirst and ReserveLast subnets of the expansion are labelled
+	// RESERVED, or left out with SkipReserved.
+	ReserveFirst int
+	ReserveLast  int
+	SkipReserved bool
+	Columns      string // CSV and xlsx columns, comma-separated
+	Levels       string // xlsx sheets for these prefix lengths above the subnets, comma-separated
+	NameTemplate string // text/template labelling each subnet, e.g. site-{{.Index}}
+	NamesFile    string // one label per line, by subnet index
+	Compress     bool   // gzip the output; implied by an output file ending in .gz
+}
+
+// runSubnets generates the subnets of prefix, skipping those overlapping the
+// active entries of the exclude file, to the output file or stdout.
+func runSubnets(prefix string, newPrefixLength int, opts subnetOptions) {
+	columns, err := parseSubnetColumns(opts.Columns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	start, err := resolveSubnetStart(prefix, newPrefixLength, opts.StartIndex, opts.StartAt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var excluded []prefixEntry
+	if opts.ExcludeFile != "" {
+		if excluded, err = readPrefixFile(opts.ExcludeFile); err != nil {
 			log.Fatal(err)
 		}
-		defer outputFileHandle.Close()
-		for _, subnet := range subnets {
-			_, err := outputFileHandle.WriteString(subnet + "\n")
-			if err != nil {
-				log.Fatal(err)
+		// Expired allocations are free again even before -gc removes them.
+		if excluded, _, err = splitExpired(excluded, time.Now()); err != nil {
+			log.Fatalf("%s: %v", opts.ExcludeFile, err)
+		}
+	}
+	reserved, err := reservedSubnets(prefix, newPrefixLength, opts.ReserveFirst, opts.ReserveLast)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if opts.SkipReserved {
+		excluded = append(excluded, reservedEntries(reserved)...)
+		reserved = nil
+	}
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	levels, err := parseSubnetLevels(opts.Levels, parent.Bits(), newPrefixLength)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if levels != nil && outputFormat != "xlsx" {
+		log.Fatal("-levels applies only to -output-format xlsx")
+	}
+	namer, err := newSubnetNamer(opts.NameTemplate, opts.NamesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	record := namer.labelled(subnetRecords(parent, reserved))
+	seed := opts.SampleSeed
+	if opts.Strategy == strategyRandom || opts.Sample > 0 {
+		if seed, err = sampleSeed(seed); err != nil {
+			log.Fatal(err)
+		}
+	}
+	order, err := parseSubnetStrategy(opts.Strategy, max(newPrefixLength-parent.Bits(), 0), seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if order != nil && (opts.Resume != "" || opts.Sample > 0 || opts.StartAt != "") {
+		log.Fatal("-strategy cannot be combined with -resume, -sample, or -start-at")
+	}
+	if opts.Resume != "" {
+		runResumableSubnets(prefix, newPrefixLength, start, excluded, record, opts)
+		return
+	}
+	var sampled []netip.Prefix
+	if opts.Sample > 0 {
+		if sampled, err = sampleSubnets(prefix, newPrefixLength, opts.Sample, seed, excluded); err != nil {
+			log.Fatal(err)
+		}
+	}
+	out, err := openSubnetOutput(opts.OutputFile, opts.Compress)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := newSubnetWriter(out, subnetLayout{Parent: parent, NewLength: newPrefixLength, Columns: columns, Levels: levels})
+	write := func(subnet netip.Prefix) error {
+		return w.Write(record(subnet))
+	}
+	if opts.Sample > 0 {
+		for _, subnet := range sampled {
+			if err = write(subnet); err != nil {
+				break
 			}
 		}
-		fmt.Printf("Subnets saved to %s\n", *outputFile)
 	} else {
-		for _, subnet := range subnets {
-			fmt.Println(subnet)
-		}
+		_, err = streamSubnets(prefix, newPrefixLength, start, order, opts.Limit, excluded, write)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if opts.OutputFile != "" {
+		statusf("Subnets saved to %s\n", opts.OutputFile)
 	}
 }
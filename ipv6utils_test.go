@@ -1,11 +1,30 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"net/netip"
+	"os"
 	"sort"
+	"strings"
 	"testing"
 )
 
+// TestMain points the dataset cache at an empty directory, so what a user has
+// downloaded, such as a newer special-purpose registry, cannot change results.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "ipv6utils-test-cache-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	cacheRoot = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestNetworkAddress(t *testing.T) {
 	cases := []struct {
 		name      string
@@ -336,7 +355,7 @@ func TestClassifyIPv6(t *testing.T) {
 		{name: "NAT64 well-known", input: "64:ff9b::c0a8:101", expect: "NAT64 Well-Known Prefix (64:ff9b::/96)"},
 		{name: "NAT64 network-specific", input: "64:ff9b:1::1", expect: "NAT64 Network-Specific (64:ff9b:1::/48)"},
 		{name: "Teredo", input: "2001::1", expect: "Teredo (2001:0000::/32)"},
-		{name: "6to4", input: "2002:c0a8:101::1", expect: "6to4 (2002::/16)"},
+		{name: "6to4", input: "2002:c0a8:101::1", expect: "6to4 (2002::/16), IPv4: 192.168.1.1"},
 		{name: "discard", input: "100::1", expect: "Discard-Only (100::/64)"},
 		{name: "IPv4-mapped", input: "::ffff:192.168.1.1", expect: "IPv4-Mapped (::ffff:0:0/96)"},
 		{name: "global unicast", input: "2607:f8b0:4004:800::200e", expect: "Global Unicast (2000::/3)"},
@@ -433,3 +452,153 @@ func TestCompressionPermutations(t *testing.T) {
 		})
 	}
 }
+
+// collectSubnets gathers the subnets streamSubnets emits, in order.
+func collectSubnets(t *testing.T, prefix string, newPrefixLength, limit int, exclude []prefixEntry) []string {
+	t.Helper()
+	var subnets []string
+	if _, err := streamSubnets(prefix, newPrefixLength, nil, nil, limit, exclude, func(subnet netip.Prefix) error {
+		subnets = append(subnets, subnet.String())
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return subnets
+}
+
+func TestStreamSubnetsExclude(t *testing.T) {
+	exclude := []prefixEntry{
+		{Net: &net.IPNet{IP: net.ParseIP("3fff:0:1:100::"), Mask: net.CIDRMask(56, 128)}},
+		{Net: &net.IPNet{IP: net.ParseIP("3fff:0:1:210::1"), Mask: net.CIDRMask(128, 128)}},
+		{Net: &net.IPNet{IP: net.ParseIP("3fff:0:1::"), Mask: net.CIDRMask(60, 128)}},
+	}
+	subnets := collectSubnets(t, "3fff:0:1::/48", 56, 3, exclude)
+	want := []string{"3fff:0:1:300::/56", "3fff:0:1:400::/56", "3fff:0:1:500::/56"}
+	if len(subnets) != len(want) {
+		t.Fatalf("expected %v, got %v", want, subnets)
+	}
+	for i := range want {
+		if subnets[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, subnets)
+		}
+	}
+}
+
+func TestCountSubnets(t *testing.T) {
+	cases := []struct {
+		prefix    string
+		newLen    int
+		count     string
+		formatted string
+		wantErr   bool
+	}{
+		{"3fff::/32", 40, "256", "256", false},
+		{"2001:db8::/64", 127, "9223372036854775808", "9223372036854775808 = 2^63 (9.2e18)", false},
+		{"2001:db8::/64", 128, "18446744073709551616", "18446744073709551616 = 2^64 (1.8e19)", false},
+		{"::/0", 128, "340282366920938463463374607431768211456", "340282366920938463463374607431768211456 = 2^128 (3.4e38)", false},
+		{"3fff::/32", 52, "1048576", "1048576 = 2^20 (1.0e06)", false},
+		{"3fff::/32", 32, "", "", true},
+		{"3fff::/32", 129, "", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s to /%d", tc.prefix, tc.newLen), func(t *testing.T) {
+			count, err := countSubnets(tc.prefix, tc.newLen)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if count.String() != tc.count {
+				t.Errorf("expected %s, got %s", tc.count, count)
+			}
+			if got := formatSubnetCount(count); got != tc.formatted {
+				t.Errorf("expected %q, got %q", tc.formatted, got)
+			}
+		})
+	}
+}
+
+func TestStreamSubnetsLargeDelta(t *testing.T) {
+	subnets := collectSubnets(t, "2001:db8::/64", 127, 2, nil)
+	if len(subnets) != 2 || subnets[1] != "2001:db8::2/127" {
+		t.Errorf("unexpected subnets %v", subnets)
+	}
+}
+
+func TestStreamSubnets(t *testing.T) {
+	var got []string
+	n, err := streamSubnets("2001:db8::/32", 64, nil, nil, 3, nil, func(subnet netip.Prefix) error {
+		got = append(got, subnet.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 || len(got) != 3 || got[2] != "2001:db8:0:2::/64" {
+		t.Errorf("emitted %d: %v", n, got)
+	}
+
+	stop := errors.New("stop")
+	n, err = streamSubnets("2001:db8::/32", 64, nil, nil, 0, nil, func(netip.Prefix) error { return stop })
+	if err != stop || n != 0 {
+		t.Errorf("got %d, %v; want 0, stop", n, err)
+	}
+}
+
+func TestAlignPrefixLength(t *testing.T) {
+	cases := []struct {
+		length  int
+		mode    string
+		want    int
+		wantErr bool
+	}{
+		{57, "", 57, false},
+		{57, nibbleRoundUp, 60, false},
+		{57, nibbleRoundDown, 56, false},
+		{60, nibbleRoundUp, 60, false},
+		{60, nibbleRoundDown, 60, false},
+		{127, nibbleRoundUp, 128, false},
+		{64, nibbleStrict, 64, false},
+		{57, nibbleStrict, 0, true},
+		{57, "nearest", 0, true},
+	}
+	for _, c := range cases {
+		got, err := alignPrefixLength(c.length, c.mode)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("alignPrefixLength(%d, %q) = %d, %v; want %d", c.length, c.mode, got, err, c.want)
+		}
+	}
+}
+
+func TestStableOutputRepeats(t *testing.T) {
+	defer func() { stableOutput = false }()
+	for _, args := range [][]string{
+		{"subnet", "3fff::/32", "-n", "48", "-l", "3", "-stable"},
+		{"subnet", "3fff::/32", "-n", "48", "-sample", "2", "-seed", "7", "-stable"},
+		{"subnet", "3fff::/32", "-n", "48", "-strategy", "random", "-seed", "7", "-l", "4", "-stable"},
+	} {
+		first := captureStdout(t, func() { runSubcommandArgs(t, args...) })
+		second := captureStdout(t, func() { runSubcommandArgs(t, args...) })
+		if first == "" || first != second {
+			t.Errorf("%v: runs differ:\n%s\n---\n%s", args, first, second)
+		}
+		if strings.Contains(first, "Generating") {
+			t.Errorf("%v: status line in stable output:\n%s", args, first)
+		}
+	}
+}
+
+func TestSampleSeedStable(t *testing.T) {
+	defer func() { stableOutput = false }()
+	stableOutput = true
+	if _, err := sampleSeed(0); err == nil {
+		t.Error("-stable with no seed: expected an error")
+	}
+	if seed, err := sampleSeed(7); err != nil || seed != 7 {
+		t.Errorf("-stable with a seed: got %d, %v", seed, err)
+	}
+}
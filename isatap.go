@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// sharedAddressSpace is the carrier-grade NAT space of RFC 6598, which is no
+// more globally unique than the private space of RFC 1918.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isatapResult is the JSON form of an ISATAP conversion.
+type isatapResult struct {
+	IPv4    string `json:"ipv4"`
+	Address string `json:"address"`
+}
+
+// isISATAPIID reports whether an interface ID, the last 8 bytes of an address,
+// has the form of RFC 5214 section 6.1: 0000:5efe, or 0200:5efe for a globally
+// unique IPv4 address, then the IPv4 address.
+func isISATAPIID(iid []byte) bool {
+	return iid[0]&^0x02 == 0x00 && iid[1] == 0x00 && iid[2] == 0x5e && iid[3] == 0xfe
+}
+
+// isatapIPv4 returns the IPv4 address of an ISATAP interface ID.
+func isatapIPv4(a netip.Addr) (netip.Addr, bool) {
+	b := a.As16()
+	if !isISATAPIID(b[8:]) {
+		return netip.Addr{}, false
+	}
+	return netip.AddrFrom4([4]byte(b[12:])), true
+}
+
+// isatapAddress returns the ISATAP address of an IPv4 address in a /64, with
+// the universal/local bit set when the IPv4 address is globally unique.
+func isatapAddress(prefix netip.Prefix, v4 netip.Addr) netip.Addr {
+	b := prefix.Masked().Addr().As16()
+	b[10], b[11] = 0x5e, 0xfe
+	if !v4.IsPrivate() && !sharedAddressSpace.Contains(v4) && !v4.IsLoopback() && !v4.IsLinkLocalUnicast() {
+		b[8] = 0x02
+	}
+	ip := v4.As4()
+	copy(b[12:], ip[:])
+	return netip.AddrFrom16(b)
+}
+
+// parseISATAPPrefix reads the /64 an ISATAP address is formed in; without a
+// length it is taken to be a /64.
+func parseISATAPPrefix(s string) (netip.Prefix, error) {
+	text, lenText, hasLength := strings.Cut(s, "/")
+	a, err := netip.ParseAddr(text)
+	if err != nil || !a.Is6() || a.Is4In6() {
+		return netip.Prefix{}, fmt.Errorf("invalid ISATAP prefix %q", s)
+	}
+	if hasLength {
+		if n, err := strconv.Atoi(lenText); err != nil || n != 64 {
+			return netip.Prefix{}, fmt.Errorf("ISATAP prefix must be a /64, got %q", s)
+		}
+	}
+	return netip.PrefixFrom(a, 64).Masked(), nil
+}
+
+// isatapConverter forms the ISATAP address of an IPv4 address in prefix, or
+// gives the IPv4 address of an ISATAP address.
+func isatapConverter(prefix string) func(string) (conversion, error) {
+	return func(input string) (conversion, error) {
+		a, err := netip.ParseAddr(input)
+		if err != nil {
+			return conversion{}, fmt.Errorf("Invalid IP address: %s", input)
+		}
+		if a.Is4() {
+			p, err := parseISATAPPrefix(prefix)
+			if err != nil {
+				return conversion{}, err
+			}
+			isatap := addrText(isatapAddress(p, a))
+			return conversion{
+				isatapResult{IPv4: a.String(), Address: isatap},
+				"Converted IPv4 to ISATAP address: " + isatap,
+				batchLine(input, isatap),
+			}, nil
+		}
+		v4, ok := isatapIPv4(a)
+		if !ok {
+			return conversion{}, fmt.Errorf("%s does not have an ISATAP interface ID (::5efe:a.b.c.d)", input)
+		}
+		return conversion{
+			isatapResult{IPv4: v4.String(), Address: addrText(a)},
+			"Converted ISATAP address to IPv4: " + v4.String(),
+			batchLine(input, v4.String()),
+		}, nil
+	}
+}
+
+// runISATAP prints the ISATAP address of an IPv4 address in prefix, or the
+// IPv4 address of an ISATAP address, or of each read from stdin.
+func runISATAP(input, prefix string) {
+	runConversion(input, isatapConverter(prefix))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestISATAPConverter(t *testing.T) {
+	tests := []struct{ prefix, in, ipv4, addr string }{
+		{"fe80::/64", "192.0.2.1", "192.0.2.1", "fe80::200:5efe:c000:201"},
+		{"2001:db8:0:10::", "10.1.2.3", "10.1.2.3", "2001:db8:0:10:0:5efe:a01:203"},       // private: u bit clear
+		{"2001:db8:0:10::/64", "100.64.0.1", "100.64.0.1", "2001:db8:0:10:0:5efe:6440:1"}, // shared: u bit clear
+		{"fe80::/64", "fe80::200:5efe:c000:201", "192.0.2.1", "fe80::200:5efe:c000:201"},
+		{"fe80::/64", "2001:db8:0:10::5efe:a01:203", "10.1.2.3", "2001:db8:0:10:0:5efe:a01:203"},
+	}
+	for _, tt := range tests {
+		c, err := isatapConverter(tt.prefix)(tt.in)
+		if err != nil {
+			t.Errorf("%s in %s: %v", tt.in, tt.prefix, err)
+			continue
+		}
+		if r := c.Result.(isatapResult); r.IPv4 != tt.ipv4 || r.Address != tt.addr {
+			t.Errorf("%s in %s: got %+v, want %s %s", tt.in, tt.prefix, r, tt.ipv4, tt.addr)
+		}
+	}
+	for _, tt := range []struct{ prefix, in string }{
+		{"fe80::/64", "2001:db8::1"},   // not ISATAP
+		{"2001:db8::/48", "192.0.2.1"}, // not a /64
+		{"192.0.2.0/24", "192.0.2.1"},  // not IPv6
+		{"fe80::/64", "bogus"},
+	} {
+		if _, err := isatapConverter(tt.prefix)(tt.in); err == nil {
+			t.Errorf("%s in %s: accepted", tt.in, tt.prefix)
+		}
+	}
+}
+
+func TestClassifyISATAP(t *testing.T) {
+	tests := []struct{ addr, isatap string }{
+		{"fe80::5efe:a00:1", "10.0.0.1"},
+		{"2001:db8::200:5efe:c000:201", "192.0.2.1"},
+		{"2001:db8::1", ""},
+		{"ff02::5efe:a00:1", ""}, // multicast
+	}
+	for _, tt := range tests {
+		if c := classifyAddress(netip.MustParseAddr(tt.addr)); c.ISATAP != tt.isatap {
+			t.Errorf("%s: got %q, want %q", tt.addr, c.ISATAP, tt.isatap)
+		}
+	}
+}
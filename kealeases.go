@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keaLeaseTypes maps Kea's numeric lease6 types to IA names.
+var keaLeaseTypes = map[string]string{"0": "ia-na", "1": "ia-ta", "2": "ia-pd", "IA_NA": "ia-na", "IA_TA": "ia-ta", "IA_PD": "ia-pd"}
+
+// keaLeaseStates maps Kea's numeric lease states to the names ISC dhcpd uses.
+var keaLeaseStates = map[int]string{0: "active", 1: "declined", 2: "expired", 3: "released"}
+
+// keaLease builds a lease from the fields common to Kea's CSV and JSON forms.
+func keaLease(address string, prefixLen int, leaseType string, state int, expire int64, validLifetime int64) (dhcpLease, error) {
+	ip, _, err := parseIPv6WithOptionalPrefix(address)
+	if err != nil {
+		return dhcpLease{}, err
+	}
+	kind, ok := keaLeaseTypes[leaseType]
+	if !ok {
+		return dhcpLease{}, fmt.Errorf("unknown lease type %q", leaseType)
+	}
+	if kind != "ia-pd" || prefixLen <= 0 {
+		prefixLen = 128
+	}
+	mask := net.CIDRMask(prefixLen, 128)
+	l := dhcpLease{Kind: kind, Net: &net.IPNet{IP: ip.Mask(mask), Mask: mask}, State: keaLeaseStates[state]}
+	if l.State == "" {
+		l.State = strconv.Itoa(state)
+	}
+	if validLifetime == 0 {
+		l.State = "released" // memfile writes a zero lifetime when a lease is deleted
+	}
+	if expire > 0 && validLifetime != 0xffffffff {
+		l.Ends = time.Unix(expire, 0).UTC()
+	}
+	return l, nil
+}
+
+// readKeaLeaseCSV reads a Kea memfile lease6 CSV. Like dhcpd6.leases the file is
+// a journal, so later rows for the same address or prefix replace earlier ones.
+func readKeaLeaseCSV(r io.Reader) ([]dhcpLease, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header: %v", err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[name] = i
+	}
+	for _, name := range []string{"address", "valid_lifetime", "expire", "lease_type", "prefix_len"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("not a Kea lease6 file: no %s column", name)
+		}
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+	var leases []dhcpLease
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var nums [4]int64
+		for i, name := range []string{"valid_lifetime", "expire", "prefix_len", "state"} {
+			v := field(rec, name)
+			if v == "" && name == "state" {
+				continue
+			}
+			if nums[i], err = strconv.ParseInt(v, 10, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, name, v)
+			}
+		}
+		l, err := keaLease(field(rec, "address"), int(nums[2]), field(rec, "lease_type"), int(nums[3]), nums[1], nums[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		leases = append(leases, l)
+	}
+	return latestLeases(leases), nil
+}
+
+// keaLeaseDump is the response to the lease6-get-all (or lease6-get-page) command.
+type keaLeaseDump struct {
+	Result    int    `json:"result"`
+	Text      string `json:"text"`
+	Arguments struct {
+		Leases []struct {
+			Address   string `json:"ip-address"`
+			PrefixLen int    `json:"prefix-len"`
+			Type      string `json:"type"`
+			State     int    `json:"state"`
+			CLTT      int64  `json:"cltt"`
+			ValidLft  int64  `json:"valid-lft"`
+		} `json:"leases"`
+	} `json:"arguments"`
+}
+
+// readKeaLeaseJSON reads a lease6-get-all response, either the bare response from
+// the DHCPv6 server's control socket or the one-element list the Control Agent returns.
+func readKeaLeaseJSON(data []byte) ([]dhcpLease, error) {
+	var dumps []keaLeaseDump
+	if err := json.Unmarshal(data, &dumps); err != nil {
+		var one keaLeaseDump
+		if err := json.Unmarshal(data, &one); err != nil {
+			return nil, fmt.Errorf("invalid Kea response: %v", err)
+		}
+		dumps = []keaLeaseDump{one}
+	}
+	var leases []dhcpLease
+	for _, d := range dumps {
+		if d.Result != 0 && d.Result != 3 { // 3 means "no leases found"
+			return nil, fmt.Errorf("Kea returned result %d: %s", d.Result, d.Text)
+		}
+		for _, e := range d.Arguments.Leases {
+			l, err := keaLease(e.Address, e.PrefixLen, e.Type, e.State, e.CLTT+e.ValidLft, e.ValidLft)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", e.Address, err)
+			}
+			leases = append(leases, l)
+		}
+	}
+	return latestLeases(leases), nil
+}
+
+// readKeaLeases reads either a memfile CSV or a lease6-get-all JSON export.
+func readKeaLeases(r io.Reader) ([]dhcpLease, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return readKeaLeaseJSON(trimmed)
+	}
+	return readKeaLeaseCSV(bytes.NewReader(data))
+}
+
+// leaseMatch pairs a lease with the most specific planned range holding it, or
+// nil when the lease falls outside the plan.
+type leaseMatch struct {
+	Lease dhcpLease
+	Plan  *prefixEntry
+}
+
+// reconcileLeases finds the planned range for every lease. A delegated prefix
+// must lie entirely inside a planned range.
+func reconcileLeases(leases []dhcpLease, plan []prefixEntry) []leaseMatch {
+	matches := make([]leaseMatch, len(leases))
+	for i, l := range leases {
+		matches[i].Lease = l
+		best := -1
+		for j, p := range plan {
+			if !prefixWithin(l.Net, p.Net) {
+				continue
+			}
+			if best == -1 || prefixWithin(p.Net, plan[best].Net) {
+				best = j
+			}
+		}
+		if best >= 0 {
+			matches[i].Plan = &plan[best]
+		}
+	}
+	return matches
+}
+
+// writeLeaseReconciliation prints each lease with its planned range and returns
+// how many were outside the plan.
+func writeLeaseReconciliation(w io.Writer, matches []leaseMatch) int {
+	outside := 0
+	for _, m := range matches {
+		where := "OUTSIDE PLAN"
+		if m.Plan != nil {
+			where = strings.TrimSpace(m.Plan.Net.String() + " " + m.Plan.Label)
+		} else {
+			outside++
+		}
+		fmt.Fprintf(w, "%-44s %-6s %s\n", m.Lease.Net, m.Lease.Kind, where)
+	}
+	return outside
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadKeaLeaseCSV(t *testing.T) {
+	f, err := os.Open("testdata/kea-leases6.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	leases, err := readKeaLeases(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(leases) != 6 {
+		t.Fatalf("expected 6 leases, got %+v", leases)
+	}
+	// The zero-lifetime row records the deletion of ::1:102.
+	if l := leases[2]; l.Net.String() != "3fff:0:1:10::1:102/128" || l.State != "released" {
+		t.Errorf("expected released lease, got %+v", l)
+	}
+	if l := leases[4]; l.Kind != "ia-pd" || l.Net.String() != "3fff:0:1:1000::/56" || !l.Ends.Equal(time.Unix(2082758400, 0)) {
+		t.Errorf("unexpected delegated prefix %+v", l)
+	}
+
+	for _, input := range []string{
+		"address,duid\n3fff::1,00\n",
+		"address,valid_lifetime,expire,lease_type,prefix_len\n3fff::1,x,0,0,128\n",
+		"address,valid_lifetime,expire,lease_type,prefix_len\n3fff::1,10,0,7,128\n",
+	} {
+		if _, err := readKeaLeases(strings.NewReader(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestReadKeaLeaseJSON(t *testing.T) {
+	agent := `[{"result": 0, "text": "2 IPv6 lease(s) found.", "arguments": {"leases": [
+		{"ip-address": "3fff:0:1:10::1:100", "prefix-len": 128, "type": "IA_NA", "state": 0, "cltt": 1791892800, "valid-lft": 3600},
+		{"ip-address": "3fff:0:1:1000::", "prefix-len": 56, "type": "IA_PD", "state": 1, "cltt": 1791892800, "valid-lft": 3600}]}}]`
+	leases, err := readKeaLeases(strings.NewReader(agent))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(leases) != 2 || leases[0].Ends.Unix() != 1791896400 || leases[1].Net.String() != "3fff:0:1:1000::/56" || leases[1].State != "declined" {
+		t.Errorf("unexpected leases %+v", leases)
+	}
+	if leases, err := readKeaLeases(strings.NewReader(`{"result": 3, "text": "0 IPv6 lease(s) found."}`)); err != nil || len(leases) != 0 {
+		t.Errorf("expected no leases, got %+v: %v", leases, err)
+	}
+	if _, err := readKeaLeases(strings.NewReader(`{"result": 1, "text": "unknown command"}`)); err == nil {
+		t.Error("expected error for failed command")
+	}
+}
+
+func TestReconcileLeases(t *testing.T) {
+	plan, err := readPrefixFile("testdata/dhcp-plan.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leases, err := readKeaLeases(strings.NewReader("address,valid_lifetime,expire,lease_type,prefix_len\n" +
+		"3fff:0:1:10::5,10,0,0,128\n3fff:0:1:1000::,10,0,2,56\n3fff:0:1::,10,0,2,52\n3fff:0:2::1,10,0,0,128\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The /52 delegation covers a planned /64 but is not inside any planned range.
+	var buf bytes.Buffer
+	outside := writeLeaseReconciliation(&buf, reconcileLeases(leases, plan))
+	want := "3fff:0:1::/52                                ia-pd  OUTSIDE PLAN\n" +
+		"3fff:0:1:10::5/128                           ia-na  3fff:0:1:10::/64 clients-vlan10\n" +
+		"3fff:0:1:1000::/56                           ia-pd  3fff:0:1:1000::/52 pd-pool\n" +
+		"3fff:0:2::1/128                              ia-na  OUTSIDE PLAN\n"
+	if outside != 2 || buf.String() != want {
+		t.Errorf("expected 2 outside and\n%s\ngot %d and\n%s", want, outside, buf.String())
+	}
+}
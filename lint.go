@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// Lint problem kinds beyond those of plan validation.
+const (
+	lintInvalid   = "invalid"
+	lintHostBits  = "host bits set"
+	lintContained = "contained"
+)
+
+// lintResult is the result of linting a prefix list.
+type lintResult struct {
+	File    string      `json:"file"`
+	Entries int         `json:"entries"`
+	Valid   bool        `json:"valid"`
+	Issues  []planIssue `json:"issues"`
+}
+
+// lintPrefixList checks a prefix list line by line, carrying on past bad lines
+// that readPrefixEntries would stop at: lines that do not parse, prefixes
+// written with host bits set (2001:db8::1/48 is read as 2001:db8::/48, which
+// is rarely what was meant, and is how two prefixes seem to partially
+// overlap), prefixes listed twice, and prefixes inside another, unless nested.
+// It returns the entries read and the problems in line order.
+func lintPrefixList(r io.Reader, nested bool) ([]prefixEntry, []planIssue, error) {
+	var entries []prefixEntry
+	var issues []planIssue
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		label := strings.Join(fields[1:], " ")
+		ip, prefixLen, err := parseIPv6WithOptionalPrefix(fields[0])
+		if err != nil {
+			issues = append(issues, planIssue{Line: lineNo, Prefix: fields[0], Label: label, Kind: lintInvalid, Detail: err.Error()})
+			continue
+		}
+		if prefixLen < 0 {
+			prefixLen = 128
+		}
+		mask := net.CIDRMask(prefixLen, 128)
+		e := prefixEntry{Net: &net.IPNet{IP: ip.Mask(mask), Mask: mask}, Label: label, Line: lineNo}
+		if !ip.Equal(e.Net.IP) {
+			issues = append(issues, planIssue{Line: lineNo, Prefix: fields[0], Label: label, Kind: lintHostBits, Detail: "the network is " + e.Net.String()})
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	for _, i := range validatePlan(entries, netip.Prefix{}, nested) {
+		switch i.Kind {
+		case planIssueUnaligned:
+			continue
+		case planIssueOverlap:
+			i.Kind = lintContained
+		}
+		issues = append(issues, i)
+	}
+	sortIssues(issues)
+	return entries, issues, nil
+}
+
+// runLint lints a prefix list file, printing its problems as file:line
+// messages and exiting non-zero when there are any, to gate CI.
+func runLint(path string, nested bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	entries, issues, err := lintPrefixList(f, nested)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	result := lintResult{File: path, Entries: len(entries), Valid: len(issues) == 0, Issues: issues}
+	if result.Issues == nil {
+		result.Issues = []planIssue{}
+	}
+	writeResult(result, func() {
+		for _, i := range issues {
+			name := i.Prefix
+			if i.Label != "" {
+				name += " " + i.Label
+			}
+			fmt.Printf("%s:%d: %s: %s: %s\n", path, i.Line, name, i.Kind, i.Detail)
+		}
+	})
+	if len(issues) > 0 {
+		log.Fatalf("%d problem(s) in %d entries", len(issues), len(entries))
+	}
+}
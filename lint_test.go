@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLintPrefixList(t *testing.T) {
+	f, err := os.Open("testdata/lint-bad.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, issues, err := lintPrefixList(f, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Errorf("got %d entries, want 5", len(entries))
+	}
+	var got []string
+	for _, i := range issues {
+		got = append(got, i.Kind)
+	}
+	if want := "host bits set,contained,duplicate,invalid"; strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+	if issues[1].Line != 4 || !strings.Contains(issues[1].Detail, "at line 2") {
+		t.Errorf("containment: got %+v", issues[1])
+	}
+
+	_, issues, err = lintPrefixList(strings.NewReader("3fff::/32 all\n3fff:0:1::/48 site\n"), true)
+	if err != nil || len(issues) != 0 {
+		t.Errorf("nested: got %v, %v", issues, err)
+	}
+	// Lint leaves nibble alignment to plan validate.
+	_, issues, _ = lintPrefixList(strings.NewReader("3fff::/33\n"), false)
+	if len(issues) != 0 {
+		t.Errorf("unaligned prefix: got %v", issues)
+	}
+}
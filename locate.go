@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+)
+
+// locateResult is the child subnet of a prefix holding an address.
+type locateResult struct {
+	Parent  string `json:"parent"`
+	Length  int    `json:"length"`
+	Address string `json:"address"`
+	Subnet  string `json:"subnet"`
+	Index   string `json:"index"`
+}
+
+// locateChild finds the /newLen subnet of parent holding address, which may
+// also be a prefix no shorter than newLen, and its 0-based index: the position
+// it has in subnet's output, and so in a plan generated from parent.
+func locateChild(parent netip.Prefix, newLen int, address string) (locateResult, error) {
+	if newLen < parent.Bits() || newLen > 128 {
+		return locateResult{}, fmt.Errorf("length must be between %d and 128, got %d", parent.Bits(), newLen)
+	}
+	q, err := parseLPMQuery(address)
+	if err != nil {
+		return locateResult{}, err
+	}
+	if !parent.Contains(q.Addr()) {
+		return locateResult{}, fmt.Errorf("%s is not within %s", address, parent)
+	}
+	if q.Bits() < newLen {
+		return locateResult{}, fmt.Errorf("%s spans several /%d subnets", q, newLen)
+	}
+	return locateResult{
+		Parent:  prefixText(parent),
+		Length:  newLen,
+		Address: address,
+		Subnet:  prefixText(netip.PrefixFrom(q.Addr(), newLen).Masked()),
+		Index:   childIndex(parent, newLen, q.Addr()).String(),
+	}, nil
+}
+
+// runLocate prints the /newLen subnet of prefix an address belongs to, and its
+// index, to map a host seen on the network back to its entry in a plan.
+func runLocate(prefix string, newLen int, address string) {
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := locateChild(parent, newLen, address)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeResult(result, func() {
+		fmt.Printf("%s index %s\n", result.Subnet, result.Index)
+	})
+}
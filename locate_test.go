@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestLocateChild(t *testing.T) {
+	parent := netip.MustParsePrefix("2001:db8::/48")
+	tests := []struct {
+		newLen  int
+		address string
+		subnet  string
+		index   string
+	}{
+		{64, "2001:db8:0:2a::1", "2001:db8:0:2a::/64", "42"},
+		{64, "2001:db8::", "2001:db8::/64", "0"},
+		{64, "2001:db8:0:ffff:1:2:3:4", "2001:db8:0:ffff::/64", "65535"},
+		{56, "2001:db8:0:2a01::1", "2001:db8:0:2a00::/56", "42"},
+		{64, "2001:db8:0:7::/64", "2001:db8:0:7::/64", "7"},
+		{48, "2001:db8::5:1", "2001:db8::/48", "0"},
+	}
+	for _, tt := range tests {
+		got, err := locateChild(parent, tt.newLen, tt.address)
+		if err != nil || got.Subnet != tt.subnet || got.Index != tt.index {
+			t.Errorf("%s /%d: got %+v, %v, want %s index %s", tt.address, tt.newLen, got, err, tt.subnet, tt.index)
+		}
+	}
+	for _, bad := range []struct {
+		newLen  int
+		address string
+	}{{64, "2001:db9::1"}, {64, "2001:db8::/56"}, {40, "2001:db8::1"}, {64, "nope"}} {
+		if _, err := locateChild(parent, bad.newLen, bad.address); err == nil {
+			t.Errorf("%s /%d: no error", bad.address, bad.newLen)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math/bits"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// prefixTrie is a path-compressed binary radix tree of prefixes for
+// longest-prefix match. A node stands for a whole run of bits, so a lookup
+// visits at most one node per distinct prefix length on the way to the
+// address, however large the table.
+type prefixTrie struct {
+	root *trieNode
+}
+
+// trieNode is a prefix of the table, or with a nil entry, the common part of
+// two branches.
+type trieNode struct {
+	prefix netip.Prefix
+	entry  *prefixEntry
+	child  [2]*trieNode
+}
+
+// addrBit returns bit i of an address, counting from the most significant.
+func addrBit(a netip.Addr, i int) int {
+	b := a.As16()
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// commonBits returns how many leading bits two addresses share.
+func commonBits(a, b netip.Addr) int {
+	x, y := a.As16(), b.As16()
+	for i := range x {
+		if d := x[i] ^ y[i]; d != 0 {
+			return i*8 + bits.LeadingZeros8(d)
+		}
+	}
+	return 128
+}
+
+// insert adds an entry. The first of duplicate prefixes is kept.
+func (t *prefixTrie) insert(e prefixEntry) {
+	p := entryPrefix(e)
+	n := &t.root
+	for {
+		cur := *n
+		if cur == nil {
+			*n = &trieNode{prefix: p, entry: &e}
+			return
+		}
+		common := min(commonBits(cur.prefix.Addr(), p.Addr()), cur.prefix.Bits(), p.Bits())
+		switch {
+		case common == cur.prefix.Bits() && common == p.Bits():
+			if cur.entry == nil {
+				cur.entry = &e
+			}
+			return
+		case common == cur.prefix.Bits():
+			n = &cur.child[addrBit(p.Addr(), common)]
+			continue
+		case common == p.Bits():
+			// The new prefix holds the node: it goes above it.
+			above := &trieNode{prefix: p, entry: &e}
+			above.child[addrBit(cur.prefix.Addr(), common)] = cur
+			*n = above
+		default:
+			// They part at bit common: join them under a branch node there.
+			branch := &trieNode{prefix: netip.PrefixFrom(p.Addr(), common).Masked()}
+			branch.child[addrBit(p.Addr(), common)] = &trieNode{prefix: p, entry: &e}
+			branch.child[addrBit(cur.prefix.Addr(), common)] = cur
+			*n = branch
+		}
+		return
+	}
+}
+
+// lookup returns the most specific entry holding q, an address as a /128 or a
+// whole prefix, or nil when none does.
+func (t *prefixTrie) lookup(q netip.Prefix) *prefixEntry {
+	var best *prefixEntry
+	for n := t.root; n != nil && n.prefix.Bits() <= q.Bits() && n.prefix.Contains(q.Addr()); {
+		if n.entry != nil {
+			best = n.entry
+		}
+		if n.prefix.Bits() == 128 {
+			break
+		}
+		n = n.child[addrBit(q.Addr(), n.prefix.Bits())]
+	}
+	return best
+}
+
+// newPrefixTrie loads a prefix list into a trie.
+func newPrefixTrie(entries []prefixEntry) *prefixTrie {
+	t := &prefixTrie{}
+	for _, e := range entries {
+		t.insert(e)
+	}
+	return t
+}
+
+// lpmResult is the longest-prefix match of an address.
+type lpmResult struct {
+	Address string `json:"address"`
+	Prefix  string `json:"prefix,omitempty"` // empty when nothing matches
+	Label   string `json:"label,omitempty"`
+	Error   string `json:"error,omitempty"` // the address did not parse
+}
+
+// matchAddress looks up an address or prefix in the table.
+func matchAddress(t *prefixTrie, s string) lpmResult {
+	r := lpmResult{Address: s}
+	q, err := parseLPMQuery(s)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if e := t.lookup(q); e != nil {
+		r.Prefix, r.Label = e.Net.String(), e.Label
+	}
+	return r
+}
+
+// parseLPMQuery parses an address, as a /128, or a prefix. Plain IPv6 takes
+// the fast netip parsers; anything else gets the full parser and its
+// diagnostics.
+func parseLPMQuery(s string) (netip.Prefix, error) {
+	if a, err := netip.ParseAddr(s); err == nil && a.Is6() && a.Zone() == "" {
+		return netip.PrefixFrom(a, 128), nil
+	}
+	if p, err := netip.ParsePrefix(s); err == nil && p.Addr().Is6() {
+		return p.Masked(), nil
+	}
+	ip, length, err := parseIPv6WithOptionalPrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address or prefix %q: %v", s, err)
+	}
+	if length < 0 {
+		length = 128
+	}
+	return netip.PrefixFrom(netip.AddrFrom16([16]byte(ip.To16())), length).Masked(), nil
+}
+
+// lpmLine is the text form of a match.
+func lpmLine(r lpmResult) string {
+	switch {
+	case r.Error != "":
+		return r.Address + " invalid"
+	case r.Prefix == "":
+		return r.Address + " -"
+	}
+	return strings.TrimSpace(r.Address + " " + r.Prefix + " " + r.Label)
+}
+
+// matchAddresses looks up each address read from r, one per line with blank
+// lines and '#' comments skipped, passing each result to emit. It returns
+// whether all of them matched.
+func matchAddresses(t *prefixTrie, r io.Reader, emit func(lpmResult) error) (bool, error) {
+	all := true
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		m := matchAddress(t, fields[0])
+		all = all && m.Prefix != ""
+		if err := emit(m); err != nil {
+			return false, err
+		}
+	}
+	return all, scanner.Err()
+}
+
+// runLPM prints the most specific prefix of the table holding address, or with
+// an address of "-", of each address read from stdin. It exits with status 1
+// unless all of them matched.
+func runLPM(tablePath, address string) {
+	entries, err := readPrefixFile(tablePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	t := newPrefixTrie(entries)
+	if address != "-" {
+		r := matchAddress(t, address)
+		if r.Error != "" {
+			log.Fatal(r.Error)
+		}
+		writeResult(r, func() {
+			if r.Prefix == "" {
+				fmt.Println("no match")
+				return
+			}
+			fmt.Println(strings.TrimSpace(r.Prefix + " " + r.Label))
+		})
+		if r.Prefix == "" {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Plain text is buffered for throughput on large inputs; JSON is one
+	// array, and the other formats a result per line.
+	var results []lpmResult
+	out := bufio.NewWriter(os.Stdout)
+	all, err := matchAddresses(t, os.Stdin, func(r lpmResult) error {
+		switch {
+		case outputFormat == "json":
+			results = append(results, r)
+		case outputFormat == "text" && outputTemplate == nil:
+			_, err := fmt.Fprintln(out, lpmLine(r))
+			return err
+		default:
+			writeResult(r, nil)
+		}
+		return nil
+	})
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFormat == "json" {
+		if results == nil {
+			results = []lpmResult{}
+		}
+		writeResult(results, nil)
+	}
+	if !all {
+		os.Exit(1)
+	}
+}
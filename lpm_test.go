@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math/rand"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestPrefixTrie(t *testing.T) {
+	entries, err := readPrefixEntries(strings.NewReader(`::/0 default
+2001:db8::/32 aggregate
+2001:db8:1::/48 site
+2001:db8:1::/48 site-duplicate
+2001:db8:1:5::/64 lan
+2001:db8:1:5::1 router
+2001:db8:8000::/33 upper
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trie := newPrefixTrie(entries)
+	tests := []struct {
+		query, want string
+	}{
+		{"2001:db8:1:5::1", "router"},
+		{"2001:db8:1:5::2", "lan"},
+		{"2001:db8:1:6::1", "site"},
+		{"2001:db8:2::1", "aggregate"},
+		{"2001:db8:9000::1", "upper"},
+		{"3fff::1", "default"},
+		{"2001:db8:1::/56", "site"},
+		{"2001:db8::/31", "default"},
+	}
+	for _, tt := range tests {
+		if got := matchAddress(trie, tt.query); got.Label != tt.want {
+			t.Errorf("%s: got %+v, want %s", tt.query, got, tt.want)
+		}
+	}
+	if got := matchAddress(newPrefixTrie(entries[1:]), "3fff::1"); got.Prefix != "" {
+		t.Errorf("no default route: got %+v", got)
+	}
+	if got := matchAddress(trie, "bogus"); got.Error == "" {
+		t.Error("invalid address accepted")
+	}
+}
+
+func TestPrefixTrieRandom(t *testing.T) {
+	// Check the trie against a linear scan on prefixes clustered so that
+	// they nest and share bits.
+	rng := rand.New(rand.NewSource(1))
+	randomAddr := func() netip.Addr {
+		var a [16]byte
+		a[0], a[1], a[2] = 0x20, 0x01, byte(rng.Intn(4))
+		rng.Read(a[3:])
+		return netip.AddrFrom16(a)
+	}
+	var entries []prefixEntry
+	for i := range 2000 {
+		p := netip.PrefixFrom(randomAddr(), 16+rng.Intn(113)).Masked()
+		entries = append(entries, prefixEntry{Net: prefixToIPNet(p), Line: i + 1})
+	}
+	trie := newPrefixTrie(entries)
+	for range 5000 {
+		var addr netip.Addr
+		if rng.Intn(2) == 0 {
+			// An address inside one of the prefixes.
+			e := entries[rng.Intn(len(entries))]
+			addr = netip.AddrFrom16([16]byte(e.Net.IP.To16()))
+		} else {
+			addr = randomAddr()
+		}
+		var want *prefixEntry
+		for i, e := range entries {
+			if e.Net.Contains(net.IP(addr.AsSlice())) {
+				if ones, _ := e.Net.Mask.Size(); want == nil || ones > mustOnes(want) {
+					want = &entries[i]
+				}
+			}
+		}
+		got := trie.lookup(netip.PrefixFrom(addr, 128))
+		if (got == nil) != (want == nil) || got != nil && got.Net.String() != want.Net.String() {
+			t.Fatalf("%s: got %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func mustOnes(e *prefixEntry) int {
+	ones, _ := e.Net.Mask.Size()
+	return ones
+}
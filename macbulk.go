@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// macPattern matches MAC addresses in colon, hyphen, or Cisco dotted notation as
+// they appear in switch MAC table exports.
+var macPattern = regexp.MustCompile(`\b(?:[0-9A-Fa-f]{2}[:-]){5}[0-9A-Fa-f]{2}\b|\b[0-9A-Fa-f]{4}\.[0-9A-Fa-f]{4}\.[0-9A-Fa-f]{4}\b`)
+
+// slaacAddress is the EUI-64 SLAAC address a host would form in one prefix.
+type slaacAddress struct {
+	Prefix  string `json:"prefix"`
+	Address string `json:"address"`
+}
+
+// macAddresses holds every address derived from one MAC.
+type macAddresses struct {
+	MAC       string         `json:"mac"`
+	LinkLocal string         `json:"link_local"`
+	SLAAC     []slaacAddress `json:"slaac,omitempty"`
+}
+
+// eui64Address combines the /64 network of prefix with the modified EUI-64 interface
+// ID derived from mac (RFC 4291 appendix A).
+func eui64Address(prefix *net.IPNet, mac net.HardwareAddr) net.IP {
+	ip := networkAddress(prefix.IP.To16(), 64)
+	ip[8] = mac[0] ^ 0x02
+	ip[9] = mac[1]
+	ip[10] = mac[2]
+	ip[11] = 0xff
+	ip[12] = 0xfe
+	ip[13] = mac[3]
+	ip[14] = mac[4]
+	ip[15] = mac[5]
+	return ip
+}
+
+// extractMACs finds the first MAC address on each line of a MAC table export and
+// returns the unique MACs in the order first seen. Lines without one (headers,
+// separators) are skipped.
+func extractMACs(r io.Reader) ([]net.HardwareAddr, error) {
+	var macs []net.HardwareAddr
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := macPattern.FindString(scanner.Text())
+		if m == "" {
+			continue
+		}
+		mac, err := net.ParseMAC(m)
+		if err != nil || len(mac) != 6 {
+			continue
+		}
+		if !seen[mac.String()] {
+			seen[mac.String()] = true
+			macs = append(macs, mac)
+		}
+	}
+	return macs, scanner.Err()
+}
+
+// convertMACs derives the link-local address and one SLAAC address per prefix for
+// every MAC. Prefixes must be /64 or shorter; the first /64 of each is used.
+func convertMACs(macs []net.HardwareAddr, prefixes []string) ([]macAddresses, error) {
+	var nets []*net.IPNet
+	for _, p := range prefixes {
+		ipnet, err := parseIPv6Prefix(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix: %v", err)
+		}
+		if ones, _ := ipnet.Mask.Size(); ones > 64 {
+			return nil, fmt.Errorf("SLAAC prefix %s must be /64 or shorter", p)
+		}
+		nets = append(nets, ipnet)
+	}
+	linkLocal := &net.IPNet{IP: net.ParseIP("fe80::"), Mask: net.CIDRMask(64, 128)}
+
+	results := make([]macAddresses, 0, len(macs))
+	for _, mac := range macs {
+		r := macAddresses{MAC: mac.String(), LinkLocal: eui64Address(linkLocal, mac).String()}
+		for _, n := range nets {
+			ones, _ := n.Mask.Size()
+			r.SLAAC = append(r.SLAAC, slaacAddress{
+				Prefix:  fmt.Sprintf("%s/%d", n.IP, ones),
+				Address: eui64Address(n, mac).String(),
+			})
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// writeMACCSV renders the conversion results as CSV, with a column per SLAAC
+// prefix.
+func writeMACCSV(w io.Writer, results []macAddresses) error {
+	cw := csv.NewWriter(w)
+	header := []string{"mac", "link_local"}
+	if len(results) > 0 {
+		for _, s := range results[0].SLAAC {
+			header = append(header, s.Prefix)
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range results {
+		row := []string{r.MAC, r.LinkLocal}
+		for _, s := range r.SLAAC {
+			row = append(row, s.Address)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMACLine prints one conversion result as a line of text.
+func writeMACLine(w io.Writer, r macAddresses) {
+	fields := []string{r.MAC, r.LinkLocal}
+	for _, s := range r.SLAAC {
+		fields = append(fields, s.Address)
+	}
+	fmt.Fprintln(w, strings.Join(fields, "  "))
+}
+
+// convertMACFile converts every MAC in a file and prints the results: as CSV,
+// as one JSON array, or a result per MAC otherwise.
+func convertMACFile(path string, slaacPrefixes string) {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	macs, err := extractMACs(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var prefixes []string
+	if slaacPrefixes != "" {
+		prefixes = strings.Split(slaacPrefixes, ",")
+	}
+	results, err := convertMACs(macs, prefixes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch outputFormat {
+	case "csv":
+		if err := writeMACCSV(os.Stdout, results); err != nil {
+			log.Fatal(err)
+		}
+	case "json":
+		writeResult(results, nil)
+	default:
+		for _, r := range results {
+			writeResult(r, func() { writeMACLine(os.Stdout, r) })
+		}
+	}
+}
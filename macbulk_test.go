@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExtractMACs(t *testing.T) {
+	macs, err := extractMACs(strings.NewReader(`Vlan    Mac Address       Type        Ports
+----    -----------       --------    -----
+  10    0011.2233.4455    DYNAMIC     Gi1/0/1
+  10    a4:5e:60:c2:11:0f DYNAMIC     Gi1/0/2
+  20    0011.2233.4455    DYNAMIC     Gi1/0/1
+  20    F0-18-98-AA-BB-CC STATIC      Gi1/0/7
+Total Mac Addresses for this criterion: 4
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"00:11:22:33:44:55", "a4:5e:60:c2:11:0f", "f0:18:98:aa:bb:cc"}
+	if len(macs) != len(want) {
+		t.Fatalf("expected %d MACs, got %d: %v", len(want), len(macs), macs)
+	}
+	for i, w := range want {
+		if macs[i].String() != w {
+			t.Errorf("MAC %d: expected %s, got %s", i, w, macs[i])
+		}
+	}
+}
+
+func TestConvertMACs(t *testing.T) {
+	macs, _ := extractMACs(strings.NewReader("0011.2233.4455\n"))
+	cases := []struct {
+		name     string
+		prefixes []string
+		want     []string
+		wantErr  bool
+	}{
+		{"link-local only", nil, nil, false},
+		{"one prefix", []string{"2001:db8:10::/64"}, []string{"2001:db8:10:0:211:22ff:fe33:4455"}, false},
+		{"shorter prefix uses first /64", []string{"2001:db8::/48"}, []string{"2001:db8::211:22ff:fe33:4455"}, false},
+		{"longer than /64", []string{"2001:db8::/80"}, nil, true},
+		{"invalid prefix", []string{"2001:db8::1/64"}, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := convertMACs(macs, tc.prefixes)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", results)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if results[0].LinkLocal != "fe80::211:22ff:fe33:4455" {
+				t.Errorf("unexpected link-local %s", results[0].LinkLocal)
+			}
+			if len(results[0].SLAAC) != len(tc.want) {
+				t.Fatalf("expected %d SLAAC addresses, got %+v", len(tc.want), results[0].SLAAC)
+			}
+			for i, w := range tc.want {
+				if results[0].SLAAC[i].Address != w {
+					t.Errorf("expected %s, got %s", w, results[0].SLAAC[i].Address)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteMACAddresses(t *testing.T) {
+	macs, _ := extractMACs(strings.NewReader("00:11:22:33:44:55\n"))
+	results, err := convertMACs(macs, []string{"2001:db8:10::/64"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	writeMACLine(&buf, results[0])
+	if want := "00:11:22:33:44:55  fe80::211:22ff:fe33:4455  2001:db8:10:0:211:22ff:fe33:4455\n"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := writeMACCSV(&buf, results); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "mac,link_local,2001:db8:10::/64\n00:11:22:33:44:55,fe80::211:22ff:fe33:4455,2001:db8:10:0:211:22ff:fe33:4455\n"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// outputManifest describes a generated file so automation can check that it is
+// complete and unmodified, and see how it was produced.
+type outputManifest struct {
+	File       string            `json:"file"`
+	SHA256     string            `json:"sha256"`
+	Bytes      int64             `json:"bytes"`
+	Lines      int               `json:"lines"`
+	Generator  string            `json:"generator"`
+	Created    string            `json:"created,omitempty"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+// manifestPath is where the manifest for an output file is written.
+func manifestPath(outputFile string) string {
+	return outputFile + ".manifest.json"
+}
+
+// buildManifest hashes and counts the lines of r. A final line without a newline
+// still counts.
+func buildManifest(name string, r io.Reader, parameters map[string]string, created time.Time) (outputManifest, error) {
+	m := outputManifest{File: name, Generator: "ipv6utils " + version, Parameters: parameters}
+	if !created.IsZero() {
+		m.Created = created.UTC().Format(time.RFC3339)
+	}
+	h := sha256.New()
+	br := bufio.NewReader(io.TeeReader(r, h))
+	for {
+		line, err := br.ReadString('\n')
+		m.Bytes += int64(len(line))
+		if len(line) > 0 {
+			m.Lines++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, err
+		}
+	}
+	m.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return m, nil
+}
+
+// setFlags returns the flags of fs given on this run.
+func setFlags(fs *flag.FlagSet) map[string]string {
+	parameters := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		parameters[f.Name] = f.Value.String()
+	})
+	return parameters
+}
+
+// commandParameters returns the flags given to a subcommand, with its
+// positional arguments comma-separated under "args".
+func commandParameters(fs *flag.FlagSet, args []string) map[string]string {
+	parameters := setFlags(fs)
+	if len(args) > 0 {
+		parameters["args"] = strings.Join(args, ",")
+	}
+	return parameters
+}
+
+// writeManifest writes the manifest for a finished output file next to it,
+// recording the parameters it was made with. With -stable the creation time is
+// left out so an unchanged plan yields an unchanged manifest.
+func writeManifest(outputFile string, parameters map[string]string) error {
+	f, err := os.Open(outputFile)
+	if err != nil {
+		return fmt.Errorf("manifest: %v", err)
+	}
+	defer f.Close()
+	created := time.Now()
+	if stableOutput {
+		created = time.Time{}
+	}
+	m, err := buildManifest(filepath.Base(outputFile), f, parameters, created)
+	if err != nil {
+		return fmt.Errorf("manifest: %v", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifestPath(outputFile), append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	statusf("Manifest saved to %s\n", manifestPath(outputFile))
+	return nil
+}
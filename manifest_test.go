@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildManifest(t *testing.T) {
+	cases := []struct {
+		name  string
+		data  string
+		lines int
+		sum   string
+	}{
+		{"empty", "", 0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"newline terminated", "3fff::/40\n3fff:0:100::/40\n", 2, ""},
+		{"no final newline", "3fff::/40\n3fff:0:100::/40", 2, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := buildManifest("plan.txt", strings.NewReader(tc.data), map[string]string{"p": "3fff::/32"}, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Lines != tc.lines || m.Bytes != int64(len(tc.data)) {
+				t.Errorf("got %d lines, %d bytes", m.Lines, m.Bytes)
+			}
+			if tc.sum != "" && m.SHA256 != tc.sum {
+				t.Errorf("got sha256 %s", m.SHA256)
+			}
+			if m.Created != "" {
+				t.Errorf("expected no creation time, got %s", m.Created)
+			}
+		})
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "subnets.txt")
+	if err := os.WriteFile(out, []byte("3fff::/40\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeManifest(out, map[string]string{"p": "3fff::/32"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(manifestPath(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m outputManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("invalid manifest JSON: %v", err)
+	}
+	if m.File != "subnets.txt" || m.Lines != 1 || m.Created == "" {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+	if err := writeManifest(filepath.Join(t.TempDir(), "missing.txt"), nil); err == nil {
+		t.Error("expected error for a missing output file")
+	}
+}
@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Merge conflict kinds.
+const (
+	conflictSamePrefix = "same prefix, different name"
+	conflictOverlap    = "overlapping prefixes"
+	conflictSameName   = "duplicate name"
+)
+
+// mergeConflict is a disagreement between the two plans and how it was resolved.
+type mergeConflict struct {
+	Kind       string
+	First      prefixEntry
+	Second     prefixEntry
+	Resolution string
+}
+
+// mergeItem is an entry in the merged plan with the plan it came from: 0 for the
+// first, 1 for the second, or 2 when the prefix is in both.
+type mergeItem struct {
+	Entry   prefixEntry
+	Side    int
+	Dropped bool
+}
+
+// mergePlans combines two prefix-list plans. Identical entries are merged
+// silently. Conflicts are resolved by strategy: "first" or "second" keeps that
+// plan's side, "fail" leaves both sides in place and only reports the conflicts.
+func mergePlans(first, second []prefixEntry, strategy string) ([]prefixEntry, []mergeConflict, error) {
+	if strategy != "first" && strategy != "second" && strategy != "fail" {
+		return nil, nil, fmt.Errorf("unknown merge strategy %q (want first, second, or fail)", strategy)
+	}
+	var items []*mergeItem
+	for _, e := range first {
+		items = append(items, &mergeItem{Entry: e, Side: 0})
+	}
+	var conflicts []mergeConflict
+	resolution := map[string]string{"first": "kept first", "second": "kept second", "fail": "unresolved"}[strategy]
+
+	for _, e := range second {
+		add := true
+		// An allocation present in both plans is only compared by name: its
+		// children in either plan are not overlaps.
+		var same *mergeItem
+		for _, it := range items {
+			if it.Side != 1 && !it.Dropped && it.Entry.Net.String() == e.Net.String() {
+				same = it
+				break
+			}
+		}
+		if same != nil {
+			if same.Entry.Label != e.Label {
+				conflicts = append(conflicts, mergeConflict{Kind: conflictSamePrefix, First: same.Entry, Second: e, Resolution: resolution})
+				if strategy == "second" {
+					same.Entry.Label = e.Label
+				}
+			}
+			same.Side = 2
+			continue
+		}
+		for _, it := range items {
+			if it.Side != 0 || it.Dropped || !prefixesOverlap(it.Entry.Net, e.Net) {
+				continue
+			}
+			conflicts = append(conflicts, mergeConflict{Kind: conflictOverlap, First: it.Entry, Second: e, Resolution: resolution})
+			switch strategy {
+			case "first":
+				add = false
+			case "second":
+				it.Dropped = true
+			}
+		}
+		if add {
+			items = append(items, &mergeItem{Entry: e, Side: 1})
+		}
+	}
+
+	// Names must stay unique: the losing side's duplicate gets a numeric suffix.
+	names := map[string]*mergeItem{}
+	for _, it := range items {
+		if it.Dropped || it.Entry.Label == "" {
+			continue
+		}
+		prev, ok := names[it.Entry.Label]
+		if !ok {
+			names[it.Entry.Label] = it
+			continue
+		}
+		if prev.Side == it.Side || prev.Side == 2 || it.Side == 2 {
+			continue // a name repeated within one plan is that plan's business
+		}
+		firstItem, secondItem := prev, it
+		if prev.Side == 1 {
+			firstItem, secondItem = it, prev
+		}
+		c := mergeConflict{Kind: conflictSameName, First: firstItem.Entry, Second: secondItem.Entry, Resolution: resolution}
+		loser := map[string]*mergeItem{"first": secondItem, "second": firstItem}[strategy]
+		if loser != nil {
+			loser.Entry.Label = uniqueLabel(loser.Entry.Label, names)
+			c.Resolution = "renamed " + loser.Entry.Net.String() + " to " + loser.Entry.Label
+			names[loser.Entry.Label] = loser
+			if loser == prev {
+				names[it.Entry.Label] = it
+			}
+		}
+		conflicts = append(conflicts, c)
+	}
+
+	var merged []prefixEntry
+	for _, it := range items {
+		if !it.Dropped {
+			merged = append(merged, it.Entry)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if c := ipToBigInt(merged[i].Net.IP).Cmp(ipToBigInt(merged[j].Net.IP)); c != 0 {
+			return c < 0
+		}
+		li, _ := merged[i].Net.Mask.Size()
+		lj, _ := merged[j].Net.Mask.Size()
+		return li < lj
+	})
+	return merged, conflicts, nil
+}
+
+// uniqueLabel appends -2, -3, ... to label until it is not in use.
+func uniqueLabel(label string, used map[string]*mergeItem) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", label, n)
+		if _, ok := used[candidate]; !ok {
+			return candidate
+		}
+	}
+}
+
+// describeEntry formats a plan entry as "prefix (name)" for conflict reports.
+func describeEntry(e prefixEntry) string {
+	if e.Label == "" {
+		return e.Net.String()
+	}
+	return fmt.Sprintf("%s (%s)", e.Net, e.Label)
+}
+
+// writeMergeConflicts prints one line per conflict.
+func writeMergeConflicts(w io.Writer, conflicts []mergeConflict) {
+	for _, c := range conflicts {
+		fmt.Fprintf(w, "%s: %s vs %s: %s\n", c.Kind, describeEntry(c.First), describeEntry(c.Second), c.Resolution)
+	}
+}
+
+// runMerge merges two comma-separated plan files, writing the unified plan to
+// outputFile (or stdout) and the conflicts to stderr.
+func runMerge(files string, strategy string, outputFile string) {
+	paths := strings.Split(files, ",")
+	if len(paths) != 2 {
+		log.Fatal("-merge takes exactly two plan files, separated by a comma")
+	}
+	var plans [2][]prefixEntry
+	for i, path := range paths {
+		entries, err := readPrefixFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		plans[i] = entries
+	}
+	merged, conflicts, err := mergePlans(plans[0], plans[1], strategy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeMergeConflicts(os.Stderr, conflicts)
+	if strategy == "fail" && len(conflicts) > 0 {
+		log.Fatalf("%d conflict(s); choose -merge-strategy first or second to resolve them", len(conflicts))
+	}
+	out := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writePrefixList(out, merged); err != nil {
+		log.Fatal(err)
+	}
+	if outputFile != "" {
+		statusf("Merged plan (%d entries, %d conflict(s) resolved) saved to %s\n", len(merged), len(conflicts), outputFile)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMergePlans(t *testing.T) {
+	first, err := readPrefixFile("testdata/plan-acme.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := readPrefixFile("testdata/plan-globex.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		strategy  string
+		conflicts []string
+		merged    string
+	}{
+		{"fail", []string{conflictSamePrefix, conflictOverlap, conflictSameName}, ""},
+		{"first", []string{conflictSamePrefix, conflictOverlap, conflictSameName},
+			"3fff:0:1::/48 site-chicago,3fff:0:1:10::/64 clients-chicago,3fff:0:2::/48 site-denver,3fff:0:2:8000::/49 globex-lab,3fff:0:9::/48 mgmt-2,3fff:0:ffff::/64 mgmt"},
+		{"second", []string{conflictSamePrefix, conflictOverlap, conflictSameName},
+			"3fff:0:1::/48 site-chicago,3fff:0:1:10::/60 globex-wifi,3fff:0:2::/48 globex-hq,3fff:0:2:8000::/49 globex-lab,3fff:0:9::/48 mgmt,3fff:0:ffff::/64 mgmt-2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.strategy, func(t *testing.T) {
+			merged, conflicts, err := mergePlans(first, second, tc.strategy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(conflicts) != len(tc.conflicts) {
+				t.Fatalf("expected conflicts %v, got %+v", tc.conflicts, conflicts)
+			}
+			for i, c := range conflicts {
+				if c.Kind != tc.conflicts[i] {
+					t.Errorf("conflict %d: expected %s, got %s", i, tc.conflicts[i], c.Kind)
+				}
+			}
+			if tc.merged == "" {
+				return
+			}
+			var got []string
+			for _, e := range merged {
+				got = append(got, e.Net.String()+" "+e.Label)
+			}
+			if strings.Join(got, ",") != tc.merged {
+				t.Errorf("expected %s\ngot      %s", tc.merged, strings.Join(got, ","))
+			}
+		})
+	}
+	if _, _, err := mergePlans(first, second, "newest"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestMergeIdenticalPlans(t *testing.T) {
+	plan, _ := readPrefixEntries(strings.NewReader("3fff:0:1::/48 a\n3fff:0:1:10::/64 b\n3fff:0:2::/48\n"))
+	merged, conflicts, err := mergePlans(plan, plan, "fail")
+	if err != nil || len(conflicts) != 0 || len(merged) != 3 {
+		t.Fatalf("expected a clean merge, got %+v %+v: %v", merged, conflicts, err)
+	}
+	var buf bytes.Buffer
+	if err := writePrefixList(&buf, merged); err != nil {
+		t.Fatal(err)
+	}
+	want := "3fff:0:1::/48                                a\n3fff:0:1:10::/64                             b\n3fff:0:2::/48\n"
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math/big"
+	"net/netip"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// subnetNamer gives generated subnets their labels: the line of the names file
+// matching the subnet's index, otherwise the name template rendered with the
+// subnet. Reserved subnets keep their RESERVED label.
+type subnetNamer struct {
+	names    []string
+	template *template.Template
+}
+
+// newSubnetNamer parses -name-template and reads the -names file. It returns
+// nil when neither is set.
+func newSubnetNamer(nameTemplate, namesFile string) (*subnetNamer, error) {
+	if nameTemplate == "" && namesFile == "" {
+		return nil, nil
+	}
+	n := &subnetNamer{}
+	if nameTemplate != "" {
+		t, err := template.New("name").Parse(nameTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name template: %v", err)
+		}
+		n.template = t
+	}
+	if namesFile != "" {
+		names, err := readNames(namesFile)
+		if err != nil {
+			return nil, err
+		}
+		n.names = names
+	}
+	return n, nil
+}
+
+// readNames reads a names file: one name per line, naming the subnets from
+// index 0 on. Blank lines and # comments are skipped.
+func readNames(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, scanner.Err()
+}
+
+// name returns the label for r, or "" when it has none.
+func (n *subnetNamer) name(r subnetRecord) (string, error) {
+	if r.Reserved {
+		return "", nil
+	}
+	return n.render(r.Index, r)
+}
+
+// render returns the name for the subnet with the given index, executing the
+// template with data; "" when there is neither a line nor a template for it.
+func (n *subnetNamer) render(index *big.Int, data any) (string, error) {
+	if n == nil {
+		return "", nil
+	}
+	if index.IsInt64() && index.Int64() < int64(len(n.names)) {
+		return n.names[index.Int64()], nil
+	}
+	if n.template == nil {
+		return "", nil
+	}
+	var sb strings.Builder
+	if err := n.template.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// labelled wraps a record function so each record carries its label.
+func (n *subnetNamer) labelled(record func(netip.Prefix) subnetRecord) func(netip.Prefix) subnetRecord {
+	if n == nil {
+		return record
+	}
+	return func(p netip.Prefix) subnetRecord {
+		r := record(p)
+		label, err := n.name(r)
+		if err != nil {
+			log.Fatalf("name template: %v", err)
+		}
+		r.Label = label
+		return r
+	}
+}
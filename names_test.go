@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubnetNamer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.txt")
+	if err := os.WriteFile(path, []byte("# sites\nhq\n\nlab\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	parent := netip.MustParsePrefix("2001:db8::/62")
+	reserved, _ := reservedSubnets("2001:db8::/62", 64, 0, 1)
+
+	tests := []struct {
+		template, file string
+		want           []string
+	}{
+		{"site-{{.Index}}", "", []string{"site-0", "site-1", "site-2", "RESERVED"}},
+		{`{{printf "vlan%03d" .Index}}`, "", []string{"vlan000", "vlan001", "vlan002", "RESERVED"}},
+		{"", path, []string{"hq", "lab", "", "RESERVED"}},
+		{"site-{{.Index}}", path, []string{"hq", "lab", "site-2", "RESERVED"}},
+	}
+	for _, tt := range tests {
+		namer, err := newSubnetNamer(tt.template, tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		record := namer.labelled(subnetRecords(parent, reserved))
+		var got []string
+		for p := range subnetSeq(parent, 64) {
+			got = append(got, record(p).Name())
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("got %v", got)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q %q: names %v, want %v", tt.template, tt.file, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestNewSubnetNamerErrors(t *testing.T) {
+	if n, err := newSubnetNamer("", ""); n != nil || err != nil {
+		t.Errorf("no template or file: got %v, %v", n, err)
+	}
+	if _, err := newSubnetNamer("{{.Index", ""); err == nil {
+		t.Error("expected an error for an unterminated template")
+	}
+	if _, err := newSubnetNamer("", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing names file")
+	}
+}
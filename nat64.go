@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// nat64Lengths are the prefix lengths RFC 6052 section 2.2 defines.
+var nat64Lengths = []int{32, 40, 48, 56, 64, 96}
+
+// wellKnownNAT64 is the Well-Known Prefix of RFC 6052 section 2.1.
+var wellKnownNAT64 = netip.MustParsePrefix("64:ff9b::/96")
+
+// nat64UOctet is the byte of bits 64 to 71, which RFC 6052 reserves and which
+// must be zero: the IPv4 address is split around it.
+const nat64UOctet = 8
+
+// nat64Octets returns the bytes of the address that hold the four octets of
+// the IPv4 address under a prefix of length prefixLen: from the end of the
+// prefix on, skipping the u-octet.
+func nat64Octets(prefixLen int) ([]int, error) {
+	if !slices.Contains(nat64Lengths, prefixLen) {
+		return nil, fmt.Errorf("NAT64 prefix length must be 32, 40, 48, 56, 64, or 96, got %d", prefixLen)
+	}
+	var octets []int
+	for i := prefixLen / 8; len(octets) < 4; i++ {
+		if i != nat64UOctet {
+			octets = append(octets, i)
+		}
+	}
+	return octets, nil
+}
+
+// parseNAT64Prefix reads a NAT64 prefix, such as 64:ff9b::/96. Without a
+// prefix length the prefix is taken to be a /96, as with -k 64:ff9b::; a
+// length other than 0 overrides it. The prefix must be one of the lengths of
+// RFC 6052, have no bits set past its length, and leave the u-octet zero.
+func parseNAT64Prefix(s string, length int) (netip.Prefix, error) {
+	p, err := readNAT64Prefix(s, length)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if errs := nat64PrefixErrors(p); len(errs) > 0 {
+		return netip.Prefix{}, errs[0]
+	}
+	return p, nil
+}
+
+// readNAT64Prefix reads a NAT64 prefix as parseNAT64Prefix does, without
+// checking it against RFC 6052.
+func readNAT64Prefix(s string, length int) (netip.Prefix, error) {
+	text, lenText, hasLength := strings.Cut(s, "/")
+	a, err := netip.ParseAddr(text)
+	if err != nil || !a.Is6() || a.Is4In6() {
+		return netip.Prefix{}, fmt.Errorf("invalid NAT64 prefix %q", s)
+	}
+	bits := 96
+	if hasLength {
+		if _, err := fmt.Sscanf(lenText, "%d", &bits); err != nil || bits < 0 || bits > 128 {
+			return netip.Prefix{}, fmt.Errorf("invalid NAT64 prefix %q", s)
+		}
+	}
+	if length != 0 {
+		bits = length
+	}
+	return netip.PrefixFrom(a, bits), nil
+}
+
+// nat64PrefixErrors returns every way a prefix breaks the rules of RFC 6052
+// section 2.2: its length, bits set past it, and the u-octet.
+func nat64PrefixErrors(p netip.Prefix) []error {
+	var errs []error
+	if _, err := nat64Octets(p.Bits()); err != nil {
+		errs = append(errs, err)
+	}
+	if p.Masked() != p {
+		errs = append(errs, fmt.Errorf("NAT64 prefix %s has bits set past /%d", p.Addr(), p.Bits()))
+	}
+	if p.Addr().As16()[nat64UOctet] != 0 {
+		errs = append(errs, fmt.Errorf("NAT64 prefix %s sets bits 64 to 71, the u-octet, which must be zero", p))
+	}
+	return errs
+}
+
+// synthesizeNAT64 embeds an IPv4 address in a NAT64 prefix as RFC 6052
+// section 2.2 lays it out, with the suffix after it zero.
+func synthesizeNAT64(prefix netip.Prefix, v4 netip.Addr) (netip.Addr, error) {
+	octets, err := nat64Octets(prefix.Bits())
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	b, ip := prefix.Masked().Addr().As16(), v4.As4()
+	for i, o := range octets {
+		b[o] = ip[i]
+	}
+	return netip.AddrFrom16(b), nil
+}
+
+// extractNAT64 returns the IPv4 address embedded in a synthesized address
+// under a prefix of length prefixLen, and that prefix.
+func extractNAT64(a netip.Addr, prefixLen int) (netip.Addr, netip.Prefix, error) {
+	octets, err := nat64Octets(prefixLen)
+	if err != nil {
+		return netip.Addr{}, netip.Prefix{}, err
+	}
+	b := a.As16()
+	if b[nat64UOctet] != 0 && prefixLen != 96 {
+		return netip.Addr{}, netip.Prefix{}, fmt.Errorf("%s sets the u-octet, so is not synthesized under a /%d", a, prefixLen)
+	}
+	var ip [4]byte
+	for i, o := range octets {
+		ip[i] = b[o]
+	}
+	return netip.AddrFrom4(ip), netip.PrefixFrom(a, prefixLen).Masked(), nil
+}
+
+// detectNAT64Length guesses the length of the prefix an address was
+// synthesized under. The Well-Known Prefix, and any address setting the
+// u-octet, is a /96. Otherwise the suffix after the IPv4 address is zero, so
+// the shortest length leaving a zero suffix is taken, falling back to /96. An
+// IPv4 address ending in zero octets looks like one under a shorter prefix,
+// so when the length is known it is better given.
+func detectNAT64Length(a netip.Addr) int {
+	b := a.As16()
+	if wellKnownNAT64.Contains(a) || b[nat64UOctet] != 0 {
+		return 96
+	}
+	for _, n := range nat64Lengths[:len(nat64Lengths)-1] {
+		octets, _ := nat64Octets(n)
+		if !slices.ContainsFunc(b[octets[3]+1:], func(x byte) bool { return x != 0 }) {
+			return n
+		}
+	}
+	return 96
+}
+
+// localUseNAT64 is the prefix RFC 8215 sets aside for network-specific NAT64
+// prefixes of local use.
+var localUseNAT64 = netip.MustParsePrefix("64:ff9b:1::/48")
+
+// nat64Check is whether a prefix can be used for NAT64, and why not.
+type nat64Check struct {
+	Prefix   string   `json:"prefix"`
+	Valid    bool     `json:"valid"`
+	Kind     string   `json:"kind"` // well-known, local-use, or network-specific
+	Problems []string `json:"problems"`
+}
+
+// checkNAT64Prefix checks a candidate NAT64 prefix against RFC 6052: its
+// length, the u-octet, and the special-purpose registry entries it overlaps,
+// other than the Well-Known Prefix itself and the local-use prefix of RFC 8215
+// that it may lie within.
+func checkNAT64Prefix(s string, length int, entries []specialEntry) (nat64Check, error) {
+	p, err := readNAT64Prefix(s, length)
+	if err != nil {
+		return nat64Check{}, err
+	}
+	c := nat64Check{Prefix: prefixText(p), Kind: "network-specific", Problems: []string{}}
+	switch {
+	case p == wellKnownNAT64:
+		c.Kind = "well-known"
+	case localUseNAT64.Contains(p.Addr()) && p.Bits() >= localUseNAT64.Bits():
+		c.Kind = "local-use"
+	}
+	for _, err := range nat64PrefixErrors(p) {
+		c.Problems = append(c.Problems, err.Error())
+	}
+	for _, e := range entries {
+		if !e.Prefix.Overlaps(p) || e.Prefix == wellKnownNAT64 && c.Kind == "well-known" ||
+			e.Prefix == localUseNAT64 && c.Kind == "local-use" {
+			continue
+		}
+		c.Problems = append(c.Problems, fmt.Sprintf("overlaps %s, %s (%s)", prefixText(e.Prefix), e.Name, e.RFC))
+	}
+	if classifyAddress(p.Addr()).Class == "multicast" {
+		c.Problems = append(c.Problems, "is a multicast prefix")
+	}
+	c.Valid = len(c.Problems) == 0
+	return c, nil
+}
+
+// runNAT64Check prints whether a prefix can be used for NAT64, and whether it
+// is the Well-Known Prefix or a network-specific one, checking it against the
+// embedded special-purpose registry or registry. It exits with status 1 when
+// the prefix cannot be used.
+func runNAT64Check(prefix string, length int, registry string) {
+	entries, err := loadSpecialRegistry(registry)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c, err := checkNAT64Prefix(prefix, length, entries)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeResult(c, func() {
+		valid := "yes"
+		if !c.Valid {
+			valid = "no"
+		}
+		fmt.Printf("%-10s%s\n", "Prefix:", c.Prefix)
+		fmt.Printf("%-10s%s\n", "Valid:", valid)
+		fmt.Printf("%-10s%s\n", "Kind:", c.Kind)
+		for _, p := range c.Problems {
+			fmt.Printf("%-10s%s\n", "Problem:", p)
+		}
+	})
+	if !c.Valid {
+		os.Exit(1)
+	}
+}
+
+// ipv4OnlyARPA are the addresses of ipv4only.arpa, RFC 7050, which a DNS64
+// resolver synthesizes AAAA records from, so its NAT64 prefix can be learned.
+var ipv4OnlyARPA = []netip.Addr{netip.MustParseAddr("192.0.0.170"), netip.MustParseAddr("192.0.0.171")}
+
+// findNAT64 looks for an IPv4 address that match accepts embedded in a under
+// a prefix of each length of RFC 6052, the longest first, returning it and the
+// prefix.
+func findNAT64(a netip.Addr, match func(netip.Addr) bool) (netip.Addr, netip.Prefix, bool) {
+	for _, n := range slices.Backward(nat64Lengths) {
+		v4, p, err := extractNAT64(a, n)
+		if err == nil && match(v4) {
+			return v4, p, true
+		}
+	}
+	return netip.Addr{}, netip.Prefix{}, false
+}
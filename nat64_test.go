@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+)
+
+// rfc6052Examples is the table of RFC 6052 section 2.4: 192.0.2.33 under
+// each length of prefix.
+var rfc6052Examples = []struct{ prefix, addr string }{
+	{"2001:db8::/32", "2001:db8:c000:221::"},
+	{"2001:db8:100::/40", "2001:db8:1c0:2:21::"},
+	{"2001:db8:122::/48", "2001:db8:122:c000:2:2100::"},
+	{"2001:db8:122:300::/56", "2001:db8:122:3c0:0:221::"},
+	{"2001:db8:122:344::/64", "2001:db8:122:344:c0:2:2100:0"},
+	{"2001:db8:122:344::/96", "2001:db8:122:344::c000:221"},
+}
+
+func TestSynthesizeNAT64(t *testing.T) {
+	v4 := netip.MustParseAddr("192.0.2.33")
+	for _, tt := range rfc6052Examples {
+		p, err := parseNAT64Prefix(tt.prefix, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := synthesizeNAT64(p, v4)
+		if err != nil || got.String() != tt.addr {
+			t.Errorf("%s: got %s, %v, want %s", tt.prefix, got, err, tt.addr)
+		}
+	}
+}
+
+func TestExtractNAT64(t *testing.T) {
+	for _, tt := range rfc6052Examples {
+		a := netip.MustParseAddr(tt.addr)
+		want := netip.MustParsePrefix(tt.prefix)
+		if n := detectNAT64Length(a); n != want.Bits() {
+			t.Errorf("%s: detected /%d, want /%d", tt.addr, n, want.Bits())
+		}
+		v4, p, err := extractNAT64(a, want.Bits())
+		if err != nil || v4.String() != "192.0.2.33" || p != want {
+			t.Errorf("%s: got %s %s, %v, want 192.0.2.33 %s", tt.addr, v4, p, err, want)
+		}
+	}
+	if n := detectNAT64Length(netip.MustParseAddr("64:ff9b::a00:0")); n != 96 {
+		t.Errorf("well-known prefix detected as /%d, want /96", n)
+	}
+	if _, _, err := extractNAT64(netip.MustParseAddr("2001:db8:122:344:ff00:2:2100:0"), 64); err == nil {
+		t.Error("u-octet set under a /64 accepted")
+	}
+}
+
+func TestParseNAT64Prefix(t *testing.T) {
+	tests := []struct {
+		in     string
+		length int
+		want   string
+	}{
+		{"64:ff9b::", 0, "64:ff9b::/96"},
+		{"2001:db8:100::/40", 0, "2001:db8:100::/40"},
+		{"2001:db8:100::", 40, "2001:db8:100::/40"},
+		{"2001:db8::/33", 0, ""},          // not an RFC 6052 length
+		{"2001:db8::1/64", 0, ""},         // bits past the length
+		{"2001:db8:0:0:ff00::/96", 0, ""}, // u-octet set
+		{"192.0.2.0/24", 0, ""},
+	}
+	for _, tt := range tests {
+		p, err := parseNAT64Prefix(tt.in, tt.length)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: got %s, want an error", tt.in, p)
+			}
+			continue
+		}
+		if err != nil || p.String() != tt.want {
+			t.Errorf("%s: got %s, %v, want %s", tt.in, p, err, tt.want)
+		}
+	}
+}
+
+func TestCheckNAT64Prefix(t *testing.T) {
+	entries, err := loadSpecialRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in       string
+		kind     string
+		problems int
+	}{
+		{"64:ff9b::", "well-known", 0},
+		{"64:ff9b::/96", "well-known", 0},
+		{"64:ff9b:1:ab00::/56", "local-use", 0},
+		{"2001:470:b:64::/96", "network-specific", 0},
+		{"64:ff9b::/64", "network-specific", 1},      // holds the Well-Known Prefix
+		{"64:ff9b::/32", "network-specific", 2},      // and the local-use prefix
+		{"2001:db8:122::/48", "network-specific", 1}, // documentation
+		{"2001:470:b:64::/33", "network-specific", 2},
+		{"2001:470:b:64:100::/96", "network-specific", 1},
+		{"ff0e::/96", "network-specific", 1},
+	}
+	for _, tt := range tests {
+		c, err := checkNAT64Prefix(tt.in, 0, entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Kind != tt.kind || len(c.Problems) != tt.problems || c.Valid != (tt.problems == 0) {
+			t.Errorf("%s: got %+v, want kind %s with %d problems", tt.in, c, tt.kind, tt.problems)
+		}
+	}
+	if _, err := checkNAT64Prefix("192.0.2.0/24", 0, entries); err == nil {
+		t.Error("IPv4 prefix accepted")
+	}
+}
+
+func TestNAT64ConverterGivenPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		length int
+		in     string
+		want   string
+	}{
+		// An IPv4 address ending in .0 looks like one under a shorter
+		// prefix; a prefix given decides the length.
+		{"2001:db8::/96", 0, "2001:db8::a00:0", "10.0.0.0"},
+		{"2001:db8::", 0, "2001:db8::a00:0", "10.0.0.0"},
+		{"2001:db8:122::/48", 0, "2001:db8:122:c000:2::", "192.0.2.0"},
+		{"", 48, "2001:db8:122:c000:2::", "192.0.2.0"},
+		{"", 0, "2001:db8:122:3c0:0:221::", "192.0.2.33"},
+	}
+	for _, tt := range tests {
+		c, err := nat64Converter(tt.prefix, tt.length)(tt.in)
+		if err != nil {
+			t.Errorf("%s under %q: %v", tt.in, tt.prefix, err)
+			continue
+		}
+		if got := c.Result.(nat64Result).IPv4; got != tt.want {
+			t.Errorf("%s under %q: got %s, want %s", tt.in, tt.prefix, got, tt.want)
+		}
+	}
+	if _, err := nat64Converter("2001:db8::/96", 0)("2001:db9::a00:0"); err == nil {
+		t.Error("address outside the given prefix accepted")
+	}
+	if c, err := nat64Converter("", 0)("192.0.2.1"); err != nil || c.Result.(nat64Result).IPv6 != "64:ff9b::c000:201" {
+		t.Errorf("no prefix: got %+v, %v, want 64:ff9b::c000:201", c, err)
+	}
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+)
+
+// prefixNeighbors is a prefix with its siblings of the same size and its place
+// under a parent.
+type prefixNeighbors struct {
+	Prefix   *net.IPNet
+	Previous *net.IPNet // nil for the first prefix of the address space
+	Next     *net.IPNet // nil for the last
+	Parent   *net.IPNet
+	Index    *big.Int // 0-based position among the parent's children of this size
+	Siblings *big.Int
+}
+
+// defaultParentLength is the nearest nibble boundary above a prefix length, so
+// a /64 sits in a /60 and a /48 in a /44.
+func defaultParentLength(prefixLen int) int {
+	if prefixLen == 0 {
+		return 0
+	}
+	return (prefixLen - 1) / 4 * 4
+}
+
+// findNeighbors computes the siblings of a prefix and its position within the
+// parent of parentLen.
+func findNeighbors(prefix string, parentLen int) (prefixNeighbors, error) {
+	ipnet, err := parseIPv6Prefix(prefix)
+	if err != nil {
+		return prefixNeighbors{}, fmt.Errorf("invalid prefix: %v", err)
+	}
+	prefixLen, _ := ipnet.Mask.Size()
+	if parentLen < 0 || parentLen > prefixLen {
+		return prefixNeighbors{}, fmt.Errorf("parent length must be between 0 and %d", prefixLen)
+	}
+	n := prefixNeighbors{Prefix: ipnet}
+	mask := ipnet.Mask
+	step := new(big.Int).Lsh(big.NewInt(1), uint(128-prefixLen))
+	v := ipToBigInt(ipnet.IP)
+	if v.Sign() > 0 {
+		n.Previous = &net.IPNet{IP: bigIntToIP(new(big.Int).Sub(v, step)), Mask: mask}
+	}
+	last := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), step)
+	if v.Cmp(last) < 0 {
+		n.Next = &net.IPNet{IP: bigIntToIP(new(big.Int).Add(v, step)), Mask: mask}
+	}
+	parentMask := net.CIDRMask(parentLen, 128)
+	n.Parent = &net.IPNet{IP: ipnet.IP.Mask(parentMask), Mask: parentMask}
+	n.Index = new(big.Int).Rsh(new(big.Int).Sub(v, ipToBigInt(n.Parent.IP)), uint(128-prefixLen))
+	n.Siblings = new(big.Int).Lsh(big.NewInt(1), uint(prefixLen-parentLen))
+	return n, nil
+}
+
+// writeNeighbors prints the neighbors report. Siblings outside the parent are
+// marked so it is clear when a step crosses into the next parent.
+func writeNeighbors(w io.Writer, n prefixNeighbors) {
+	describe := func(p *net.IPNet, edge string) string {
+		if p == nil {
+			return "none (" + edge + " of the address space)"
+		}
+		if !n.Parent.Contains(p.IP) {
+			return p.String() + " (outside " + n.Parent.String() + ")"
+		}
+		return p.String()
+	}
+	fmt.Fprintf(w, "%-16s %s\n", "Prefix:", n.Prefix)
+	fmt.Fprintf(w, "%-16s %s\n", "Previous:", describe(n.Previous, "start"))
+	fmt.Fprintf(w, "%-16s %s\n", "Next:", describe(n.Next, "end"))
+	fmt.Fprintf(w, "%-16s %s\n", "Parent:", n.Parent)
+	fmt.Fprintf(w, "%-16s %s of %s (index %s)\n", "Position:", new(big.Int).Add(n.Index, big.NewInt(1)), n.Siblings, n.Index)
+}
+
+// reportNeighbors prints the neighbors of a prefix. A parentLen below zero uses
+// the nearest nibble boundary.
+func reportNeighbors(prefix string, parentLen int) {
+	if parentLen < 0 {
+		ipnet, err := parseIPv6Prefix(prefix)
+		if err != nil {
+			log.Fatalf("invalid prefix: %v", err)
+		}
+		ones, _ := ipnet.Mask.Size()
+		parentLen = defaultParentLength(ones)
+	}
+	n, err := findNeighbors(prefix, parentLen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeResult(newNeighborsResult(n), func() {
+		writeNeighbors(os.Stdout, n)
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDefaultParentLength(t *testing.T) {
+	cases := map[int]int{64: 60, 48: 44, 56: 52, 63: 60, 1: 0, 0: 0, 128: 124}
+	for in, want := range cases {
+		if got := defaultParentLength(in); got != want {
+			t.Errorf("defaultParentLength(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestFindNeighbors(t *testing.T) {
+	cases := []struct {
+		prefix    string
+		parentLen int
+		previous  string
+		next      string
+		parent    string
+		index     string
+		siblings  string
+	}{
+		{"2001:db8:0:120::/64", 48, "2001:db8:0:11f::/64", "2001:db8:0:121::/64", "2001:db8::/48", "288", "65536"},
+		{"2001:db8:0:12f::/64", 60, "2001:db8:0:12e::/64", "2001:db8:0:130::/64", "2001:db8:0:120::/60", "15", "16"},
+		{"::/64", 60, "", "0:0:0:1::/64", "::/60", "0", "16"},
+		{"ffff:ffff:ffff:ffff::/64", 0, "ffff:ffff:ffff:fffe::/64", "", "::/0", "18446744073709551615", "18446744073709551616"},
+		{"2001:db8::/32", 32, "2001:db7::/32", "2001:db9::/32", "2001:db8::/32", "0", "1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			n, err := findNeighbors(tc.prefix, tc.parentLen)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			str := func(p interface{ String() string }, isNil bool) string {
+				if isNil {
+					return ""
+				}
+				return p.String()
+			}
+			if got := str(n.Previous, n.Previous == nil); got != tc.previous {
+				t.Errorf("previous: expected %q, got %q", tc.previous, got)
+			}
+			if got := str(n.Next, n.Next == nil); got != tc.next {
+				t.Errorf("next: expected %q, got %q", tc.next, got)
+			}
+			if n.Parent.String() != tc.parent || n.Index.String() != tc.index || n.Siblings.String() != tc.siblings {
+				t.Errorf("got parent %s index %s of %s", n.Parent, n.Index, n.Siblings)
+			}
+		})
+	}
+}
+
+func TestFindNeighborsErrors(t *testing.T) {
+	if _, err := findNeighbors("2001:db8::1/64", 48); err == nil {
+		t.Error("expected error for a prefix with host bits set")
+	}
+	if _, err := findNeighbors("2001:db8::/48", 56); err == nil || !strings.Contains(err.Error(), "between 0 and 48") {
+		t.Errorf("expected parent length error, got %v", err)
+	}
+}
+
+func TestWriteNeighbors(t *testing.T) {
+	n, err := findNeighbors("2001:db8:0:120::/64", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	writeNeighbors(&buf, n)
+	for _, want := range []string{
+		"Previous:        2001:db8:0:11f::/64 (outside 2001:db8:0:120::/60)\n",
+		"Position:        1 of 16 (index 0)\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
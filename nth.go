@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"net/netip"
+)
+
+// nthResult is the subnet or address at an index of a prefix.
+type nthResult struct {
+	Prefix  string `json:"prefix"`
+	Length  int    `json:"length"`
+	Index   string `json:"index"`
+	FromEnd bool   `json:"from_end"`
+	Result  string `json:"result"` // an address when Length is 128
+}
+
+// nthSubnet returns the /newLen subnet of parent at a 0-based index, counted
+// from the last one with fromEnd. With a newLen of 128 the subnet is a single
+// address.
+func nthSubnet(parent netip.Prefix, newLen int, index *big.Int, fromEnd bool) (netip.Prefix, error) {
+	if newLen < parent.Bits() || newLen > 128 {
+		return netip.Prefix{}, fmt.Errorf("length must be between %d and 128, got %d", parent.Bits(), newLen)
+	}
+	count := new(big.Int).Lsh(big.NewInt(1), uint(newLen-parent.Bits()))
+	if index.Sign() < 0 || index.Cmp(count) >= 0 {
+		return netip.Prefix{}, fmt.Errorf("index %s is out of range: %s holds %s /%d", index, parent, count, newLen)
+	}
+	i := new(big.Int).Set(index)
+	if fromEnd {
+		i.Sub(count, i).Sub(i, big.NewInt(1))
+	}
+	addr := addBigIntToIP(parent.Addr().AsSlice(), i.Lsh(i, uint(128-newLen)))
+	return netip.PrefixFrom(netip.AddrFrom16([16]byte(addr)), newLen), nil
+}
+
+// runNth prints the address, or /newLen subnet, of a prefix at an index, as used
+// for numbering hosts by convention: the router at ::1, the last address of a
+// pool, the 42nd /64.
+func runNth(prefix string, newLen int, index string, fromEnd bool) {
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	i, ok := new(big.Int).SetString(index, 0)
+	if !ok {
+		log.Fatalf("invalid index %q: want a decimal or 0x hex number", index)
+	}
+	subnet, err := nthSubnet(parent, newLen, i, fromEnd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	result := nthResult{Prefix: prefixText(parent), Length: newLen, Index: i.String(), FromEnd: fromEnd, Result: prefixText(subnet)}
+	if newLen == 128 {
+		result.Result = addrText(subnet.Addr())
+	}
+	writeResult(result, func() {
+		fmt.Println(result.Result)
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math/big"
+	"net/netip"
+	"testing"
+)
+
+func TestNthSubnet(t *testing.T) {
+	parent := netip.MustParsePrefix("2001:db8:1::/48")
+	tests := []struct {
+		newLen  int
+		index   int64
+		fromEnd bool
+		want    string
+	}{
+		{128, 0, false, "2001:db8:1::/128"},
+		{128, 42, false, "2001:db8:1::2a/128"},
+		{128, 0, true, "2001:db8:1:ffff:ffff:ffff:ffff:ffff/128"},
+		{128, 1, true, "2001:db8:1:ffff:ffff:ffff:ffff:fffe/128"},
+		{64, 42, false, "2001:db8:1:2a::/64"},
+		{64, 0, true, "2001:db8:1:ffff::/64"},
+		{48, 0, false, "2001:db8:1::/48"},
+	}
+	for _, tt := range tests {
+		got, err := nthSubnet(parent, tt.newLen, big.NewInt(tt.index), tt.fromEnd)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("/%d index %d from end %v: got %v, %v, want %s", tt.newLen, tt.index, tt.fromEnd, got, err, tt.want)
+		}
+	}
+	for _, bad := range []struct {
+		newLen int
+		index  int64
+	}{{64, 65536}, {64, -1}, {47, 0}, {129, 0}} {
+		if _, err := nthSubnet(parent, bad.newLen, big.NewInt(bad.index), false); err == nil {
+			t.Errorf("/%d index %d: no error", bad.newLen, bad.index)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+)
+
+// ouiRegistry maps an OUI, the first three bytes of a MAC as six upper-case hex
+// digits, to the organization the IEEE assigned it to.
+type ouiRegistry map[string]string
+
+// readOUIRegistry reads the IEEE MA-L registry in its CSV form: Registry,
+// Assignment, Organization Name, Organization Address.
+func readOUIRegistry(r io.Reader) (ouiRegistry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	o := ouiRegistry{}
+	for i, rec := range records {
+		if i == 0 || len(rec) < 3 || len(rec[1]) != 6 {
+			continue
+		}
+		o[strings.ToUpper(rec[1])] = strings.TrimSpace(rec[2])
+	}
+	if len(o) == 0 {
+		return nil, fmt.Errorf("no assignments found")
+	}
+	return o, nil
+}
+
+// loadCachedOUIRegistry reads the registry -update-data oui downloaded, or
+// returns nil when there is none, leaving every vendor unknown.
+func loadCachedOUIRegistry() ouiRegistry {
+	paths, ok := datasets["oui"].cached()
+	if !ok {
+		return nil
+	}
+	f, err := os.Open(paths[0])
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	defer f.Close()
+	o, err := readOUIRegistry(f)
+	if err != nil {
+		log.Printf("%s: %v", paths[0], err)
+		return nil
+	}
+	return o
+}
+
+// vendor returns the organization assigned the OUI of mac, or "unknown" when
+// the registry does not list it or was never downloaded.
+func (o ouiRegistry) vendor(mac string) string {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) < 3 {
+		return "unknown"
+	}
+	if name, ok := o[fmt.Sprintf("%02X%02X%02X", hw[0], hw[1], hw[2])]; ok {
+		return name
+	}
+	return "unknown"
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOUICSV = `Registry,Assignment,Organization Name,Organization Address
+MA-L,001122,"CIMSYS Inc","#301,Sinsung-clean BLDG,140, Nongseo-Ri,Kiheung-Eup Yongin-City Kyunggi-Do KR 449-711 "
+MA-L,3C22FB,Apple Inc.,1 Infinite Loop Cupertino CA US 95014 
+`
+
+func TestOUIRegistryVendor(t *testing.T) {
+	o, err := readOUIRegistry(strings.NewReader(testOUICSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ mac, want string }{
+		{"00:11:22:33:44:55", "CIMSYS Inc"},
+		{"3c:22:fb:01:02:03", "Apple Inc."},
+		{"02:11:22:33:44:55", "unknown"}, // locally administered
+		{"not-a-mac", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := o.vendor(tt.mac); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.mac, got, tt.want)
+		}
+	}
+	if got := ouiRegistry(nil).vendor("00:11:22:33:44:55"); got != "unknown" {
+		t.Errorf("no registry: got %q, want unknown", got)
+	}
+}
+
+func TestLoadCachedOUIRegistry(t *testing.T) {
+	defer func(root string) { cacheRoot = root }(cacheRoot)
+	cacheRoot = t.TempDir()
+	if o := loadCachedOUIRegistry(); o != nil {
+		t.Fatalf("nothing cached: got %d entries", len(o))
+	}
+	d := datasets["oui"]
+	dir, err := cacheDir(d.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, cacheFileName(d.URLs[0])), []byte(testOUICSV), 0o644)
+	if got := loadCachedOUIRegistry().vendor("00:11:22:33:44:55"); got != "CIMSYS Inc" {
+		t.Errorf("cached registry: got %q", got)
+	}
+}
@@ -0,0 +1,567 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math/big"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormat selects how the core commands print their results: text for
+// people, or json or ndjson (one compact object per line) for scripts. Subnet
+// generation also takes csv, yaml, sql, sqlite, and xlsx; commandOutputFormats
+// lists what each command takes. Set by -output-format.
+var outputFormat = "text"
+
+// outputTemplateText is a text/template that replaces the text output of the
+// core commands, run once per result or generated subnet. Set by -template.
+var outputTemplateText string
+
+// outputTemplate is outputTemplateText parsed by checkOutputFormat.
+var outputTemplate *template.Template
+
+// explodedOutput writes addresses and prefixes fully expanded, for DNS and
+// vendor tools that want every leading zero. Set by -exploded.
+var explodedOutput bool
+
+// addrText is an address as the output is to show it: compressed, or expanded
+// with -exploded.
+func addrText(a netip.Addr) string {
+	if explodedOutput {
+		return expandedForm(a)
+	}
+	return a.String()
+}
+
+// prefixText is a prefix as the output is to show it.
+func prefixText(p netip.Prefix) string {
+	return fmt.Sprintf("%s/%d", addrText(p.Addr()), p.Bits())
+}
+
+// checkOutputFormat validates -output-format against the formats command can
+// write, going by commandOutputFormats, and parses -template, which only
+// commands with structured output take.
+func checkOutputFormat(command string) error {
+	formats := commandOutputFormats[command]
+	if outputFormat != "text" && !slices.Contains(formats, outputFormat) {
+		if len(formats) == 0 {
+			return fmt.Errorf("%s writes only text, not -output-format %s", command, outputFormat)
+		}
+		return fmt.Errorf("%s cannot write -output-format %s (want text, %s)", command, outputFormat, strings.Join(formats, ", "))
+	}
+	if outputTemplateText == "" {
+		return nil
+	}
+	if outputFormat != "text" {
+		return fmt.Errorf("use -template or -output-format %s, not both", outputFormat)
+	}
+	if len(formats) == 0 {
+		return fmt.Errorf("%s writes only text and takes no -template", command)
+	}
+	t, err := template.New("output").Parse(outputTemplateText)
+	if err != nil {
+		return fmt.Errorf("invalid template: %v", err)
+	}
+	outputTemplate = t
+	return nil
+}
+
+// executeTemplate renders v with -template as one line, adding the newline the
+// template leaves off.
+func executeTemplate(w io.Writer, v any) error {
+	var sb strings.Builder
+	if err := outputTemplate.Execute(&sb, v); err != nil {
+		return err
+	}
+	line := sb.String()
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	_, err := io.WriteString(w, line)
+	return err
+}
+
+// writeJSON prints v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeResult prints a command's result: v as JSON with -output-format json or
+// ndjson, through -template if given, otherwise through text.
+func writeResult(v any, text func()) {
+	var err error
+	switch outputFormat {
+	case "json":
+		err = writeJSON(os.Stdout, v)
+	case "ndjson":
+		err = json.NewEncoder(os.Stdout).Encode(v)
+	case "csv", "yaml", "sql", "sqlite", "xlsx":
+		log.Fatalf("%s output is only supported for subnet generation", outputFormat)
+	default:
+		if outputTemplate != nil {
+			err = executeTemplate(os.Stdout, v)
+			break
+		}
+		text()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// nat64Result is the JSON form of an RFC 6052 conversion.
+type nat64Result struct {
+	IPv4   string `json:"ipv4"`
+	IPv6   string `json:"ipv6"`
+	Prefix string `json:"prefix"` // the NAT64 prefix, with its length
+}
+
+// macResult is the JSON form of a MAC conversion.
+type macResult struct {
+	MAC     string `json:"mac"`
+	Address string `json:"address"`
+}
+
+// arpaResult is the JSON form of a reverse DNS name.
+type arpaResult struct {
+	Address    string `json:"address"`
+	ZoneLength int    `json:"zone_length"`
+	Name       string `json:"name"`
+}
+
+// countResult is the JSON form of a subnet count.
+type countResult struct {
+	Prefix    string `json:"prefix"`
+	NewLength int    `json:"new_length"`
+	Count     string `json:"count"` // decimal, as it can exceed 64 bits
+}
+
+// neighborsResult is the JSON form of prefixNeighbors. Previous and Next are
+// null at the ends of the address space.
+type neighborsResult struct {
+	Prefix   string   `json:"prefix"`
+	Previous *string  `json:"previous"`
+	Next     *string  `json:"next"`
+	Parent   string   `json:"parent"`
+	Index    *big.Int `json:"index"`
+	Siblings *big.Int `json:"siblings"`
+}
+
+// newNeighborsResult converts a neighbors report.
+func newNeighborsResult(n prefixNeighbors) neighborsResult {
+	r := neighborsResult{Prefix: n.Prefix.String(), Parent: n.Parent.String(), Index: n.Index, Siblings: n.Siblings}
+	if n.Previous != nil {
+		s := n.Previous.String()
+		r.Previous = &s
+	}
+	if n.Next != nil {
+		s := n.Next.String()
+		r.Next = &s
+	}
+	return r
+}
+
+// subnetRecord is one generated subnet with its place in the expansion.
+type subnetRecord struct {
+	Prefix        netip.Prefix `json:"prefix"`
+	Index         *big.Int     `json:"index"` // 0-based, among all subnets of the parent
+	Parent        netip.Prefix `json:"parent"`
+	NibbleAligned bool         `json:"nibble_aligned"`
+	Reserved      bool         `json:"reserved"`
+	Label         string       `json:"label,omitempty"` // from -name-template or -names
+}
+
+// subnetRecords returns a function describing each generated subnet of parent,
+// marking those in reserved.
+func subnetRecords(parent netip.Prefix, reserved []netip.Prefix) func(netip.Prefix) subnetRecord {
+	set := map[netip.Prefix]bool{}
+	for _, p := range reserved {
+		set[p] = true
+	}
+	base := ipToBigInt(parent.Addr().AsSlice())
+	return func(p netip.Prefix) subnetRecord {
+		index := new(big.Int).Sub(ipToBigInt(p.Addr().AsSlice()), base)
+		return subnetRecord{
+			Prefix:        p,
+			Index:         index.Rsh(index, uint(128-p.Bits())),
+			Parent:        parent,
+			NibbleAligned: isNibbleAligned(p.Bits()),
+			Reserved:      set[p],
+		}
+	}
+}
+
+// textSubnetLine is a subnet as one line of a prefix list: the prefix, followed
+// by its name when it has one.
+func textSubnetLine(r subnetRecord) string {
+	if name := r.Name(); name != "" {
+		return fmt.Sprintf("%-44s %s", prefixText(r.Prefix), name)
+	}
+	return prefixText(r.Prefix)
+}
+
+// subnetWriter writes generated subnets in the -output-format; Close ends the
+// output and flushes it.
+type subnetWriter interface {
+	Write(r subnetRecord) error
+	Close() error
+}
+
+// createOutput opens where generated output goes: the named file, or stdout
+// when name is empty. The output is gzipped when compress is set or the name
+// ends in .gz. Closing it finishes the gzip stream and closes the file, but
+// never stdout.
+func createOutput(name string, compress bool) (io.WriteCloser, error) {
+	var out io.WriteCloser = nopWriteCloser{os.Stdout}
+	if name != "" {
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		out = f
+	}
+	if compress || strings.HasSuffix(name, ".gz") {
+		return gzipOutput{gzip.NewWriter(out), out}, nil
+	}
+	return out, nil
+}
+
+// openSubnetOutput opens where generated subnets go: the -o database with
+// -output-format sqlite, otherwise as createOutput does.
+func openSubnetOutput(name string, compress bool) (io.WriteCloser, error) {
+	if outputFormat == "sqlite" {
+		return openSQLite(name)
+	}
+	return createOutput(name, compress)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// gzipOutput closes the gzip stream and then the output under it.
+type gzipOutput struct {
+	*gzip.Writer
+	out io.Closer
+}
+
+func (g gzipOutput) Close() error {
+	err := g.Writer.Close()
+	if cerr := g.out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// subnetLayout describes a subnet generation run to the writers whose output
+// records more than the subnets themselves.
+type subnetLayout struct {
+	Parent    netip.Prefix
+	NewLength int
+	Columns   []string // CSV and xlsx columns, checked with parseSubnetColumns
+	Levels    []int    // xlsx sheets above the subnets, checked with parseSubnetLevels
+	// LevelNames name the xlsx sheets of Levels and then NewLength, when they
+	// have names rather than just lengths.
+	LevelNames []string
+}
+
+// newSubnetWriter returns the subnetWriter for the -output-format.
+func newSubnetWriter(w io.Writer, layout subnetLayout) subnetWriter {
+	bw := bufio.NewWriter(w)
+	switch outputFormat {
+	case "json":
+		return &jsonSubnetWriter{w: bw}
+	case "ndjson":
+		return ndjsonSubnetWriter{bw}
+	case "csv":
+		return &csvSubnetWriter{w: csv.NewWriter(bw), bw: bw, columns: layout.Columns}
+	case "yaml":
+		return &yamlSubnetWriter{w: bw, layout: layout}
+	case "sql", "sqlite":
+		return &sqlSubnetWriter{w: bw}
+	case "xlsx":
+		return newXLSXSubnetWriter(bw, layout)
+	}
+	if outputTemplate != nil {
+		return templateSubnetWriter{bw}
+	}
+	return textSubnetWriter{bw}
+}
+
+type textSubnetWriter struct {
+	w *bufio.Writer
+}
+
+func (t textSubnetWriter) Write(r subnetRecord) error {
+	_, err := t.w.WriteString(textSubnetLine(r) + "\n")
+	return err
+}
+
+func (t textSubnetWriter) Close() error {
+	return t.w.Flush()
+}
+
+// templateSubnetWriter renders each subnet with -template.
+type templateSubnetWriter struct {
+	w *bufio.Writer
+}
+
+func (t templateSubnetWriter) Write(r subnetRecord) error {
+	return executeTemplate(t.w, r)
+}
+
+func (t templateSubnetWriter) Close() error {
+	return t.w.Flush()
+}
+
+// jsonSubnetWriter writes a JSON array one element at a time, so large
+// expansions stream like the text output does.
+type jsonSubnetWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (j *jsonSubnetWriter) Write(r subnetRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if j.count == 0 {
+		sep = "[\n  "
+	}
+	j.count++
+	if _, err := j.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonSubnetWriter) Close() error {
+	end := "\n]\n"
+	if j.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := j.w.WriteString(end); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// ndjsonSubnetWriter writes each subnet as a JSON object on its own line,
+// flushed at once so a consumer reading the stream sees it as it is generated.
+type ndjsonSubnetWriter struct {
+	w *bufio.Writer
+}
+
+func (n ndjsonSubnetWriter) Write(r subnetRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := n.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return n.w.Flush()
+}
+
+func (n ndjsonSubnetWriter) Close() error {
+	return n.w.Flush()
+}
+
+// subnetColumns are the CSV columns of a generated subnet, by name.
+var subnetColumns = map[string]func(r subnetRecord) string{
+	"index":          func(r subnetRecord) string { return r.Index.String() },
+	"prefix":         func(r subnetRecord) string { return r.Prefix.String() },
+	"network":        func(r subnetRecord) string { return r.Network().String() },
+	"length":         func(r subnetRecord) string { return strconv.Itoa(r.Length()) },
+	"parent":         func(r subnetRecord) string { return r.Parent.String() },
+	"last":           func(r subnetRecord) string { return r.Last().String() },
+	"gateway":        subnetRecord.Gateway,
+	"name":           subnetRecord.Name,
+	"nibble_aligned": func(r subnetRecord) string { return strconv.FormatBool(r.NibbleAligned) },
+	"reserved":       func(r subnetRecord) string { return strconv.FormatBool(r.Reserved) },
+}
+
+// defaultSubnetColumns are the CSV columns when -columns is not given.
+const defaultSubnetColumns = "index,prefix"
+
+// Network is the subnet's first address.
+func (r subnetRecord) Network() netip.Addr {
+	return r.Prefix.Addr()
+}
+
+// Length is the subnet's prefix length.
+func (r subnetRecord) Length() int {
+	return r.Prefix.Bits()
+}
+
+// Last is the subnet's highest address.
+func (r subnetRecord) Last() netip.Addr {
+	return lastAddr(r.Prefix)
+}
+
+// Gateway is the conventional gateway of the subnet, its ::1 address. A /128
+// has none.
+func (r subnetRecord) Gateway() string {
+	if r.Prefix.Bits() == 128 {
+		return ""
+	}
+	return r.Prefix.Addr().Next().String()
+}
+
+// Name is the label the subnet carries in a prefix list: RESERVED for a
+// reserved subnet, otherwise its -name-template or -names label, if any.
+func (r subnetRecord) Name() string {
+	if r.Reserved {
+		return reservedLabel
+	}
+	return r.Label
+}
+
+// parseSubnetColumns checks a comma-separated -columns list.
+func parseSubnetColumns(list string) ([]string, error) {
+	if list == "" {
+		list = defaultSubnetColumns
+	}
+	columns := strings.Split(list, ",")
+	for i, c := range columns {
+		columns[i] = strings.TrimSpace(c)
+		if _, ok := subnetColumns[columns[i]]; !ok {
+			names := slices.Sorted(maps.Keys(subnetColumns))
+			return nil, fmt.Errorf("unknown column %q (want %s)", c, strings.Join(names, ", "))
+		}
+	}
+	return columns, nil
+}
+
+// csvSubnetWriter writes a header row and then one row per subnet, quoted as
+// RFC 4180 requires.
+type csvSubnetWriter struct {
+	w       *csv.Writer
+	bw      *bufio.Writer
+	columns []string
+	started bool
+}
+
+// header writes the header row before the first subnet, or at Close when there
+// are none.
+func (c *csvSubnetWriter) header() error {
+	if c.started {
+		return nil
+	}
+	c.started = true
+	return c.w.Write(c.columns)
+}
+
+func (c *csvSubnetWriter) Write(r subnetRecord) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	row := make([]string, len(c.columns))
+	for i, name := range c.columns {
+		row[i] = subnetColumns[name](r)
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvSubnetWriter) Close() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
+// yamlSubnet is a generated subnet in the YAML plan document.
+type yamlSubnet struct {
+	Prefix string  `yaml:"prefix"`
+	Index  yamlInt `yaml:"index"`
+	Label  string  `yaml:"label,omitempty"`
+}
+
+// yamlInt writes a big.Int as a YAML integer rather than a quoted string.
+type yamlInt struct{ *big.Int }
+
+func (i yamlInt) MarshalYAML() (any, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: i.String()}, nil
+}
+
+// yamlSubnetWriter writes a plan document: the parent, the new length, and the
+// subnets, each marshaled as it is generated so large plans stream.
+type yamlSubnetWriter struct {
+	w      *bufio.Writer
+	layout subnetLayout
+	count  int
+}
+
+// header writes the parent and new length and opens the subnets list, which
+// stays empty when there are no subnets.
+func (y *yamlSubnetWriter) header(empty bool) error {
+	data, err := yaml.Marshal(struct {
+		Parent    string `yaml:"parent"`
+		NewLength int    `yaml:"new_length"`
+	}{y.layout.Parent.String(), y.layout.NewLength})
+	if err != nil {
+		return err
+	}
+	subnets := "subnets:\n"
+	if empty {
+		subnets = "subnets: []\n"
+	}
+	_, err = y.w.WriteString(string(data) + subnets)
+	return err
+}
+
+func (y *yamlSubnetWriter) Write(r subnetRecord) error {
+	if y.count == 0 {
+		if err := y.header(false); err != nil {
+			return err
+		}
+	}
+	y.count++
+	data, err := yaml.Marshal([]yamlSubnet{{Prefix: r.Prefix.String(), Index: yamlInt{r.Index}, Label: r.Name()}})
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if _, err := y.w.WriteString("  " + line); err != nil {
+			return err
+		}
+	}
+	_, err = y.w.WriteString("\n")
+	return err
+}
+
+func (y *yamlSubnetWriter) Close() error {
+	if y.count == 0 {
+		if err := y.header(true); err != nil {
+			return err
+		}
+	}
+	return y.w.Flush()
+}
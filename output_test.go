@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSubnetRecords(t *testing.T) {
+	parent := netip.MustParsePrefix("2001:db8::/60")
+	reserved, _ := reservedSubnets("2001:db8::/60", 64, 1, 0)
+	record := subnetRecords(parent, reserved)
+	cases := []struct {
+		subnet string
+		index  string
+		line   string
+	}{
+		{"2001:db8::/64", "0", "2001:db8::/64                                RESERVED"},
+		{"2001:db8:0:a::/64", "10", "2001:db8:0:a::/64"},
+	}
+	for _, c := range cases {
+		t.Run(c.subnet, func(t *testing.T) {
+			r := record(netip.MustParsePrefix(c.subnet))
+			if r.Index.String() != c.index || r.Parent != parent || !r.NibbleAligned {
+				t.Errorf("unexpected record %+v", r)
+			}
+			if got := textSubnetLine(r); got != c.line {
+				t.Errorf("line %q, want %q", got, c.line)
+			}
+		})
+	}
+}
+
+func TestJSONSubnetWriter(t *testing.T) {
+	defer func(format string) { outputFormat = format }(outputFormat)
+	outputFormat = "json"
+	parent := netip.MustParsePrefix("2001:db8::/62")
+	record := subnetRecords(parent, nil)
+
+	var buf bytes.Buffer
+	w := newSubnetWriter(&buf, subnetLayout{})
+	for p := range subnetSeq(parent, 63) {
+		if err := w.Write(record(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(got) != 2 || got[1]["prefix"] != "2001:db8:0:2::/63" || got[1]["index"] != 1.0 || got[1]["nibble_aligned"] != false {
+		t.Errorf("unexpected records %v", got)
+	}
+
+	buf.Reset()
+	w = newSubnetWriter(&buf, subnetLayout{})
+	w.Close()
+	if buf.String() != "[]\n" {
+		t.Errorf("empty output %q", buf.String())
+	}
+}
+
+func TestCheckOutputFormat(t *testing.T) {
+	defer func(format, text string) { outputFormat, outputTemplateText = format, text }(outputFormat, outputTemplateText)
+	cases := []struct {
+		command, format, template string
+		ok                        bool
+	}{
+		{"subnet", "text", "", true},
+		{"subnet", "json", "", true},
+		{"subnet", "xlsx", "", true},
+		{"subnet", "xml", "", false},
+		{"nat64", "ndjson", "", true},
+		{"nat64", "csv", "", false},
+		{"stats", "ndjson", "", true},
+		{"mac file", "csv", "", true},
+		{"vanity", "text", "", true},
+		{"vanity", "json", "", false},
+		{"doctor", "ndjson", "", false},
+		{"pd-sim", "text", "{{.}}", false},
+		{"neighbors", "text", "{{.Prefix}}", true},
+	}
+	for _, c := range cases {
+		outputFormat, outputTemplateText = c.format, c.template
+		if err := checkOutputFormat(c.command); (err == nil) != c.ok {
+			t.Errorf("%s -output-format %s -template %q: got %v", c.command, c.format, c.template, err)
+		}
+	}
+}
+
+func TestCSVSubnetWriter(t *testing.T) {
+	defer func(format string) { outputFormat = format }(outputFormat)
+	outputFormat = "csv"
+	parent := netip.MustParsePrefix("2001:db8::/63")
+	reserved, _ := reservedSubnets("2001:db8::/63", 64, 1, 0)
+	record := subnetRecords(parent, reserved)
+
+	columns, err := parseSubnetColumns("index, prefix,gateway,last,name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := newSubnetWriter(&buf, subnetLayout{Columns: columns})
+	for p := range subnetSeq(parent, 64) {
+		if err := w.Write(record(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expect := "index,prefix,gateway,last,name\n" +
+		"0,2001:db8::/64,2001:db8::1,2001:db8::ffff:ffff:ffff:ffff,RESERVED\n" +
+		"1,2001:db8:0:1::/64,2001:db8:0:1::1,2001:db8:0:1:ffff:ffff:ffff:ffff,\n"
+	if buf.String() != expect {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), expect)
+	}
+
+	buf.Reset()
+	w = newSubnetWriter(&buf, subnetLayout{Columns: columns})
+	w.Close()
+	if buf.String() != "index,prefix,gateway,last,name\n" {
+		t.Errorf("empty output %q", buf.String())
+	}
+}
+
+func TestExplodedOutput(t *testing.T) {
+	defer func(exploded bool) { explodedOutput = exploded }(explodedOutput)
+	p := netip.MustParsePrefix("2001:db8:0:1::/64")
+	explodedOutput = false
+	if got := textSubnetLine(subnetRecord{Prefix: p}); got != "2001:db8:0:1::/64" {
+		t.Errorf("compressed: got %s", got)
+	}
+	explodedOutput = true
+	if got := textSubnetLine(subnetRecord{Prefix: p}); got != "2001:0db8:0000:0001:0000:0000:0000:0000/64" {
+		t.Errorf("exploded: got %s", got)
+	}
+	if got := addrText(netip.MustParseAddr("::1")); got != "0000:0000:0000:0000:0000:0000:0000:0001" {
+		t.Errorf("exploded address: got %s", got)
+	}
+}
+
+func TestParseSubnetColumns(t *testing.T) {
+	if columns, err := parseSubnetColumns(""); err != nil || len(columns) != 2 {
+		t.Errorf("default columns: %v, %v", columns, err)
+	}
+	if _, err := parseSubnetColumns("prefix,vrf"); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+}
+
+func TestYAMLSubnetWriter(t *testing.T) {
+	defer func(format string) { outputFormat = format }(outputFormat)
+	outputFormat = "yaml"
+	parent := netip.MustParsePrefix("2001:db8::/63")
+	reserved, _ := reservedSubnets("2001:db8::/63", 64, 0, 1)
+	record := subnetRecords(parent, reserved)
+	layout := subnetLayout{Parent: parent, NewLength: 64}
+
+	var buf bytes.Buffer
+	w := newSubnetWriter(&buf, layout)
+	for p := range subnetSeq(parent, 64) {
+		if err := w.Write(record(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expect := `parent: 2001:db8::/63
+new_length: 64
+subnets:
+  - prefix: 2001:db8::/64
+    index: 0
+  - prefix: 2001:db8:0:1::/64
+    index: 1
+    label: RESERVED
+`
+	if buf.String() != expect {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), expect)
+	}
+	var doc struct {
+		Parent  string `yaml:"parent"`
+		Subnets []struct {
+			Prefix string `yaml:"prefix"`
+			Index  int    `yaml:"index"`
+		} `yaml:"subnets"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &doc); err != nil || len(doc.Subnets) != 2 || doc.Subnets[1].Index != 1 {
+		t.Errorf("document does not parse back: %+v, %v", doc, err)
+	}
+
+	buf.Reset()
+	w = newSubnetWriter(&buf, layout)
+	w.Close()
+	if !strings.HasSuffix(buf.String(), "subnets: []\n") {
+		t.Errorf("empty output %q", buf.String())
+	}
+}
+
+func TestNDJSONSubnetWriter(t *testing.T) {
+	defer func(format string) { outputFormat = format }(outputFormat)
+	outputFormat = "ndjson"
+	parent := netip.MustParsePrefix("2001:db8::/63")
+	record := subnetRecords(parent, nil)
+
+	var buf bytes.Buffer
+	w := newSubnetWriter(&buf, subnetLayout{})
+	for p := range subnetSeq(parent, 64) {
+		if err := w.Write(record(p)); err != nil {
+			t.Fatal(err)
+		}
+		// Each line is written through before the next subnet is generated.
+		if !strings.HasSuffix(buf.String(), "\n") {
+			t.Fatalf("line for %s not flushed: %q", p, buf.String())
+		}
+	}
+	w.Close()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %q", len(lines), buf.String())
+	}
+	var r map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &r); err != nil || r["prefix"] != "2001:db8:0:1::/64" {
+		t.Errorf("unexpected line %q: %v", lines[1], err)
+	}
+}
+
+func TestExecuteTemplate(t *testing.T) {
+	defer func(format, text string) { outputFormat, outputTemplateText, outputTemplate = format, text, nil }(outputFormat, outputTemplateText)
+	parent := netip.MustParsePrefix("2001:db8::/60")
+	record := subnetRecords(parent, nil)(netip.MustParsePrefix("2001:db8:0:a::/64"))
+	cases := []struct {
+		name     string
+		template string
+		v        any
+		expect   string
+	}{
+		{"subnet", "{{.Index}} {{.Prefix}} vlan{{.Index}}", record, "10 2001:db8:0:a::/64 vlan10\n"},
+		{"subnet methods", "interface Vlan{{.Index}}\n ipv6 address {{.Gateway}}/{{.Length}}\n", record, "interface Vlan10\n ipv6 address 2001:db8:0:a::1/64\n"},
+		{"conversion", "{{.IPv6}} AAAA", nat64Result{IPv4: "192.0.2.1", IPv6: "64:ff9b::c000:201"}, "64:ff9b::c000:201 AAAA\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			outputFormat, outputTemplateText = "text", c.template
+			if err := checkOutputFormat("subnet"); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := executeTemplate(&buf, c.v); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != c.expect {
+				t.Errorf("got %q, want %q", buf.String(), c.expect)
+			}
+		})
+	}
+
+	outputFormat, outputTemplateText = "json", "{{.Prefix}}"
+	if err := checkOutputFormat("subnet"); err == nil {
+		t.Error("expected an error for -template with -output-format json")
+	}
+	outputFormat, outputTemplateText = "text", "{{.Prefix"
+	if err := checkOutputFormat("subnet"); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestCreateOutputCompressed(t *testing.T) {
+	dir := t.TempDir()
+	for _, c := range []struct {
+		name     string
+		compress bool
+		gzipped  bool
+	}{
+		{"subnets.txt", false, false},
+		{"subnets.txt.gz", false, true},
+		{"subnets.txt", true, true},
+	} {
+		path := filepath.Join(dir, c.name)
+		out, err := createOutput(path, c.compress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(out, "2001:db8::/64\n")
+		if err := out.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if c.gzipped {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatalf("%s (compress %v): %v", c.name, c.compress, err)
+			}
+		}
+		data, _ := io.ReadAll(r)
+		f.Close()
+		if string(data) != "2001:db8::/64\n" {
+			t.Errorf("%s (compress %v): got %q", c.name, c.compress, data)
+		}
+	}
+}
+
+func TestCountResultJSON(t *testing.T) {
+	count, err := countSubnets("2001:db8::/32", 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(countResult{Prefix: "2001:db8::/32", NewLength: 64, Count: count.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"prefix":"2001:db8::/32","new_length":64,"count":"4294967296"}`; string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/bits"
+	"math/rand"
+	"os"
+)
+
+// pdSimMaxSlots bounds the number of delegations a simulated pool may hold.
+const pdSimMaxSlots = 1 << 22
+
+// pdSimConfig describes a prefix-delegation pool and the customer behaviour to model.
+// Rates are fractions of the active customer base per period.
+type pdSimConfig struct {
+	Pool      string
+	DelegLen  int
+	Customers int     // customers at the start of the simulation
+	Growth    float64 // net new customers per period
+	Churn     float64 // customers leaving (and being replaced) per period
+	Sticky    bool    // hold released delegations for returning customers
+	Hold      int     // periods a released delegation is held when Sticky
+	Periods   int
+	Seed      int64
+}
+
+// pdSimPeriod is the state of the pool at the end of one period.
+type pdSimPeriod struct {
+	Period      int
+	Active      int
+	Held        int
+	Free        int
+	FreeRuns    int // contiguous runs of free delegations
+	LargestFree int // prefix length of the largest aligned free block, 0 when full
+	Failed      int // delegation requests that could not be satisfied
+}
+
+// pdPool tracks which delegations of a pool are in use.
+type pdPool struct {
+	used   []bool
+	lowest int // no free slot below this index
+	inUse  int
+}
+
+// allocate hands out the lowest free delegation, or -1 when the pool is full.
+func (p *pdPool) allocate() int {
+	for i := p.lowest; i < len(p.used); i++ {
+		if !p.used[i] {
+			p.used[i] = true
+			p.lowest = i + 1
+			p.inUse++
+			return i
+		}
+	}
+	p.lowest = len(p.used)
+	return -1
+}
+
+// release returns a delegation to the pool.
+func (p *pdPool) release(i int) {
+	p.used[i] = false
+	p.inUse--
+	if i < p.lowest {
+		p.lowest = i
+	}
+}
+
+// fragmentation counts the free runs and finds the largest naturally aligned free
+// block, returned as the number of delegation bits it spans (-1 when nothing is free).
+func (p *pdPool) fragmentation() (runs int, largestBits int) {
+	largestBits = -1
+	for i := 0; i < len(p.used); {
+		if p.used[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < len(p.used) && !p.used[i] {
+			i++
+		}
+		runs++
+		for b := bits.Len(uint(i-start)) - 1; b > largestBits; b-- {
+			size := 1 << b
+			aligned := (start + size - 1) &^ (size - 1)
+			if aligned+size <= i {
+				largestBits = b
+				break
+			}
+		}
+	}
+	return runs, largestBits
+}
+
+// simulatePDPool runs the pool model period by period. Each period held delegations
+// whose hold time has expired are released, departing customers give up their
+// delegation, and replacement and new customers are served lowest-free-first.
+func simulatePDPool(cfg pdSimConfig) ([]pdSimPeriod, error) {
+	ipnet, err := parseIPv6Prefix(cfg.Pool)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %v", err)
+	}
+	poolLen, _ := ipnet.Mask.Size()
+	if cfg.DelegLen <= poolLen || cfg.DelegLen > 128 {
+		return nil, fmt.Errorf("delegation size /%d must be longer than the pool /%d", cfg.DelegLen, poolLen)
+	}
+	if cfg.DelegLen-poolLen > 22 {
+		return nil, fmt.Errorf("pool holds more than %d /%d delegations; simulate a smaller pool", pdSimMaxSlots, cfg.DelegLen)
+	}
+	if cfg.Growth < 0 || cfg.Churn < 0 || cfg.Churn > 1 {
+		return nil, fmt.Errorf("growth must be non-negative and churn between 0 and 1")
+	}
+	slots := 1 << (cfg.DelegLen - poolLen)
+	if cfg.Customers > slots {
+		return nil, fmt.Errorf("%d customers do not fit in %d delegations", cfg.Customers, slots)
+	}
+
+	pool := &pdPool{used: make([]bool, slots)}
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	var active []int
+	releaseAt := map[int][]int{} // period -> held delegations to release
+	held := 0
+	for range cfg.Customers {
+		active = append(active, pool.allocate())
+	}
+
+	var growthCarry, churnCarry float64
+	var out []pdSimPeriod
+	for period := 1; period <= cfg.Periods; period++ {
+		for _, slot := range releaseAt[period] {
+			pool.release(slot)
+			held--
+		}
+		delete(releaseAt, period)
+
+		churnCarry += float64(len(active)) * cfg.Churn
+		growthCarry += float64(len(active)) * cfg.Growth
+		leaving := min(int(churnCarry), len(active))
+		joining := leaving + int(growthCarry)
+		churnCarry -= float64(int(churnCarry))
+		growthCarry -= float64(int(growthCarry))
+
+		for range leaving {
+			i := rng.Intn(len(active))
+			slot := active[i]
+			active[i] = active[len(active)-1]
+			active = active[:len(active)-1]
+			if cfg.Sticky && cfg.Hold > 0 {
+				releaseAt[period+cfg.Hold] = append(releaseAt[period+cfg.Hold], slot)
+				held++
+			} else {
+				pool.release(slot)
+			}
+		}
+
+		failed := 0
+		for range joining {
+			slot := pool.allocate()
+			if slot == -1 {
+				failed++
+				continue
+			}
+			active = append(active, slot)
+		}
+
+		runs, largestBits := pool.fragmentation()
+		largest := 0
+		if largestBits >= 0 {
+			largest = cfg.DelegLen - largestBits
+		}
+		out = append(out, pdSimPeriod{
+			Period:      period,
+			Active:      len(active),
+			Held:        held,
+			Free:        slots - pool.inUse,
+			FreeRuns:    runs,
+			LargestFree: largest,
+			Failed:      failed,
+		})
+	}
+	return out, nil
+}
+
+// writePDSimReport prints the per-period table followed by the pool lifetime.
+func writePDSimReport(w io.Writer, cfg pdSimConfig, periods []pdSimPeriod) {
+	mode := "dynamic"
+	if cfg.Sticky {
+		mode = fmt.Sprintf("sticky, %d-period hold", cfg.Hold)
+	}
+	fmt.Fprintf(w, "%-16s %s\n", "Pool:", cfg.Pool)
+	if len(periods) > 0 {
+		p := periods[0]
+		fmt.Fprintf(w, "%-16s /%d (%d delegations)\n", "Delegation:", cfg.DelegLen, p.Active+p.Held+p.Free)
+	}
+	fmt.Fprintf(w, "%-16s %s\n", "Assignment:", mode)
+	fmt.Fprintf(w, "%-16s %.1f%% growth, %.1f%% churn per period\n\n", "Rates:", cfg.Growth*100, cfg.Churn*100)
+
+	fmt.Fprintf(w, "%6s %10s %8s %10s %7s %9s %13s\n", "Period", "Customers", "Held", "Free", "Used", "FreeRuns", "LargestFree")
+	exhausted := 0
+	for _, p := range periods {
+		total := p.Active + p.Held + p.Free
+		largest := "-"
+		if p.LargestFree > 0 {
+			largest = fmt.Sprintf("/%d", p.LargestFree)
+		}
+		fmt.Fprintf(w, "%6d %10d %8d %10d %6.1f%% %9d %13s\n",
+			p.Period, p.Active, p.Held, p.Free, float64(p.Active+p.Held)*100/float64(total), p.FreeRuns, largest)
+		if p.Failed > 0 && exhausted == 0 {
+			exhausted = p.Period
+		}
+	}
+	fmt.Fprintln(w)
+	if exhausted > 0 {
+		fmt.Fprintf(w, "Pool exhausted in period %d (%d requests refused)\n", exhausted, periods[exhausted-1].Failed)
+	} else if len(periods) > 0 {
+		fmt.Fprintf(w, "Pool not exhausted after %d periods\n", len(periods))
+	}
+}
+
+// runPDSimulation simulates the pool and prints the report.
+func runPDSimulation(cfg pdSimConfig) {
+	periods, err := simulatePDPool(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writePDSimReport(os.Stdout, cfg, periods)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPDPoolFragmentation(t *testing.T) {
+	cases := []struct {
+		name        string
+		used        string
+		wantRuns    int
+		wantLargest int
+	}{
+		{"empty", "........", 1, 3},
+		{"full", "########", 0, -1},
+		{"unaligned run", "#...#...", 2, 1},
+		{"aligned half", "####....", 1, 2},
+		{"checkerboard", "#.#.#.#.", 4, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &pdPool{used: make([]bool, len(tc.used))}
+			for i, c := range tc.used {
+				p.used[i] = c == '#'
+			}
+			runs, largest := p.fragmentation()
+			if runs != tc.wantRuns || largest != tc.wantLargest {
+				t.Errorf("expected %d runs and %d bits, got %d and %d", tc.wantRuns, tc.wantLargest, runs, largest)
+			}
+		})
+	}
+}
+
+func TestSimulatePDPool(t *testing.T) {
+	cfg := pdSimConfig{Pool: "2001:db8::/48", DelegLen: 56, Customers: 200, Growth: 0.1, Periods: 6, Seed: 1}
+	periods, err := simulatePDPool(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 200 -> 220 -> 242 -> 266 (exhausts the 256 delegations).
+	if periods[0].Active != 220 || periods[1].Active != 242 {
+		t.Errorf("unexpected growth %+v", periods[:2])
+	}
+	if periods[2].Active != 256 || periods[2].Failed != 10 || periods[2].Free != 0 {
+		t.Errorf("expected exhaustion in period 3, got %+v", periods[2])
+	}
+
+	var buf bytes.Buffer
+	writePDSimReport(&buf, cfg, periods)
+	if !strings.Contains(buf.String(), "Pool exhausted in period 3 (10 requests refused)") {
+		t.Errorf("unexpected report:\n%s", buf.String())
+	}
+}
+
+func TestSimulatePDPoolSticky(t *testing.T) {
+	base := pdSimConfig{Pool: "2001:db8::/48", DelegLen: 56, Customers: 100, Churn: 0.1, Periods: 4, Seed: 7}
+	dynamic, err := simulatePDPool(base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sticky := base
+	sticky.Sticky, sticky.Hold = true, 2
+	held, err := simulatePDPool(sticky)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range dynamic {
+		if dynamic[i].Active != 100 || dynamic[i].Held != 0 {
+			t.Errorf("dynamic period %d: unexpected %+v", i+1, dynamic[i])
+		}
+	}
+	// Each period 10 customers leave; held delegations are released after 2 periods.
+	wantHeld := []int{10, 20, 20, 20}
+	for i, w := range wantHeld {
+		if held[i].Held != w || held[i].Free != 256-100-w {
+			t.Errorf("sticky period %d: expected %d held, got %+v", i+1, w, held[i])
+		}
+	}
+}
+
+func TestSimulatePDPoolErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  pdSimConfig
+	}{
+		{"delegation shorter than pool", pdSimConfig{Pool: "2001:db8::/56", DelegLen: 48}},
+		{"pool too large", pdSimConfig{Pool: "2001:db8::/32", DelegLen: 64}},
+		{"too many customers", pdSimConfig{Pool: "2001:db8::/48", DelegLen: 56, Customers: 300}},
+		{"churn above 1", pdSimConfig{Pool: "2001:db8::/48", DelegLen: 56, Churn: 1.5}},
+		{"invalid prefix", pdSimConfig{Pool: "2001:db8::1/48", DelegLen: 56}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := simulatePDPool(tc.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/netip"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// planLevel is one tier of an address plan: the prefix length its subnets get,
+// how many of them each parent on the tier above hands out, and how they are
+// named.
+type planLevel struct {
+	Name     string   `yaml:"name"`
+	Length   int      `yaml:"length"`
+	Count    int      `yaml:"count"`    // per parent; 0 allocates every subnet
+	Names    []string `yaml:"names"`    // names of the first subnets of each parent, by index
+	Template string   `yaml:"template"` // text/template naming the rest
+
+	namer *subnetNamer
+}
+
+// planSchema describes a hierarchical address plan, as written in a plan file:
+//
+//	prefix: 2001:db8::/32
+//	name: acme
+//	levels:
+//	  - name: region
+//	    length: 36
+//	    names: [emea, amer, apac]
+//	  - name: site
+//	    length: 48
+//	    count: 4
+//	    template: '{{.ParentName}}-site{{.Index}}'
+//	  - name: vlan
+//	    length: 64
+//	    count: 3
+type planSchema struct {
+	Prefix string      `yaml:"prefix"`
+	Name   string      `yaml:"name"`
+	Levels []planLevel `yaml:"levels"`
+
+	parent netip.Prefix
+}
+
+// planNode is a subnet of the plan as its level's template sees it: the
+// subnet's fields and methods, its level, and its parent's name.
+type planNode struct {
+	subnetRecord
+	Level      string
+	ParentName string
+	Depth      int // 0 for the first level
+}
+
+// parsePlanSchema reads and validates a YAML plan. Unknown keys are rejected so
+// a misspelt setting is not silently ignored.
+func parsePlanSchema(r io.Reader) (*planSchema, error) {
+	var s planSchema
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid plan: %v", err)
+	}
+	var err error
+	if s.parent, err = parseSubnetParent(s.Prefix); err != nil {
+		return nil, fmt.Errorf("prefix: %v", err)
+	}
+	if len(s.Levels) == 0 {
+		return nil, fmt.Errorf("plan has no levels")
+	}
+	prev := s.parent.Bits()
+	seen := map[string]bool{}
+	for i := range s.Levels {
+		l := &s.Levels[i]
+		if l.Name == "" {
+			l.Name = fmt.Sprintf("level%d", i+1)
+		}
+		if seen[l.Name] {
+			return nil, fmt.Errorf("level %d: duplicate name %q", i+1, l.Name)
+		}
+		seen[l.Name] = true
+		if l.Length <= prev || l.Length > 128 {
+			return nil, fmt.Errorf("level %s: length must be between /%d and /128, not /%d", l.Name, prev+1, l.Length)
+		}
+		if l.Count < 0 {
+			return nil, fmt.Errorf("level %s: count must not be negative", l.Name)
+		}
+		if available := new(big.Int).Lsh(big.NewInt(1), uint(l.Length-prev)); l.Count > 0 && available.Cmp(big.NewInt(int64(l.Count))) < 0 {
+			return nil, fmt.Errorf("level %s: count %d exceeds the %s /%d subnets of a /%d", l.Name, l.Count, available, l.Length, prev)
+		}
+		if l.namer, err = newSubnetNamer(l.Template, ""); err != nil {
+			return nil, fmt.Errorf("level %s: %v", l.Name, err)
+		}
+		if len(l.Names) > 0 {
+			if l.namer == nil {
+				l.namer = &subnetNamer{}
+			}
+			l.namer.names = l.Names
+		}
+		prev = l.Length
+	}
+	return &s, nil
+}
+
+// loadPlanSchema reads a plan file.
+func loadPlanSchema(path string) (*planSchema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := parsePlanSchema(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+// lengths returns the prefix lengths and names of the levels.
+func (s *planSchema) lengths() ([]int, []string) {
+	lengths := make([]int, len(s.Levels))
+	names := make([]string, len(s.Levels))
+	for i, l := range s.Levels {
+		lengths[i], names[i] = l.Length, l.Name
+	}
+	return lengths, names
+}
+
+// expand emits every subnet of the plan depth first, each followed by the
+// subnets allocated inside it, labelled with its name. A subnet without a name
+// from its level is named after its parent, level, and index, such as
+// acme-region0-site3.
+func (s *planSchema) expand(emit func(subnetRecord) error) error {
+	return s.expandLevel(0, s.parent, s.Name, emit)
+}
+
+func (s *planSchema) expandLevel(depth int, parent netip.Prefix, parentName string, emit func(subnetRecord) error) error {
+	if depth == len(s.Levels) {
+		return nil
+	}
+	l := s.Levels[depth]
+	record := subnetRecords(parent, nil)
+	for p := range limitSubnets(subnetSeq(parent, l.Length), l.Count) {
+		r := record(p)
+		name, err := l.namer.render(r.Index, planNode{r, l.Name, parentName, depth})
+		if err != nil {
+			return fmt.Errorf("level %s: %v", l.Name, err)
+		}
+		if name == "" {
+			name = fmt.Sprintf("%s%s", l.Name, r.Index)
+			if parentName != "" {
+				name = parentName + "-" + name
+			}
+		}
+		r.Label = name
+		if err := emit(r); err != nil {
+			return err
+		}
+		if err := s.expandLevel(depth+1, p, name, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planOptions are the settings of a plan run.
+type planOptions struct {
+	OutputFile string
+	Columns    string // CSV and xlsx columns, comma-separated
+	Compress   bool
+}
+
+// runPlan generates the address plan described by a plan file in the
+// -output-format. Text output is a prefix list of the plan's subnets and names.
+func runPlan(path string, opts planOptions) {
+	schema, err := loadPlanSchema(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	columns, err := parseSubnetColumns(opts.Columns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lengths, names := schema.lengths()
+	out, err := openSubnetOutput(opts.OutputFile, opts.Compress)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := newSubnetWriter(out, subnetLayout{
+		Parent:     schema.parent,
+		NewLength:  lengths[len(lengths)-1],
+		Columns:    columns,
+		Levels:     lengths[:len(lengths)-1],
+		LevelNames: names,
+	})
+	count := 0
+	err = schema.expand(func(r subnetRecord) error {
+		count++
+		return w.Write(r)
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if opts.OutputFile != "" {
+		statusf("Plan of %d subnets saved to %s\n", count, opts.OutputFile)
+	}
+}
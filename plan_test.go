@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePlanSchemaErrors(t *testing.T) {
+	tests := map[string]string{
+		"no levels":       "prefix: 3fff::/32\n",
+		"bad prefix":      "prefix: nope\nlevels:\n  - length: 48\n",
+		"shorter level":   "prefix: 3fff::/32\nlevels:\n  - length: 48\n  - length: 40\n",
+		"too many":        "prefix: 3fff::/32\nlevels:\n  - length: 36\n    count: 17\n",
+		"duplicate names": "prefix: 3fff::/32\nlevels:\n  - {name: a, length: 40}\n  - {name: a, length: 48}\n",
+		"bad template":    "prefix: 3fff::/32\nlevels:\n  - {length: 48, template: '{{.Index'}\n",
+		"unknown key":     "prefix: 3fff::/32\nlevels:\n  - {length: 48, lenght: 56}\n",
+	}
+	for name, yaml := range tests {
+		if _, err := parsePlanSchema(strings.NewReader(yaml)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPlanExpand(t *testing.T) {
+	schema, err := loadPlanSchema("testdata/plan-schema.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = schema.expand(func(r subnetRecord) error {
+		got = append(got, fmt.Sprintf("%s %s %s %s", r.Prefix, r.Parent, r.Index, r.Label))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"3fff::/36 3fff::/32 0 emea",
+		"3fff::/48 3fff::/36 0 emea-site0",
+		"3fff::/64 3fff::/48 0 emea-site0-vlan000",
+		"3fff:0:0:1::/64 3fff::/48 1 emea-site0-vlan001",
+		"3fff:0:0:2::/64 3fff::/48 2 emea-site0-vlan002",
+		"3fff:0:1::/48 3fff::/36 1 emea-site1",
+	}
+	if len(got) != 18 {
+		t.Fatalf("got %d subnets, want 18", len(got))
+	}
+	for i, w := range want {
+		if got[i] != w {
+			t.Errorf("subnet %d = %q, want %q", i, got[i], w)
+		}
+	}
+	if last := got[len(got)-1]; last != "3fff:0:1001:2::/64 3fff:0:1001::/48 2 amer-site1-vlan002" {
+		t.Errorf("last subnet = %q", last)
+	}
+}
+
+func TestPlanDefaultNames(t *testing.T) {
+	schema, err := parsePlanSchema(strings.NewReader("prefix: 3fff::/32\nname: acme\nlevels:\n  - {name: site, length: 48, count: 1}\n  - {length: 64, count: 1}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	schema.expand(func(r subnetRecord) error {
+		got = append(got, r.Label)
+		return nil
+	})
+	if strings.Join(got, ",") != "acme-site0,acme-site0-level20" {
+		t.Errorf("got names %v", got)
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+)
+
+// Plan diff change kinds.
+const (
+	planAdded   = "added"
+	planRemoved = "removed"
+	planResized = "resized"
+	planRenamed = "renamed"
+)
+
+// planDiffEntry is one side of a plan change.
+type planDiffEntry struct {
+	Prefix string `json:"prefix"`
+	Label  string `json:"label,omitempty"`
+	Line   int    `json:"line"`
+}
+
+// planChange is a difference between two versions of a plan. Old is nil for
+// an added allocation and New for a removed one.
+type planChange struct {
+	Kind string         `json:"kind"`
+	Old  *planDiffEntry `json:"old,omitempty"`
+	New  *planDiffEntry `json:"new,omitempty"`
+
+	at prefixEntry // where the change sorts
+}
+
+// planDiff is the result of comparing two plans.
+type planDiff struct {
+	Old     string       `json:"old"`
+	New     string       `json:"new"`
+	Changes []planChange `json:"changes"`
+}
+
+func newPlanDiffEntry(e prefixEntry) *planDiffEntry {
+	return &planDiffEntry{Prefix: e.Net.String(), Label: e.Label, Line: e.Line}
+}
+
+// diffPlans compares two versions of a plan, in address order. An allocation
+// in both with a different name is renamed. An old allocation replaced by an
+// overlapping one of another length, with the same name or the same starting
+// address, is resized. Everything else is added or removed.
+func diffPlans(old, new []prefixEntry) []planChange {
+	var changes []planChange
+	newByPrefix := map[string]int{}
+	for i, e := range new {
+		if _, dup := newByPrefix[e.Net.String()]; !dup {
+			newByPrefix[e.Net.String()] = i
+		}
+	}
+	matchedOld := make([]bool, len(old))
+	matchedNew := make([]bool, len(new))
+	for i, o := range old {
+		j, ok := newByPrefix[o.Net.String()]
+		if !ok || matchedNew[j] {
+			continue
+		}
+		matchedOld[i], matchedNew[j] = true, true
+		if n := new[j]; n.Label != o.Label {
+			changes = append(changes, planChange{Kind: planRenamed, Old: newPlanDiffEntry(o), New: newPlanDiffEntry(n), at: n})
+		}
+	}
+
+	// Pair the rest by overlap, preferring a match on name over one on the
+	// starting address.
+	resize := func(i, j int) {
+		matchedOld[i], matchedNew[j] = true, true
+		changes = append(changes, planChange{Kind: planResized, Old: newPlanDiffEntry(old[i]), New: newPlanDiffEntry(new[j]), at: new[j]})
+	}
+	for _, sameName := range []bool{true, false} {
+		for i, o := range old {
+			if matchedOld[i] {
+				continue
+			}
+			for j, n := range new {
+				if matchedNew[j] || !prefixesOverlap(o.Net, n.Net) {
+					continue
+				}
+				if sameName && o.Label != "" && o.Label == n.Label || !sameName && o.Net.IP.Equal(n.Net.IP) {
+					resize(i, j)
+					break
+				}
+			}
+		}
+	}
+
+	for i, o := range old {
+		if !matchedOld[i] {
+			changes = append(changes, planChange{Kind: planRemoved, Old: newPlanDiffEntry(o), at: o})
+		}
+	}
+	for j, n := range new {
+		if !matchedNew[j] {
+			changes = append(changes, planChange{Kind: planAdded, New: newPlanDiffEntry(n), at: n})
+		}
+	}
+	slices.SortStableFunc(changes, func(a, b planChange) int {
+		pa, pb := entryPrefix(a.at), entryPrefix(b.at)
+		if c := pa.Addr().Compare(pb.Addr()); c != 0 {
+			return c
+		}
+		return pa.Bits() - pb.Bits()
+	})
+	return changes
+}
+
+// planChangeLine describes a change as one line of the text report.
+func planChangeLine(c planChange) string {
+	name := func(e *planDiffEntry) string {
+		if e.Label == "" {
+			return e.Prefix
+		}
+		return e.Prefix + " " + e.Label
+	}
+	switch c.Kind {
+	case planAdded:
+		return "+ " + name(c.New)
+	case planRemoved:
+		return "- " + name(c.Old)
+	case planRenamed:
+		return fmt.Sprintf("~ %s renamed %q to %q", c.New.Prefix, c.Old.Label, c.New.Label)
+	}
+	line := fmt.Sprintf("~ %s resized to %s", name(c.Old), c.New.Prefix)
+	if c.New.Label != c.Old.Label {
+		line += " " + c.New.Label
+	}
+	return line
+}
+
+// runPlanDiff compares two plan files, printing the changes from the old to
+// the new one. Like diff, it exits with status 1 when they differ.
+func runPlanDiff(oldPath, newPath string) {
+	old, err := readPrefixFile(oldPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	new, err := readPrefixFile(newPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	changes := diffPlans(old, new)
+	result := planDiff{Old: oldPath, New: newPath, Changes: changes}
+	if result.Changes == nil {
+		result.Changes = []planChange{}
+	}
+	writeResult(result, func() {
+		counts := map[string]int{}
+		for _, c := range changes {
+			fmt.Println(planChangeLine(c))
+			counts[c.Kind]++
+		}
+		fmt.Printf("%d added, %d removed, %d resized, %d renamed\n",
+			counts[planAdded], counts[planRemoved], counts[planResized], counts[planRenamed])
+	})
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func readTestPlan(t *testing.T, text string) []prefixEntry {
+	t.Helper()
+	entries, err := readPrefixEntries(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestDiffPlans(t *testing.T) {
+	old, err := readPrefixFile("testdata/plan-v1.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := readPrefixFile("testdata/plan-v2.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range diffPlans(old, new) {
+		got = append(got, planChangeLine(c))
+	}
+	want := []string{
+		`~ 3fff:0:1:10::/64 renamed "clients" to "clients-wired"`,
+		`~ 3fff:0:2::/48 renamed "site-denver" to "site-boulder"`,
+		`~ 3fff:0:3::/52 lab resized to 3fff:0:3::/48`,
+		`+ 3fff:0:4::/48 site-austin`,
+		`- 3fff:0:9::/48 site-closed`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if changes := diffPlans(old, old); len(changes) != 0 {
+		t.Errorf("a plan differs from itself: %+v", changes)
+	}
+}
+
+func TestDiffPlansResize(t *testing.T) {
+	tests := []struct {
+		name, old, new string
+		want           []string
+	}{
+		{"same name", "3fff:0:10::/48 lab\n", "3fff:0:10:8000::/49 lab\n", []string{planResized}},
+		{"same start, renamed", "3fff:0:10::/48 lab\n", "3fff:0:10::/44 lab-wide\n", []string{planResized}},
+		{"overlap only", "3fff:0:10::/48 lab\n", "3fff:0:10:8000::/49 other\n", []string{planRemoved, planAdded}},
+		{"name wins over start", "3fff::/48 a\n3fff:0:0:8000::/49 b\n", "3fff::/49 b\n", []string{planResized, planRemoved}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range diffPlans(readTestPlan(t, tt.old), readTestPlan(t, tt.new)) {
+				got = append(got, c.Kind)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
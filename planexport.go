@@ -0,0 +1,287 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// planSubnet is a plan entry with the service metadata given as key=value words in
+// its label, e.g. "clients gw=3fff:0:1:10::1 dns=3fff:0:1::53 ntp=3fff:0:1::123 vlan=10".
+type planSubnet struct {
+	Net     *net.IPNet
+	Name    string
+	Gateway net.IP
+	DNS     []net.IP
+	NTP     []net.IP
+	VLAN    int
+	Line    int
+}
+
+// HasMetadata reports whether any service metadata was given for the subnet.
+func (s planSubnet) HasMetadata() bool {
+	return s.Gateway != nil || len(s.DNS) > 0 || len(s.NTP) > 0 || s.VLAN != 0
+}
+
+// parseAddressList parses a comma-separated list of IPv6 addresses.
+func parseAddressList(value string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, s := range strings.Split(value, ",") {
+		ip := net.ParseIP(s)
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("invalid IPv6 address %q", s)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// parsePlanSubnet splits a plan entry's label into its name and metadata. Unknown
+// keys are errors, so a misspelt key cannot silently drop configuration.
+func parsePlanSubnet(e prefixEntry) (planSubnet, error) {
+	s := planSubnet{Net: e.Net, Line: e.Line}
+	var name []string
+	for _, word := range strings.Fields(e.Label) {
+		key, value, ok := strings.Cut(word, "=")
+		if !ok {
+			name = append(name, word)
+			continue
+		}
+		var err error
+		switch key {
+		case "gw":
+			s.Gateway = net.ParseIP(value)
+			if s.Gateway == nil || s.Gateway.To4() != nil {
+				return s, fmt.Errorf("line %d: invalid gateway %q", e.Line, value)
+			}
+			if !e.Net.Contains(s.Gateway) {
+				return s, fmt.Errorf("line %d: gateway %s is outside %s", e.Line, value, e.Net)
+			}
+		case "dns":
+			s.DNS, err = parseAddressList(value)
+		case "ntp":
+			s.NTP, err = parseAddressList(value)
+		case "expires":
+			_, err = parseExpiry(value)
+		case "vlan":
+			s.VLAN, err = strconv.Atoi(value)
+			if err == nil && (s.VLAN < 1 || s.VLAN > 4094) {
+				err = fmt.Errorf("VLAN %d out of range 1-4094", s.VLAN)
+			}
+		default:
+			return s, fmt.Errorf("line %d: unknown metadata key %q (want gw, dns, ntp, vlan, or expires)", e.Line, key)
+		}
+		if err != nil {
+			return s, fmt.Errorf("line %d: %s: %v", e.Line, key, err)
+		}
+	}
+	s.Name = strings.Join(name, " ")
+	if s.Name == "" {
+		s.Name = e.Net.String()
+	}
+	return s, nil
+}
+
+// readPlanSubnets reads a plan file and keeps the entries that carry metadata;
+// aggregates without any are there for structure and produce no configuration.
+// Expired allocations are left out.
+func readPlanSubnets(path string) ([]planSubnet, error) {
+	entries, err := readPrefixFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if entries, _, err = splitExpired(entries, time.Now()); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	var subnets []planSubnet
+	for _, e := range entries {
+		s, err := parsePlanSubnet(e)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if s.HasMetadata() {
+			subnets = append(subnets, s)
+		}
+	}
+	return subnets, nil
+}
+
+// joinIPs formats addresses for configuration files.
+func joinIPs(ips []net.IP, sep string) string {
+	parts := make([]string, len(ips))
+	for i, ip := range ips {
+		parts[i] = ip.String()
+	}
+	return strings.Join(parts, sep)
+}
+
+// exportKea writes a Kea Dhcp6 configuration fragment with one subnet6 per plan
+// subnet. DNS and NTP servers become the dns-servers and sntp-servers options.
+func exportKea(w io.Writer, subnets []planSubnet) error {
+	type option struct {
+		Name string `json:"name"`
+		Data string `json:"data"`
+	}
+	type subnet6 struct {
+		ID          int            `json:"id"`
+		Subnet      string         `json:"subnet"`
+		OptionData  []option       `json:"option-data,omitempty"`
+		UserContext map[string]any `json:"user-context"`
+	}
+	var out []subnet6
+	for i, s := range subnets {
+		sn := subnet6{ID: i + 1, Subnet: s.Net.String(), UserContext: map[string]any{"name": s.Name}}
+		if len(s.DNS) > 0 {
+			sn.OptionData = append(sn.OptionData, option{"dns-servers", joinIPs(s.DNS, ", ")})
+		}
+		if len(s.NTP) > 0 {
+			sn.OptionData = append(sn.OptionData, option{"sntp-servers", joinIPs(s.NTP, ", ")})
+		}
+		if s.VLAN != 0 {
+			sn.UserContext["vlan"] = s.VLAN
+		}
+		if s.Gateway != nil {
+			sn.UserContext["gateway"] = s.Gateway.String()
+		}
+		out = append(out, sn)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{"Dhcp6": map[string]any{"subnet6": out}})
+}
+
+// planInterface names the interface a subnet lives on: its VLAN interface when a
+// VLAN is given, otherwise the subnet name.
+func planInterface(s planSubnet, vlanPrefix string) string {
+	if s.VLAN != 0 {
+		return fmt.Sprintf("%s%d", vlanPrefix, s.VLAN)
+	}
+	return strings.ReplaceAll(s.Name, " ", "-")
+}
+
+// exportRadvd writes a radvd.conf with one interface block per plan subnet.
+// Autonomous (SLAAC) addressing is only enabled on /64s.
+func exportRadvd(w io.Writer, subnets []planSubnet) error {
+	for _, s := range subnets {
+		ones, _ := s.Net.Mask.Size()
+		fmt.Fprintf(w, "# %s\ninterface %s {\n    AdvSendAdvert on;\n", s.Name, planInterface(s, "vlan"))
+		fmt.Fprintf(w, "    prefix %s {\n        AdvOnLink on;\n        AdvAutonomous %s;\n    };\n", s.Net, map[bool]string{true: "on", false: "off"}[ones == 64])
+		if len(s.DNS) > 0 {
+			fmt.Fprintf(w, "    RDNSS %s {\n    };\n", joinIPs(s.DNS, " "))
+		}
+		fmt.Fprintln(w, "};")
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// exportIOS writes Cisco IOS-XE interface configuration for plan subnets with a
+// VLAN and gateway, and global NTP servers. Other subnets are noted and skipped.
+func exportIOS(w io.Writer, subnets []planSubnet) error {
+	ntp := map[string]bool{}
+	for _, s := range subnets {
+		for _, ip := range s.NTP {
+			if !ntp[ip.String()] {
+				ntp[ip.String()] = true
+				fmt.Fprintf(w, "ntp server %s\n", ip)
+			}
+		}
+	}
+	for _, s := range subnets {
+		fmt.Fprintln(w, "!")
+		if s.VLAN == 0 || s.Gateway == nil {
+			fmt.Fprintf(w, "! %s %s: needs vlan= and gw= for an interface, skipped\n", s.Net, s.Name)
+			continue
+		}
+		ones, _ := s.Net.Mask.Size()
+		fmt.Fprintf(w, "interface Vlan%d\n description %s\n ipv6 address %s/%d\n", s.VLAN, s.Name, s.Gateway, ones)
+		for _, dns := range s.DNS {
+			fmt.Fprintf(w, " ipv6 nd ra dns server %s\n", dns)
+		}
+	}
+	fmt.Fprintln(w, "end")
+	return nil
+}
+
+// exportTerraform writes a Terraform JSON variables file (.auto.tfvars.json)
+// holding a "subnets" map keyed by subnet name.
+func exportTerraform(w io.Writer, subnets []planSubnet) error {
+	type tfSubnet struct {
+		Prefix  string   `json:"prefix"`
+		Gateway string   `json:"gateway,omitempty"`
+		DNS     []string `json:"dns"`
+		NTP     []string `json:"ntp"`
+		VLAN    *int     `json:"vlan"`
+	}
+	out := map[string]tfSubnet{}
+	for _, s := range subnets {
+		if _, dup := out[s.Name]; dup {
+			return fmt.Errorf("line %d: duplicate subnet name %q", s.Line, s.Name)
+		}
+		t := tfSubnet{Prefix: s.Net.String(), DNS: []string{}, NTP: []string{}}
+		if s.Gateway != nil {
+			t.Gateway = s.Gateway.String()
+		}
+		for _, ip := range s.DNS {
+			t.DNS = append(t.DNS, ip.String())
+		}
+		for _, ip := range s.NTP {
+			t.NTP = append(t.NTP, ip.String())
+		}
+		if s.VLAN != 0 {
+			vlan := s.VLAN
+			t.VLAN = &vlan
+		}
+		out[s.Name] = t
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{"subnets": out})
+}
+
+// planExporters maps -export names to their writers.
+var planExporters = map[string]func(io.Writer, []planSubnet) error{
+	"kea":       exportKea,
+	"radvd":     exportRadvd,
+	"ios":       exportIOS,
+	"terraform": exportTerraform,
+}
+
+// runPlanExport renders the annotated plan in the given format to outputFile or stdout.
+func runPlanExport(planFile string, format string, outputFile string) {
+	export, err := planExporter(format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if planFile == "" {
+		log.Fatal("an annotated plan must be given with -plan")
+	}
+	subnets, err := readPlanSubnets(planFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := export(out, subnets); err != nil {
+		log.Fatal(err)
+	}
+	if outputFile != "" {
+		statusf("%d subnet(s) exported to %s\n", len(subnets), outputFile)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParsePlanSubnet(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("3fff:0:1:10::/64")
+	cases := []struct {
+		label   string
+		name    string
+		vlan    int
+		dns     int
+		wantErr string
+	}{
+		{"clients gw=3fff:0:1:10::1 dns=3fff::53,3fff::54 vlan=10", "clients", 10, 2, ""},
+		{"guest wifi ntp=3fff::123", "guest wifi", 0, 0, ""},
+		{"", "3fff:0:1:10::/64", 0, 0, ""},
+		{"clients gw=3fff:0:1:11::1", "", 0, 0, "outside"},
+		{"clients vlan=4095", "", 0, 0, "out of range"},
+		{"clients dns=192.0.2.53", "", 0, 0, "invalid IPv6 address"},
+		{"clients gateway=3fff:0:1:10::1", "", 0, 0, "unknown metadata key"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.label, func(t *testing.T) {
+			s, err := parsePlanSubnet(prefixEntry{Net: ipnet, Label: tc.label, Line: 3})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.Name != tc.name || s.VLAN != tc.vlan || len(s.DNS) != tc.dns {
+				t.Errorf("got name %q vlan %d dns %v", s.Name, s.VLAN, s.DNS)
+			}
+		})
+	}
+}
+
+func TestPlanExporters(t *testing.T) {
+	subnets, err := readPlanSubnets("testdata/plan-services.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets) != 3 {
+		t.Fatalf("expected 3 annotated subnets (the /48 has no metadata), got %d", len(subnets))
+	}
+	cases := []struct {
+		format string
+		want   []string
+	}{
+		{"kea", []string{`"subnet": "3fff:0:1:10::/64"`, `"data": "3fff:0:1::53, 3fff:0:2::53"`, `"sntp-servers"`, `"vlan": 20`}},
+		{"radvd", []string{"interface vlan10 {", "prefix 3fff:0:1:20::/64 {", "RDNSS 3fff:0:1::53 3fff:0:2::53 {", "interface servers {"}},
+		{"ios", []string{"ntp server 3fff:0:1::123\n!", "interface Vlan10\n description clients\n ipv6 address 3fff:0:1:10::1/64\n", " ipv6 nd ra dns server 3fff:0:2::53", "3fff:0:1:ff::/64 servers: needs vlan= and gw="}},
+		{"terraform", []string{`"voice": {`, `"gateway": "3fff:0:1:ff::1"`, `"vlan": null`}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := planExporters[tc.format](&buf, subnets); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("missing %q in:\n%s", want, buf.String())
+				}
+			}
+			if tc.format == "kea" || tc.format == "terraform" {
+				var v map[string]any
+				if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+					t.Errorf("invalid JSON: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestExportTerraformDuplicateName(t *testing.T) {
+	_, a, _ := net.ParseCIDR("3fff:0:1::/64")
+	_, b, _ := net.ParseCIDR("3fff:0:2::/64")
+	subnets := []planSubnet{{Net: a, Name: "lab", VLAN: 1}, {Net: b, Name: "lab", VLAN: 2, Line: 4}}
+	if err := exportTerraform(&bytes.Buffer{}, subnets); err == nil || !strings.Contains(err.Error(), "duplicate subnet name") {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"slices"
+)
+
+// Plan validation problem kinds.
+const (
+	planIssueDuplicate = "duplicate"
+	planIssueOverlap   = "overlap"
+	planIssueOutside   = "outside parent"
+	planIssueUnaligned = "not nibble-aligned"
+)
+
+// planIssue is a problem with one allocation of a plan.
+type planIssue struct {
+	Line   int    `json:"line"`
+	Prefix string `json:"prefix"`
+	Label  string `json:"label,omitempty"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// planValidation is the result of validating a plan.
+type planValidation struct {
+	File    string      `json:"file"`
+	Parent  string      `json:"parent,omitempty"`
+	Entries int         `json:"entries"`
+	Valid   bool        `json:"valid"`
+	Issues  []planIssue `json:"issues"`
+}
+
+// entryPrefix converts a prefix-list entry to a netip.Prefix.
+func entryPrefix(e prefixEntry) netip.Prefix {
+	ones, _ := e.Net.Mask.Size()
+	return netip.PrefixFrom(netip.AddrFrom16([16]byte(e.Net.IP.To16())), ones)
+}
+
+// entryAt is describeEntry with the entry's line, for pointing at the other
+// entry of a problem.
+func entryAt(e prefixEntry) string {
+	return fmt.Sprintf("%s at line %d", describeEntry(e), e.Line)
+}
+
+// validatePlan checks a plan's allocations, returning its problems in line
+// order: a prefix listed more than once, an allocation inside another (allowed
+// with nested, for plans listing each tier's aggregates), an allocation outside
+// parent when parent is valid, and prefix lengths off a nibble boundary.
+func validatePlan(entries []prefixEntry, parent netip.Prefix, nested bool) []planIssue {
+	var issues []planIssue
+	report := func(e prefixEntry, kind, detail string) {
+		issues = append(issues, planIssue{Line: e.Line, Prefix: e.Net.String(), Label: e.Label, Kind: kind, Detail: detail})
+	}
+	for _, e := range entries {
+		p := entryPrefix(e)
+		if parent.IsValid() && (p.Bits() < parent.Bits() || !parent.Contains(p.Addr())) {
+			report(e, planIssueOutside, "not within "+parent.String())
+		}
+		if !isNibbleAligned(p.Bits()) {
+			report(e, planIssueUnaligned, fmt.Sprintf("/%d is not a multiple of 4", p.Bits()))
+		}
+	}
+
+	// In address order, with shorter prefixes first, an entry overlaps an
+	// earlier one exactly when it lies inside the innermost still-open entry.
+	sorted := slices.Clone(entries)
+	slices.SortStableFunc(sorted, func(a, b prefixEntry) int {
+		pa, pb := entryPrefix(a), entryPrefix(b)
+		if c := pa.Addr().Compare(pb.Addr()); c != 0 {
+			return c
+		}
+		return pa.Bits() - pb.Bits()
+	})
+	var open []prefixEntry
+	for _, e := range sorted {
+		p := entryPrefix(e)
+		for len(open) > 0 {
+			top := entryPrefix(open[len(open)-1])
+			if top.Contains(p.Addr()) {
+				break
+			}
+			open = open[:len(open)-1]
+		}
+		if len(open) > 0 {
+			outer := open[len(open)-1]
+			if entryPrefix(outer) == p {
+				// The sort is stable, so outer is the earlier listing.
+				report(e, planIssueDuplicate, "same prefix as "+entryAt(outer))
+				continue
+			}
+			if !nested {
+				report(e, planIssueOverlap, "inside "+entryAt(outer))
+			}
+		}
+		open = append(open, e)
+	}
+	sortIssues(issues)
+	return issues
+}
+
+// sortIssues puts problems in line order, keeping the order of those on a line.
+func sortIssues(issues []planIssue) {
+	slices.SortStableFunc(issues, func(a, b planIssue) int { return a.Line - b.Line })
+}
+
+// runPlanValidate validates a plan file, printing its problems and exiting
+// non-zero when there are any.
+func runPlanValidate(path, parent string, nested bool) {
+	entries, err := readPrefixFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var parentPrefix netip.Prefix
+	if parent != "" {
+		if parentPrefix, err = parseSubnetParent(parent); err != nil {
+			log.Fatal(err)
+		}
+	}
+	issues := validatePlan(entries, parentPrefix, nested)
+	result := planValidation{File: path, Entries: len(entries), Valid: len(issues) == 0, Issues: issues}
+	if parentPrefix.IsValid() {
+		result.Parent = parentPrefix.String()
+	}
+	if result.Issues == nil {
+		result.Issues = []planIssue{}
+	}
+	writeResult(result, func() {
+		for _, i := range issues {
+			name := i.Prefix
+			if i.Label != "" {
+				name += " " + i.Label
+			}
+			fmt.Printf("line %d: %s: %s: %s\n", i.Line, name, i.Kind, i.Detail)
+		}
+		if len(issues) == 0 {
+			fmt.Printf("%d entries in %s are valid\n", len(entries), path)
+		}
+	})
+	if len(issues) > 0 {
+		log.Fatalf("%d problem(s) in %d entries", len(issues), len(entries))
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"testing"
+)
+
+func TestValidatePlan(t *testing.T) {
+	f, err := os.Open("testdata/plan-invalid.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := readPrefixEntries(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := netip.MustParsePrefix("3fff::/32")
+
+	tests := []struct {
+		name   string
+		parent netip.Prefix
+		nested bool
+		want   []planIssue
+	}{
+		{"all", parent, false, []planIssue{
+			{Line: 3, Kind: planIssueOverlap, Detail: "inside 3fff:0:1::/48 (site-chicago) at line 2"},
+			{Line: 5, Kind: planIssueDuplicate, Detail: "same prefix as 3fff:0:2::/48 (site-denver) at line 4"},
+			{Line: 6, Kind: planIssueUnaligned, Detail: "/50 is not a multiple of 4"},
+			{Line: 7, Kind: planIssueOutside, Detail: "not within 3fff::/32"},
+		}},
+		{"nested without parent", netip.Prefix{}, true, []planIssue{
+			{Line: 5, Kind: planIssueDuplicate, Detail: "same prefix as 3fff:0:2::/48 (site-denver) at line 4"},
+			{Line: 6, Kind: planIssueUnaligned, Detail: "/50 is not a multiple of 4"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validatePlan(entries, tt.parent, tt.nested)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d issues, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				if got[i].Line != w.Line || got[i].Kind != w.Kind || got[i].Detail != w.Detail {
+					t.Errorf("issue %d = %+v, want %+v", i, got[i], w)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePlanGenerated(t *testing.T) {
+	schema, err := loadPlanSchema("testdata/plan-schema.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []prefixEntry
+	schema.expand(func(r subnetRecord) error {
+		entries = append(entries, prefixEntry{Net: prefixToIPNet(r.Prefix), Label: r.Label, Line: len(entries) + 1})
+		return nil
+	})
+	if issues := validatePlan(entries, schema.parent, true); len(issues) != 0 {
+		t.Errorf("generated plan has issues: %+v", issues)
+	}
+	if issues := validatePlan(entries, schema.parent, false); len(issues) != 16 {
+		t.Errorf("got %d overlaps without -nested, want 16", len(issues))
+	}
+}
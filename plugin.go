@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Plugins are executables on PATH, found by name the way git finds its
+// subcommands:
+//
+//	ipv6utils-export-NAME   -export NAME: reads the annotated plan as JSON on
+//	                        stdin and writes the configuration to stdout.
+//	ipv6utils-plan-NAME     -plan plugin:NAME[:ARG]: writes a prefix list to
+//	                        stdout, e.g. fetched from an IPAM system. ARG, if
+//	                        given, is its only argument.
+//
+// A plugin that exits non-zero fails the command; its stderr is passed through.
+const (
+	exportPluginPrefix = "ipv6utils-export-"
+	planPluginPrefix   = "ipv6utils-plan-"
+	planPluginScheme   = "plugin:"
+)
+
+// pluginSubnet is the JSON form of a planSubnet given to exporter plugins.
+type pluginSubnet struct {
+	Prefix  string   `json:"prefix"`
+	Name    string   `json:"name"`
+	Gateway string   `json:"gateway,omitempty"`
+	DNS     []string `json:"dns"`
+	NTP     []string `json:"ntp"`
+	VLAN    int      `json:"vlan,omitempty"`
+}
+
+// pluginInput is the document written to an exporter plugin's stdin.
+type pluginInput struct {
+	Subnets []pluginSubnet `json:"subnets"`
+}
+
+// newPluginInput converts the plan subnets, in plan order.
+func newPluginInput(subnets []planSubnet) pluginInput {
+	in := pluginInput{Subnets: []pluginSubnet{}}
+	for _, s := range subnets {
+		p := pluginSubnet{Prefix: s.Net.String(), Name: s.Name, DNS: []string{}, NTP: []string{}, VLAN: s.VLAN}
+		if s.Gateway != nil {
+			p.Gateway = s.Gateway.String()
+		}
+		for _, ip := range s.DNS {
+			p.DNS = append(p.DNS, ip.String())
+		}
+		for _, ip := range s.NTP {
+			p.NTP = append(p.NTP, ip.String())
+		}
+		in.Subnets = append(in.Subnets, p)
+	}
+	return in
+}
+
+// runPlugin runs a plugin executable with stdin, returning its stdout.
+func runPlugin(path string, args []string, stdin io.Reader) ([]byte, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", path, err)
+	}
+	return out, nil
+}
+
+// planExporter returns the built-in exporter for format, or else one backed by
+// an ipv6utils-export-FORMAT plugin.
+func planExporter(format string) (func(io.Writer, []planSubnet) error, error) {
+	if export, ok := planExporters[format]; ok {
+		return export, nil
+	}
+	path, err := exec.LookPath(exportPluginPrefix + format)
+	if err != nil {
+		return nil, fmt.Errorf("unknown export format %q (want kea, radvd, ios, terraform, or an %s%s plugin on PATH)", format, exportPluginPrefix, format)
+	}
+	return func(w io.Writer, subnets []planSubnet) error {
+		in, err := json.Marshal(newPluginInput(subnets))
+		if err != nil {
+			return err
+		}
+		out, err := runPlugin(path, nil, bytes.NewReader(in))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}, nil
+}
+
+// readPlanPlugin reads the prefix list produced by -plan plugin:NAME[:ARG].
+func readPlanPlugin(source string) ([]prefixEntry, error) {
+	name, arg, hasArg := strings.Cut(strings.TrimPrefix(source, planPluginScheme), ":")
+	path, err := exec.LookPath(planPluginPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: no %s%s plugin on PATH", source, planPluginPrefix, name)
+	}
+	var args []string
+	if hasArg {
+		args = []string{arg}
+	}
+	out, err := runPlugin(path, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readPrefixEntries(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", source, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePlugin installs a shell-script plugin in a temporary directory on PATH.
+func writePlugin(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestExportPlugin(t *testing.T) {
+	writePlugin(t, "ipv6utils-export-echo", "echo begin\ncat\necho end\n")
+	export, err := planExporter("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subnets, err := readPlanSubnets("testdata/plan-services.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := export(&buf, subnets); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"begin\n", `{"subnets":[{"prefix":"3fff:0:1:10::/64","name":"clients","gateway":"3fff:0:1:10::1"`, `"vlan":10}`, "end\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in %s", want, buf.String())
+		}
+	}
+}
+
+func TestExportPluginErrors(t *testing.T) {
+	writePlugin(t, "ipv6utils-export-broken", "exit 3\n")
+	if _, err := planExporter("no-such-format"); err == nil || !strings.Contains(err.Error(), "unknown export format") {
+		t.Errorf("expected unknown format error, got %v", err)
+	}
+	export, err := planExporter("broken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := export(&bytes.Buffer{}, nil); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("expected exit status error, got %v", err)
+	}
+}
+
+func TestPlanPlugin(t *testing.T) {
+	writePlugin(t, "ipv6utils-plan-static", "echo \"3fff:0:1::/48 site-$1\"\necho '3fff:0:1:10::/64 clients'\n")
+	cases := []struct {
+		source string
+		first  string
+	}{
+		{"plugin:static", "site-"},
+		{"plugin:static:chicago", "site-chicago"},
+	}
+	for _, tc := range cases {
+		entries, err := readPrefixFile(tc.source)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.source, err)
+		}
+		if len(entries) != 2 || entries[0].Label != tc.first {
+			t.Errorf("%s: got %+v", tc.source, entries)
+		}
+	}
+	if _, err := readPrefixFile("plugin:missing"); err == nil || !strings.Contains(err.Error(), "no ipv6utils-plan-missing plugin") {
+		t.Errorf("expected missing plugin error, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// pref64Type is the Router Advertisement option type of PREF64, RFC 8781.
+const pref64Type = 38
+
+// pref64MaxLifetime is the longest lifetime PREF64 carries: 8191 units of 8
+// seconds.
+const pref64MaxLifetime = 8191 * 8
+
+// pref64PLCs are the prefix lengths in the order of their Prefix Length Codes:
+// code 0 is a /96, code 5 a /32.
+var pref64PLCs = []int{96, 64, 56, 48, 40, 32}
+
+// pref64Option is the PREF64 option announcing a NAT64 prefix.
+type pref64Option struct {
+	Prefix   string `json:"prefix"`
+	Lifetime int    `json:"lifetime"` // seconds, rounded up to a multiple of 8
+	PLC      int    `json:"plc"`
+	Hex      string `json:"hex"`
+}
+
+// newPREF64Option encodes prefix as a PREF64 option of RFC 8781 section 4: the
+// type and length, the lifetime in units of 8 seconds with the Prefix Length
+// Code in the low 3 bits, and the first 96 bits of the prefix.
+func newPREF64Option(prefix netip.Prefix, lifetime int) (pref64Option, error) {
+	if lifetime < 0 || lifetime > pref64MaxLifetime {
+		return pref64Option{}, fmt.Errorf("PREF64 lifetime must be between 0 and %d seconds, got %d", pref64MaxLifetime, lifetime)
+	}
+	plc := slices.Index(pref64PLCs, prefix.Bits())
+	if plc < 0 {
+		return pref64Option{}, fmt.Errorf("PREF64 has no code for a /%d prefix", prefix.Bits())
+	}
+	scaled := (lifetime + 7) / 8
+	b := prefix.Addr().As16()
+	option := append([]byte{pref64Type, 2, byte(scaled >> 5), byte(scaled<<3 | plc)}, b[:12]...)
+	return pref64Option{
+		Prefix:   prefixText(prefix),
+		Lifetime: scaled * 8,
+		PLC:      plc,
+		Hex:      hex.EncodeToString(option),
+	}, nil
+}
+
+// hexBytes spaces the bytes of a hex string apart, as a packet dump does.
+func hexBytes(s string) string {
+	var parts []string
+	for i := 0; i < len(s); i += 2 {
+		parts = append(parts, s[i:i+2])
+	}
+	return strings.Join(parts, " ")
+}
+
+// writePREF64Radvd writes the radvd.conf interface section announcing the
+// prefix.
+func writePREF64Radvd(w io.Writer, iface string, o pref64Option) {
+	fmt.Fprintf(w, "interface %s {\n", iface)
+	fmt.Fprintln(w, "    AdvSendAdvert on;")
+	fmt.Fprintf(w, "    nat64prefix %s {\n", o.Prefix)
+	fmt.Fprintf(w, "        AdvValidLifetime %d;\n", o.Lifetime)
+	fmt.Fprintln(w, "    };")
+	fmt.Fprintln(w, "};")
+}
+
+// v6OnlyWait is the V6ONLY_WAIT RFC 8925 suggests, in seconds.
+const v6OnlyWait = 1800
+
+// writePREF64Kea writes the Kea DHCPv4 subnet options of an IPv6-mostly
+// network. Kea sends no Router Advertisements, so the option itself comes from
+// the routers; what Kea adds is option 108 of RFC 8925, telling clients able
+// to run IPv6-only, with the NAT64 prefix learned from PREF64, to do so.
+func writePREF64Kea(w io.Writer, iface string, o pref64Option) {
+	fmt.Fprintf(w, "// PREF64 %s is announced by the routers; Kea sends no RAs.\n", o.Prefix)
+	fmt.Fprintln(w, "// Option 108 (RFC 8925) lets clients that can go IPv6-only do so.")
+	fmt.Fprintln(w, `"option-data": [`)
+	fmt.Fprintln(w, "    {")
+	fmt.Fprintln(w, `        "name": "v6-only-preferred",`)
+	fmt.Fprintf(w, "        \"data\": \"%d\"\n", v6OnlyWait)
+	fmt.Fprintln(w, "    }")
+	fmt.Fprintln(w, "]")
+}
+
+// pref64Configs maps -config names to their writers.
+var pref64Configs = map[string]func(io.Writer, string, pref64Option){
+	"radvd": writePREF64Radvd,
+	"kea":   writePREF64Kea,
+}
+
+// runPREF64 prints the PREF64 Router Advertisement option announcing a NAT64
+// prefix, as the bytes of the option, or with config, as the configuration of
+// radvd, for the interface iface, or Kea.
+func runPREF64(prefix string, lifetime int, config, iface string) {
+	write, ok := pref64Configs[config]
+	if config != "" && !ok {
+		log.Fatalf("unknown config %q (want radvd or kea)", config)
+	}
+	p, err := parseNAT64Prefix(prefix, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	o, err := newPREF64Option(p, lifetime)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeResult(o, func() {
+		if write != nil {
+			write(os.Stdout, iface, o)
+			return
+		}
+		fmt.Printf("PREF64 option for %s, lifetime %ds:\n", o.Prefix, o.Lifetime)
+		fmt.Println(hexBytes(o.Hex))
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestNewPREF64Option(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		lifetime int
+		hex      string
+		seconds  int
+	}{
+		{"64:ff9b::/96", 1800, "260207080064ff9b0000000000000000", 1800},
+		{"2001:db8:122:300::/56", 600, "2602025a20010db80122030000000000", 600},
+		{"2001:db8::/32", 65528, "2602fffd20010db80000000000000000", 65528},
+		{"2001:db8:100::/40", 1, "2602000c20010db80100000000000000", 8}, // rounded up
+		{"2001:db8:122:344::/64", 0, "2602000120010db80122034400000000", 0},
+	}
+	for _, tt := range tests {
+		o, err := newPREF64Option(netip.MustParsePrefix(tt.prefix), tt.lifetime)
+		if err != nil || o.Hex != tt.hex || o.Lifetime != tt.seconds {
+			t.Errorf("%s lifetime %d: got %+v, %v, want %s, %ds", tt.prefix, tt.lifetime, o, err, tt.hex, tt.seconds)
+		}
+	}
+	if _, err := newPREF64Option(netip.MustParsePrefix("64:ff9b::/96"), 65529); err == nil {
+		t.Error("lifetime past 65528 accepted")
+	}
+	if _, err := newPREF64Option(netip.MustParsePrefix("2001:db8::/33"), 1800); err == nil {
+		t.Error("/33 accepted")
+	}
+}
+
+func TestPREF64Configs(t *testing.T) {
+	o, err := newPREF64Option(netip.MustParsePrefix("64:ff9b::/96"), 1800)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	writePREF64Radvd(&buf, "eth1", o)
+	if !strings.Contains(buf.String(), "interface eth1 {") || !strings.Contains(buf.String(), "nat64prefix 64:ff9b::/96 {") {
+		t.Errorf("radvd:\n%s", buf.String())
+	}
+	buf.Reset()
+	writePREF64Kea(&buf, "eth1", o)
+	if !strings.Contains(buf.String(), `"name": "v6-only-preferred"`) {
+		t.Errorf("kea:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// prefixEntry is one line of a prefix list file: a prefix (or a bare address, read
+// as a /128) with an optional free-text label and the line it came from.
+type prefixEntry struct {
+	Net   *net.IPNet
+	Label string
+	Line  int
+}
+
+// Host reports whether the entry was a single address rather than a prefix.
+func (e prefixEntry) Host() bool {
+	ones, _ := e.Net.Mask.Size()
+	return ones == 128
+}
+
+// readPrefixEntries reads one prefix or address per line, followed by an optional
+// label. Blank lines and '#' comments are skipped.
+func readPrefixEntries(r io.Reader) ([]prefixEntry, error) {
+	var entries []prefixEntry
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		ip, prefixLen, err := parseIPv6WithOptionalPrefix(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		if prefixLen < 0 {
+			prefixLen = 128
+		}
+		mask := net.CIDRMask(prefixLen, 128)
+		entries = append(entries, prefixEntry{
+			Net:   &net.IPNet{IP: ip.Mask(mask), Mask: mask},
+			Label: strings.Join(fields[1:], " "),
+			Line:  lineNo,
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// readPrefixFile opens a prefix list file and reads its entries. A path of the
+// form plugin:NAME[:ARG] reads the list from an ipv6utils-plan-NAME plugin instead.
+func readPrefixFile(path string) ([]prefixEntry, error) {
+	if strings.HasPrefix(path, planPluginScheme) {
+		return readPlanPlugin(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := readPrefixEntries(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// writePrefixList prints entries in the prefix-list format the tools read.
+func writePrefixList(w io.Writer, entries []prefixEntry) error {
+	for _, e := range entries {
+		line := strings.TrimSpace(fmt.Sprintf("%-44s %s", e.Net, e.Label))
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
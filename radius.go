@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+)
+
+// subscriber is one line of a subscriber allocation file: the RADIUS user name,
+// the WAN prefix (Framed-IPv6-Prefix), and the delegated prefix
+// (Delegated-IPv6-Prefix). Either prefix may be absent.
+type subscriber struct {
+	User      string
+	Framed    *net.IPNet
+	Delegated *net.IPNet
+	Line      int
+}
+
+// readSubscribers reads "USER FRAMED-PREFIX DELEGATED-PREFIX" lines, with "-" for
+// an absent prefix. Blank lines and '#' comments are skipped.
+func readSubscribers(r io.Reader) ([]subscriber, error) {
+	var subs []subscriber
+	seen := map[string]int{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: want USER FRAMED-PREFIX DELEGATED-PREFIX, got %d fields", lineNo, len(fields))
+		}
+		if prev, ok := seen[fields[0]]; ok {
+			return nil, fmt.Errorf("line %d: user %s already defined on line %d", lineNo, fields[0], prev)
+		}
+		seen[fields[0]] = lineNo
+		s := subscriber{User: fields[0], Line: lineNo}
+		for i, dst := range []**net.IPNet{&s.Framed, &s.Delegated} {
+			if fields[i+1] == "-" {
+				continue
+			}
+			ipnet, err := parseIPv6Prefix(fields[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			*dst = ipnet
+		}
+		if s.Framed == nil && s.Delegated == nil {
+			return nil, fmt.Errorf("line %d: user %s has no prefixes", lineNo, s.User)
+		}
+		subs = append(subs, s)
+	}
+	return subs, scanner.Err()
+}
+
+// radiusReplies returns the reply attributes for a subscriber.
+func radiusReplies(s subscriber) [][2]string {
+	var attrs [][2]string
+	if s.Framed != nil {
+		attrs = append(attrs, [2]string{"Framed-IPv6-Prefix", s.Framed.String()})
+	}
+	if s.Delegated != nil {
+		attrs = append(attrs, [2]string{"Delegated-IPv6-Prefix", s.Delegated.String()})
+	}
+	return attrs
+}
+
+// freeradiusUserName quotes a user name for the users file when it contains
+// characters that would otherwise end the name.
+func freeradiusUserName(user string) string {
+	if strings.ContainsAny(user, "\"#,=") {
+		return `"` + strings.ReplaceAll(strings.ReplaceAll(user, `\`, `\\`), `"`, `\"`) + `"`
+	}
+	return user
+}
+
+// sqlQuote quotes a string literal for SQL.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// writeRADIUSEntries writes the subscribers as a FreeRADIUS users file ("users")
+// or as INSERT statements for the rlm_sql radreply table ("sql").
+func writeRADIUSEntries(w io.Writer, subs []subscriber, outputFormat string) error {
+	switch outputFormat {
+	case "", "users":
+		for _, s := range subs {
+			fmt.Fprintln(w, freeradiusUserName(s.User))
+			attrs := radiusReplies(s)
+			for i, a := range attrs {
+				sep := ","
+				if i == len(attrs)-1 {
+					sep = ""
+				}
+				fmt.Fprintf(w, "\t%s = %s%s\n", a[0], a[1], sep)
+			}
+			fmt.Fprintln(w)
+		}
+	case "sql":
+		for _, s := range subs {
+			for _, a := range radiusReplies(s) {
+				fmt.Fprintf(w, "INSERT INTO radreply (username, attribute, op, value) VALUES (%s, %s, ':=', %s);\n",
+					sqlQuote(s.User), sqlQuote(a[0]), sqlQuote(a[1]))
+			}
+		}
+	default:
+		return fmt.Errorf("unknown output format %q (want users or sql)", outputFormat)
+	}
+	return nil
+}
+
+// exportRADIUS converts a subscriber allocation file ("-" for stdin) to RADIUS entries.
+func exportRADIUS(path string, outputFormat string) {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	subs, err := readSubscribers(in)
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	if err := writeRADIUSEntries(os.Stdout, subs, outputFormat); err != nil {
+		log.Fatal(err)
+	}
+}
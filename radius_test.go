@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadSubscribers(t *testing.T) {
+	f, err := os.Open("testdata/subscribers.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	subs, err := readSubscribers(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(subs) != 3 || subs[0].Framed.String() != "3fff:0:100:1::/64" || subs[2].Framed != nil || subs[2].Delegated.String() != "3fff:0:200:300::/56" {
+		t.Errorf("unexpected subscribers %+v", subs)
+	}
+
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"missing column", "alice 3fff::/64\n"},
+		{"host bits", "alice 3fff::1/64 -\n"},
+		{"no prefixes", "alice - -\n"},
+		{"duplicate user", "alice 3fff::/64 -\nalice 3fff:0:0:1::/64 -\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := readSubscribers(strings.NewReader(tc.input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestWriteRADIUSEntries(t *testing.T) {
+	subs, err := readSubscribers(strings.NewReader("o'brien 3fff:0:100:1::/64 3fff:0:200:100::/56\nguest=1 - 3fff:0:200:200::/56\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{"users", "o'brien\n\tFramed-IPv6-Prefix = 3fff:0:100:1::/64,\n\tDelegated-IPv6-Prefix = 3fff:0:200:100::/56\n\n" +
+			"\"guest=1\"\n\tDelegated-IPv6-Prefix = 3fff:0:200:200::/56\n\n"},
+		{"sql", "INSERT INTO radreply (username, attribute, op, value) VALUES ('o''brien', 'Framed-IPv6-Prefix', ':=', '3fff:0:100:1::/64');\n" +
+			"INSERT INTO radreply (username, attribute, op, value) VALUES ('o''brien', 'Delegated-IPv6-Prefix', ':=', '3fff:0:200:100::/56');\n" +
+			"INSERT INTO radreply (username, attribute, op, value) VALUES ('guest=1', 'Delegated-IPv6-Prefix', ':=', '3fff:0:200:200::/56');\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeRADIUSEntries(&buf, subs, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tc.want {
+				t.Errorf("expected\n%s\ngot\n%s", tc.want, buf.String())
+			}
+		})
+	}
+	if err := writeRADIUSEntries(&bytes.Buffer{}, subs, "ldif"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// advertisedPrefix is a prefix (PIO) or route (RIO) sent in router advertisements.
+type advertisedPrefix struct {
+	Iface string
+	Kind  string // "prefix" or "route"
+	Net   *net.IPNet
+}
+
+// parseAdvertisedNet parses a prefix from an RA configuration, rejecting host bits.
+func parseAdvertisedNet(s string) (*net.IPNet, error) {
+	ipnet, err := parseIPv6Prefix(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %v", err)
+	}
+	return ipnet, nil
+}
+
+// parseRadvdConf reads the prefix and route blocks of every interface in a
+// radvd.conf. The special prefix ::/64, which advertises whatever is configured on
+// the interface, cannot be resolved from the file and is returned in skipped.
+func parseRadvdConf(r io.Reader) (prefixes []advertisedPrefix, skipped []string, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	stmts, err := parseBraceConfig(string(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, iface := range stmts {
+		if len(iface.Words) != 2 || iface.Words[0] != "interface" {
+			continue
+		}
+		for _, s := range iface.Body {
+			if len(s.Words) != 2 || (s.Words[0] != "prefix" && s.Words[0] != "route") {
+				continue
+			}
+			if s.Words[0] == "prefix" && s.Words[1] == "::/64" {
+				skipped = append(skipped, iface.Words[1]+": prefix ::/64 (advertises the interface's own prefixes)")
+				continue
+			}
+			ipnet, err := parseAdvertisedNet(s.Words[1])
+			if err != nil {
+				return nil, nil, fmt.Errorf("interface %s: %v", iface.Words[1], err)
+			}
+			prefixes = append(prefixes, advertisedPrefix{Iface: iface.Words[1], Kind: s.Words[0], Net: ipnet})
+		}
+	}
+	return prefixes, skipped, nil
+}
+
+// parseNetworkdFile reads the [IPv6Prefix] and [IPv6RoutePrefix] sections of a
+// systemd-networkd .network file. They are only advertised when IPv6SendRA is on.
+func parseNetworkdFile(r io.Reader) ([]advertisedPrefix, error) {
+	var prefixes []advertisedPrefix
+	iface := "*"
+	sendRA := false
+	section := ""
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case section == "Match" && key == "Name":
+			iface = value
+		case section == "Network" && key == "IPv6SendRA":
+			sendRA = parseSystemdBool(value)
+		case section == "IPv6Prefix" && key == "Prefix", section == "IPv6RoutePrefix" && key == "Route":
+			ipnet, err := parseAdvertisedNet(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			kind := "prefix"
+			if section == "IPv6RoutePrefix" {
+				kind = "route"
+			}
+			prefixes = append(prefixes, advertisedPrefix{Kind: kind, Net: ipnet})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !sendRA {
+		return nil, nil
+	}
+	for i := range prefixes {
+		prefixes[i].Iface = iface
+	}
+	return prefixes, nil
+}
+
+// parseSystemdBool reads a systemd boolean setting.
+func parseSystemdBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "yes", "true", "on":
+		return true
+	}
+	return false
+}
+
+// readRAConfig reads a radvd.conf or, for files ending in .network, a
+// systemd-networkd configuration.
+func readRAConfig(path string) ([]advertisedPrefix, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	if filepath.Ext(path) == ".network" {
+		prefixes, err := parseNetworkdFile(f)
+		return prefixes, nil, err
+	}
+	return parseRadvdConf(f)
+}
+
+// writeAdvertisedPrefixes prints the prefixes as a prefix list labelled with the
+// interface and whether each is an on-link prefix or a route.
+func writeAdvertisedPrefixes(w io.Writer, prefixes []advertisedPrefix) {
+	for _, p := range prefixes {
+		fmt.Fprintf(w, "%-44s %s %s\n", p.Net, p.Iface, p.Kind)
+	}
+}
+
+// importRAConfigs converts comma-separated radvd/networkd files to one prefix list.
+func importRAConfigs(files string) {
+	var all []advertisedPrefix
+	for _, path := range strings.Split(files, ",") {
+		prefixes, skipped, err := readRAConfig(path)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		for _, s := range skipped {
+			log.Printf("%s: skipped %s", path, s)
+		}
+		all = append(all, prefixes...)
+	}
+	writeAdvertisedPrefixes(os.Stdout, all)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseRadvdConf(t *testing.T) {
+	f, err := os.Open("testdata/radvd.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	prefixes, skipped, err := parseRadvdConf(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	writeAdvertisedPrefixes(&buf, prefixes)
+	want := "3fff:0:1:10::/64                             eth1 prefix\n" +
+		"3fff:0:1::/48                                eth1 route\n" +
+		"3fff:0:1:11::/64                             eth2 prefix\n" +
+		"fd12:3456:789a:11::/64                       eth2 prefix\n"
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+	if len(skipped) != 1 || !strings.HasPrefix(skipped[0], "eth2: prefix ::/64") {
+		t.Errorf("expected the ::/64 prefix to be skipped, got %v", skipped)
+	}
+
+	for _, input := range []string{
+		"interface eth0 { prefix 3fff::1/64 { }; };",
+		"interface eth0 { prefix 3fff::/64 { };",
+	} {
+		if _, _, err := parseRadvdConf(strings.NewReader(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestParseNetworkdFile(t *testing.T) {
+	prefixes, _, err := readRAConfig("testdata/lan.network")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prefixes) != 2 || prefixes[0].Iface != "br0" || prefixes[0].Net.String() != "3fff:0:1:20::/64" ||
+		prefixes[1].Kind != "route" || prefixes[1].Net.String() != "3fff:0:1:2000::/52" {
+		t.Errorf("unexpected prefixes %+v", prefixes)
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"RA disabled", "[Network]\nIPv6SendRA=no\n[IPv6Prefix]\nPrefix=3fff::/64\n", 0},
+		{"RA enabled", "[Network]\nIPv6SendRA=true\n[IPv6Prefix]\nPrefix=3fff::/64\n[IPv6Prefix]\nPrefix=3fff:0:0:1::/64\n", 2},
+		{"other sections", "[Network]\nIPv6SendRA=yes\nAddress=3fff::1/64\n[Route]\nDestination=3fff:1::/48\n", 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefixes, err := parseNetworkdFile(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(prefixes) != tc.want || (tc.want > 0 && prefixes[0].Iface != "*") {
+				t.Errorf("expected %d prefixes on any interface, got %+v", tc.want, prefixes)
+			}
+		})
+	}
+	if _, err := parseNetworkdFile(strings.NewReader("[IPv6Prefix]\nPrefix\n")); err == nil {
+		t.Error("expected error for line without '='")
+	}
+}
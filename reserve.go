@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+)
+
+// reservedLabel tags the subnets held back by -reserve-first and -reserve-last.
+const reservedLabel = "RESERVED"
+
+// reservedSubnets returns the first and last subnets of splitting prefix into
+// /newLen, which policy often keeps for infrastructure or anycast use.
+func reservedSubnets(prefix string, newLen, first, last int) ([]netip.Prefix, error) {
+	if first < 0 || last < 0 {
+		return nil, fmt.Errorf("reserved subnet counts must not be negative")
+	}
+	if first == 0 && last == 0 {
+		return nil, nil
+	}
+	count, err := countSubnets(prefix, newLen)
+	if err != nil {
+		return nil, err
+	}
+	if count.Cmp(big.NewInt(int64(first)+int64(last))) < 0 {
+		return nil, fmt.Errorf("cannot reserve %d first and %d last of %s subnets", first, last, count)
+	}
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var reserved []netip.Prefix
+	if first > 0 {
+		for p := range limitSubnets(subnetSeq(parent, newLen), first) {
+			reserved = append(reserved, p)
+		}
+	}
+	if last > 0 {
+		lastStart := new(big.Int).Sub(count, big.NewInt(int64(last)))
+		for p := range subnetSeqFrom(parent, newLen, lastStart) {
+			reserved = append(reserved, p)
+		}
+	}
+	return reserved, nil
+}
+
+// reservedEntries turns the reserved subnets into prefix-list entries, to be
+// excluded when they are skipped rather than marked.
+func reservedEntries(reserved []netip.Prefix) []prefixEntry {
+	entries := make([]prefixEntry, len(reserved))
+	for i, p := range reserved {
+		entries[i] = prefixEntry{Net: prefixToIPNet(p), Label: reservedLabel}
+	}
+	return entries
+}
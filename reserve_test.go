@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestReservedSubnets(t *testing.T) {
+	cases := []struct {
+		name   string
+		prefix string
+		first  int
+		last   int
+		expect []string
+		err    bool
+	}{
+		{"none", "2001:db8::/60", 0, 0, nil, false},
+		{"first and last", "2001:db8::/60", 2, 1, []string{"2001:db8::/64", "2001:db8:0:1::/64", "2001:db8:0:f::/64"}, false},
+		{"last only", "2001:db8::/32", 0, 2, []string{"2001:db8:ffff:fffe::/64", "2001:db8:ffff:ffff::/64"}, false},
+		{"whole expansion", "2001:db8::/63", 1, 1, []string{"2001:db8::/64", "2001:db8:0:1::/64"}, false},
+		{"more than the expansion", "2001:db8::/63", 2, 1, nil, true},
+		{"negative", "2001:db8::/60", -1, 0, nil, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := reservedSubnets(c.prefix, 64, c.first, c.last)
+			if c.err {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(c.expect) {
+				t.Fatalf("got %v, want %v", got, c.expect)
+			}
+			for i := range got {
+				if got[i].String() != c.expect[i] {
+					t.Errorf("got %v, want %v", got, c.expect)
+				}
+			}
+		})
+	}
+}
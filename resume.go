@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/big"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cursorInterval is how many subnets -resume writes between checkpoints.
+const cursorInterval = 10000
+
+// subnetCursor is the -resume checkpoint of a subnet generation run: the last
+// subnet written and the size of the output file just after it. Resuming
+// truncates the output to that size, so subnets written after the checkpoint
+// by an interrupted run are not repeated.
+type subnetCursor struct {
+	Prefix   string `json:"prefix"`
+	Length   int    `json:"length"`
+	Output   string `json:"output"`
+	Last     string `json:"last,omitempty"`
+	Index    string `json:"index,omitempty"` // 0-based index of Last in the expansion
+	Bytes    int64  `json:"bytes"`
+	Complete bool   `json:"complete"`
+}
+
+// readCursor reads a cursor file; a missing file gives nil and no error.
+func readCursor(path string) (*subnetCursor, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c subnetCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// writeCursor replaces the cursor file atomically, so an interruption leaves
+// either the old checkpoint or the new one.
+func writeCursor(path string, c subnetCursor) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cursor-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// resumeStart checks that a cursor belongs to this run and returns the index of
+// the next subnet to generate.
+func (c *subnetCursor) resumeStart(prefix string, newLen int, outputFile string) (*big.Int, error) {
+	if c.Prefix != prefix || c.Length != newLen || c.Output != outputFile {
+		return nil, fmt.Errorf("cursor is for %s split into /%d written to %s, not %s into /%d to %s",
+			c.Prefix, c.Length, c.Output, prefix, newLen, outputFile)
+	}
+	if c.Index == "" {
+		return nil, nil
+	}
+	index, ok := new(big.Int).SetString(c.Index, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor index %q", c.Index)
+	}
+	return index.Add(index, big.NewInt(1)), nil
+}
+
+// runResumableSubnets is runSubnets with -resume: it continues from the cursor
+// file if there is one, and checkpoints to it every cursorInterval subnets and
+// at the end.
+func runResumableSubnets(prefix string, newPrefixLength int, start *big.Int, excluded []prefixEntry, record func(netip.Prefix) subnetRecord, opts subnetOptions) {
+	if opts.OutputFile == "" {
+		log.Fatal("-resume requires -o")
+	}
+	if outputFormat != "text" || outputTemplate != nil {
+		log.Fatal("-resume supports only text output")
+	}
+	if opts.Compress || strings.HasSuffix(opts.OutputFile, ".gz") {
+		log.Fatal("-resume cannot continue compressed output")
+	}
+	cursor, err := readCursor(opts.Resume)
+	if err != nil {
+		log.Fatal(err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if cursor != nil {
+		if cursor.Complete {
+			statusf("%s is already complete (last subnet %s)\n", opts.OutputFile, cursor.Last)
+			return
+		}
+		if start, err = cursor.resumeStart(prefix, newPrefixLength, opts.OutputFile); err != nil {
+			log.Fatalf("%s: %v", opts.Resume, err)
+		}
+		flags = os.O_WRONLY
+		statusf("Resuming after %s\n", cursor.Last)
+	} else {
+		cursor = &subnetCursor{Prefix: prefix, Length: newPrefixLength, Output: opts.OutputFile}
+	}
+	f, err := os.OpenFile(opts.OutputFile, flags, 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(cursor.Bytes); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := f.Seek(cursor.Bytes, 0); err != nil {
+		log.Fatal(err)
+	}
+
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	var last netip.Prefix
+	written, count := cursor.Bytes, 0
+	checkpoint := func() error {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if last.IsValid() {
+			index, err := subnetIndex(parent, newPrefixLength, last.Addr().String())
+			if err != nil {
+				return err
+			}
+			cursor.Last, cursor.Index = last.String(), index.String()
+		}
+		cursor.Bytes = written
+		return writeCursor(opts.Resume, *cursor)
+	}
+	emitted, err := streamSubnets(prefix, newPrefixLength, start, nil, opts.Limit, excluded, func(subnet netip.Prefix) error {
+		n, err := w.WriteString(textSubnetLine(record(subnet)) + "\n")
+		if err != nil {
+			return err
+		}
+		last, written = subnet, written+int64(n)
+		if count++; count%cursorInterval == 0 {
+			return checkpoint()
+		}
+		return nil
+	})
+	if err == nil {
+		// A run cut short by -l may have more to do; one that ran out of subnets has not.
+		cursor.Complete = opts.Limit == 0 || emitted < opts.Limit
+		err = checkpoint()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	statusf("Subnets saved to %s\n", opts.OutputFile)
+}
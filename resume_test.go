@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResumableSubnets(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "subnets.txt")
+	cursorFile := filepath.Join(dir, "cursor.json")
+	opts := subnetOptions{OutputFile: output, Resume: cursorFile, Limit: 3}
+
+	runResumableSubnets("2001:db8::/60", 64, nil, nil, subnetRecords(netip.MustParsePrefix("2001:db8::/60"), nil), opts)
+	c, err := readCursor(cursorFile)
+	if err != nil || c == nil {
+		t.Fatalf("cursor not written: %v", err)
+	}
+	if c.Last != "2001:db8:0:2::/64" || c.Index != "2" || c.Complete {
+		t.Errorf("unexpected cursor %+v", c)
+	}
+
+	// Output written after the checkpoint by an interrupted run is dropped on resume.
+	f, err := os.OpenFile(output, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("2001:db8:0:3::/64\n2001:db8:0:4")
+	f.Close()
+
+	opts.Limit = 0
+	runResumableSubnets("2001:db8::/60", 64, nil, nil, subnetRecords(netip.MustParsePrefix("2001:db8::/60"), nil), opts)
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 16 || lines[3] != "2001:db8:0:3::/64" || lines[15] != "2001:db8:0:f::/64" {
+		t.Errorf("unexpected output %v", lines)
+	}
+	if c, _ := readCursor(cursorFile); c == nil || !c.Complete || c.Index != "15" {
+		t.Errorf("unexpected final cursor %+v", c)
+	}
+}
+
+func TestCursorResumeStart(t *testing.T) {
+	c := &subnetCursor{Prefix: "2001:db8::/32", Length: 64, Output: "out.txt", Index: "41"}
+	start, err := c.resumeStart("2001:db8::/32", 64, "out.txt")
+	if err != nil || start.String() != "42" {
+		t.Errorf("got %v, %v; want 42", start, err)
+	}
+	if _, err := c.resumeStart("2001:db8::/32", 56, "out.txt"); err == nil {
+		t.Error("expected an error for a cursor from a different split")
+	}
+}
@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rirStatsURLs are the delegated-extended statistics published by each RIR.
+var rirStatsURLs = []struct {
+	Registry string
+	URL      string
+}{
+	{"afrinic", "https://ftp.afrinic.net/pub/stats/afrinic/delegated-afrinic-extended-latest"},
+	{"apnic", "https://ftp.apnic.net/stats/apnic/delegated-apnic-extended-latest"},
+	{"arin", "https://ftp.arin.net/pub/stats/arin/delegated-arin-extended-latest"},
+	{"lacnic", "https://ftp.lacnic.net/pub/stats/lacnic/delegated-lacnic-extended-latest"},
+	{"ripencc", "https://ftp.ripe.net/pub/stats/ripencc/delegated-ripencc-extended-latest"},
+}
+
+// rirStatsMaxAge is how long cached statistics are used before being fetched again.
+const rirStatsMaxAge = 24 * time.Hour
+
+// rirDelegation is one IPv6 record from a delegated-extended statistics file.
+type rirDelegation struct {
+	Registry string
+	CC       string
+	Net      *net.IPNet
+	Date     string
+	Status   string
+	OpaqueID string
+}
+
+// readRIRStats parses the IPv6 records of a delegated-extended file, skipping the
+// version line, summaries, comments, and other address families:
+//
+//	registry|cc|ipv6|start|prefixlen|date|status|opaque-id
+func readRIRStats(r io.Reader) ([]rirDelegation, error) {
+	var out []rirDelegation
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "|")
+		if len(f) < 7 || f[2] != "ipv6" || f[1] == "*" {
+			continue
+		}
+		prefixLen, err := strconv.Atoi(f[4])
+		if err != nil || prefixLen < 0 || prefixLen > 128 {
+			return nil, fmt.Errorf("line %d: invalid prefix length %q", lineNo, f[4])
+		}
+		ip := net.ParseIP(f[3])
+		if ip == nil {
+			return nil, fmt.Errorf("line %d: invalid address %q", lineNo, f[3])
+		}
+		mask := net.CIDRMask(prefixLen, 128)
+		d := rirDelegation{
+			Registry: f[0],
+			CC:       strings.ToUpper(f[1]),
+			Net:      &net.IPNet{IP: ip.Mask(mask), Mask: mask},
+			Date:     f[5],
+			Status:   f[6],
+		}
+		if len(f) > 7 {
+			d.OpaqueID = f[7]
+		}
+		out = append(out, d)
+	}
+	return out, scanner.Err()
+}
+
+// lookupRIRDelegation returns the most specific delegation containing prefix.
+func lookupRIRDelegation(delegations []rirDelegation, prefix *net.IPNet) (rirDelegation, bool) {
+	var best rirDelegation
+	bestLen := -1
+	for _, d := range delegations {
+		ones, _ := d.Net.Mask.Size()
+		if ones > bestLen && prefixWithin(prefix, d.Net) {
+			best, bestLen = d, ones
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// countryDelegations returns the allocated and assigned prefixes for an economy,
+// sorted by address.
+func countryDelegations(delegations []rirDelegation, cc string) []rirDelegation {
+	var out []rirDelegation
+	for _, d := range delegations {
+		if d.CC == strings.ToUpper(cc) && (d.Status == "allocated" || d.Status == "assigned") {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i].Net.IP, out[j].Net.IP) < 0 })
+	return out
+}
+
+// loadRIRStats reads the given statistics files, or else the cached copy of every
+// RIR's file, downloading those that are missing, older than rirStatsMaxAge, or
+// when refresh is set.
+func loadRIRStats(files string, refresh bool) ([]rirDelegation, error) {
+	var paths []string
+	if files != "" {
+		paths = strings.Split(files, ",")
+	} else {
+		var err error
+		if paths, err = datasets["rir"].fetch(refresh); err != nil {
+			return nil, err
+		}
+	}
+
+	var all []rirDelegation
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		d, err := readRIRStats(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		all = append(all, d...)
+	}
+	return all, nil
+}
+
+// reportRIRLookup prints the RIR and economy a prefix or address was delegated to.
+func reportRIRLookup(input string, files string, refresh bool) {
+	ip, prefixLen, err := parseIPv6WithOptionalPrefix(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if prefixLen < 0 {
+		prefixLen = 128
+	}
+	mask := net.CIDRMask(prefixLen, 128)
+	query := &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+
+	delegations, err := loadRIRStats(files, refresh)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d, ok := lookupRIRDelegation(delegations, query)
+	if !ok {
+		log.Fatalf("%s is not covered by any RIR delegation", query)
+	}
+	fmt.Printf("%-16s %s\n", "Query:", query)
+	fmt.Printf("%-16s %s\n", "Delegation:", d.Net)
+	fmt.Printf("%-16s %s\n", "RIR:", d.Registry)
+	fmt.Printf("%-16s %s\n", "Economy:", d.CC)
+	fmt.Printf("%-16s %s\n", "Status:", d.Status)
+	if d.Date != "" && d.Date != "00000000" {
+		fmt.Printf("%-16s %s\n", "Date:", d.Date)
+	}
+	if d.OpaqueID != "" {
+		fmt.Printf("%-16s %s\n", "Holder ID:", d.OpaqueID)
+	}
+}
+
+// reportRIRCountry prints every prefix delegated to an economy, one per line.
+func reportRIRCountry(cc string, files string, refresh bool) {
+	delegations, err := loadRIRStats(files, refresh)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, d := range countryDelegations(delegations, cc) {
+		fmt.Println(d.Net)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testRIRStats = `2|ripencc|1729033199|8|19830705|20241015|+0200
+ripencc|*|ipv6|*|4|summary
+ripencc|FR|ipv4|2.0.0.0|1048576|20100712|allocated|a1b2c3
+ripencc|FR|ipv6|2001:660::|32|19990819|allocated|a1b2c3
+ripencc|DE|ipv6|2001:638::|32|19990819|allocated|d4e5f6
+ripencc|FR|ipv6|2a01:e00::|26|20070723|allocated|f7a8b9
+arin|US|ipv6|2620:0:2d0::|48|20070301|assigned|a0b1c2
+arin|ZZ|ipv6|2620::|23|00000000|reserved|
+`
+
+func TestReadRIRStats(t *testing.T) {
+	delegations, err := readRIRStats(strings.NewReader(testRIRStats))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(delegations) != 5 {
+		t.Fatalf("expected 5 IPv6 delegations, got %d", len(delegations))
+	}
+	if d := delegations[0]; d.Registry != "ripencc" || d.CC != "FR" || d.Net.String() != "2001:660::/32" || d.OpaqueID != "a1b2c3" {
+		t.Errorf("unexpected first delegation %+v", d)
+	}
+
+	if _, err := readRIRStats(strings.NewReader("apnic|AU|ipv6|2001:dc0::|x|20000101|allocated\n")); err == nil {
+		t.Error("expected error for invalid prefix length")
+	}
+}
+
+func TestLookupRIRDelegation(t *testing.T) {
+	delegations, _ := readRIRStats(strings.NewReader(testRIRStats))
+	cases := []struct {
+		query   string
+		want    string
+		wantCC  string
+		wantHit bool
+	}{
+		{"2a01:e34:1234::/48", "2a01:e00::/26", "FR", true},
+		{"2001:638::1/128", "2001:638::/32", "DE", true},
+		{"2620:0:2d0::/48", "2620:0:2d0::/48", "US", true}, // more specific than the reserved /23
+		{"2620:1::/48", "2620::/23", "ZZ", true},
+		{"2001:600::/24", "", "", false}, // larger than any delegation
+		{"2c0f::/16", "", "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			query, err := parseIPv6Prefix(tc.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			d, ok := lookupRIRDelegation(delegations, query)
+			if ok != tc.wantHit {
+				t.Fatalf("expected hit=%v, got %v (%+v)", tc.wantHit, ok, d)
+			}
+			if ok && (d.Net.String() != tc.want || d.CC != tc.wantCC) {
+				t.Errorf("expected %s %s, got %s %s", tc.want, tc.wantCC, d.Net, d.CC)
+			}
+		})
+	}
+}
+
+func TestCountryDelegations(t *testing.T) {
+	delegations, _ := readRIRStats(strings.NewReader(testRIRStats))
+	got := countryDelegations(delegations, "fr")
+	if len(got) != 2 || got[0].Net.String() != "2001:660::/32" || got[1].Net.String() != "2a01:e00::/26" {
+		t.Errorf("unexpected FR delegations %+v", got)
+	}
+	if got := countryDelegations(delegations, "ZZ"); len(got) != 0 {
+		t.Errorf("reserved space should not be listed, got %+v", got)
+	}
+}
@@ -0,0 +1,284 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// configPrefix is an IPv6 prefix in use according to a router configuration: the
+// subnet of an interface address, or the destination of a static route.
+type configPrefix struct {
+	Net    *net.IPNet
+	Device string
+	Where  string // interface name, or "static via NEXT-HOP"
+}
+
+var (
+	// junosComment matches Junos /* annotations */, which the brace tokenizer does not know.
+	junosComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	// junosSetStyle and junosHierarchical recognize the two Junos output forms.
+	junosSetStyle     = regexp.MustCompile(`(?m)^set (interfaces|routing-options|system) `)
+	junosHierarchical = regexp.MustCompile(`(?m)^(interfaces|routing-options|system) \{`)
+)
+
+// interfacePrefix turns an interface address like 3fff:0:1:10::1/64 into its
+// subnet. Link-local addresses describe no allocation and are ignored.
+func interfacePrefix(addr string) (*net.IPNet, bool) {
+	ip, prefixLen, err := parseIPv6WithOptionalPrefix(addr)
+	if err != nil || prefixLen < 0 || ip.IsLinkLocalUnicast() {
+		return nil, false
+	}
+	mask := net.CIDRMask(prefixLen, 128)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, true
+}
+
+// staticPrefix parses a static route destination. The default route is not an
+// allocation and is ignored.
+func staticPrefix(dest string) (*net.IPNet, bool) {
+	ipnet, err := parsePrefixStrict(dest)
+	if err != nil {
+		return nil, false
+	}
+	if ones, _ := ipnet.Mask.Size(); ones == 0 {
+		return nil, false
+	}
+	return ipnet, true
+}
+
+// staticNextHop describes where a static route points: "static via ADDRESS" for a
+// next-hop address, or "static IFACE" for an interface such as Null0.
+func staticNextHop(hop string) string {
+	if ip := net.ParseIP(hop); ip != nil {
+		return "static via " + ip.String()
+	}
+	return "static " + hop
+}
+
+// parseIOSConfig reads interface addresses and static routes from Cisco IOS,
+// IOS-XE, or FRR configuration, which share the same syntax for both.
+func parseIOSConfig(r io.Reader) ([]configPrefix, error) {
+	var out []configPrefix
+	device, iface := "", ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "!" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			iface = ""
+		}
+		switch {
+		case fields[0] == "hostname" && len(fields) > 1:
+			device = fields[1]
+		case fields[0] == "interface" && len(fields) > 1:
+			iface = fields[1]
+		case iface != "" && len(fields) >= 3 && fields[0] == "ipv6" && fields[1] == "address":
+			if ipnet, ok := interfacePrefix(fields[2]); ok {
+				out = append(out, configPrefix{Net: ipnet, Where: iface})
+			}
+		case len(fields) >= 3 && fields[0] == "ipv6" && fields[1] == "route":
+			args := fields[2:]
+			if args[0] == "vrf" && len(args) > 2 {
+				args = args[2:]
+			}
+			ipnet, ok := staticPrefix(args[0])
+			if !ok {
+				continue
+			}
+			where := "static"
+			if len(args) > 1 {
+				where = staticNextHop(args[1])
+			}
+			out = append(out, configPrefix{Net: ipnet, Where: where})
+		}
+	}
+	for i := range out {
+		out[i].Device = device
+	}
+	return out, scanner.Err()
+}
+
+// junosConfig collects prefixes from Junos statements given as set-command words
+// without the leading "set".
+type junosConfig struct {
+	device   string
+	prefixes []configPrefix
+}
+
+// add handles "system host-name H", "interfaces IF unit N family inet6 address
+// A/L", and "routing-options [rib inet6.0] static route P next-hop H".
+func (c *junosConfig) add(words []string) {
+	if len(words) < 2 {
+		return
+	}
+	switch words[0] {
+	case "system":
+		if words[1] == "host-name" && len(words) > 2 {
+			c.device = words[2]
+		}
+	case "interfaces":
+		for i := 2; i+2 < len(words); i++ {
+			if words[i] == "inet6" && words[i+1] == "address" {
+				if ipnet, ok := interfacePrefix(words[i+2]); ok {
+					c.prefixes = append(c.prefixes, configPrefix{Net: ipnet, Where: junosUnitName(words[1:i])})
+				}
+				return
+			}
+		}
+	case "routing-options":
+		if p, ok := junosStaticRoute(words[1:]); ok {
+			c.prefixes = append(c.prefixes, p)
+		}
+	}
+}
+
+// result returns the prefixes, all attributed to the configured host name.
+func (c *junosConfig) result() []configPrefix {
+	for i := range c.prefixes {
+		c.prefixes[i].Device = c.device
+	}
+	return c.prefixes
+}
+
+// parseJunosSetConfig reads "show configuration | display set" output.
+func parseJunosSetConfig(r io.Reader) ([]configPrefix, error) {
+	var c junosConfig
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 && fields[0] == "set" {
+			c.add(fields[1:])
+		}
+	}
+	return c.result(), scanner.Err()
+}
+
+// junosUnitName turns "ge-0/0/0 unit 0 family" into "ge-0/0/0.0".
+func junosUnitName(words []string) string {
+	name := words[0]
+	for i := 1; i+1 < len(words); i++ {
+		if words[i] == "unit" {
+			name += "." + words[i+1]
+		}
+	}
+	return name
+}
+
+// junosStaticRoute reads "[rib inet6.0] static route P next-hop H" words. Routes
+// in other tables, or with IPv4 destinations, are skipped.
+func junosStaticRoute(words []string) (configPrefix, bool) {
+	if len(words) >= 2 && words[0] == "rib" {
+		if !strings.HasSuffix(words[1], "inet6.0") {
+			return configPrefix{}, false
+		}
+		words = words[2:]
+	}
+	if len(words) < 3 || words[0] != "static" || words[1] != "route" {
+		return configPrefix{}, false
+	}
+	ipnet, ok := staticPrefix(words[2])
+	if !ok {
+		return configPrefix{}, false
+	}
+	where := "static"
+	for i := 3; i+1 < len(words); i++ {
+		if words[i] == "next-hop" {
+			where = staticNextHop(words[i+1])
+			break
+		}
+	}
+	if where == "static" && len(words) > 3 {
+		where += " " + words[3] // discard, reject, receive
+	}
+	return configPrefix{Net: ipnet, Where: where}, true
+}
+
+// parseJunosConfig reads a hierarchical Junos configuration ("show configuration")
+// by flattening every statement into the equivalent set-command words.
+func parseJunosConfig(data string) ([]configPrefix, error) {
+	stmts, err := parseBraceConfig(junosComment.ReplaceAllString(data, ""))
+	if err != nil {
+		return nil, err
+	}
+	var c junosConfig
+	var walk func(path []string, stmts []confStatement)
+	walk = func(path []string, stmts []confStatement) {
+		for _, s := range stmts {
+			words := append(append([]string{}, path...), s.Words...)
+			c.add(words)
+			walk(words, s.Body)
+		}
+	}
+	walk(nil, stmts)
+	return c.result(), nil
+}
+
+// parseRouterConfig detects the configuration syntax and extracts its prefixes.
+// A device without a hostname in the file is named after the file.
+func parseRouterConfig(data string, name string) ([]configPrefix, error) {
+	var prefixes []configPrefix
+	var err error
+	switch {
+	case junosSetStyle.MatchString(data):
+		prefixes, err = parseJunosSetConfig(strings.NewReader(data))
+	case junosHierarchical.MatchString(data):
+		prefixes, err = parseJunosConfig(data)
+	default:
+		prefixes, err = parseIOSConfig(strings.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	// An address with options ("address A/L { primary; }") is seen once per
+	// option, so drop repeats.
+	seen := map[string]bool{}
+	var out []configPrefix
+	for _, p := range prefixes {
+		if p.Device == "" {
+			p.Device = name
+		}
+		key := p.Net.String() + " " + p.Device + " " + p.Where
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// writeConfigPrefixes prints the prefixes as a prefix list labelled with the
+// device and interface or next hop.
+func writeConfigPrefixes(w io.Writer, prefixes []configPrefix) {
+	for _, p := range prefixes {
+		fmt.Fprintf(w, "%-44s %s %s\n", p.Net, p.Device, p.Where)
+	}
+}
+
+// importRouterConfigs converts comma-separated saved router configurations into
+// one prefix list of existing allocations.
+func importRouterConfigs(files string) {
+	var all []configPrefix
+	for _, path := range strings.Split(files, ",") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		prefixes, err := parseRouterConfig(string(data), strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		all = append(all, prefixes...)
+	}
+	writeConfigPrefixes(os.Stdout, all)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestParseRouterConfig(t *testing.T) {
+	junosSet := `set system host-name core2
+set interfaces xe-0/0/1 unit 100 family inet6 address 3fff:0:3::2/127
+set interfaces xe-0/0/1 unit 100 family inet6 address fe80::2/64
+set interfaces lo0 unit 0 family inet address 192.0.2.1/32
+set routing-options rib inet6.0 static route 3fff:0:2::/48 next-hop 3fff:0:3::3
+set routing-options rib inet6.0 static route ::/0 next-hop 3fff:0:3::3
+set routing-options static route 192.0.2.0/24 discard
+`
+	frr := `frr version 9.1
+hostname
+!
+interface eth0
+ ipv6 address 3fff:0:4::1/64
+exit
+!
+vrf red
+ ipv6 route 3fff:0:4:8000::/49 blackhole
+exit-vrf
+`
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"junos set", junosSet, "3fff:0:3::2/127                              core2 xe-0/0/1.100\n" +
+			"3fff:0:2::/48                                core2 static via 3fff:0:3::3\n"},
+		{"frr", frr, "3fff:0:4::/64                                r9 eth0\n" +
+			"3fff:0:4:8000::/49                           r9 static blackhole\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefixes, err := parseRouterConfig(tc.input, "r9")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var buf bytes.Buffer
+			writeConfigPrefixes(&buf, prefixes)
+			if buf.String() != tc.want {
+				t.Errorf("expected\n%s\ngot\n%s", tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestParseRouterConfigFiles(t *testing.T) {
+	cases := []struct {
+		file  string
+		count int
+		first string
+		last  string
+	}{
+		{"testdata/configs/edge1.cfg", 6, "3fff:0:3::/127 edge1 GigabitEthernet0/0", "3fff:0:ffff::/64 edge1 static Null0"},
+		{"testdata/configs/core1.conf", 4, "3fff:0:3::/127 core1 xe-0/0/0.0", "3fff:0:8000::/33 core1 static discard"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := os.ReadFile(tc.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prefixes, err := parseRouterConfig(string(data), "unused")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			describe := func(p configPrefix) string { return p.Net.String() + " " + p.Device + " " + p.Where }
+			if len(prefixes) != tc.count || describe(prefixes[0]) != tc.first || describe(prefixes[len(prefixes)-1]) != tc.last {
+				t.Errorf("unexpected prefixes %+v", prefixes)
+			}
+		})
+	}
+	if _, err := parseRouterConfig("interfaces {\n xe-0/0/0 {\n", "r1"); err == nil {
+		t.Error("expected error for unbalanced Junos braces")
+	}
+}
@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// roleRule requires prefixes whose label matches Match to have one of Lengths.
+type roleRule struct {
+	Role    string `yaml:"role"`
+	Match   string `yaml:"match"`
+	Lengths []int  `yaml:"lengths"`
+
+	match *regexp.Regexp
+}
+
+// policyRules is an organization's allocation policy as written in a rules file:
+//
+//	allowed_parents: [2001:db8::/32]
+//	forbidden: [2001:db8:ffff::/48]
+//	naming: '^[a-z][a-z0-9-]*$'
+//	roles:
+//	  - role: site
+//	    match: '^site-'
+//	    lengths: [48]
+type policyRules struct {
+	AllowedParents []string   `yaml:"allowed_parents"`
+	Forbidden      []string   `yaml:"forbidden"`
+	Naming         string     `yaml:"naming"`
+	Roles          []roleRule `yaml:"roles"`
+
+	allowed   []*net.IPNet
+	forbidden []*net.IPNet
+	naming    *regexp.Regexp
+}
+
+// policyViolation is one rule broken by one prefix list entry.
+type policyViolation struct {
+	Entry  prefixEntry
+	Reason string
+}
+
+// parsePolicyRules reads and validates a YAML rules file. Unknown keys are rejected
+// so a misspelt rule is not silently ignored.
+func parsePolicyRules(r io.Reader) (*policyRules, error) {
+	var rules policyRules
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid rules: %v", err)
+	}
+	parsePrefixes := func(key string, in []string) ([]*net.IPNet, error) {
+		var out []*net.IPNet
+		for _, p := range in {
+			ipnet, err := parseIPv6Prefix(p)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid prefix: %v", key, err)
+			}
+			out = append(out, ipnet)
+		}
+		return out, nil
+	}
+	var err error
+	if rules.allowed, err = parsePrefixes("allowed_parents", rules.AllowedParents); err != nil {
+		return nil, err
+	}
+	if rules.forbidden, err = parsePrefixes("forbidden", rules.Forbidden); err != nil {
+		return nil, err
+	}
+	if rules.Naming != "" {
+		if rules.naming, err = regexp.Compile(rules.Naming); err != nil {
+			return nil, fmt.Errorf("naming: %v", err)
+		}
+	}
+	for i := range rules.Roles {
+		role := &rules.Roles[i]
+		if role.Role == "" || role.Match == "" {
+			return nil, fmt.Errorf("roles[%d]: role and match are required", i)
+		}
+		if role.match, err = regexp.Compile(role.Match); err != nil {
+			return nil, fmt.Errorf("role %s: %v", role.Role, err)
+		}
+		for _, l := range role.Lengths {
+			if l < 0 || l > 128 {
+				return nil, fmt.Errorf("role %s: invalid prefix length %d", role.Role, l)
+			}
+		}
+	}
+	return &rules, nil
+}
+
+// prefixesOverlap reports whether one prefix contains the other.
+func prefixesOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// prefixWithin reports whether inner lies entirely inside outer.
+func prefixWithin(inner, outer *net.IPNet) bool {
+	innerLen, _ := inner.Mask.Size()
+	outerLen, _ := outer.Mask.Size()
+	return innerLen >= outerLen && outer.Contains(inner.IP)
+}
+
+// auditPrefixes checks every entry against the rules, returning violations in
+// entry order.
+func auditPrefixes(rules *policyRules, entries []prefixEntry) []policyViolation {
+	var out []policyViolation
+	for _, e := range entries {
+		add := func(format string, a ...any) {
+			out = append(out, policyViolation{Entry: e, Reason: fmt.Sprintf(format, a...)})
+		}
+		if len(rules.allowed) > 0 && !slices.ContainsFunc(rules.allowed, func(p *net.IPNet) bool { return prefixWithin(e.Net, p) }) {
+			add("outside allowed parent blocks %s", strings.Join(rules.AllowedParents, ", "))
+		}
+		for i, f := range rules.forbidden {
+			if prefixesOverlap(e.Net, f) {
+				add("overlaps forbidden range %s", rules.Forbidden[i])
+			}
+		}
+		if rules.naming != nil && !rules.naming.MatchString(e.Label) {
+			if e.Label == "" {
+				add("missing name")
+			} else {
+				add("name %q does not match %s", e.Label, rules.Naming)
+			}
+		}
+		ones, _ := e.Net.Mask.Size()
+		for _, role := range rules.Roles {
+			if role.match.MatchString(e.Label) && len(role.Lengths) > 0 && !slices.Contains(role.Lengths, ones) {
+				add("role %s requires %s, not /%d", role.Role, formatLengths(role.Lengths), ones)
+			}
+		}
+	}
+	return out
+}
+
+// formatLengths renders prefix lengths as "/48 or /56".
+func formatLengths(lengths []int) string {
+	parts := make([]string, len(lengths))
+	for i, l := range lengths {
+		parts[i] = fmt.Sprintf("/%d", l)
+	}
+	return strings.Join(parts, " or ")
+}
+
+// runPolicyAudit audits a prefix list against a rules file and exits non-zero when
+// any rule is broken.
+func runPolicyAudit(prefixFile string, rulesFile string) {
+	if rulesFile == "" {
+		log.Fatal("a rules file must be given with -rules")
+	}
+	f, err := os.Open(rulesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rules, err := parsePolicyRules(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", rulesFile, err)
+	}
+	entries, err := readPrefixFile(prefixFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	violations := auditPrefixes(rules, entries)
+	for _, v := range violations {
+		name := v.Entry.Net.String()
+		if v.Entry.Label != "" {
+			name += " " + v.Entry.Label
+		}
+		fmt.Printf("line %d: %s: %s\n", v.Entry.Line, name, v.Reason)
+	}
+	if len(violations) > 0 {
+		log.Fatalf("%d policy violation(s) in %d entries", len(violations), len(entries))
+	}
+	fmt.Printf("%d entries comply with %s\n", len(entries), rulesFile)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testPolicy = `
+allowed_parents:
+  - 3fff::/20
+forbidden:
+  - 3fff:0:ffff::/48
+naming: '^[a-z][a-z0-9-]*$'
+roles:
+  - role: site
+    match: '^site-'
+    lengths: [48]
+  - role: link
+    match: '^p2p-'
+    lengths: [64, 127]
+`
+
+func TestAuditPrefixes(t *testing.T) {
+	rules, err := parsePolicyRules(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct {
+		name string
+		line string
+		want string // empty when the entry complies
+	}{
+		{"compliant site", "3fff:0:1::/48 site-chicago", ""},
+		{"compliant link", "3fff:0:3::/127 p2p-chi-den", ""},
+		{"wrong role size", "3fff:0:2::/56 site-denver", "role site requires /48, not /56"},
+		{"link size list", "3fff:0:3::/56 p2p-x", "role link requires /64 or /127, not /56"},
+		{"forbidden child", "3fff:0:ffff::/64 mgmt", "overlaps forbidden range 3fff:0:ffff::/48"},
+		{"forbidden parent", "3fff::/32 backbone", "overlaps forbidden range 3fff:0:ffff::/48"},
+		{"outside parents", "2001:db8:1::/48 lab", "outside allowed parent blocks 3fff::/20"},
+		{"bad name", "3fff:0:4::/48 Site_Boston", `name "Site_Boston" does not match ^[a-z][a-z0-9-]*$`},
+		{"missing name", "3fff:0:5::/48", "missing name"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := readPrefixEntries(strings.NewReader(tc.line))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			violations := auditPrefixes(rules, entries)
+			if tc.want == "" {
+				if len(violations) != 0 {
+					t.Errorf("expected no violations, got %+v", violations)
+				}
+				return
+			}
+			if len(violations) != 1 || violations[0].Reason != tc.want {
+				t.Errorf("expected %q, got %+v", tc.want, violations)
+			}
+		})
+	}
+}
+
+func TestParsePolicyRulesErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules string
+	}{
+		{"unknown key", "alowed_parents: [3fff::/20]"},
+		{"bad prefix", "forbidden: [3fff::1/20]"},
+		{"bad regexp", "naming: '('"},
+		{"role without match", "roles:\n  - role: site\n    lengths: [48]"},
+		{"bad length", "roles:\n  - role: site\n    match: x\n    lengths: [129]"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parsePolicyRules(strings.NewReader(tc.rules)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if _, err := parsePolicyRules(strings.NewReader("")); err != nil {
+		t.Errorf("empty rules file should be valid, got %v", err)
+	}
+}
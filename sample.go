@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+)
+
+// sampleAttempts bounds the random draws per requested subnet when most of the
+// expansion is excluded.
+const sampleAttempts = 100
+
+// sampleSubnets picks n distinct subnets of newLen within prefix uniformly at
+// random, skipping those overlapping exclude, and returns them in address order.
+// Each pick is an index computed from the seeded generator, so the expansion is
+// never enumerated and a /32 split into /64s is as cheap as a /60.
+func sampleSubnets(prefix string, newLen, n int, seed int64, exclude []prefixEntry) ([]netip.Prefix, error) {
+	count, err := countSubnets(prefix, newLen)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("sample size must be positive")
+	}
+	if count.Cmp(big.NewInt(int64(n))) < 0 {
+		return nil, fmt.Errorf("cannot sample %d of %s subnets", n, count)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	subnetAt := func(index *big.Int) (netip.Prefix, bool) {
+		for p := range subnetSeqFrom(parent, newLen, index) {
+			return p, !overlapsAny(prefixToIPNet(p), exclude)
+		}
+		return netip.Prefix{}, false
+	}
+
+	var picked []netip.Prefix
+	if count.IsInt64() && count.Int64() <= 4*int64(n) {
+		// Few subnets to choose from: shuffle all the indexes rather than
+		// redrawing collisions.
+		for _, i := range rng.Perm(int(count.Int64())) {
+			if p, ok := subnetAt(big.NewInt(int64(i))); ok {
+				picked = append(picked, p)
+				if len(picked) == n {
+					break
+				}
+			}
+		}
+	} else {
+		seen := map[netip.Prefix]bool{}
+		for attempts := 0; len(picked) < n && attempts < sampleAttempts*n; attempts++ {
+			p, ok := subnetAt(new(big.Int).Rand(rng, count))
+			if ok && !seen[p] {
+				seen[p] = true
+				picked = append(picked, p)
+			}
+		}
+	}
+	if len(picked) < n {
+		return nil, fmt.Errorf("found only %d of %d subnets outside the excluded ranges", len(picked), n)
+	}
+	slices.SortFunc(picked, func(a, b netip.Prefix) int { return a.Addr().Compare(b.Addr()) })
+	return picked, nil
+}
+
+// sampleSeed returns seed, or when it is 0, a new one from the clock, reported
+// on stderr so the run can be repeated whatever the output format. -stable
+// promises the same output every run, so there it must be given.
+func sampleSeed(seed int64) (int64, error) {
+	if seed != 0 {
+		return seed, nil
+	}
+	if stableOutput {
+		return 0, errors.New("-stable needs a fixed random seed for -sample and -strategy random: give one with -seed (-sample-seed with the flat flags)")
+	}
+	seed = time.Now().UnixNano()
+	fmt.Fprintf(os.Stderr, "Sample seed: %d\n", seed)
+	return seed, nil
+}
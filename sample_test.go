@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSampleSubnets(t *testing.T) {
+	cases := []struct {
+		name    string
+		prefix  string
+		newLen  int
+		n       int
+		exclude string
+		err     bool
+	}{
+		{"huge expansion", "2001:db8::/32", 64, 20, "", false},
+		{"small expansion", "2001:db8::/60", 64, 10, "", false},
+		{"all subnets", "2001:db8::/60", 64, 16, "", false},
+		{"with exclusions", "2001:db8::/60", 64, 8, "2001:db8::/61", false},
+		{"too many", "2001:db8::/60", 64, 17, "", true},
+		{"too many after exclusions", "2001:db8::/60", 64, 9, "2001:db8::/61", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var exclude []prefixEntry
+			if c.exclude != "" {
+				_, ipnet, _ := net.ParseCIDR(c.exclude)
+				exclude = []prefixEntry{{Net: ipnet}}
+			}
+			got, err := sampleSubnets(c.prefix, c.newLen, c.n, 1, exclude)
+			if c.err {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != c.n {
+				t.Fatalf("got %d subnets, want %d", len(got), c.n)
+			}
+			_, parent, _ := net.ParseCIDR(c.prefix)
+			for i, p := range got {
+				if p.Bits() != c.newLen || !parent.Contains(p.Addr().AsSlice()) || overlapsAny(prefixToIPNet(p), exclude) {
+					t.Errorf("unexpected subnet %s", p)
+				}
+				if i > 0 && got[i-1].Addr().Compare(p.Addr()) >= 0 {
+					t.Errorf("subnets not distinct and sorted: %v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestSampleSubnetsSeed(t *testing.T) {
+	a, _ := sampleSubnets("2001:db8::/32", 64, 5, 42, nil)
+	b, _ := sampleSubnets("2001:db8::/32", 64, 5, 42, nil)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("same seed gave different samples: %v, %v", a, b)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+)
+
+// documentationPrefix is the space sanitize moves addresses into (RFC 3849).
+var documentationPrefix = netip.MustParsePrefix("2001:db8::/32")
+
+// sanitizer maps the /48 of each global or unique local address to a /48 of
+// 2001:db8::/32, in the order they are first seen, keeping the subnet ID and
+// interface ID. The same address always maps to the same one, and addresses
+// that share a /48 or a /64 still do.
+type sanitizer struct {
+	sites map[netip.Prefix]netip.Prefix // real /48 to documentation /48
+	used  map[netip.Prefix]bool         // documentation /48s given to a site
+	next  int                           // the next site number to try
+}
+
+func newSanitizer() *sanitizer {
+	return &sanitizer{sites: map[netip.Prefix]netip.Prefix{}, used: map[netip.Prefix]bool{}, next: 1}
+}
+
+// sanitize returns the documentation address standing in for a, or a itself
+// unless it is global unicast, unique local, or documentation. A documentation
+// /48 stands for itself while no other /48 has been given it; otherwise it is
+// mapped like any other, so no two /48s ever map to the same one.
+func (s *sanitizer) sanitize(a netip.Addr) (netip.Addr, error) {
+	site := netip.PrefixFrom(a, 48).Masked()
+	if class := classifyAddress(a).Class; class != "gua" && class != "ula" && !documentationPrefix.Contains(a) {
+		return a, nil
+	}
+	doc, ok := s.sites[site]
+	if !ok && documentationPrefix.Contains(a) && !s.used[site] {
+		doc, ok = site, true
+		s.sites[site], s.used[site] = site, true
+	}
+	if !ok {
+		for ; ; s.next++ {
+			if s.next > 0xffff {
+				return netip.Addr{}, fmt.Errorf("more than %d /48s to map into %s", 0xffff, documentationPrefix)
+			}
+			b := documentationPrefix.Addr().As16()
+			b[4], b[5] = byte(s.next>>8), byte(s.next)
+			if doc = netip.PrefixFrom(netip.AddrFrom16(b), 48); !s.used[doc] {
+				break
+			}
+		}
+		s.sites[site], s.used[doc] = doc, true
+	}
+	b, d := a.As16(), doc.Addr().As16()
+	copy(b[:6], d[:6])
+	return netip.AddrFrom16(b), nil
+}
+
+// runSanitize copies stdin to stdout with every global and unique local
+// address moved into 2001:db8::/32 by a sanitizer, so configurations and logs
+// can be shared without giving the addressing away. In JSON and the other
+// formats it lists the addresses found and what they became.
+func runSanitize() {
+	s := newSanitizer()
+	runRewrite("-", func(a netip.Addr) string {
+		doc, err := s.sanitize(a)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return addrText(doc)
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	s := newSanitizer()
+	tests := []struct{ in, want string }{
+		{"2001:db8:1::5", "2001:db8:1::5"}, // documentation, and its /48 taken
+		{"2606:4700:10:5::1", "2001:db8:2:5::1"},
+		{"2606:4700:10:6::1", "2001:db8:2:6::1"}, // same /48
+		{"fd12:3456:789a:1::1", "2001:db8:3:1::1"},
+		{"2606:4700:10:5::1", "2001:db8:2:5::1"}, // same address again
+		{"fe80::1", "fe80::1"},
+		{"ff02::1", "ff02::1"},
+		{"::1", "::1"},
+	}
+	for _, tt := range tests {
+		got, err := s.sanitize(netip.MustParseAddr(tt.in))
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s: got %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestSanitizeStream(t *testing.T) {
+	s := newSanitizer()
+	var out strings.Builder
+	in := " ipv6 address 2606:4700:10:5::1/64\n ipv6 route ::/0 2600:1f18:4c3:ab00::1\n"
+	err := rewriteStream(strings.NewReader(in), &out, func(a netip.Addr) string {
+		doc, err := s.sanitize(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc.String()
+	}, func(rewrittenAddress) {})
+	want := " ipv6 address 2001:db8:1:5::1/64\n ipv6 route ::/0 2001:db8:2:ab00::1\n"
+	if err != nil || out.String() != want {
+		t.Errorf("got %q, %v, want %q", out.String(), err, want)
+	}
+}
+
+func TestSanitizeExhausted(t *testing.T) {
+	s := newSanitizer()
+	s.next = 0xffff
+	if _, err := s.sanitize(netip.MustParseAddr("2606:4700::1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.sanitize(netip.MustParseAddr("2606:4701::1")); err == nil {
+		t.Error("want an error once every /48 is used")
+	}
+}
+
+func TestSanitizeDocumentationTaken(t *testing.T) {
+	// 2600::/48 is given 2001:db8:1::/48 first, so the documentation /48
+	// seen after it must be moved, not kept, or two inputs would share it.
+	s := newSanitizer()
+	tests := []struct{ in, want string }{
+		{"2600::5", "2001:db8:1::5"},
+		{"2001:db8:1::5", "2001:db8:2::5"},
+		{"2001:db8:1::6", "2001:db8:2::6"},
+		{"2600::5", "2001:db8:1::5"},
+		{"2001:db8:3::1", "2001:db8:3::1"}, // still free, so kept
+		{"2601::1", "2001:db8:4::1"},
+	}
+	for _, tt := range tests {
+		got, err := s.sanitize(netip.MustParseAddr(tt.in))
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s: got %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
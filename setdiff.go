@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"net/netip"
+	"os"
+	"slices"
+)
+
+// setDiffResult compares the address space of two prefix lists.
+type setDiffResult struct {
+	A     string   `json:"a"`
+	B     string   `json:"b"`
+	OnlyA []string `json:"only_a"`
+	OnlyB []string `json:"only_b"`
+	Both  []string `json:"both"`
+}
+
+// intersectRanges returns the addresses in both sorted, disjoint range lists.
+func intersectRanges(a, b []addrRange) []addrRange {
+	var out []addrRange
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		from, to := a[i].From, a[i].To
+		if b[j].From.Compare(from) > 0 {
+			from = b[j].From
+		}
+		if b[j].To.Compare(to) < 0 {
+			to = b[j].To
+		}
+		if from.Compare(to) <= 0 {
+			out = append(out, addrRange{from, to})
+		}
+		if a[i].To.Compare(b[j].To) < 0 {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// subtractRanges returns the addresses of the sorted, disjoint ranges a that
+// are not in b.
+func subtractRanges(a, b []addrRange) []addrRange {
+	var out []addrRange
+	j := 0
+	for _, r := range a {
+		from := r.From
+		for j < len(b) && b[j].To.Compare(from) < 0 {
+			j++
+		}
+		done := false
+		for k := j; k < len(b) && b[k].From.Compare(r.To) <= 0; k++ {
+			if b[k].From.Compare(from) > 0 {
+				out = append(out, addrRange{from, b[k].From.Prev()})
+			}
+			if b[k].To.Compare(r.To) >= 0 {
+				done = true
+				break
+			}
+			from = b[k].To.Next()
+		}
+		if !done {
+			out = append(out, addrRange{from, r.To})
+		}
+	}
+	return out
+}
+
+// rangePrefixes returns the fewest prefixes covering a range exactly: from the
+// start, each time the largest prefix beginning there that ends within range.
+func rangePrefixes(r addrRange) []netip.Prefix {
+	var prefixes []netip.Prefix
+	from, to := ipToBigInt(r.From.AsSlice()), ipToBigInt(r.To.AsSlice())
+	one := big.NewInt(1)
+	for from.Cmp(to) <= 0 {
+		size := 128
+		if from.Sign() != 0 {
+			size = int(from.TrailingZeroBits())
+		}
+		for ; size > 0; size-- {
+			last := new(big.Int).Lsh(one, uint(size))
+			if last.Add(last, from).Sub(last, one).Cmp(to) <= 0 {
+				break
+			}
+		}
+		addr := netip.AddrFrom16([16]byte(bigIntToIP(from).To16()))
+		prefixes = append(prefixes, netip.PrefixFrom(addr, 128-size))
+		from.Add(from, new(big.Int).Lsh(one, uint(size)))
+	}
+	return prefixes
+}
+
+// rangeStrings lists the ranges as prefixes.
+func rangeStrings(ranges []addrRange) []string {
+	out := []string{}
+	for _, r := range ranges {
+		for _, p := range rangePrefixes(r) {
+			out = append(out, p.String())
+		}
+	}
+	return out
+}
+
+// diffPrefixSets compares the address space of two prefix lists, aggregated
+// into the fewest prefixes: the space only the first covers, only the second
+// covers, and both cover. How each list divides its space does not matter, so
+// two /49s and the /48 they make up are the same.
+func diffPrefixSets(a, b []prefixEntry) (onlyA, onlyB, both []addrRange) {
+	ra, rb := excludedRanges(a), excludedRanges(b)
+	return subtractRanges(ra, rb), subtractRanges(rb, ra), intersectRanges(ra, rb)
+}
+
+// runSetDiff compares two prefix lists, such as a router configuration and an
+// IPAM export. Like diff, it exits with status 1 when they differ.
+func runSetDiff(pathA, pathB string) {
+	a, err := readPrefixFile(pathA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := readPrefixFile(pathB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	onlyA, onlyB, both := diffPrefixSets(a, b)
+	result := setDiffResult{A: pathA, B: pathB, OnlyA: rangeStrings(onlyA), OnlyB: rangeStrings(onlyB), Both: rangeStrings(both)}
+	writeResult(result, func() {
+		type line struct {
+			mark   string
+			prefix netip.Prefix
+		}
+		var lines []line
+		for mark, list := range map[string][]string{"<": result.OnlyA, ">": result.OnlyB, "=": result.Both} {
+			for _, p := range list {
+				lines = append(lines, line{mark, netip.MustParsePrefix(p)})
+			}
+		}
+		slices.SortFunc(lines, func(x, y line) int { return x.prefix.Addr().Compare(y.prefix.Addr()) })
+		for _, l := range lines {
+			fmt.Printf("%s %s\n", l.mark, l.prefix)
+		}
+		fmt.Printf("%d only in %s, %d only in %s, %d in both\n", len(result.OnlyA), pathA, len(result.OnlyB), pathB, len(result.Both))
+	})
+	if len(result.OnlyA) > 0 || len(result.OnlyB) > 0 {
+		os.Exit(1)
+	}
+}
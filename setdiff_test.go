@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestDiffPrefixSets(t *testing.T) {
+	a, err := readPrefixEntries(strings.NewReader("3fff:0:1::/49\n3fff:0:1:8000::/49\n3fff:0:2::/48\n3fff:0:9::/48\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := readPrefixEntries(strings.NewReader("3fff:0:1::/48\n3fff:0:2:100::/56\n3fff:0:3::/64\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	onlyA, onlyB, both := diffPrefixSets(a, b)
+	// The two /49s are the /48 of the other list.
+	for _, tt := range []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{"only A", rangeStrings(onlyA), "[3fff:0:2::/56 3fff:0:2:200::/55 3fff:0:2:400::/54 3fff:0:2:800::/53 3fff:0:2:1000::/52 3fff:0:2:2000::/51 3fff:0:2:4000::/50 3fff:0:2:8000::/49 3fff:0:9::/48]"},
+		{"only B", rangeStrings(onlyB), "[3fff:0:3::/64]"},
+		{"both", rangeStrings(both), "[3fff:0:1::/48 3fff:0:2:100::/56]"},
+	} {
+		if fmt.Sprint(tt.got) != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestRangePrefixes(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"2001:db8::", "2001:db8::ffff", "[2001:db8::/112]"},
+		{"2001:db8::1", "2001:db8::6", "[2001:db8::1/128 2001:db8::2/127 2001:db8::4/127 2001:db8::6/128]"},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "[::/0]"},
+		{"8000::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "[8000::/1]"},
+	}
+	for _, tt := range tests {
+		got := rangePrefixes(addrRange{netip.MustParseAddr(tt.from), netip.MustParseAddr(tt.to)})
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s-%s: got %v, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
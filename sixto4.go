@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// sixToFour is the prefix of 6to4, RFC 3056: 2002::/16 followed by the IPv4
+// address of the site's 6to4 router makes the /48 of the site.
+var sixToFour = netip.MustParsePrefix("2002::/16")
+
+// sixToFourRelay is the anycast address of the 6to4 relays of RFC 3068,
+// deprecated by RFC 7526.
+var sixToFourRelay = netip.MustParseAddr("192.88.99.1")
+
+// sixToFourResult is the JSON form of a 6to4 conversion.
+type sixToFourResult struct {
+	IPv4   string `json:"ipv4"`
+	Prefix string `json:"prefix"` // the /48 of the 6to4 site
+}
+
+// sixToFourPrefix returns the 6to4 /48 of an IPv4 address.
+func sixToFourPrefix(v4 netip.Addr) netip.Prefix {
+	var b [16]byte
+	b[0], b[1] = 0x20, 0x02
+	ip := v4.As4()
+	copy(b[2:6], ip[:])
+	return netip.PrefixFrom(netip.AddrFrom16(b), 48)
+}
+
+// sixToFourIPv4 returns the IPv4 address embedded in a 6to4 address, bits 16
+// to 47.
+func sixToFourIPv4(a netip.Addr) (netip.Addr, error) {
+	if !sixToFour.Contains(a) {
+		return netip.Addr{}, fmt.Errorf("%s is not a 6to4 address (2002::/16)", addrText(a))
+	}
+	b := a.As16()
+	return netip.AddrFrom4([4]byte(b[2:6])), nil
+}
+
+// convertSixToFour gives the 6to4 prefix of an IPv4 address, or the IPv4
+// address embedded in a 6to4 address or prefix of a /48 or longer.
+func convertSixToFour(input string) (conversion, error) {
+	text, lenText, hasLength := strings.Cut(input, "/")
+	a, err := netip.ParseAddr(text)
+	if err != nil {
+		return conversion{}, fmt.Errorf("Invalid IP address: %s", input)
+	}
+	if a.Is4() && !hasLength {
+		p := sixToFourPrefix(a)
+		text := "Converted IPv4 to 6to4 prefix: " + prefixText(p)
+		if a == sixToFourRelay {
+			text += " (the relay anycast address of RFC 3068, deprecated by RFC 7526)"
+		}
+		return conversion{sixToFourResult{IPv4: a.String(), Prefix: prefixText(p)}, text, batchLine(input, prefixText(p))}, nil
+	}
+	if hasLength {
+		if n, err := strconv.Atoi(lenText); err != nil || n < 48 || n > 128 {
+			return conversion{}, fmt.Errorf("%s does not lie within one 6to4 /48", input)
+		}
+	}
+	v4, err := sixToFourIPv4(a)
+	if err != nil {
+		return conversion{}, err
+	}
+	return conversion{
+		sixToFourResult{IPv4: v4.String(), Prefix: prefixText(sixToFourPrefix(v4))},
+		"Converted 6to4 address to IPv4: " + v4.String(),
+		batchLine(input, v4.String()),
+	}, nil
+}
+
+// runSixToFour prints the 6to4 prefix of an IPv4 address, or the IPv4 address
+// of a 6to4 address, or of each read from stdin.
+func runSixToFour(input string) {
+	runConversion(input, convertSixToFour)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestConvertSixToFour(t *testing.T) {
+	tests := []struct{ in, ipv4, prefix string }{
+		{"192.0.2.1", "192.0.2.1", "2002:c000:201::/48"},
+		{"203.0.113.5", "203.0.113.5", "2002:cb00:7105::/48"},
+		{"2002:c000:201::1", "192.0.2.1", "2002:c000:201::/48"},
+		{"2002:cb00:7105:10::c0:ffee", "203.0.113.5", "2002:cb00:7105::/48"},
+		{"2002:c000:201::/48", "192.0.2.1", "2002:c000:201::/48"},
+	}
+	for _, tt := range tests {
+		c, err := convertSixToFour(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if r := c.Result.(sixToFourResult); r.IPv4 != tt.ipv4 || r.Prefix != tt.prefix {
+			t.Errorf("%s: got %+v, want %s %s", tt.in, r, tt.ipv4, tt.prefix)
+		}
+	}
+	for _, in := range []string{"2001:db8::1", "2002::/16", "2002:c000::/32", "192.0.2.0/24", "bogus"} {
+		if _, err := convertSixToFour(in); err == nil {
+			t.Errorf("%s: accepted", in)
+		}
+	}
+}
+
+func TestSixToFourRoundTrip(t *testing.T) {
+	v4 := netip.MustParseAddr("198.51.100.7")
+	p := sixToFourPrefix(v4)
+	got, err := sixToFourIPv4(p.Addr())
+	if err != nil || got != v4 {
+		t.Errorf("%s: got %s, %v", p, got, err)
+	}
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+)
+
+// sizeResult is the prefix length that splits a prefix into enough subnets.
+type sizeResult struct {
+	Prefix    string   `json:"prefix"`
+	Need      *big.Int `json:"need"`
+	NewLength int      `json:"new_length"`
+	Count     *big.Int `json:"count"`
+	Nibble    bool     `json:"nibble"` // rounded to a nibble boundary
+}
+
+// requiredLength is the inverse of countSubnets: the longest prefix length that
+// still splits prefix into at least need subnets, moved up to the next nibble
+// boundary with nibble.
+func requiredLength(prefix string, need *big.Int, nibble bool) (int, error) {
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		return 0, err
+	}
+	if need.Sign() <= 0 {
+		return 0, fmt.Errorf("need must be at least 1")
+	}
+	// need subnets take as many bits as need-1 has, so 1024 fits in ten.
+	length := parent.Bits() + new(big.Int).Sub(need, big.NewInt(1)).BitLen()
+	if nibble {
+		length = (length + 3) / 4 * 4
+	}
+	if length > 128 {
+		return 0, fmt.Errorf("%s has fewer than %s subnets", parent, need)
+	}
+	return length, nil
+}
+
+// runSize prints the prefix length to split prefix into at least need subnets.
+func runSize(prefix, need string, nibble bool) {
+	n, ok := new(big.Int).SetString(need, 10)
+	if !ok {
+		log.Fatalf("invalid need %q: want a number of subnets", need)
+	}
+	length, err := requiredLength(prefix, n, nibble)
+	if err != nil {
+		log.Fatal(err)
+	}
+	parent, _ := parseSubnetParent(prefix)
+	count := new(big.Int).Lsh(big.NewInt(1), uint(length-parent.Bits()))
+	writeResult(sizeResult{Prefix: parent.String(), Need: n, NewLength: length, Count: count, Nibble: nibble}, func() {
+		fmt.Printf("Use /%d (%s subnets)\n", length, formatSubnetCount(count))
+	})
+}
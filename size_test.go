@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestRequiredLength(t *testing.T) {
+	tests := []struct {
+		prefix string
+		need   int64
+		nibble bool
+		want   int
+	}{
+		{"2001:db8::/48", 600, false, 58},
+		{"2001:db8::/48", 600, true, 60},
+		{"2001:db8::/48", 1024, false, 58},
+		{"2001:db8::/48", 1025, false, 59},
+		{"2001:db8::/48", 1, false, 48},
+		{"2001:db8::/48", 2, true, 52},
+		{"2001:db8::/32", 65536, true, 48},
+		{"2001:db8::/126", 4, false, 128},
+	}
+	for _, tt := range tests {
+		got, err := requiredLength(tt.prefix, big.NewInt(tt.need), tt.nibble)
+		if err != nil || got != tt.want {
+			t.Errorf("%s need %d nibble %v: got /%d, %v, want /%d", tt.prefix, tt.need, tt.nibble, got, err, tt.want)
+		}
+	}
+	for _, need := range []int64{0, 5} {
+		if _, err := requiredLength("2001:db8::/126", big.NewInt(need), false); err == nil {
+			t.Errorf("need %d of a /126 accepted", need)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// sortKey is an address or prefix as sortu orders it: the 16 bytes of the
+// address, the prefix length, and whether it was written as a prefix, so that
+// comparing keys byte by byte orders them by address, then by length, with
+// 2001:db8::1 before 2001:db8::1/128.
+type sortKey [18]byte
+
+// parseSortKey reads an address, or a prefix kept as written, host bits and
+// all.
+func parseSortKey(s string) (sortKey, error) {
+	var k sortKey
+	ip, prefixLen, err := parseIPv6WithOptionalPrefix(s)
+	if err != nil {
+		return k, err
+	}
+	copy(k[:16], ip.To16())
+	k[16] = 128
+	if prefixLen >= 0 {
+		k[16], k[17] = byte(prefixLen), 1
+	}
+	return k, nil
+}
+
+func (k sortKey) String() string {
+	s := addrText(netip.AddrFrom16([16]byte(k[:16])))
+	if k[17] == 1 {
+		s += fmt.Sprintf("/%d", k[16])
+	}
+	return s
+}
+
+func compareSortKeys(a, b sortKey) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// sortRun is a sorted run of keys spilled to a temporary file, being merged.
+type sortRun struct {
+	r   *bufio.Reader
+	key sortKey
+}
+
+// next reads the run's next key, returning false at its end.
+func (s *sortRun) next() (bool, error) {
+	if _, err := io.ReadFull(s.r, s.key[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// runHeap orders runs by their current key, for merging them.
+type runHeap []*sortRun
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return compareSortKeys(h[i].key, h[j].key) < 0 }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*sortRun)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// sortInput is a named input of sortUnique.
+type sortInput struct {
+	Name string
+	R    io.Reader
+}
+
+// sortUnique reads one address or prefix per line from the inputs, with blank
+// lines and '#' comments skipped and only the first field of a line taken,
+// and passes each distinct one to emit in numeric order. No more than chunk keys
+// are held in memory: each chunk is sorted and written to a file in tmpDir,
+// and the files are merged. Lines that do not parse are passed to invalid.
+func sortUnique(inputs []sortInput, chunk int, tmpDir string, emit func(sortKey) error, invalid func(name string, line int, text string)) error {
+	if chunk < 1 {
+		return fmt.Errorf("chunk size must be at least 1, got %d", chunk)
+	}
+	var runs []*os.File
+	defer func() {
+		for _, f := range runs {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	keys := make([]sortKey, 0, min(chunk, 1<<16))
+	spill := func() error {
+		f, err := os.CreateTemp(tmpDir, "ipv6utils-sortu-*")
+		if err != nil {
+			return err
+		}
+		runs = append(runs, f)
+		w := bufio.NewWriter(f)
+		for _, k := range keys {
+			w.Write(k[:])
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		keys = keys[:0]
+		_, err = f.Seek(0, io.SeekStart)
+		return err
+	}
+
+	for _, in := range inputs {
+		scanner := bufio.NewScanner(in.R)
+		lineNo := 0
+		for scanner.Scan() {
+			lineNo++
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			k, err := parseSortKey(fields[0])
+			if err != nil {
+				invalid(in.Name, lineNo, fields[0])
+				continue
+			}
+			keys = append(keys, k)
+			if len(keys) == chunk {
+				slices.SortFunc(keys, compareSortKeys)
+				keys = slices.Compact(keys)
+				if err := spill(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("%s: %v", in.Name, err)
+		}
+	}
+	slices.SortFunc(keys, compareSortKeys)
+	keys = slices.Compact(keys)
+
+	// All of it fit in memory.
+	if len(runs) == 0 {
+		for _, k := range keys {
+			if err := emit(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(keys) > 0 {
+		if err := spill(); err != nil {
+			return err
+		}
+	}
+	h := &runHeap{}
+	for _, f := range runs {
+		s := &sortRun{r: bufio.NewReader(f)}
+		ok, err := s.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			*h = append(*h, s)
+		}
+	}
+	heap.Init(h)
+	var last sortKey
+	first := true
+	for h.Len() > 0 {
+		s := (*h)[0]
+		if first || s.key != last {
+			if err := emit(s.key); err != nil {
+				return err
+			}
+			last, first = s.key, false
+		}
+		ok, err := s.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+// runSortUnique prints the distinct addresses and prefixes of the files, or
+// stdin, in numeric order, as sort -u does for lines but with 2001:db8::9
+// before 2001:db8::10, however large the list: no more than chunk are held in
+// memory at a time. Lines that do not parse are reported on stderr.
+func runSortUnique(files []string, chunk int, tmpDir string) {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	var inputs []sortInput
+	for _, file := range files {
+		if file == "-" {
+			inputs = append(inputs, sortInput{"stdin", os.Stdin})
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		inputs = append(inputs, sortInput{file, f})
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	// JSON is one array; the other formats a result per address.
+	results := []string{}
+	err := sortUnique(inputs, chunk, tmpDir, func(k sortKey) error {
+		switch {
+		case outputFormat == "json":
+			results = append(results, k.String())
+		case outputFormat == "text" && outputTemplate == nil:
+			_, err := fmt.Fprintln(out, k)
+			return err
+		default:
+			out.Flush()
+			writeResult(k.String(), nil)
+		}
+		return nil
+	}, func(name string, line int, text string) {
+		fmt.Fprintf(os.Stderr, "%s: line %d: invalid address or prefix %q\n", name, line, text)
+	})
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFormat == "json" {
+		writeResult(results, nil)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func sortedUnique(t *testing.T, chunk int, inputs ...string) ([]string, []string) {
+	t.Helper()
+	var in []sortInput
+	for i, s := range inputs {
+		in = append(in, sortInput{fmt.Sprintf("in%d", i), strings.NewReader(s)})
+	}
+	dir := t.TempDir()
+	var got, bad []string
+	err := sortUnique(in, chunk, dir, func(k sortKey) error {
+		got = append(got, k.String())
+		return nil
+	}, func(name string, line int, text string) {
+		bad = append(bad, fmt.Sprintf("%s:%d:%s", name, line, text))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := os.ReadDir(dir); len(left) != 0 {
+		t.Errorf("%d temporary files left behind", len(left))
+	}
+	return got, bad
+}
+
+func TestSortUnique(t *testing.T) {
+	in := "2001:db8::10\n2001:db8::9\n# comment\n2001:DB8::9 label\n\n2001:db8::/32\nbogus\n::1\n2001:db8::9/128\nfe80::1\n2001:db8::a\n"
+	want := "::1 2001:db8::/32 2001:db8::9 2001:db8::9/128 2001:db8::a 2001:db8::10 fe80::1"
+	for _, chunk := range []int{1, 2, 3, 1000} {
+		got, bad := sortedUnique(t, chunk, in)
+		if strings.Join(got, " ") != want {
+			t.Errorf("chunk %d: got %v, want %s", chunk, got, want)
+		}
+		if strings.Join(bad, " ") != "in0:7:bogus" {
+			t.Errorf("chunk %d: invalid lines %v", chunk, bad)
+		}
+	}
+}
+
+func TestSortUniqueInputs(t *testing.T) {
+	got, bad := sortedUnique(t, 2, "2001:db8::2\n2001:db8::1", "2001:db8::1\nnope\n2001:db8::3\n")
+	if strings.Join(got, " ") != "2001:db8::1 2001:db8::2 2001:db8::3" {
+		t.Errorf("got %v", got)
+	}
+	if strings.Join(bad, " ") != "in1:2:nope" {
+		t.Errorf("invalid lines %v", bad)
+	}
+	if _, err := parseSortKey("2001:db8::/129"); err == nil {
+		t.Error("prefix length 129 accepted")
+	}
+	if err := sortUnique(nil, 0, "", nil, nil); err == nil {
+		t.Error("chunk size 0 accepted")
+	}
+}
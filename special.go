@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// specialRegistryCSV is a snapshot of the IANA IPv6 Special-Purpose Address
+// Registry, as downloaded by -update-data iana-special, for when no copy has
+// been.
+//
+//go:embed data/iana-ipv6-special-registry-1.csv
+var specialRegistryCSV string
+
+// specialEntry is an entry of the special-purpose registry. A flag is nil
+// where the registry gives N/A, as it does for 6to4 and Teredo, whose
+// reachability depends on the address embedded in them.
+type specialEntry struct {
+	Prefix             netip.Prefix `json:"prefix"`
+	Name               string       `json:"name"`
+	RFC                string       `json:"rfc"`
+	Allocated          string       `json:"allocated"`
+	Source             *bool        `json:"source"`
+	Destination        *bool        `json:"destination"`
+	Forwardable        *bool        `json:"forwardable"`
+	GloballyReachable  *bool        `json:"globally_reachable"`
+	ReservedByProtocol *bool        `json:"reserved_by_protocol"`
+}
+
+// specialMatch is the registry entries covering an address or prefix, the most
+// specific first.
+type specialMatch struct {
+	Query   string         `json:"query"`
+	Entries []specialEntry `json:"entries"`
+}
+
+// footnote matches a footnote reference of the registry, the " [1]" of
+// "False [1]".
+var footnote = regexp.MustCompile(`\s*\[\d+\]`)
+
+// readSpecialRegistry reads the registry in IANA's CSV form, skipping the
+// entries that have been terminated.
+func readSpecialRegistry(r io.Reader) ([]specialEntry, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var entries []specialEntry
+	for i, rec := range records {
+		if i == 0 {
+			continue // header
+		}
+		if len(rec) < 10 {
+			return nil, fmt.Errorf("line %d: want 10 fields, got %d", i+1, len(rec))
+		}
+		if end := strings.TrimSpace(rec[4]); end != "" && end != "N/A" {
+			continue
+		}
+		p, err := netip.ParsePrefix(footnote.ReplaceAllString(strings.TrimSpace(rec[0]), ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		e := specialEntry{
+			Prefix:    p,
+			Name:      strings.TrimSpace(rec[1]),
+			RFC:       strings.NewReplacer("][", ", ", "[", "", "]", "").Replace(strings.TrimSpace(rec[2])),
+			Allocated: strings.TrimSpace(rec[3]),
+		}
+		flags := []**bool{&e.Source, &e.Destination, &e.Forwardable, &e.GloballyReachable, &e.ReservedByProtocol}
+		for j, flag := range flags {
+			switch strings.ToLower(footnote.ReplaceAllString(strings.TrimSpace(rec[5+j]), "")) {
+			case "true":
+				*flag = new(bool)
+				**flag = true
+			case "false":
+				*flag = new(bool)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// loadSpecialRegistry reads the registry from path, or when path is empty, from
+// the copy -update-data iana-special cached, or failing that the embedded
+// snapshot.
+func loadSpecialRegistry(path string) ([]specialEntry, error) {
+	if path == "" {
+		if paths, ok := datasets["iana-special"].cached(); ok {
+			entries, err := readSpecialRegistryFile(paths[0])
+			if err == nil && len(entries) > 0 {
+				return entries, nil
+			}
+			log.Printf("using the built-in special-purpose registry: cached copy %s is unreadable: %v", paths[0], err)
+		}
+		return readSpecialRegistry(strings.NewReader(specialRegistryCSV))
+	}
+	return readSpecialRegistryFile(path)
+}
+
+// readSpecialRegistryFile reads the registry from a CSV file.
+func readSpecialRegistryFile(path string) ([]specialEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSpecialRegistry(f)
+}
+
+// lookupSpecial returns the entries covering q, the most specific first. A
+// prefix is covered by an entry only when it lies wholly within it.
+func lookupSpecial(entries []specialEntry, q netip.Prefix) []specialEntry {
+	var out []specialEntry
+	for _, e := range entries {
+		if e.Prefix.Bits() <= q.Bits() && e.Prefix.Contains(q.Addr()) {
+			out = append(out, e)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b specialEntry) int { return b.Prefix.Bits() - a.Prefix.Bits() })
+	return out
+}
+
+// flagText prints a registry flag as yes, no, or n/a.
+func flagText(b *bool) string {
+	switch {
+	case b == nil:
+		return "n/a"
+	case *b:
+		return "yes"
+	}
+	return "no"
+}
+
+// runSpecial prints the special-purpose registry entries covering an address or
+// prefix, with their RFCs and flags, read from the embedded snapshot or from
+// registry. It exits with status 1 when no entry covers it.
+func runSpecial(query, registry string) {
+	q, err := parseLPMQuery(query)
+	if err != nil {
+		log.Fatal(err)
+	}
+	entries, err := loadSpecialRegistry(registry)
+	if err != nil {
+		log.Fatal(err)
+	}
+	m := specialMatch{Query: query, Entries: lookupSpecial(entries, q)}
+	if m.Entries == nil {
+		m.Entries = []specialEntry{}
+	}
+	writeResult(m, func() {
+		if len(m.Entries) == 0 {
+			fmt.Printf("%s is not in the special-purpose registry\n", query)
+			return
+		}
+		for i, e := range m.Entries {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%-22s%s\n", "Prefix:", prefixText(e.Prefix))
+			fmt.Printf("%-22s%s\n", "Name:", e.Name)
+			fmt.Printf("%-22s%s\n", "RFC:", e.RFC)
+			fmt.Printf("%-22s%s\n", "Allocated:", e.Allocated)
+			fmt.Printf("%-22s%s\n", "Source:", flagText(e.Source))
+			fmt.Printf("%-22s%s\n", "Destination:", flagText(e.Destination))
+			fmt.Printf("%-22s%s\n", "Forwardable:", flagText(e.Forwardable))
+			fmt.Printf("%-22s%s\n", "Globally reachable:", flagText(e.GloballyReachable))
+			fmt.Printf("%-22s%s\n", "Reserved-by-protocol:", flagText(e.ReservedByProtocol))
+		}
+	})
+	if len(m.Entries) == 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadSpecialRegistry(t *testing.T) {
+	entries, err := loadSpecialRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name == "Deprecated (previously ORCHID)" {
+			t.Errorf("terminated entry %s was kept", e.Prefix)
+		}
+	}
+	got := lookupSpecial(entries, netip.MustParsePrefix("2001:db8::/48"))
+	if len(got) != 1 || got[0].Name != "Documentation" || got[0].RFC != "RFC3849" {
+		t.Fatalf("2001:db8::/48: got %+v", got)
+	}
+	if f := got[0].Forwardable; f == nil || *f {
+		t.Errorf("documentation forwardable = %v, want false", flagText(f))
+	}
+}
+
+func TestLoadSpecialRegistryCached(t *testing.T) {
+	defer func(root string) { cacheRoot = root }(cacheRoot)
+	cacheRoot = t.TempDir()
+	d := datasets["iana-special"]
+	dir, err := cacheDir(d.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, cacheFileName(d.URLs[0]))
+
+	// A newer registry downloaded by -update-data is read instead of the
+	// built-in one.
+	newer := strings.Replace(specialRegistryCSV, "Documentation,", "Documentation (updated),", 1)
+	os.WriteFile(path, []byte(newer), 0o644)
+	entries, err := loadSpecialRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lookupSpecial(entries, netip.MustParsePrefix("2001:db8::/48")); len(got) != 1 || got[0].Name != "Documentation (updated)" {
+		t.Errorf("cached registry not read: got %+v", got)
+	}
+
+	// One that does not parse falls back to the built-in copy.
+	os.WriteFile(path, []byte("<html>\"unterminated</html>\n"), 0o644)
+	if entries, err = loadSpecialRegistry(""); err != nil || len(entries) == 0 {
+		t.Errorf("unreadable cache: got %d entries, %v", len(entries), err)
+	}
+}
+
+func TestLookupSpecial(t *testing.T) {
+	entries, err := loadSpecialRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"2001::1", []string{"2001::/32", "2001::/23"}},
+		{"2001:1::1", []string{"2001:1::1/128", "2001::/23"}},
+		{"fd00::/8", []string{"fc00::/7"}},
+		{"fc00::/6", nil}, // wider than fc00::/7
+		{"::1", []string{"::1/128"}},
+		{"2001:4860::1", nil},
+	}
+	for _, tt := range tests {
+		q, err := parseLPMQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range lookupSpecial(entries, q) {
+			got = append(got, e.Prefix.String())
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSpecialFlags(t *testing.T) {
+	entries, err := readSpecialRegistry(strings.NewReader("Address Block,Name,RFC,Allocation Date,Termination Date,Source,Destination,Forwardable,Globally Reachable,Reserved-by-Protocol\n" +
+		"2002::/16,6to4,[RFC3056],2001-02,N/A,True,True,True,N/A [3],False\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := entries[0]
+	got := []string{flagText(e.Source), flagText(e.Forwardable), flagText(e.GloballyReachable), flagText(e.ReservedByProtocol)}
+	if strings.Join(got, " ") != "yes yes n/a no" {
+		t.Errorf("flags: got %v", got)
+	}
+	if _, err := readSpecialRegistry(strings.NewReader("header\nbogus,row\n")); err == nil {
+		t.Error("short row: want an error")
+	}
+}
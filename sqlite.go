@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// subnetSchema is the table -output-format sql and sqlite write subnets into.
+// idx is the 0-based index in the expansion ("index" is an SQL keyword);
+// created_at is set by the database when a prefix is first inserted.
+const subnetSchema = `CREATE TABLE IF NOT EXISTS subnets (
+  prefix     TEXT PRIMARY KEY,
+  idx        INTEGER NOT NULL,
+  parent     TEXT NOT NULL,
+  label      TEXT,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// sqlSubnetWriter writes subnets as an SQLite script: the schema, then one
+// upsert per subnet in a single transaction. Running it again over the same
+// database updates the rows in place, keeping created_at and any label set by
+// hand where the new run has none.
+type sqlSubnetWriter struct {
+	w       *bufio.Writer
+	started bool
+}
+
+// begin writes the schema and opens the transaction.
+func (s *sqlSubnetWriter) begin() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	_, err := s.w.WriteString(subnetSchema + "BEGIN;\n")
+	return err
+}
+
+func (s *sqlSubnetWriter) Write(r subnetRecord) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	// SQLite integers are 64-bit; larger indexes are kept exactly as text.
+	index := r.Index.String()
+	if !r.Index.IsInt64() {
+		index = sqlQuote(index)
+	}
+	label := "NULL"
+	if name := r.Name(); name != "" {
+		label = sqlQuote(name)
+	}
+	_, err := fmt.Fprintf(s.w, "INSERT INTO subnets (prefix, idx, parent, label) VALUES (%s, %s, %s, %s)"+
+		" ON CONFLICT(prefix) DO UPDATE SET idx = excluded.idx, parent = excluded.parent, label = COALESCE(excluded.label, label);\n",
+		sqlQuote(r.Prefix.String()), index, sqlQuote(r.Parent.String()), label)
+	return err
+}
+
+func (s *sqlSubnetWriter) Close() error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	if _, err := s.w.WriteString("COMMIT;\n"); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// sqliteOutput feeds output to the sqlite3 command to apply it to the database
+// file; closing it waits for sqlite3 to finish.
+type sqliteOutput struct {
+	stdin io.WriteCloser
+	cmd   *exec.Cmd
+}
+
+// openSQLite starts sqlite3 on the database, which is created if it does not
+// exist yet.
+func openSQLite(database string) (*sqliteOutput, error) {
+	if database == "" {
+		return nil, fmt.Errorf("sqlite output needs a database file (-o plan.db)")
+	}
+	path, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return nil, fmt.Errorf("sqlite output needs the sqlite3 command on PATH; -output-format sql writes the same statements to load yourself")
+	}
+	cmd := exec.Command(path, "-bail", database)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &sqliteOutput{stdin, cmd}, nil
+}
+
+func (s *sqliteOutput) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+func (s *sqliteOutput) Close() error {
+	s.stdin.Close()
+	if err := s.cmd.Wait(); err != nil {
+		return fmt.Errorf("sqlite3: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net/netip"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLSubnetWriter(t *testing.T) {
+	parent := netip.MustParsePrefix("2001:db8::/63")
+	reserved, _ := reservedSubnets("2001:db8::/63", 64, 1, 0)
+	record := subnetRecords(parent, reserved)
+
+	var buf bytes.Buffer
+	w := &sqlSubnetWriter{w: bufio.NewWriter(&buf)}
+	for p := range subnetSeq(parent, 64) {
+		if err := w.Write(record(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS subnets",
+		"BEGIN;\n",
+		"VALUES ('2001:db8::/64', 0, '2001:db8::/63', 'RESERVED')",
+		"VALUES ('2001:db8:0:1::/64', 1, '2001:db8::/63', NULL)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if !strings.HasSuffix(out, "COMMIT;\n") {
+		t.Errorf("script does not end with COMMIT:\n%s", out)
+	}
+}
+
+func TestSQLiteOutput(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	database := filepath.Join(t.TempDir(), "plan.db")
+	parent := netip.MustParsePrefix("2001:db8::/62")
+	for _, reserveFirst := range []int{1, 0} {
+		out, err := openSQLite(database)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reserved, _ := reservedSubnets("2001:db8::/62", 64, reserveFirst, 0)
+		record := subnetRecords(parent, reserved)
+		w := &sqlSubnetWriter{w: bufio.NewWriter(out)}
+		for p := range subnetSeq(parent, 64) {
+			w.Write(record(p))
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := out.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := exec.Command("sqlite3", database, "SELECT count(*), group_concat(label) FROM subnets").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second run updates the rows in place and keeps the label it does not set.
+	if strings.TrimSpace(string(got)) != "4|RESERVED" {
+		t.Errorf("got %q", got)
+	}
+}
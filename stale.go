@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sighting records that an address was confirmed active at a point in time.
+type sighting struct {
+	IP   net.IP
+	Seen time.Time
+}
+
+// staleAllocation is an allocation with the most recent sighting inside it.
+type staleAllocation struct {
+	Entry    prefixEntry
+	LastSeen time.Time // zero when never seen
+}
+
+// parseSightingTime accepts RFC 3339 timestamps, "YYYY-MM-DD HH:MM:SS", dates, and
+// Unix epoch seconds.
+func parseSightingTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", s)
+}
+
+// readSightings reads activity records of the form "ADDRESS TIMESTAMP" (whitespace
+// or comma separated), as exported from flow collectors, ND cache scrapes, or DNS
+// query logs. Blank lines and '#' comments are skipped.
+func readSightings(r io.Reader) ([]sighting, error) {
+	var out []sighting
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected an address and a timestamp", lineNo)
+		}
+		ip, _, err := parseIPv6WithOptionalPrefix(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		// A "date time" pair without a T separator spans two fields.
+		ts := fields[1]
+		if len(fields) > 2 && len(ts) == 10 && strings.Count(fields[2], ":") == 2 {
+			ts += " " + fields[2]
+		}
+		seen, err := parseSightingTime(ts)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		out = append(out, sighting{IP: ip, Seen: seen})
+	}
+	return out, scanner.Err()
+}
+
+// findStaleAllocations returns the allocations whose most recent sighting is older
+// than maxAge relative to now, including those never seen at all.
+func findStaleAllocations(allocations []prefixEntry, sightings []sighting, maxAge time.Duration, now time.Time) []staleAllocation {
+	cutoff := now.Add(-maxAge)
+	var stale []staleAllocation
+	for _, a := range allocations {
+		var last time.Time
+		for _, s := range sightings {
+			if a.Net.Contains(s.IP) && s.Seen.After(last) {
+				last = s.Seen
+			}
+		}
+		if last.Before(cutoff) {
+			stale = append(stale, staleAllocation{Entry: a, LastSeen: last})
+		}
+	}
+	return stale
+}
+
+// reportStaleAllocations prints allocations that have not been seen active for the
+// given number of days.
+func reportStaleAllocations(allocationFile string, sightingFiles string, days int) {
+	if sightingFiles == "" {
+		log.Fatal("at least one activity file must be given with -seen")
+	}
+	allocations, err := readPrefixFile(allocationFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var sightings []sighting
+	for _, path := range strings.Split(sightingFiles, ",") {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s, err := readSightings(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		sightings = append(sightings, s...)
+	}
+
+	stale := findStaleAllocations(allocations, sightings, time.Duration(days)*24*time.Hour, time.Now())
+	for _, s := range stale {
+		last := "never"
+		if !s.LastSeen.IsZero() {
+			last = s.LastSeen.Format("2006-01-02")
+		}
+		fmt.Printf("%-40s %-12s %s\n", s.Entry.Net, last, s.Entry.Label)
+	}
+	fmt.Printf("%d of %d allocations not seen active in %d days\n", len(stale), len(allocations), days)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindStaleAllocations(t *testing.T) {
+	allocations, err := readPrefixEntries(strings.NewReader(`
+2001:db8:1::/48  active
+2001:db8:2::/48  quiet
+2001:db8:3::/48  never seen
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sightings, err := readSightings(strings.NewReader(`# flow export
+2001:db8:1::10,2024-06-01T00:00:00Z
+2001:db8:2::10 2024-01-01 12:00:00
+2001:db8:2::11	1700000000
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sightings) != 3 {
+		t.Fatalf("expected 3 sightings, got %d", len(sightings))
+	}
+
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	stale := findStaleAllocations(allocations, sightings, 90*24*time.Hour, now)
+	if len(stale) != 2 {
+		t.Fatalf("expected 2 stale allocations, got %d: %+v", len(stale), stale)
+	}
+	if stale[0].Entry.Label != "quiet" || stale[0].LastSeen.Format(time.RFC3339) != "2024-01-01T12:00:00Z" {
+		t.Errorf("unexpected first stale allocation %+v", stale[0])
+	}
+	if stale[1].Entry.Label != "never seen" || !stale[1].LastSeen.IsZero() {
+		t.Errorf("unexpected second stale allocation %+v", stale[1])
+	}
+}
+
+func TestReadSightingsErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing timestamp", input: "2001:db8::1\n"},
+		{name: "bad address", input: "192.0.2.1 2024-01-01\n"},
+		{name: "bad timestamp", input: "2001:db8::1 yesterday\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := readSightings(strings.NewReader(tc.input)); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Address categories counted by -stats.
+const (
+	categoryGUA       = "GUA"
+	categoryULA       = "ULA"
+	categoryLinkLocal = "link-local"
+	categoryMulticast = "multicast"
+	categoryOther     = "other"
+)
+
+// prefixCount is a covering prefix and how many unique addresses fall inside it.
+type prefixCount struct {
+	Prefix    string `json:"prefix"`
+	Addresses int    `json:"addresses"`
+}
+
+// addressStats is the aggregate summary of an address list.
+type addressStats struct {
+	Total      int            `json:"total"`
+	Unique     int            `json:"unique"`
+	Invalid    int            `json:"invalid"`
+	ByType     map[string]int `json:"by_type"`
+	Unique48   int            `json:"unique_48s"`
+	Unique56   int            `json:"unique_56s"`
+	Unique64   int            `json:"unique_64s"`
+	Top48      []prefixCount  `json:"top_48s"`
+	Top64      []prefixCount  `json:"top_64s"`
+	IIDPattern map[string]int `json:"iid_patterns"`
+}
+
+// addressCategory sorts an address into one of the -stats categories.
+func addressCategory(ip net.IP) string {
+	b := ip.To16()
+	switch {
+	case b[0] == 0xff:
+		return categoryMulticast
+	case b[0] == 0xfe && b[1]&0xc0 == 0x80:
+		return categoryLinkLocal
+	case b[0]&0xfe == 0xfc:
+		return categoryULA
+	case b[0]&0xe0 == 0x20:
+		return categoryGUA
+	}
+	return categoryOther
+}
+
+// statsCollector accumulates addresses as they are read, keeping only counters
+// and a 16-byte key per unique address.
+type statsCollector struct {
+	total, invalid int
+	seen           map[[16]byte]bool
+	byType         map[string]int
+	per48, per64   map[[16]byte]int
+	per56          map[[16]byte]bool
+	patterns       map[string]int
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{
+		seen:     map[[16]byte]bool{},
+		byType:   map[string]int{},
+		per48:    map[[16]byte]int{},
+		per56:    map[[16]byte]bool{},
+		per64:    map[[16]byte]int{},
+		patterns: map[string]int{},
+	}
+}
+
+// add counts one address. Duplicates only count towards the total.
+func (c *statsCollector) add(ip net.IP) {
+	c.total++
+	var key [16]byte
+	copy(key[:], ip.To16())
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+	category := addressCategory(ip)
+	c.byType[category]++
+	if category == categoryMulticast || category == categoryOther {
+		return // interface IDs are only meaningful for unicast
+	}
+	c.patterns[scoreIID(ip).Pattern]++
+	if category == categoryLinkLocal {
+		return // every link shares fe80::/64
+	}
+	c.per48[prefixKey(ip, 48)]++
+	c.per56[prefixKey(ip, 56)] = true
+	c.per64[prefixKey(ip, 64)]++
+}
+
+// prefixKey returns the network address of ip at the given length as a map key.
+func prefixKey(ip net.IP, prefixLen int) [16]byte {
+	var key [16]byte
+	copy(key[:], networkAddress(ip.To16(), prefixLen))
+	return key
+}
+
+// topPrefixes returns the n prefixes with the most addresses, ties broken by
+// address so the output is stable.
+func topPrefixes(counts map[[16]byte]int, prefixLen int, n int) []prefixCount {
+	keys := make([][16]byte, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return ipToBigInt(keys[i][:]).Cmp(ipToBigInt(keys[j][:])) < 0
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	top := make([]prefixCount, len(keys))
+	for i, k := range keys {
+		top[i] = prefixCount{Prefix: fmt.Sprintf("%s/%d", net.IP(k[:]), prefixLen), Addresses: counts[k]}
+	}
+	return top
+}
+
+// result summarizes everything added so far, keeping the top n covering prefixes.
+func (c *statsCollector) result(n int) *addressStats {
+	return &addressStats{
+		Total:      c.total,
+		Unique:     len(c.seen),
+		Invalid:    c.invalid,
+		ByType:     c.byType,
+		Unique48:   len(c.per48),
+		Unique56:   len(c.per56),
+		Unique64:   len(c.per64),
+		Top48:      topPrefixes(c.per48, 48, n),
+		Top64:      topPrefixes(c.per64, 64, n),
+		IIDPattern: c.patterns,
+	}
+}
+
+// summarizeAddresses reads one address per line (extra fields, prefix lengths,
+// blank lines, and '#' comments are ignored) and returns aggregate statistics.
+// Unparseable lines are counted as invalid rather than stopping the run, since
+// bulk exports usually contain a few.
+func summarizeAddresses(r io.Reader, top int) (*addressStats, error) {
+	c := newStatsCollector()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ip, _, err := parseIPv6WithOptionalPrefix(strings.Fields(line)[0])
+		if err != nil {
+			c.invalid++
+			continue
+		}
+		c.add(ip)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c.result(top), nil
+}
+
+// sortedCounts returns the keys of counts ordered by count, then name.
+func sortedCounts(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// writeAddressStats prints the summary as text.
+func writeAddressStats(w io.Writer, s *addressStats) {
+	fmt.Fprintf(w, "%-16s%d\n", "Addresses:", s.Total)
+	fmt.Fprintf(w, "%-16s%d\n", "Unique:", s.Unique)
+	if s.Invalid > 0 {
+		fmt.Fprintf(w, "%-16s%d\n", "Invalid lines:", s.Invalid)
+	}
+	fmt.Fprintf(w, "%-16s%d\n", "Unique /48s:", s.Unique48)
+	fmt.Fprintf(w, "%-16s%d\n", "Unique /56s:", s.Unique56)
+	fmt.Fprintf(w, "%-16s%d\n", "Unique /64s:", s.Unique64)
+	fmt.Fprintln(w, "Types:")
+	for _, name := range sortedCounts(s.ByType) {
+		fmt.Fprintf(w, "  %-14s%d\n", name, s.ByType[name])
+	}
+	fmt.Fprintln(w, "Interface IDs:")
+	for _, name := range sortedCounts(s.IIDPattern) {
+		fmt.Fprintf(w, "  %-14s%d\n", name, s.IIDPattern[name])
+	}
+	for _, t := range []struct {
+		label string
+		top   []prefixCount
+	}{{"Top /48s:", s.Top48}, {"Top /64s:", s.Top64}} {
+		fmt.Fprintln(w, t.label)
+		for _, p := range t.top {
+			fmt.Fprintf(w, "  %-40s %d\n", p.Prefix, p.Addresses)
+		}
+	}
+}
+
+// reportAddressStats summarizes the address list at path ("-" for stdin).
+func reportAddressStats(path string, top int) {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	s, err := summarizeAddresses(in, top)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeResult(s, func() { writeAddressStats(os.Stdout, s) })
+}
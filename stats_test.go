@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAddressCategory(t *testing.T) {
+	cases := []struct {
+		addr string
+		want string
+	}{
+		{"2001:db8::1", categoryGUA},
+		{"3fff::1", categoryGUA},
+		{"fd00::1", categoryULA},
+		{"fe80::1", categoryLinkLocal},
+		{"ff02::1", categoryMulticast},
+		{"::1", categoryOther},
+		{"::ffff:192.0.2.1", categoryOther},
+	}
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			if got := addressCategory(net.ParseIP(tc.addr)); got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSummarizeAddresses(t *testing.T) {
+	f, err := os.Open("testdata/clients.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, err := summarizeAddresses(f, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Total != 11 || s.Unique != 10 || s.Invalid != 1 {
+		t.Errorf("unexpected counts total=%d unique=%d invalid=%d", s.Total, s.Unique, s.Invalid)
+	}
+	if s.ByType[categoryGUA] != 7 || s.ByType[categoryULA] != 1 || s.ByType[categoryLinkLocal] != 1 || s.ByType[categoryMulticast] != 1 {
+		t.Errorf("unexpected types %v", s.ByType)
+	}
+	if s.Unique48 != 3 || s.Unique56 != 3 || s.Unique64 != 4 {
+		t.Errorf("unexpected unique prefixes %d/%d/%d", s.Unique48, s.Unique56, s.Unique64)
+	}
+	if len(s.Top48) != 2 || s.Top48[0] != (prefixCount{"3fff:0:1::/48", 6}) || s.Top48[1] != (prefixCount{"3fff:0:2::/48", 1}) {
+		t.Errorf("unexpected top /48s %v", s.Top48)
+	}
+	if s.Top64[0] != (prefixCount{"3fff:0:1:10::/64", 4}) {
+		t.Errorf("unexpected top /64s %v", s.Top64)
+	}
+	if s.IIDPattern[patternLowByte] != 4 || s.IIDPattern[patternEUI64] != 2 || s.IIDPattern[patternWordy] != 1 {
+		t.Errorf("unexpected interface-ID patterns %v", s.IIDPattern)
+	}
+}
+
+func TestWriteAddressStats(t *testing.T) {
+	s, _ := summarizeAddresses(strings.NewReader("2001:db8::1\n2001:db8::2\n"), 10)
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded["unique_64s"] != 1.0 || decoded["top_64s"].([]any)[0].(map[string]any)["prefix"] != "2001:db8::/64" {
+		t.Errorf("unexpected JSON %s", buf.String())
+	}
+	buf.Reset()
+	writeAddressStats(&buf, s)
+	if !strings.Contains(buf.String(), "Unique /64s:    1\n") {
+		t.Errorf("unexpected text output %q", buf.String())
+	}
+}
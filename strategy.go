@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"iter"
+	"math/big"
+	"math/rand"
+	"net/netip"
+)
+
+// Allocation strategies of RFC 3531, naming which bits of the subnet number
+// are set first.
+const (
+	strategyLeftmost  = "leftmost"  // most significant first: 0, 8, 4, 12, ... of 16
+	strategyRightmost = "rightmost" // least significant first, in address order
+	strategyCenter    = "center"    // middle bits first, growing outwards
+	strategyRandom    = "random"    // a seeded random permutation
+)
+
+// subnetOrder maps the position of a subnet in the allocation order to its
+// 0-based index in address order. Every order is a permutation, so each subnet
+// is handed out exactly once.
+type subnetOrder func(position *big.Int) *big.Int
+
+// parseSubnetStrategy returns the order in which a strategy hands out the
+// subnets of a parent split bits deeper, or nil for address order. Leftmost
+// spreads assignments across the parent so each can later grow into the space
+// after it, rightmost packs them at the low end so the high end stays free in
+// one block, and center starts in the middle, leaving room to grow on both
+// sides of the subnet number.
+func parseSubnetStrategy(name string, bits int, seed int64) (subnetOrder, error) {
+	positions := make([]int, 0, bits) // bit of the index each bit of the position sets
+	switch name {
+	case "", strategyRightmost:
+		return nil, nil
+	case strategyLeftmost:
+		for b := bits - 1; b >= 0; b-- {
+			positions = append(positions, b)
+		}
+	case strategyCenter:
+		mid := bits / 2
+		positions = append(positions, mid)
+		for d := 1; len(positions) < bits; d++ {
+			for _, b := range []int{mid - d, mid + d} {
+				if b >= 0 && b < bits {
+					positions = append(positions, b)
+				}
+			}
+		}
+	case strategyRandom:
+		return randomSubnetOrder(bits, seed), nil
+	default:
+		return nil, fmt.Errorf("unknown strategy %q: want leftmost, rightmost, center, or random", name)
+	}
+	return func(position *big.Int) *big.Int {
+		index := new(big.Int)
+		for i, b := range positions {
+			index.SetBit(index, b, position.Bit(i))
+		}
+		return index
+	}, nil
+}
+
+// randomSubnetOrder is a seeded permutation of the 2^bits subnet indexes: two
+// rounds of multiplying by a random odd number and folding the high half into
+// the low half, each invertible modulo 2^bits. Unlike drawing indexes at
+// random, it never repeats a subnet and needs no memory of those drawn.
+func randomSubnetOrder(bits int, seed int64) subnetOrder {
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	mask := new(big.Int).Sub(size, big.NewInt(1))
+	rng := rand.New(rand.NewSource(seed))
+	var mult, add [2]*big.Int
+	for r := range mult {
+		mult[r] = new(big.Int).Rand(rng, size)
+		mult[r].SetBit(mult[r], 0, 1)
+		add[r] = new(big.Int).Rand(rng, size)
+	}
+	return func(position *big.Int) *big.Int {
+		x := new(big.Int).Set(position)
+		for r := range mult {
+			x.Mul(x, mult[r]).Add(x, add[r]).And(x, mask)
+			x.Xor(x, new(big.Int).Rsh(x, uint(bits+1)/2))
+		}
+		return x
+	}
+}
+
+// orderedSubnetSeq is subnetSeqExcluding handing out the subnets in order
+// rather than by address, beginning at the position start (nil for the first).
+// Excluded subnets are stepped over one at a time.
+func orderedSubnetSeq(parent netip.Prefix, newLen int, start *big.Int, exclude []prefixEntry, order subnetOrder) iter.Seq[netip.Prefix] {
+	if order == nil {
+		return subnetSeqExcluding(parent, newLen, start, exclude)
+	}
+	ranges := excludedRanges(exclude)
+	return func(yield func(netip.Prefix) bool) {
+		if newLen < parent.Bits() || newLen > 128 {
+			return
+		}
+		count := new(big.Int).Lsh(big.NewInt(1), uint(newLen-parent.Bits()))
+		base := ipToBigInt(parent.Masked().Addr().AsSlice())
+		position := new(big.Int)
+		if start != nil {
+			position.Set(start)
+		}
+		for ; position.Sign() >= 0 && position.Cmp(count) < 0; position.Add(position, big.NewInt(1)) {
+			offset := new(big.Int).Lsh(order(position), uint(128-newLen))
+			addr := netip.AddrFrom16([16]byte(bigIntToIP(offset.Add(offset, base)).To16()))
+			subnet := netip.PrefixFrom(addr, newLen)
+			if _, ok := overlappingRange(ranges, subnet); ok {
+				continue
+			}
+			if !yield(subnet) {
+				return
+			}
+		}
+	}
+}
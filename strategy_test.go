@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestParseSubnetStrategy(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     []int64
+	}{
+		{strategyLeftmost, []int64{0, 8, 4, 12, 2, 10, 6, 14, 1, 9, 5, 13, 3, 11, 7, 15}},
+		{strategyCenter, []int64{0, 4, 2, 6, 8, 12, 10, 14, 1, 5, 3, 7, 9, 13, 11, 15}},
+	}
+	for _, tt := range tests {
+		order, err := parseSubnetStrategy(tt.strategy, 4, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tt.want {
+			if got := order(big.NewInt(int64(i))); got.Int64() != want {
+				t.Errorf("%s: position %d: got %s, want %d", tt.strategy, i, got, want)
+			}
+		}
+	}
+	for _, name := range []string{"", strategyRightmost} {
+		if order, err := parseSubnetStrategy(name, 4, 0); order != nil || err != nil {
+			t.Errorf("%q: want address order, got error %v", name, err)
+		}
+	}
+	if _, err := parseSubnetStrategy("middle", 4, 0); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+}
+
+func TestRandomSubnetOrder(t *testing.T) {
+	for _, bits := range []int{0, 1, 5, 10} {
+		order := randomSubnetOrder(bits, 42)
+		seen := map[int64]bool{}
+		for i := int64(0); i < 1<<bits; i++ {
+			index := order(big.NewInt(i))
+			if index.Int64() >= 1<<bits || seen[index.Int64()] {
+				t.Fatalf("%d bits: position %d gives index %s again or out of range", bits, i, index)
+			}
+			seen[index.Int64()] = true
+		}
+	}
+	a, b := randomSubnetOrder(64, 7), randomSubnetOrder(64, 7)
+	if a(big.NewInt(5)).Cmp(b(big.NewInt(5))) != 0 {
+		t.Error("same seed gave different orders")
+	}
+}
+
+func TestOrderedSubnetSeq(t *testing.T) {
+	order, _ := parseSubnetStrategy(strategyLeftmost, 4, 0)
+	exclude, err := readPrefixEntries(strings.NewReader("3fff:0:8::/47\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for p := range limitSubnets(orderedSubnetSeq(netip.MustParsePrefix("3fff::/44"), 48, big.NewInt(1), exclude, order), 4) {
+		got = append(got, p.String())
+	}
+	if want := "3fff:0:4::/48 3fff:0:c::/48 3fff:0:2::/48 3fff:0:a::/48"; strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
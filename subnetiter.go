@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"iter"
+	"math/big"
+	"net"
+	"net/netip"
+	"slices"
+)
+
+// subnetSeq returns an iterator over the subnets of length newLen within parent,
+// in address order. Subnets are computed as the range loop asks for them, so a
+// /32 split into /64s costs nothing until ranged over; breaking out of the loop
+// stops generation. newLen must be between the parent's length and 128.
+func subnetSeq(parent netip.Prefix, newLen int) iter.Seq[netip.Prefix] {
+	return subnetSeqFrom(parent, newLen, nil)
+}
+
+// subnetSeqFrom is subnetSeq starting at the subnet with the 0-based index start,
+// computed directly rather than by counting; nil starts at the first subnet.
+func subnetSeqFrom(parent netip.Prefix, newLen int, start *big.Int) iter.Seq[netip.Prefix] {
+	return subnetSeqExcluding(parent, newLen, start, nil)
+}
+
+// subnetSeqExcluding is subnetSeqFrom without the subnets overlapping any of the
+// exclude entries. An exclusion covering many subnets is stepped over in one
+// jump rather than subnet by subnet, so excluding a /40 from a /32 split into
+// /64s costs no more than excluding a single /64.
+func subnetSeqExcluding(parent netip.Prefix, newLen int, start *big.Int, exclude []prefixEntry) iter.Seq[netip.Prefix] {
+	ranges := excludedRanges(exclude)
+	return func(yield func(netip.Prefix) bool) {
+		if newLen < parent.Bits() || newLen > 128 {
+			return
+		}
+		addr := parent.Masked().Addr().As16()
+		if start != nil {
+			if start.Sign() < 0 || start.BitLen() > newLen-parent.Bits() {
+				return
+			}
+			offset := new(big.Int).Lsh(start, uint(128-newLen))
+			addr = [16]byte(bigIntToIP(new(big.Int).Add(ipToBigInt(addr[:]), offset)).To16())
+		}
+		for {
+			subnet := netip.PrefixFrom(netip.AddrFrom16(addr), newLen)
+			if r, ok := overlappingRange(ranges, subnet); ok {
+				// Continue from the subnet holding the end of the range; the
+				// step below moves past it.
+				if r.To.Compare(lastAddr(subnet)) > 0 {
+					addr = netip.PrefixFrom(r.To, newLen).Masked().Addr().As16()
+				}
+			} else if !yield(subnet) {
+				return
+			}
+			var carry bool
+			addr, carry = addSubnetStep(addr, newLen)
+			if carry || !parent.Contains(netip.AddrFrom16(addr)) {
+				return
+			}
+		}
+	}
+}
+
+// addrRange is an inclusive range of IPv6 addresses.
+type addrRange struct {
+	From, To netip.Addr
+}
+
+// excludedRanges merges the IPv6 entries into sorted ranges that neither overlap
+// nor touch.
+func excludedRanges(entries []prefixEntry) []addrRange {
+	var ranges []addrRange
+	for _, e := range entries {
+		ones, bits := e.Net.Mask.Size()
+		if bits != 128 {
+			continue
+		}
+		p := netip.PrefixFrom(netip.AddrFrom16([16]byte(e.Net.IP.To16())), ones).Masked()
+		ranges = append(ranges, addrRange{p.Addr(), lastAddr(p)})
+	}
+	slices.SortFunc(ranges, func(a, b addrRange) int { return a.From.Compare(b.From) })
+	var merged []addrRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 {
+			if last := &merged[n-1]; !last.To.Next().IsValid() || r.From.Compare(last.To.Next()) <= 0 {
+				if r.To.Compare(last.To) > 0 {
+					last.To = r.To
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// overlappingRange returns the range overlapping subnet, if any.
+func overlappingRange(ranges []addrRange, subnet netip.Prefix) (addrRange, bool) {
+	i, _ := slices.BinarySearchFunc(ranges, subnet.Addr(), func(r addrRange, a netip.Addr) int { return r.To.Compare(a) })
+	if i < len(ranges) && ranges[i].From.Compare(lastAddr(subnet)) <= 0 {
+		return ranges[i], true
+	}
+	return addrRange{}, false
+}
+
+// lastAddr is the highest address of a prefix.
+func lastAddr(p netip.Prefix) netip.Addr {
+	a := p.Masked().Addr().As16()
+	for i := p.Bits(); i < 128; i++ {
+		a[i/8] |= 0x80 >> (i % 8)
+	}
+	return netip.AddrFrom16(a)
+}
+
+// addSubnetStep adds the size of one /bits subnet to addr, reporting a carry out
+// of the top of the address space.
+func addSubnetStep(addr [16]byte, bits int) ([16]byte, bool) {
+	if bits == 0 {
+		return addr, true
+	}
+	i := (bits - 1) / 8
+	carry := uint16(1) << (7 - uint((bits-1)%8))
+	for ; i >= 0 && carry > 0; i-- {
+		sum := uint16(addr[i]) + carry
+		addr[i] = byte(sum)
+		carry = sum >> 8
+	}
+	return addr, carry > 0
+}
+
+// limitSubnets yields at most n subnets of seq; n of 0 or less means no limit.
+func limitSubnets(seq iter.Seq[netip.Prefix], n int) iter.Seq[netip.Prefix] {
+	if n <= 0 {
+		return seq
+	}
+	return func(yield func(netip.Prefix) bool) {
+		count := 0
+		for p := range seq {
+			if !yield(p) {
+				return
+			}
+			if count++; count >= n {
+				return
+			}
+		}
+	}
+}
+
+// prefixToIPNet converts a netip.Prefix to the net.IPNet the older helpers take.
+func prefixToIPNet(p netip.Prefix) *net.IPNet {
+	return &net.IPNet{IP: net.IP(p.Addr().AsSlice()), Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen())}
+}
+
+// subnetIndex returns the 0-based index, among the /newLen subnets of parent, of
+// the subnet holding at: a prefix or an address.
+func subnetIndex(parent netip.Prefix, newLen int, at string) (*big.Int, error) {
+	addr, err := netip.ParseAddr(at)
+	if err != nil {
+		p, perr := netip.ParsePrefix(at)
+		if perr != nil {
+			return nil, fmt.Errorf("invalid start %q: want a prefix or an address", at)
+		}
+		addr = p.Addr()
+	}
+	if !parent.Contains(addr) {
+		return nil, fmt.Errorf("start %s is not within %s", at, parent)
+	}
+	return childIndex(parent, newLen, addr), nil
+}
+
+// childIndex returns the 0-based index, among the /newLen subnets of parent, of
+// the subnet holding addr, which must be within parent.
+func childIndex(parent netip.Prefix, newLen int, addr netip.Addr) *big.Int {
+	offset := new(big.Int).Sub(ipToBigInt(addr.AsSlice()), ipToBigInt(parent.Masked().Addr().AsSlice()))
+	return offset.Rsh(offset, uint(128-newLen))
+}
+
+// resolveSubnetStart turns -start-index or -start-at into the index of the first
+// subnet to generate, checking it is within the expansion. It returns nil when
+// neither is set.
+func resolveSubnetStart(prefix string, newLen int, index, at string) (*big.Int, error) {
+	if index == "" && at == "" {
+		return nil, nil
+	}
+	if index != "" && at != "" {
+		return nil, fmt.Errorf("use -start-index or -start-at, not both")
+	}
+	count, err := countSubnets(prefix, newLen)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var start *big.Int
+	if at != "" {
+		if start, err = subnetIndex(parent, newLen, at); err != nil {
+			return nil, err
+		}
+	} else {
+		var ok bool
+		if start, ok = new(big.Int).SetString(index, 10); !ok || start.Sign() < 0 {
+			return nil, fmt.Errorf("invalid start index %q", index)
+		}
+		if start.Cmp(count) >= 0 {
+			return nil, fmt.Errorf("start index %s is past the last of %s subnets", start, count)
+		}
+	}
+	return start, nil
+}
+
+// parseSubnetParent parses the prefix being split, masked to its length.
+func parseSubnetParent(prefix string) (netip.Prefix, error) {
+	ipnet, err := parseIPv6Prefix(prefix)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid prefix: %v", err)
+	}
+	ones, _ := ipnet.Mask.Size()
+	return netip.PrefixFrom(netip.AddrFrom16([16]byte(ipnet.IP.To16())), ones).Masked(), nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestSubnetSeq(t *testing.T) {
+	cases := []struct {
+		name   string
+		parent string
+		newLen int
+		limit  int
+		expect []string
+	}{
+		{"split /62 into /64", "2001:db8::/62", 64, 0, []string{"2001:db8::/64", "2001:db8:0:1::/64", "2001:db8:0:2::/64", "2001:db8:0:3::/64"}},
+		{"unaligned parent is masked", "2001:db8::1/127", 128, 0, []string{"2001:db8::/128", "2001:db8::1/128"}},
+		{"non-octet boundary", "2001:db8::/44", 46, 0, []string{"2001:db8::/46", "2001:db8:4::/46", "2001:db8:8::/46", "2001:db8:c::/46"}},
+		{"limit", "2001:db8::/32", 64, 2, []string{"2001:db8::/64", "2001:db8:0:1::/64"}},
+		{"end of address space", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/127", 128, 0, []string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/128", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff/128"}},
+		{"whole space", "::/0", 1, 0, []string{"::/1", "8000::/1"}},
+		{"same length", "2001:db8::/48", 48, 0, []string{"2001:db8::/48"}},
+		{"shorter length", "2001:db8::/48", 40, 0, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			for p := range limitSubnets(subnetSeq(netip.MustParsePrefix(c.parent), c.newLen), c.limit) {
+				got = append(got, p.String())
+			}
+			if !slices.Equal(got, c.expect) {
+				t.Errorf("got %v, want %v", got, c.expect)
+			}
+		})
+	}
+}
+
+func TestSubnetSeqBreak(t *testing.T) {
+	count := 0
+	for range subnetSeq(netip.MustParsePrefix("::/0"), 128) {
+		if count++; count == 3 {
+			break
+		}
+	}
+	if count != 3 {
+		t.Errorf("ranged over %d subnets, want 3", count)
+	}
+}
+
+func TestSubnetSeqExcluding(t *testing.T) {
+	cases := []struct {
+		name    string
+		parent  string
+		newLen  int
+		exclude []string
+		limit   int
+		expect  []string
+	}{
+		{"single and covering", "2001:db8::/61", 64, []string{"2001:db8:0:1::/64", "2001:db8:0:2::/63"}, 0,
+			[]string{"2001:db8::/64", "2001:db8:0:4::/64", "2001:db8:0:5::/64", "2001:db8:0:6::/64", "2001:db8:0:7::/64"}},
+		{"smaller than a subnet", "2001:db8::/62", 64, []string{"2001:db8:0:1::8/126"}, 0,
+			[]string{"2001:db8::/64", "2001:db8:0:2::/64", "2001:db8:0:3::/64"}},
+		{"overlapping and adjacent", "2001:db8::/61", 64, []string{"2001:db8:0:1::/64", "2001:db8::/63", "2001:db8:0:2::/64"}, 0,
+			[]string{"2001:db8:0:3::/64", "2001:db8:0:4::/64", "2001:db8:0:5::/64", "2001:db8:0:6::/64", "2001:db8:0:7::/64"}},
+		{"covers parent", "2001:db8::/62", 64, []string{"2001:db8::/48"}, 0, nil},
+		{"covers start of parent", "2001:db8::/62", 64, []string{"2001::/16"}, 0, nil},
+		{"billions of children skipped", "2001:db8::/32", 64, []string{"2001:db8::/33"}, 2,
+			[]string{"2001:db8:8000::/64", "2001:db8:8000:1::/64"}},
+		{"end of address space", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fff0/124", 126, []string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffc/126"}, 0,
+			[]string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fff0/126", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fff4/126", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fff8/126"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var exclude []prefixEntry
+			for _, e := range c.exclude {
+				_, ipnet, _ := net.ParseCIDR(e)
+				exclude = append(exclude, prefixEntry{Net: ipnet})
+			}
+			var got []string
+			for p := range limitSubnets(subnetSeqExcluding(netip.MustParsePrefix(c.parent), c.newLen, nil, exclude), c.limit) {
+				got = append(got, p.String())
+			}
+			if !slices.Equal(got, c.expect) {
+				t.Errorf("got %v, want %v", got, c.expect)
+			}
+		})
+	}
+}
+
+func TestSubnetSeqFrom(t *testing.T) {
+	var got []string
+	parent := netip.MustParsePrefix("2001:db8::/32")
+	for p := range limitSubnets(subnetSeqFrom(parent, 64, big.NewInt(1000000)), 2) {
+		got = append(got, p.String())
+	}
+	expect := []string{"2001:db8:f:4240::/64", "2001:db8:f:4241::/64"}
+	if !slices.Equal(got, expect) {
+		t.Errorf("got %v, want %v", got, expect)
+	}
+	for p := range subnetSeqFrom(netip.MustParsePrefix("2001:db8::/62"), 64, big.NewInt(4)) {
+		t.Errorf("start past the end yielded %s", p)
+	}
+}
+
+func TestResolveSubnetStart(t *testing.T) {
+	cases := []struct {
+		name   string
+		index  string
+		at     string
+		expect string
+		err    bool
+	}{
+		{"neither", "", "", "<nil>", false},
+		{"index", "1000000", "", "1000000", false},
+		{"at prefix", "", "2001:db8:f:4240::/64", "1000000", false},
+		{"at address", "", "2001:db8:f:4240::1", "1000000", false},
+		{"last index", "4294967295", "", "4294967295", false},
+		{"index past end", "4294967296", "", "", true},
+		{"negative index", "-1", "", "", true},
+		{"at outside", "", "2001:db9::/64", "", true},
+		{"both", "1", "2001:db8::/64", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			start, err := resolveSubnetStart("2001:db8::/32", 64, c.index, c.at)
+			if c.err {
+				if err == nil {
+					t.Errorf("expected error, got %v", start)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fmt.Sprint(start); got != c.expect {
+				t.Errorf("got %s, want %s", got, c.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"strings"
+)
+
+// subtractResult is the JSON form of a subtraction.
+type subtractResult struct {
+	Prefix    string   `json:"prefix"`
+	Minus     []string `json:"minus"`
+	Remaining []string `json:"remaining"`
+}
+
+// subtractPrefixes returns what is left of base once every prefix of minus is
+// taken out, as the fewest prefixes that cover it exactly, in address order.
+// A half of base that overlaps nothing is kept whole; one that overlaps is
+// split again, so each removed prefix costs at most one split per bit.
+func subtractPrefixes(base netip.Prefix, minus []netip.Prefix) []netip.Prefix {
+	base = base.Masked()
+	var overlapping []netip.Prefix
+	for _, m := range minus {
+		if m.Bits() <= base.Bits() && m.Contains(base.Addr()) {
+			return nil
+		}
+		if m.Overlaps(base) {
+			overlapping = append(overlapping, m)
+		}
+	}
+	if len(overlapping) == 0 {
+		return []netip.Prefix{base}
+	}
+	low := netip.PrefixFrom(base.Addr(), base.Bits()+1)
+	high := netip.PrefixFrom(lastAddr(base), base.Bits()+1).Masked()
+	return append(subtractPrefixes(low, overlapping), subtractPrefixes(high, overlapping)...)
+}
+
+// parsePrefixList parses a comma-separated list of IPv6 prefixes.
+func parsePrefixList(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		p, err := parseSubnetParent(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s, err)
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes, nil
+}
+
+// runSubtract prints the free space left in prefix after removing the -minus
+// prefixes and the active entries of the -minus-file prefix list.
+func runSubtract(prefix, minus, minusFile string) {
+	base, err := parseSubnetParent(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	removed, err := parsePrefixList(minus)
+	if err != nil {
+		log.Fatal(err)
+	}
+	entries, err := readAllocations(minusFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, e := range entries {
+		removed = append(removed, entryPrefix(e))
+	}
+	result := subtractResult{Prefix: base.String(), Minus: []string{}, Remaining: []string{}}
+	for _, p := range removed {
+		result.Minus = append(result.Minus, prefixText(p))
+	}
+	for _, p := range subtractPrefixes(base, removed) {
+		result.Remaining = append(result.Remaining, prefixText(p))
+	}
+	writeResult(result, func() {
+		for _, p := range result.Remaining {
+			fmt.Println(p)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+)
+
+func TestSubtractPrefixes(t *testing.T) {
+	tests := []struct {
+		base, minus string
+		want        string
+	}{
+		{"2001:db8::/46", "2001:db8::/48", "[2001:db8:1::/48 2001:db8:2::/47]"},
+		{"2001:db8::/46", "2001:db8:3::/48,2001:db8::/48", "[2001:db8:1::/48 2001:db8:2::/48]"},
+		{"2001:db8::/48", "2001:db8::/32", "[]"},
+		{"2001:db8::/48", "2001:db8::/48", "[]"},
+		{"2001:db8::/48", "3fff::/48", "[2001:db8::/48]"},
+		{"2001:db8::/126", "2001:db8::2/128", "[2001:db8::/127 2001:db8::3/128]"},
+		// Removing a prefix and one inside it is the same as removing the outer one.
+		{"2001:db8::/47", "2001:db8:1::/48,2001:db8:1:5::/64", "[2001:db8::/48]"},
+	}
+	for _, tt := range tests {
+		minus, err := parsePrefixList(tt.minus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := subtractPrefixes(netip.MustParsePrefix(tt.base), minus)
+		if got == nil {
+			got = []netip.Prefix{}
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s minus %s: got %v, want %s", tt.base, tt.minus, got, tt.want)
+		}
+	}
+	if _, err := parsePrefixList("2001:db8::/48,bogus"); err == nil {
+		t.Error("invalid prefix accepted")
+	}
+}
+
+func TestSubtractPrefixesCount(t *testing.T) {
+	// A /48 out of a /32 leaves one prefix for each of the 16 bits between.
+	minus := []netip.Prefix{netip.MustParsePrefix("2001:db8:dead::/48")}
+	if got := subtractPrefixes(netip.MustParsePrefix("2001:db8::/32"), minus); len(got) != 16 {
+		t.Errorf("got %d prefixes, want 16: %v", len(got), got)
+	}
+}
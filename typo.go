@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// typoRule rewrites a near-miss address or prefix, returning the new text and a note
+// describing the change, or false when it does not apply.
+type typoRule func(s string) (string, string, bool)
+
+var (
+	bracketedRe  = regexp.MustCompile(`^\[([^\]]+)\](:\d+)?$`)
+	portSuffixRe = regexp.MustCompile(`^(.*:.*[0-9a-fA-F:])\.(\d{1,5})(/\d+)?$`)
+	lookalikeRe  = regexp.MustCompile(`[OoIil]`)
+)
+
+// splitPrefix separates the address part of the input from a "/len" suffix.
+func splitPrefix(s string) (string, string) {
+	if i := strings.LastIndex(s, "/"); i != -1 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// typoRules are tried in order; the first that applies is used and parsing retried.
+var typoRules = []typoRule{
+	// [2001:db8::1]:443 as copied from a URL.
+	func(s string) (string, string, bool) {
+		m := bracketedRe.FindStringSubmatch(s)
+		if m == nil {
+			return "", "", false
+		}
+		if m[2] != "" {
+			return m[1], fmt.Sprintf("removed URL brackets and port %s", m[2][1:]), true
+		}
+		return m[1], "removed URL brackets", true
+	},
+	// Embedded spaces, e.g. "2001:db8:: / 32".
+	func(s string) (string, string, bool) {
+		if !strings.ContainsAny(s, " \t") {
+			return "", "", false
+		}
+		return strings.Join(strings.Fields(s), ""), "removed whitespace", true
+	},
+	// fe80::1%eth0 or a dangling '%'.
+	func(s string) (string, string, bool) {
+		i := strings.Index(s, "%")
+		if i == -1 {
+			return "", "", false
+		}
+		addr, pfx := splitPrefix(s)
+		if i > len(addr) {
+			return "", "", false
+		}
+		if zone := addr[i+1:]; zone != "" {
+			return addr[:i] + pfx, fmt.Sprintf("dropped zone index %%%s", zone), true
+		}
+		return addr[:i] + pfx, "dropped empty zone index '%'", true
+	},
+	// Trailing punctuation picked up from prose or CSV.
+	func(s string) (string, string, bool) {
+		trimmed := strings.TrimRight(s, ".,;")
+		if trimmed == s || trimmed == "" {
+			return "", "", false
+		}
+		return trimmed, fmt.Sprintf("removed trailing %q", s[len(trimmed):]), true
+	},
+	// tcpdump-style ".port" suffixes such as 2001:db8::1.443.
+	func(s string) (string, string, bool) {
+		m := portSuffixRe.FindStringSubmatch(s)
+		if m == nil {
+			return "", "", false
+		}
+		if _, _, err := parseIPv6Text(m[1]); err != nil {
+			return "", "", false
+		}
+		return m[1] + m[3], fmt.Sprintf("dropped .%s suffix", m[2]), true
+	},
+	// Letter O for zero and letters l/I for one.
+	func(s string) (string, string, bool) {
+		addr, pfx := splitPrefix(s)
+		if !lookalikeRe.MatchString(addr) {
+			return "", "", false
+		}
+		fixed := strings.NewReplacer("O", "0", "o", "0", "I", "1", "i", "1", "l", "1").Replace(addr)
+		return fixed + pfx, "read letters O/l/I as digits 0/1", true
+	},
+	// ":::" for "::".
+	func(s string) (string, string, bool) {
+		if !strings.Contains(s, ":::") {
+			return "", "", false
+		}
+		for strings.Contains(s, ":::") {
+			s = strings.ReplaceAll(s, ":::", "::")
+		}
+		return s, "collapsed ':::' to '::'", true
+	},
+	// A single ':' where "::" was meant, e.g. 2001:db8:/32 or :1.
+	func(s string) (string, string, bool) {
+		addr, pfx := splitPrefix(s)
+		if strings.Contains(addr, "::") || addr == "" {
+			return "", "", false
+		}
+		if strings.HasSuffix(addr, ":") {
+			return addr + ":" + pfx, "read trailing ':' as '::'", true
+		}
+		if strings.HasPrefix(addr, ":") {
+			return ":" + addr + pfx, "read leading ':' as '::'", true
+		}
+		return "", "", false
+	},
+	// Host bits set beyond the prefix length.
+	func(s string) (string, string, bool) {
+		addr, pfx := splitPrefix(s)
+		if pfx == "" {
+			return "", "", false
+		}
+		ip, _, err := parseIPv6Text(addr)
+		n, convErr := strconv.Atoi(pfx[1:])
+		if err != nil || convErr != nil || n < 0 || n > 128 {
+			return "", "", false
+		}
+		network := networkAddress(ip, n)
+		if network.Equal(ip) {
+			return "", "", false
+		}
+		return fmt.Sprintf("%s/%d", ipv6String(network), n), fmt.Sprintf("cleared host bits beyond /%d", n), true
+	},
+}
+
+// canonicalIPv6Input returns the RFC 5952 form of a valid address or prefix.
+func canonicalIPv6Input(s string) (string, error) {
+	if strings.Contains(s, "/") {
+		ipnet, err := parsePrefixStrict(s)
+		if err != nil {
+			return "", err
+		}
+		ones, _ := ipnet.Mask.Size()
+		return fmt.Sprintf("%s/%d", ipv6String(ipnet.IP), ones), nil
+	}
+	ip, _, err := parseIPv6Text(s)
+	if err != nil {
+		return "", err
+	}
+	return ipv6String(ip), nil
+}
+
+// fixIPv6Input applies typo corrections to an address or prefix until it parses,
+// returning the canonical result and a note for each correction made. Input that is
+// already valid is returned in canonical form with no notes.
+func fixIPv6Input(s string) (string, []string, error) {
+	var notes []string
+	cur := strings.TrimSpace(s)
+	for range len(typoRules) + 1 {
+		canonical, err := canonicalIPv6Input(cur)
+		if err == nil {
+			return canonical, notes, nil
+		}
+		applied := false
+		for _, rule := range typoRules {
+			if next, note, ok := rule(cur); ok && next != cur {
+				cur = next
+				notes = append(notes, note)
+				applied = true
+				break
+			}
+		}
+		if !applied {
+			return "", notes, err
+		}
+	}
+	return "", notes, fmt.Errorf("could not correct %q", s)
+}
+
+// fixIPv6List corrects one address or prefix per line, writing the canonical value
+// (or the original line when it cannot be fixed) to out and a report of every change
+// to report. Blank lines and '#' comments pass through. It returns the number of
+// lines that could not be corrected.
+func fixIPv6List(in io.Reader, out io.Writer, report io.Writer) (int, error) {
+	scanner := bufio.NewScanner(in)
+	failed := 0
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			fmt.Fprintln(out, line)
+			continue
+		}
+		fixed, notes, err := fixIPv6Input(trimmed)
+		if err != nil {
+			failed++
+			fmt.Fprintf(report, "line %d: cannot fix %s: %s\n", lineNo, trimmed, strings.SplitN(err.Error(), "\n", 2)[0])
+			fmt.Fprintln(out, line)
+			continue
+		}
+		if len(notes) == 0 && fixed != trimmed {
+			notes = append(notes, "rewrote in canonical form")
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(report, "line %d: %s → %s (%s)\n", lineNo, trimmed, fixed, strings.Join(notes, "; "))
+		}
+		fmt.Fprintln(out, fixed)
+	}
+	return failed, scanner.Err()
+}
+
+// runFix corrects the list at path ("-" for stdin) to stdout, reporting changes
+// on stderr, and fails if any line could not be corrected.
+func runFix(path string) {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	failed, err := fixIPv6List(in, os.Stdout, os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if failed > 0 {
+		log.Fatalf("%d line(s) could not be corrected", failed)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFixIPv6Input(t *testing.T) {
+	cases := []struct {
+		name        string
+		input       string
+		expect      string
+		expectNotes int
+		expectError bool
+	}{
+		{name: "already valid", input: "2001:db8::1", expect: "2001:db8::1"},
+		{name: "valid but not canonical", input: "2001:0DB8::0001", expect: "2001:db8::1"},
+		{name: "single colon before length", input: "2001:db8:/32", expect: "2001:db8::/32", expectNotes: 1},
+		{name: "leading single colon", input: ":1", expect: "::1", expectNotes: 1},
+		{name: "empty zone", input: "fe80::1%", expect: "fe80::1", expectNotes: 1},
+		{name: "zone with prefix", input: "fe80::%eth0/64", expect: "fe80::/64", expectNotes: 1},
+		{name: "URL brackets and port", input: "[2001:db8::1]:8080", expect: "2001:db8::1", expectNotes: 1},
+		{name: "port suffix", input: "2001:db8::1.443", expect: "2001:db8::1", expectNotes: 1},
+		{name: "letter O and l", input: "2OO1:db8::l", expect: "2001:db8::1", expectNotes: 1},
+		{name: "triple colon", input: "2001:db8:::1", expect: "2001:db8::1", expectNotes: 1},
+		{name: "host bits", input: "2001:db8::1/32", expect: "2001:db8::/32", expectNotes: 1},
+		{name: "whitespace", input: "2001:db8:: / 48", expect: "2001:db8::/48", expectNotes: 1},
+		{name: "trailing comma", input: "2001:db8::1,", expect: "2001:db8::1", expectNotes: 1},
+		{name: "several fixes", input: "[2OO1:db8::1]", expect: "2001:db8::1", expectNotes: 2},
+		{name: "mapped IPv4 is left alone", input: "::ffff:192.0.2.1", expect: "::ffff:192.0.2.1"},
+		{name: "ambiguous double ::", input: "2001::db8::1", expectError: true},
+		{name: "hopeless", input: "hello world", expectError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, notes, err := fixIPv6Input(tc.input)
+			if (err == nil) == tc.expectError {
+				t.Fatalf("expected error %v, got %v", tc.expectError, err)
+			}
+			if err != nil {
+				return
+			}
+			if got != tc.expect {
+				t.Errorf("expected %s, got %s", tc.expect, got)
+			}
+			if len(notes) != tc.expectNotes {
+				t.Errorf("expected %d notes, got %v", tc.expectNotes, notes)
+			}
+		})
+	}
+}
+
+func TestFixIPv6List(t *testing.T) {
+	in := "# header\n2001:db8:/32\n\n2001::db8::1\n2001:db8::1\n"
+	var out, report bytes.Buffer
+	failed, err := fixIPv6List(strings.NewReader(in), &out, &report)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failed != 1 {
+		t.Errorf("expected 1 failure, got %d", failed)
+	}
+	expect := "# header\n2001:db8::/32\n\n2001::db8::1\n2001:db8::1\n"
+	if out.String() != expect {
+		t.Errorf("expected output %q, got %q", expect, out.String())
+	}
+	if !strings.Contains(report.String(), "line 2: 2001:db8:/32 → 2001:db8::/32") || !strings.Contains(report.String(), "line 4: cannot fix") {
+		t.Errorf("unexpected report %q", report.String())
+	}
+}
+
+func TestParseErrorSuggestion(t *testing.T) {
+	_, err := parseIPv6Prefix("2001:db8:/32")
+	if err == nil || !strings.Contains(err.Error(), "did you mean 2001:db8::/32?") {
+		t.Errorf("expected suggestion in error, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"net/netip"
+	"os"
+	"time"
+)
+
+// defaultHDThreshold is the HD-ratio at which the RIRs consider an IPv6 block
+// fully used and grant more space.
+const defaultHDThreshold = 0.94
+
+// utilization is how much of a parent prefix is allocated, counted in units of
+// one prefix length, such as the /56 or /48 assigned to an end site.
+type utilization struct {
+	Parent      string  `json:"parent"`
+	Unit        int     `json:"unit"`
+	Units       string  `json:"units"` // decimal, as the count can exceed 64 bits
+	UsedUnits   string  `json:"used_units"`
+	Allocations int     `json:"allocations"`
+	Outside     int     `json:"outside"` // allocations not within the parent, not counted
+	Percent     float64 `json:"percent"`
+	HDRatio     float64 `json:"hd_ratio"`
+	Threshold   float64 `json:"threshold"`
+	Reached     bool    `json:"threshold_reached"`
+}
+
+// bigLog2 returns log2(x) for a positive x, without overflowing float64 on
+// counts of 2^64 units and more.
+func bigLog2(x *big.Int) float64 {
+	mant := new(big.Float)
+	exp := new(big.Float).SetInt(x).MantExp(mant)
+	m, _ := mant.Float64()
+	return float64(exp) + math.Log2(m)
+}
+
+// measureUtilization counts the unit-length subnets of parent that hold any part
+// of an allocation and computes the HD-ratio of RFC 3194, log(used)/log(total):
+// unlike a plain percentage it allows for the space each level of a hierarchy
+// leaves free, so the RIRs use it to judge whether a block is used up.
+func measureUtilization(parent netip.Prefix, unit int, allocations []prefixEntry, threshold float64) (utilization, error) {
+	if unit <= parent.Bits() || unit > 128 {
+		return utilization{}, fmt.Errorf("unit must be between /%d and /128", parent.Bits()+1)
+	}
+	u := utilization{Parent: parent.String(), Unit: unit, Allocations: len(allocations), Threshold: threshold}
+	var inside []prefixEntry
+	for _, e := range allocations {
+		p := entryPrefix(e)
+		if !p.Overlaps(parent) {
+			u.Outside++
+			continue
+		}
+		if p.Bits() < parent.Bits() {
+			// An allocation covering the whole parent uses all of it.
+			e.Net = prefixToIPNet(parent)
+		}
+		inside = append(inside, e)
+	}
+
+	total := new(big.Int).Lsh(big.NewInt(1), uint(unit-parent.Bits()))
+	used := new(big.Int)
+	shift := uint(128 - unit)
+	last := big.NewInt(-1) // the highest unit counted so far
+	for _, r := range excludedRanges(inside) {
+		from := new(big.Int).Rsh(ipToBigInt(r.From.AsSlice()), shift)
+		to := new(big.Int).Rsh(ipToBigInt(r.To.AsSlice()), shift)
+		if from.Cmp(last) <= 0 {
+			// The range starts in a unit the previous one ended in.
+			from.Add(last, big.NewInt(1))
+		}
+		if to.Cmp(from) >= 0 {
+			used.Add(used, new(big.Int).Sub(to, from))
+			used.Add(used, big.NewInt(1))
+			last = to
+		}
+	}
+	u.Units, u.UsedUnits = total.String(), used.String()
+	u.Percent, _ = new(big.Float).Quo(new(big.Float).SetInt(used), new(big.Float).SetInt(total)).Float64()
+	u.Percent *= 100
+	if used.Sign() > 0 {
+		u.HDRatio = bigLog2(used) / bigLog2(total)
+	}
+	u.Reached = u.HDRatio >= threshold
+	return u, nil
+}
+
+// writeUtilization prints the utilization report.
+func writeUtilization(w io.Writer, u utilization) {
+	fmt.Fprintf(w, "%-16s %s\n", "Parent:", u.Parent)
+	fmt.Fprintf(w, "%-16s /%d (%s units)\n", "Unit:", u.Unit, u.Units)
+	fmt.Fprintf(w, "%-16s %d (%d outside the parent)\n", "Allocations:", u.Allocations, u.Outside)
+	fmt.Fprintf(w, "%-16s %s of %s (%.2f%%)\n", "Used units:", u.UsedUnits, u.Units, u.Percent)
+	status := "not reached"
+	if u.Reached {
+		status = "REACHED"
+	}
+	fmt.Fprintf(w, "%-16s %.4f (threshold %.2f %s)\n", "HD-ratio:", u.HDRatio, u.Threshold, status)
+}
+
+// runUtilization reports the utilization of prefix by the allocations in the
+// allocation file, or in the allocation database when no file is given.
+func runUtilization(prefix string, unit int, allocatedFile, dbPath string, threshold float64) {
+	parent, err := parseSubnetParent(prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if threshold <= 0 || threshold > 1 {
+		log.Fatal("threshold must be above 0 and at most 1")
+	}
+	var allocations []prefixEntry
+	if allocatedFile != "" {
+		if allocations, err = readAllocations(allocatedFile); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		withAllocDB(dbPath, func(db *allocDB) (bool, error) {
+			allocations, _, err = splitExpired(db.entries(), time.Now())
+			return false, err
+		})
+	}
+	u, err := measureUtilization(parent, unit, allocations, threshold)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeResult(u, func() {
+		writeUtilization(os.Stdout, u)
+	})
+}
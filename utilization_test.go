@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestMeasureUtilization(t *testing.T) {
+	allocations, err := readPrefixEntries(strings.NewReader(`3fff::/48 hq
+3fff:0:1:5::/64 lab
+3fff:0:1:6::/64 lab2
+3fff:0:2::/47 campus
+2001:db8::/48 elsewhere
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := measureUtilization(netip.MustParsePrefix("3fff::/44"), 48, allocations, defaultHDThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The two /64s share a /48, which counts once.
+	if u.UsedUnits != "4" || u.Units != "16" || u.Outside != 1 || u.Percent != 25 || u.HDRatio != 0.5 || u.Reached {
+		t.Errorf("got %+v", u)
+	}
+
+	var out bytes.Buffer
+	writeUtilization(&out, u)
+	if want := "HD-ratio:        0.5000 (threshold 0.94 not reached)\n"; !strings.HasSuffix(out.String(), want) {
+		t.Errorf("report:\n%s\nwant it to end with %q", out.String(), want)
+	}
+
+	// An allocation covering the parent uses all of it.
+	covering, _ := readPrefixEntries(strings.NewReader("3fff::/32\n"))
+	if u, _ = measureUtilization(netip.MustParsePrefix("3fff::/40"), 56, covering, defaultHDThreshold); u.HDRatio != 1 || !u.Reached {
+		t.Errorf("covered parent: got %+v", u)
+	}
+	if u, _ = measureUtilization(netip.MustParsePrefix("3fff::/40"), 56, nil, defaultHDThreshold); u.HDRatio != 0 || u.UsedUnits != "0" {
+		t.Errorf("no allocations: got %+v", u)
+	}
+	if _, err := measureUtilization(netip.MustParsePrefix("3fff::/48"), 48, nil, defaultHDThreshold); err == nil {
+		t.Error("unit as long as the parent accepted")
+	}
+}
+
+func TestBigLog2(t *testing.T) {
+	for _, tt := range []struct {
+		x    *big.Int
+		want float64
+	}{
+		{big.NewInt(1), 0},
+		{big.NewInt(1000), math.Log2(1000)},
+		{new(big.Int).Lsh(big.NewInt(3), 100), 100 + math.Log2(3)},
+	} {
+		if got := bigLog2(tt.x); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("bigLog2(%s) = %v, want %v", tt.x, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+// hexLookalikes maps letters that are commonly spelled with hex digits.
+var hexLookalikes = map[rune]rune{'o': '0', 'i': '1', 'l': '1', 's': '5', 't': '7', 'g': '9'}
+
+// vanityWord normalises a word to lowercase hex nibbles, substituting look-alike
+// digits for letters outside a-f (e.g. "f00d" and "food" are equivalent).
+func vanityWord(word string) (string, error) {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if sub, ok := hexLookalikes[r]; ok {
+			r = sub
+		}
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return "", fmt.Errorf("%q cannot be spelled in hex", word)
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("empty vanity word")
+	}
+	return sb.String(), nil
+}
+
+// setNibble writes a 4-bit value at nibble index n (0 = most significant) of a 16-byte address.
+func setNibble(b []byte, n int, v byte) {
+	if n%2 == 0 {
+		b[n/2] = (b[n/2] & 0x0f) | v<<4
+	} else {
+		b[n/2] = (b[n/2] & 0xf0) | v
+	}
+}
+
+// vanitySubnets finds children of a parent prefix whose subnet ID spells one of the
+// given words. When group is 1-8 the word is right-aligned in that 16-bit group so
+// leading zeros are suppressed (e.g. 2001:db8:0:bad::/64); group 0 tries every
+// nibble offset. Candidates with the remaining subnet bits zeroed are returned first,
+// followed by variations, until budget candidates have been produced.
+func vanitySubnets(prefix string, newPrefixLength int, words []string, group int, budget int) ([]string, error) {
+	ipnet, err := parseIPv6Prefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %v", err)
+	}
+	currentPrefixLength, _ := ipnet.Mask.Size()
+	if newPrefixLength <= currentPrefixLength || newPrefixLength > 128 {
+		return nil, fmt.Errorf("new prefix length must be larger than the current prefix length")
+	}
+	if group < 0 || group > 8 {
+		return nil, fmt.Errorf("group must be between 1 and 8, or 0 for any position")
+	}
+	if budget <= 0 {
+		return nil, fmt.Errorf("search budget must be positive")
+	}
+
+	// Only nibbles wholly inside the subnet ID can carry a word.
+	freeStart := (currentPrefixLength + 3) / 4
+	freeEnd := newPrefixLength / 4
+
+	type placement struct {
+		word  string
+		start int
+		free  []int // subnet-ID bit positions not covered by the word, least significant first
+	}
+	var placements []placement
+	for _, w := range words {
+		hw, err := vanityWord(w)
+		if err != nil {
+			return nil, err
+		}
+		var starts []int
+		if group > 0 {
+			starts = []int{group*4 - len(hw)}
+		} else {
+			for s := freeStart; s+len(hw) <= freeEnd; s++ {
+				starts = append(starts, s)
+			}
+		}
+		for _, s := range starts {
+			if s < freeStart || s+len(hw) > freeEnd {
+				continue
+			}
+			var free []int
+			for bit := newPrefixLength - 1; bit >= currentPrefixLength; bit-- {
+				if bit/4 >= s && bit/4 < s+len(hw) {
+					continue
+				}
+				free = append(free, bit)
+			}
+			placements = append(placements, placement{word: hw, start: s, free: free})
+		}
+	}
+	if len(placements) == 0 {
+		return nil, fmt.Errorf("no word fits within the subnet ID between /%d and /%d", currentPrefixLength, newPrefixLength)
+	}
+
+	base := ipnet.IP.Mask(ipnet.Mask).To16()
+	seen := map[string]bool{}
+	var found []net.IP
+	produced := 0
+	for k := uint64(0); produced < budget; k++ {
+		progressed := false
+		for _, p := range placements {
+			if len(p.free) < 64 && k >= uint64(1)<<len(p.free) {
+				continue
+			}
+			progressed = true
+			candidate := make(net.IP, net.IPv6len)
+			copy(candidate, base)
+			for i, c := range p.word {
+				var v byte
+				fmt.Sscanf(string(c), "%x", &v)
+				setNibble(candidate, p.start+i, v)
+			}
+			for j := 0; j < len(p.free) && j < 64; j++ {
+				if k&(1<<j) != 0 {
+					bit := p.free[j]
+					candidate[bit/8] |= 0x80 >> (bit % 8)
+				}
+			}
+			produced++
+			key := string(candidate)
+			if !seen[key] {
+				seen[key] = true
+				found = append(found, candidate)
+			}
+			if produced >= budget {
+				break
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+
+	subnets := make([]string, len(found))
+	for i, ip := range found {
+		subnets[i] = fmt.Sprintf("%s/%d", ip, newPrefixLength)
+	}
+	return subnets, nil
+}
+
+// reservedIIDRange is an entry of the IANA Reserved IPv6 Interface Identifiers registry (RFC 5453).
+type reservedIIDRange struct {
+	first, last uint64
+	desc        string
+}
+
+var reservedIIDs = []reservedIIDRange{
+	{0x0000000000000000, 0x0000000000000000, "Subnet-Router Anycast (RFC 4291)"},
+	{0x02005efffe000000, 0x02005efffe005212, "IANA Ethernet Block (RFC 4291)"},
+	{0x02005efffe005213, 0x02005efffe005213, "Proxy Mobile IPv6 (RFC 6543)"},
+	{0x02005efffe005214, 0x02005efffeffffff, "IANA Ethernet Block (RFC 4291)"},
+	{0xfdffffffffffff80, 0xfdffffffffffffff, "Reserved Subnet Anycast (RFC 2526)"},
+}
+
+// reservedIID returns the registry description if the 64-bit interface ID is reserved,
+// or an empty string otherwise.
+func reservedIID(iid []byte) string {
+	var v uint64
+	for _, b := range iid[:8] {
+		v = v<<8 | uint64(b)
+	}
+	for _, r := range reservedIIDs {
+		if v >= r.first && v <= r.last {
+			return r.desc
+		}
+	}
+	return ""
+}
+
+// vanityIID builds a 64-bit interface ID from text. In hex mode the text is spelled
+// with look-alike digits and right-aligned, with ':' starting a new 16-bit group
+// (e.g. "cafe:f00d" → ::cafe:f00d). In ASCII mode each character becomes one byte,
+// so up to 8 characters fit (e.g. "mail" → ::6d61:696c).
+func vanityIID(text string, ascii bool) ([]byte, error) {
+	iid := make([]byte, 8)
+	if ascii {
+		if len(text) == 0 || len(text) > 8 {
+			return nil, fmt.Errorf("ASCII interface ID must be 1-8 characters, got %d", len(text))
+		}
+		for _, r := range text {
+			if r < 0x20 || r > 0x7e {
+				return nil, fmt.Errorf("%q contains non-printable or non-ASCII characters", text)
+			}
+		}
+		copy(iid[8-len(text):], text)
+		return iid, nil
+	}
+
+	groups := strings.Split(text, ":")
+	if len(groups) > 4 {
+		return nil, fmt.Errorf("interface ID %q has more than 4 groups", text)
+	}
+	var nibbles string
+	for i, g := range groups {
+		hw, err := vanityWord(g)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			if len(hw) > 4 {
+				return nil, fmt.Errorf("group %q is longer than 4 nibbles", g)
+			}
+			hw = strings.Repeat("0", 4-len(hw)) + hw
+		}
+		nibbles += hw
+	}
+	if len(nibbles) > 16 {
+		return nil, fmt.Errorf("interface ID %q is longer than 64 bits", text)
+	}
+	nibbles = strings.Repeat("0", 16-len(nibbles)) + nibbles
+	for i, c := range nibbles {
+		var v byte
+		fmt.Sscanf(string(c), "%x", &v)
+		setNibble(iid, i, v)
+	}
+	return iid, nil
+}
+
+// vanityAddress combines the /64 (or shorter) network of prefix with a vanity
+// interface ID and returns the full address along with any reserved-IID collision.
+func vanityAddress(prefix string, text string, ascii bool) (string, string, error) {
+	ipnet, err := parseIPv6Prefix(prefix)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid prefix: %v", err)
+	}
+	if plen, _ := ipnet.Mask.Size(); plen > 64 {
+		return "", "", fmt.Errorf("prefix must be /64 or shorter to hold a 64-bit interface ID, got /%d", plen)
+	}
+	iid, err := vanityIID(text, ascii)
+	if err != nil {
+		return "", "", err
+	}
+	addr := networkAddress(ipnet.IP.To16(), 64)
+	copy(addr[8:], iid)
+	return addr.String(), reservedIID(iid), nil
+}
+
+// runVanity prints up to limit (0 for all) children of prefix at
+// newPrefixLength whose subnet IDs spell one of words.
+func runVanity(prefix string, newPrefixLength int, words []string, group int, budget int, limit int) {
+	subnets, err := vanitySubnets(prefix, newPrefixLength, words, group, budget)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i, subnet := range subnets {
+		if limit > 0 && i >= limit {
+			break
+		}
+		fmt.Println(subnet)
+	}
+}
+
+// runVanityIIDs prints the address in prefix spelling each word, warning about
+// and skipping those that land on a reserved interface ID.
+func runVanityIIDs(prefix string, words []string, ascii bool) {
+	for _, word := range words {
+		addr, reserved, err := vanityAddress(prefix, word, ascii)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if reserved != "" {
+			log.Printf("Warning: %s uses a reserved interface ID: %s", addr, reserved)
+			continue
+		}
+		fmt.Println(addr)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestVanityWord(t *testing.T) {
+	cases := []struct {
+		name        string
+		input       string
+		expect      string
+		expectError bool
+	}{
+		{name: "plain hex", input: "cafe", expect: "cafe"},
+		{name: "uppercase", input: "BEEF", expect: "beef"},
+		{name: "look-alike letters", input: "food", expect: "f00d"},
+		{name: "mixed look-alikes", input: "c0ffee", expect: "c0ffee"},
+		{name: "not spellable", input: "xyz", expectError: true},
+		{name: "empty", input: "", expectError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := vanityWord(tc.input)
+			if (err == nil) == tc.expectError {
+				t.Errorf("expected error %v, got %v", tc.expectError, err)
+			}
+			if err == nil && got != tc.expect {
+				t.Errorf("expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestVanitySubnets(t *testing.T) {
+	cases := []struct {
+		name        string
+		prefix      string
+		newLen      int
+		words       []string
+		group       int
+		budget      int
+		expectFirst []string
+		expectCount int
+		expectError bool
+	}{
+		{
+			name:        "whole group words",
+			prefix:      "2001:db8::/32",
+			newLen:      48,
+			words:       []string{"cafe", "beef"},
+			group:       3,
+			budget:      2,
+			expectFirst: []string{"2001:db8:cafe::/48", "2001:db8:beef::/48"},
+			expectCount: 2,
+		},
+		{
+			name:        "short word right-aligned in group",
+			prefix:      "2001:db8::/48",
+			newLen:      64,
+			words:       []string{"bad"},
+			group:       4,
+			budget:      3,
+			expectFirst: []string{"2001:db8:0:bad::/64", "2001:db8:0:1bad::/64", "2001:db8:0:2bad::/64"},
+			expectCount: 3,
+		},
+		{
+			name:        "exhausts the search space before the budget",
+			prefix:      "2001:db8::/48",
+			newLen:      64,
+			words:       []string{"f00d"},
+			group:       4,
+			budget:      100,
+			expectFirst: []string{"2001:db8:0:f00d::/64"},
+			expectCount: 1,
+		},
+		{
+			name:        "group outside the subnet ID",
+			prefix:      "2001:db8::/48",
+			newLen:      64,
+			words:       []string{"cafe"},
+			group:       2,
+			budget:      10,
+			expectError: true,
+		},
+		{
+			name:        "word longer than the subnet ID",
+			prefix:      "2001:db8::/56",
+			newLen:      64,
+			words:       []string{"cafe"},
+			budget:      10,
+			expectError: true,
+		},
+		{
+			name:        "unspellable word",
+			prefix:      "2001:db8::/32",
+			newLen:      48,
+			words:       []string{"hello!"},
+			budget:      10,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := vanitySubnets(tc.prefix, tc.newLen, tc.words, tc.group, tc.budget)
+			if (err == nil) == tc.expectError {
+				t.Fatalf("expected error %v, got %v", tc.expectError, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(got) != tc.expectCount {
+				t.Errorf("expected %d subnets, got %d: %v", tc.expectCount, len(got), got)
+			}
+			for i, want := range tc.expectFirst {
+				if i >= len(got) || got[i] != want {
+					t.Errorf("expected subnet %d to be %s, got %v", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestVanityAddress(t *testing.T) {
+	cases := []struct {
+		name           string
+		prefix         string
+		text           string
+		ascii          bool
+		expect         string
+		expectReserved bool
+		expectError    bool
+	}{
+		{name: "hex groups", prefix: "2001:db8:1::/64", text: "cafe:f00d", expect: "2001:db8:1::cafe:f00d"},
+		{name: "right-aligned word", prefix: "2001:db8:1::/64", text: "c0ffee", expect: "2001:db8:1::c0:ffee"},
+		{name: "look-alike letters", prefix: "2001:db8:1::/64", text: "dead:beef:food", expect: "2001:db8:1::dead:beef:f00d"},
+		{name: "ASCII", prefix: "2001:db8:1::/64", text: "mail", ascii: true, expect: "2001:db8:1::6d61:696c"},
+		{name: "shorter prefix uses its first /64", prefix: "2001:db8::/48", text: "1", expect: "2001:db8::1"},
+		{name: "subnet-router anycast", prefix: "2001:db8:1::/64", text: "0", expect: "2001:db8:1::", expectReserved: true},
+		{name: "reserved subnet anycast", prefix: "2001:db8:1::/64", text: "fdff:ffff:ffff:ff80", expect: "2001:db8:1:0:fdff:ffff:ffff:ff80", expectReserved: true},
+		{name: "IANA ethernet block", prefix: "2001:db8:1::/64", text: "200:5eff:fe00:5213", expect: "2001:db8:1:0:200:5eff:fe00:5213", expectReserved: true},
+		{name: "too long", prefix: "2001:db8:1::/64", text: "0123456789abcdef0", expectError: true},
+		{name: "ASCII too long", prefix: "2001:db8:1::/64", text: "toolongname", ascii: true, expectError: true},
+		{name: "prefix longer than /64", prefix: "2001:db8:1::/80", text: "cafe", expectError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, reserved, err := vanityAddress(tc.prefix, tc.text, tc.ascii)
+			if (err == nil) == tc.expectError {
+				t.Fatalf("expected error %v, got %v", tc.expectError, err)
+			}
+			if err != nil {
+				return
+			}
+			if got != tc.expect {
+				t.Errorf("expected %s, got %s", tc.expect, got)
+			}
+			if (reserved != "") != tc.expectReserved {
+				t.Errorf("expected reserved %v, got %q", tc.expectReserved, reserved)
+			}
+		})
+	}
+}
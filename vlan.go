@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// vlanSubnetID maps a VLAN ID to a subnet ID. With the "decimal" encoding the
+// decimal digits are written as hex nibbles, so VLAN 120 becomes subnet :120:;
+// with "hex" the VLAN ID is used as is, so VLAN 120 becomes :78:.
+func vlanSubnetID(vlan int, encoding string) (uint64, error) {
+	if vlan < 1 || vlan > 4094 {
+		return 0, fmt.Errorf("VLAN %d out of range 1-4094", vlan)
+	}
+	switch encoding {
+	case "decimal":
+		return strconv.ParseUint(strconv.Itoa(vlan), 16, 64)
+	case "hex":
+		return uint64(vlan), nil
+	}
+	return 0, fmt.Errorf("unknown VLAN encoding %q (want decimal or hex)", encoding)
+}
+
+// vlanFromSubnetID reverses vlanSubnetID. In the decimal encoding a subnet ID
+// containing a-f nibbles does not belong to any VLAN.
+func vlanFromSubnetID(id uint64, encoding string) (int, error) {
+	var vlan uint64
+	switch encoding {
+	case "decimal":
+		var err error
+		if vlan, err = strconv.ParseUint(strconv.FormatUint(id, 16), 10, 64); err != nil {
+			return 0, fmt.Errorf("subnet ID %x is not a decimal-as-hex VLAN ID", id)
+		}
+	case "hex":
+		vlan = id
+	default:
+		return 0, fmt.Errorf("unknown VLAN encoding %q (want decimal or hex)", encoding)
+	}
+	if vlan < 1 || vlan > 4094 {
+		return 0, fmt.Errorf("subnet ID %x is VLAN %d, outside 1-4094", id, vlan)
+	}
+	return int(vlan), nil
+}
+
+// parseVLANList parses a comma-separated list of VLAN IDs and ranges, e.g. "10,20,100-110".
+func parseVLANList(s string) ([]int, error) {
+	var vlans []int
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VLAN %q", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("invalid VLAN range %q", part)
+			}
+		}
+		for v := first; v <= last; v++ {
+			vlans = append(vlans, v)
+		}
+	}
+	return vlans, nil
+}
+
+// vlanSubnet is a child prefix whose subnet ID carries a VLAN ID.
+type vlanSubnet struct {
+	VLAN int
+	Net  *net.IPNet
+}
+
+// vlanParent parses the parent prefix and checks that the subnet ID between it
+// and newPrefixLength is wide enough for the largest VLAN ID in the encoding.
+func vlanParent(prefix string, newPrefixLength int, encoding string) (*net.IPNet, error) {
+	ipnet, err := parseIPv6Prefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %v", err)
+	}
+	currentPrefixLength, _ := ipnet.Mask.Size()
+	if newPrefixLength <= currentPrefixLength || newPrefixLength > 128 {
+		return nil, fmt.Errorf("new prefix length must be larger than the current prefix length")
+	}
+	maxID, err := vlanSubnetID(4094, encoding)
+	if err != nil {
+		return nil, err
+	}
+	if need := big.NewInt(0).SetUint64(maxID).BitLen(); newPrefixLength-currentPrefixLength < need {
+		return nil, fmt.Errorf("a %s VLAN ID needs %d subnet-ID bits, but /%d to /%d has %d", encoding, need, currentPrefixLength, newPrefixLength, newPrefixLength-currentPrefixLength)
+	}
+	return ipnet, nil
+}
+
+// vlanSubnets builds the child of prefix for each VLAN, with the VLAN's subnet ID
+// right-aligned in the bits just above newPrefixLength.
+func vlanSubnets(prefix string, newPrefixLength int, vlans []int, encoding string) ([]vlanSubnet, error) {
+	ipnet, err := vlanParent(prefix, newPrefixLength, encoding)
+	if err != nil {
+		return nil, err
+	}
+	base := ipToBigInt(ipnet.IP)
+	mask := net.CIDRMask(newPrefixLength, 128)
+	subnets := make([]vlanSubnet, 0, len(vlans))
+	for _, vlan := range vlans {
+		id, err := vlanSubnetID(vlan, encoding)
+		if err != nil {
+			return nil, err
+		}
+		offset := new(big.Int).Lsh(new(big.Int).SetUint64(id), uint(128-newPrefixLength))
+		ip := bigIntToIP(new(big.Int).Or(base, offset))
+		subnets = append(subnets, vlanSubnet{VLAN: vlan, Net: &net.IPNet{IP: ip, Mask: mask}})
+	}
+	return subnets, nil
+}
+
+// decodeVLAN finds the VLAN an address or prefix inside the parent belongs to.
+func decodeVLAN(prefix string, newPrefixLength int, input string, encoding string) (vlanSubnet, error) {
+	ipnet, err := vlanParent(prefix, newPrefixLength, encoding)
+	if err != nil {
+		return vlanSubnet{}, err
+	}
+	ip, _, err := parseIPv6WithOptionalPrefix(input)
+	if err != nil {
+		return vlanSubnet{}, err
+	}
+	if !ipnet.Contains(ip) {
+		return vlanSubnet{}, fmt.Errorf("%s is not inside %s", input, ipnet)
+	}
+	currentPrefixLength, _ := ipnet.Mask.Size()
+	id := new(big.Int).Rsh(ipToBigInt(ip), uint(128-newPrefixLength))
+	id.And(id, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(newPrefixLength-currentPrefixLength)), big.NewInt(1)))
+	if !id.IsUint64() {
+		return vlanSubnet{}, fmt.Errorf("%s: subnet ID %x is not a VLAN ID", input, id)
+	}
+	vlan, err := vlanFromSubnetID(id.Uint64(), encoding)
+	if err != nil {
+		return vlanSubnet{}, fmt.Errorf("%s: %v", input, err)
+	}
+	mask := net.CIDRMask(newPrefixLength, 128)
+	return vlanSubnet{VLAN: vlan, Net: &net.IPNet{IP: ip.Mask(mask), Mask: mask}}, nil
+}
+
+// writeVLANSubnets prints the subnets as a prefix list labelled "vlanN".
+func writeVLANSubnets(w io.Writer, subnets []vlanSubnet) {
+	for _, s := range subnets {
+		fmt.Fprintf(w, "%-44s vlan%d\n", s.Net, s.VLAN)
+	}
+}
+
+// runVLANSubnets prints the VLAN subnets of a comma-separated VLAN list.
+func runVLANSubnets(prefix string, newPrefixLength int, list string, encoding string) {
+	vlans, err := parseVLANList(list)
+	if err != nil {
+		log.Fatal(err)
+	}
+	subnets, err := vlanSubnets(prefix, newPrefixLength, vlans, encoding)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeVLANSubnets(os.Stdout, subnets)
+}
+
+// runVLANDecode prints the VLAN of each comma-separated address or prefix.
+func runVLANDecode(prefix string, newPrefixLength int, inputs string, encoding string) {
+	for _, input := range strings.Split(inputs, ",") {
+		s, err := decodeVLAN(prefix, newPrefixLength, strings.TrimSpace(input), encoding)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%-40s VLAN %d (%s)\n", input, s.VLAN, s.Net)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVLANSubnetID(t *testing.T) {
+	cases := []struct {
+		vlan     int
+		encoding string
+		id       uint64
+	}{
+		{120, "decimal", 0x120},
+		{4094, "decimal", 0x4094},
+		{120, "hex", 0x78},
+		{1, "hex", 1},
+	}
+	for _, tc := range cases {
+		id, err := vlanSubnetID(tc.vlan, tc.encoding)
+		if err != nil || id != tc.id {
+			t.Errorf("vlanSubnetID(%d, %s) = %x, %v; want %x", tc.vlan, tc.encoding, id, err, tc.id)
+			continue
+		}
+		vlan, err := vlanFromSubnetID(id, tc.encoding)
+		if err != nil || vlan != tc.vlan {
+			t.Errorf("vlanFromSubnetID(%x, %s) = %d, %v; want %d", id, tc.encoding, vlan, err, tc.vlan)
+		}
+	}
+	for _, bad := range []int{0, 4095} {
+		if _, err := vlanSubnetID(bad, "decimal"); err == nil {
+			t.Errorf("expected error for VLAN %d", bad)
+		}
+	}
+	if _, err := vlanFromSubnetID(0x12a, "decimal"); err == nil {
+		t.Error("expected error for subnet ID with a-f nibbles in decimal encoding")
+	}
+}
+
+func TestParseVLANList(t *testing.T) {
+	vlans, err := parseVLANList("10, 20,100-102")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(vlans); got != 5 || vlans[4] != 102 {
+		t.Errorf("got %v", vlans)
+	}
+	for _, bad := range []string{"x", "10-5", "10-"} {
+		if _, err := parseVLANList(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestVLANSubnets(t *testing.T) {
+	cases := []struct {
+		prefix   string
+		newLen   int
+		encoding string
+		want     string
+		wantErr  string
+	}{
+		{"2001:db8::/48", 64, "decimal", "2001:db8:0:120::/64", ""},
+		{"2001:db8::/48", 64, "hex", "2001:db8:0:78::/64", ""},
+		{"2001:db8::/44", 60, "decimal", "2001:db8:0:1200::/60", ""},
+		{"2001:db8::/52", 64, "hex", "2001:db8:0:78::/64", ""},
+		{"2001:db8::/52", 64, "decimal", "", "needs 15 subnet-ID bits"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.prefix+"/"+tc.encoding, func(t *testing.T) {
+			subnets, err := vlanSubnets(tc.prefix, tc.newLen, []int{120}, tc.encoding)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := subnets[0].Net.String(); got != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+			s, err := decodeVLAN(tc.prefix, tc.newLen, tc.want, tc.encoding)
+			if err != nil || s.VLAN != 120 {
+				t.Errorf("decodeVLAN(%s) = %d, %v; want 120", tc.want, s.VLAN, err)
+			}
+		})
+	}
+}
+
+func TestDecodeVLANOutsideParent(t *testing.T) {
+	if _, err := decodeVLAN("2001:db8::/48", 64, "2001:db9:0:120::1", "decimal"); err == nil || !strings.Contains(err.Error(), "not inside") {
+		t.Errorf("expected not-inside error, got %v", err)
+	}
+}
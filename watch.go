@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileStamp is what -watch compares to notice that a file was edited.
+type fileStamp struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// watchedFiles returns the input files named by flag values: every value, or
+// comma-separated part of one, that is an existing regular file. The output file
+// and its manifest are left out so writing them does not trigger another run.
+func watchedFiles(flags map[string]string, outputFile string) []string {
+	skip := map[string]bool{}
+	if outputFile != "" {
+		skip[filepath.Clean(outputFile)] = true
+		skip[filepath.Clean(manifestPath(outputFile))] = true
+	}
+	seen := map[string]bool{}
+	var files []string
+	for _, value := range flags {
+		for _, part := range strings.Split(value, ",") {
+			path := filepath.Clean(strings.TrimSpace(part))
+			if part == "" || seen[path] || skip[path] {
+				continue
+			}
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				seen[path] = true
+				files = append(files, path)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// fileStamps records the modification time and size of each file. A file that
+// cannot be read (e.g. mid-save by an editor) gets a zero stamp.
+func fileStamps(files []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(files))
+	for _, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fileStamp{ModTime: info.ModTime(), Size: info.Size()}
+		} else {
+			stamps[path] = fileStamp{}
+		}
+	}
+	return stamps
+}
+
+// changedFiles lists the files whose stamps differ between two polls.
+func changedFiles(before, after map[string]fileStamp) []string {
+	var changed []string
+	for path, stamp := range after {
+		if prev := before[path]; !prev.ModTime.Equal(stamp.ModTime) || prev.Size != stamp.Size {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// watchArgs removes -watch and -watch-interval from the command line so each
+// regeneration runs the command once.
+func watchArgs(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") {
+			out = append(out, args[i])
+			continue
+		}
+		switch name {
+		case "watch":
+		case "watch-interval":
+			if !hasValue {
+				i++
+			}
+		default:
+			out = append(out, args[i])
+		}
+	}
+	return out
+}
+
+// runWatch reruns the current command whenever one of the input files named by
+// its parameters changes, until interrupted. A failed run is reported and watching continues.
+func runWatch(parameters map[string]string, outputFile string, interval time.Duration) {
+	files := watchedFiles(parameters, outputFile)
+	if len(files) == 0 {
+		log.Fatal("-watch found no input files among the given flags and arguments")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	args := watchArgs(os.Args[1:])
+	regenerate := func() {
+		cmd := exec.Command(exe, args...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Printf("regeneration failed: %v", err)
+		}
+	}
+
+	statusf("Watching %s\n", strings.Join(files, ", "))
+	stamps := fileStamps(files)
+	regenerate()
+	for {
+		time.Sleep(interval)
+		current := fileStamps(files)
+		if changed := changedFiles(stamps, current); len(changed) > 0 {
+			statusf("%s changed, regenerating\n", strings.Join(changed, ", "))
+			stamps = current
+			regenerate()
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchedFiles(t *testing.T) {
+	dir := t.TempDir()
+	plan := filepath.Join(dir, "plan.txt")
+	hosts := filepath.Join(dir, "hosts.txt")
+	out := filepath.Join(dir, "out.txt")
+	for _, path := range []string{plan, hosts, out, manifestPath(out)} {
+		if err := os.WriteFile(path, []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flags := map[string]string{
+		"plan":   plan,
+		"merge":  plan + "," + hosts,
+		"export": "ios",
+		"p":      "3fff::/32",
+		"o":      out,
+		"zone":   dir, // directories are not watched
+	}
+	got := watchedFiles(flags, out)
+	if want := []string{hosts, plan}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	now := time.Now()
+	before := map[string]fileStamp{"a": {now, 10}, "b": {now, 10}, "c": {now, 10}}
+	after := map[string]fileStamp{"a": {now, 10}, "b": {now.Add(time.Second), 10}, "c": {now, 11}}
+	if got := changedFiles(before, after); strings.Join(got, ",") != "b,c" {
+		t.Errorf("expected b,c, got %v", got)
+	}
+}
+
+func TestWatchArgs(t *testing.T) {
+	cases := []struct {
+		args string
+		want string
+	}{
+		{"-plan p.txt -export ios -watch", "-plan p.txt -export ios"},
+		{"--watch -watch-interval 5s -p 3fff::/32", "-p 3fff::/32"},
+		{"-watch=true -watch-interval=2s -stats hosts.txt", "-stats hosts.txt"},
+	}
+	for _, tc := range cases {
+		if got := strings.Join(watchArgs(strings.Fields(tc.args)), " "); got != tc.want {
+			t.Errorf("watchArgs(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// SPDX-License-Identifier: BSD-3-Clause-LBNL
+// Copyright (C) buraglio@forwardingplane.net
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// xlsxMaxRows is the most rows an Excel worksheet holds, header included.
+const xlsxMaxRows = 1048576
+
+// parseSubnetLevels checks a comma-separated -levels list: the intermediate
+// prefix lengths, in increasing order, between the parent and the new length.
+func parseSubnetLevels(list string, parentLen, newLen int) ([]int, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var levels []int
+	prev := parentLen
+	for _, f := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(f), "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid level %q", f)
+		}
+		if n <= prev || n >= newLen {
+			return nil, fmt.Errorf("levels must increase from /%d and stay shorter than /%d, not /%d", parentLen, newLen, n)
+		}
+		levels = append(levels, n)
+		prev = n
+	}
+	return levels, nil
+}
+
+// xlsxSheet is a worksheet of the subnets of one prefix length.
+type xlsxSheet struct {
+	name   string
+	length int
+	rows   []subnetRecord
+	last   netip.Prefix // the subnet most recently added, to skip repeats
+}
+
+// xlsxSubnetWriter writes an Excel workbook with one sheet per level of the
+// plan: one for each -levels length and one for the generated subnets, each
+// subnet listed under its parent in the level above, plus a summary sheet.
+// Workbooks are zip files written in one piece, so the rows are held until
+// Close.
+type xlsxSubnetWriter struct {
+	w      *bufio.Writer
+	layout subnetLayout
+	sheets []*xlsxSheet
+}
+
+func newXLSXSubnetWriter(w *bufio.Writer, layout subnetLayout) *xlsxSubnetWriter {
+	x := &xlsxSubnetWriter{w: w, layout: layout}
+	for i, l := range append(slices.Clone(layout.Levels), layout.NewLength) {
+		name := fmt.Sprintf("Length %d", l)
+		if i < len(layout.LevelNames) {
+			name = xlsxSheetName(layout.LevelNames[i])
+		}
+		x.sheets = append(x.sheets, &xlsxSheet{name: name, length: l})
+	}
+	return x
+}
+
+// Write adds r and the subnets holding it on the levels above to their sheets.
+// Records of a level's own length, as in an address plan, fill that sheet
+// directly.
+func (x *xlsxSubnetWriter) Write(r subnetRecord) error {
+	parent := x.layout.Parent
+	for _, s := range x.sheets {
+		if s.length > r.Prefix.Bits() {
+			break
+		}
+		p := netip.PrefixFrom(r.Prefix.Addr(), s.length).Masked()
+		if p != s.last {
+			if len(s.rows)+1 >= xlsxMaxRows {
+				return fmt.Errorf("more than %d /%d subnets for one xlsx sheet; use -l or a shorter -n", xlsxMaxRows-1, s.length)
+			}
+			level := subnetRecords(parent, nil)(p)
+			if p == r.Prefix {
+				level.Reserved, level.Label = r.Reserved, r.Label
+			}
+			s.rows = append(s.rows, level)
+			s.last = p
+		}
+		parent = p
+	}
+	return nil
+}
+
+func (x *xlsxSubnetWriter) Close() error {
+	zw := zip.NewWriter(x.w)
+	names := []string{"Summary"}
+	for _, s := range x.sheets {
+		names = append(names, s.name)
+	}
+	files := []struct {
+		name string
+		data string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes(len(names))},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook(names)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(names))},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, f := range files {
+		if err := xlsxWriteFile(zw, f.name, f.data); err != nil {
+			return err
+		}
+	}
+	if err := xlsxWriteFile(zw, "xl/worksheets/sheet1.xml", x.summarySheet()); err != nil {
+		return err
+	}
+	for i, s := range x.sheets {
+		if err := xlsxWriteFile(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+2), x.subnetSheet(s)); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return x.w.Flush()
+}
+
+// summarySheet lists the parent and, for each level, its sheet, the subnets
+// on it, and how many fit in each parent of the level above.
+func (x *xlsxSubnetWriter) summarySheet() string {
+	var rows [][]xlsxCell
+	rows = append(rows,
+		[]xlsxCell{xlsxString("Parent"), xlsxString(x.layout.Parent.String())},
+		nil,
+		xlsxHeader("Sheet", "Length", "Subnets", "Per parent", "Nibble aligned"))
+	prev := x.layout.Parent.Bits()
+	for _, s := range x.sheets {
+		perParent := new(big.Int).Lsh(big.NewInt(1), uint(s.length-prev))
+		rows = append(rows, []xlsxCell{
+			xlsxString(s.name),
+			xlsxNumber(strconv.Itoa(s.length)),
+			xlsxNumber(strconv.Itoa(len(s.rows))),
+			xlsxNumber(perParent.String()),
+			xlsxBool(isNibbleAligned(s.length)),
+		})
+		prev = s.length
+	}
+	return xlsxWorksheet(rows, []float64{18, 44, 10, 22, 16}, false)
+}
+
+// subnetSheet lists the subnets of one level with the -columns.
+func (x *xlsxSubnetWriter) subnetSheet(s *xlsxSheet) string {
+	rows := [][]xlsxCell{xlsxHeader(x.layout.Columns...)}
+	widths := make([]float64, len(x.layout.Columns))
+	for i, name := range x.layout.Columns {
+		widths[i] = 14
+		switch name {
+		case "prefix", "network", "parent", "last", "gateway":
+			widths[i] = 44
+		}
+	}
+	for _, r := range s.rows {
+		row := make([]xlsxCell, len(x.layout.Columns))
+		for i, name := range x.layout.Columns {
+			v := subnetColumns[name](r)
+			switch name {
+			case "index", "length":
+				row[i] = xlsxNumber(v)
+			case "nibble_aligned", "reserved":
+				row[i] = xlsxBool(v == "true")
+			default:
+				row[i] = xlsxString(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return xlsxWorksheet(rows, widths, true)
+}
+
+// xlsxSheetName makes a level name a valid sheet name: at most 31 characters,
+// none of them ones Excel reserves.
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
+
+// xlsxCell is one worksheet cell: an inline string, a number, or a boolean,
+// optionally bold.
+type xlsxCell struct {
+	kind  byte // 's', 'n', or 'b'
+	value string
+	bold  bool
+}
+
+func xlsxString(s string) xlsxCell { return xlsxCell{kind: 's', value: s} }
+
+// xlsxNumber is a numeric cell, or a string one for integers past what a
+// spreadsheet's floating point holds exactly.
+func xlsxNumber(s string) xlsxCell {
+	if len(s) > 15 {
+		return xlsxString(s)
+	}
+	return xlsxCell{kind: 'n', value: s}
+}
+
+func xlsxBool(b bool) xlsxCell {
+	if b {
+		return xlsxCell{kind: 'b', value: "1"}
+	}
+	return xlsxCell{kind: 'b', value: "0"}
+}
+
+// xlsxHeader is a bold header row.
+func xlsxHeader(names ...string) []xlsxCell {
+	row := make([]xlsxCell, len(names))
+	for i, n := range names {
+		row[i] = xlsxCell{kind: 's', value: n, bold: true}
+	}
+	return row
+}
+
+// xlsxColumn is the letter name of the 0-based column i: A, B, ... Z, AA, ...
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxWorksheet renders the rows as worksheet XML with the given column widths,
+// freezing the first row when frozenHeader is set.
+func xlsxWorksheet(rows [][]xlsxCell, widths []float64, frozenHeader bool) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if frozenHeader {
+		sb.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	if len(widths) > 0 {
+		sb.WriteString("<cols>")
+		for i, w := range widths {
+			fmt.Fprintf(&sb, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, w)
+		}
+		sb.WriteString("</cols>")
+	}
+	sb.WriteString("<sheetData>")
+	for r, row := range rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := xlsxColumn(c) + strconv.Itoa(r+1)
+			style := ""
+			if cell.bold {
+				style = ` s="1"`
+			}
+			switch cell.kind {
+			case 'n':
+				fmt.Fprintf(&sb, `<c r="%s"%s><v>%s</v></c>`, ref, style, cell.value)
+			case 'b':
+				fmt.Fprintf(&sb, `<c r="%s"%s t="b"><v>%s</v></c>`, ref, style, cell.value)
+			default:
+				fmt.Fprintf(&sb, `<c r="%s"%s t="inlineStr"><is><t>%s</t></is></c>`, ref, style, xmlEscape(cell.value))
+			}
+		}
+		sb.WriteString("</row>")
+	}
+	sb.WriteString("</sheetData></worksheet>")
+	return sb.String()
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+func xlsxWriteFile(zw *zip.Writer, name, data string) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, data)
+	return err
+}
+
+func xlsxContentTypes(sheets int) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&sb, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	sb.WriteString("</Types>")
+	return sb.String()
+}
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+func xlsxWorkbook(names []string) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range names {
+		fmt.Fprintf(&sb, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(name), i+1, i+1)
+	}
+	sb.WriteString("</sheets></workbook>")
+	return sb.String()
+}
+
+// xlsxWorkbookRels links the workbook to its sheets, rId1 onwards, and to the
+// styles after them.
+func xlsxWorkbookRels(sheets int) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1)
+	sb.WriteString("</Relationships>")
+	return sb.String()
+}
+
+// xlsxStyles defines the default cell style and, as style 1, bold for headers.
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSubnetLevels(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []int
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"48,56", []int{48, 56}, false},
+		{"/48, /56", []int{48, 56}, false},
+		{"56,48", nil, true},
+		{"32", nil, true},
+		{"64", nil, true},
+		{"x", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSubnetLevels(tt.list, 32, 64)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("parseSubnetLevels(%q) = %v, %v", tt.list, got, err)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestXLSXSubnetWriter(t *testing.T) {
+	parent := netip.MustParsePrefix("2001:db8::/46")
+	layout := subnetLayout{Parent: parent, NewLength: 56, Columns: []string{"index", "prefix", "parent", "name"}, Levels: []int{48}}
+	reserved, _ := reservedSubnets("2001:db8::/46", 56, 1, 0)
+	record := subnetRecords(parent, reserved)
+
+	var buf bytes.Buffer
+	w := newXLSXSubnetWriter(bufio.NewWriter(&buf), layout)
+	for p := range limitSubnets(subnetSeq(parent, 56), 300) {
+		if err := w.Write(record(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	for _, want := range []string{`name="Summary"`, `name="Length 48"`, `name="Length 56"`} {
+		if !strings.Contains(files["xl/workbook.xml"], want) {
+			t.Errorf("workbook missing sheet %s", want)
+		}
+	}
+	// 300 /56s span two /48s.
+	if got := strings.Count(files["xl/worksheets/sheet2.xml"], "<row "); got != 3 {
+		t.Errorf("/48 sheet has %d rows, want 3", got)
+	}
+	if got := strings.Count(files["xl/worksheets/sheet3.xml"], "<row "); got != 301 {
+		t.Errorf("/56 sheet has %d rows, want 301", got)
+	}
+	for _, want := range []string{
+		// The 257th /56 is the first of the second /48, indexed within it.
+		`<row r="258"><c r="A258"><v>0</v></c><c r="B258" t="inlineStr"><is><t>2001:db8:1::/56</t></is></c><c r="C258" t="inlineStr"><is><t>2001:db8:1::/48</t></is></c>`,
+		`<t>RESERVED</t>`,
+	} {
+		if !strings.Contains(files["xl/worksheets/sheet3.xml"], want) {
+			t.Errorf("/56 sheet missing %s", want)
+		}
+	}
+	if want := `<c r="C5"><v>300</v></c>`; !strings.Contains(files["xl/worksheets/sheet1.xml"], want) {
+		t.Errorf("summary missing %s", want)
+	}
+}